@@ -44,9 +44,12 @@ func batchGetBlockAttrs(c *gin.Context) {
 	}
 
 	ids := arg["ids"].([]interface{})
+	access := model.GetPublishAccess(c)
 	var idList []string
 	for _, id := range ids {
-		idList = append(idList, id.(string))
+		if access.IsBlockAllowed(id.(string)) {
+			idList = append(idList, id.(string))
+		}
 	}
 
 	ret.Data = sql.BatchGetBlockAttrs(idList)
@@ -66,6 +69,11 @@ func getBlockAttrs(c *gin.Context) {
 		return
 	}
 
+	if !model.GetPublishAccess(c).IsBlockAllowed(id) {
+		ret.Data = map[string]string{}
+		return
+	}
+
 	ret.Data = sql.GetBlockAttrs(id)
 }
 
@@ -105,6 +113,7 @@ func setBlockAttrs(c *gin.Context) {
 		ret.Msg = err.Error()
 		return
 	}
+	model.RecordBlockEditors(c, id)
 }
 
 func batchSetBlockAttrs(c *gin.Context) {
@@ -118,6 +127,7 @@ func batchSetBlockAttrs(c *gin.Context) {
 
 	blockAttrsArg := arg["blockAttrs"].([]interface{})
 	var blockAttrs []map[string]interface{}
+	var ids []string
 	for _, blockAttrArg := range blockAttrsArg {
 		blockAttr := blockAttrArg.(map[string]interface{})
 		id := blockAttr["id"].(string)
@@ -139,6 +149,7 @@ func batchSetBlockAttrs(c *gin.Context) {
 			"id":    id,
 			"attrs": nameValues,
 		})
+		ids = append(ids, id)
 	}
 
 	err := model.BatchSetBlockAttrs(blockAttrs)
@@ -147,6 +158,7 @@ func batchSetBlockAttrs(c *gin.Context) {
 		ret.Msg = err.Error()
 		return
 	}
+	model.RecordBlockEditors(c, ids...)
 }
 
 func resetBlockAttrs(c *gin.Context) {
@@ -170,4 +182,5 @@ func resetBlockAttrs(c *gin.Context) {
 		ret.Msg = err.Error()
 		return
 	}
+	model.RecordBlockEditors(c, id)
 }
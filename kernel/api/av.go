@@ -17,10 +17,14 @@
 package api
 
 import (
+	"io"
 	"net/http"
+	"os"
+	"path/filepath"
 
 	"github.com/88250/gulu"
 	"github.com/gin-gonic/gin"
+	"github.com/siyuan-note/logging"
 	"github.com/siyuan-note/siyuan/kernel/av"
 	"github.com/siyuan-note/siyuan/kernel/model"
 	"github.com/siyuan-note/siyuan/kernel/treenode"
@@ -50,6 +54,102 @@ func duplicateAttributeViewBlock(c *gin.Context) {
 	}
 }
 
+func importAttributeView(c *gin.Context) {
+	ret := gulu.Ret.NewResult()
+	defer c.JSON(http.StatusOK, ret)
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		logging.LogErrorf("parse import attribute view failed: %s", err)
+		ret.Code = -1
+		ret.Msg = err.Error()
+		return
+	}
+
+	files := form.File["file"]
+	if 1 > len(files) {
+		logging.LogErrorf("parse import attribute view failed, no file found")
+		ret.Code = -1
+		ret.Msg = "no file found"
+		return
+	}
+	file := files[0]
+	reader, err := file.Open()
+	if err != nil {
+		logging.LogErrorf("read import attribute view failed: %s", err)
+		ret.Code = -1
+		ret.Msg = err.Error()
+		return
+	}
+	defer reader.Close()
+
+	importDir := filepath.Join(util.TempDir, "import")
+	if err = os.MkdirAll(importDir, 0755); err != nil {
+		logging.LogErrorf("make import dir [%s] failed: %s", importDir, err)
+		ret.Code = -1
+		ret.Msg = err.Error()
+		return
+	}
+	// 每次导入使用单独的临时目录，避免同时导入同名文件时互相覆盖
+	tmpDir, err := os.MkdirTemp(importDir, "av-")
+	if err != nil {
+		logging.LogErrorf("make import temp dir failed: %s", err)
+		ret.Code = -1
+		ret.Msg = err.Error()
+		return
+	}
+	defer os.RemoveAll(tmpDir)
+	writePath := filepath.Join(tmpDir, filepath.Base(file.Filename))
+	writer, err := os.OpenFile(writePath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		logging.LogErrorf("open import attribute view [%s] failed: %s", writePath, err)
+		ret.Code = -1
+		ret.Msg = err.Error()
+		return
+	}
+	if _, err = io.Copy(writer, reader); err != nil {
+		writer.Close()
+		logging.LogErrorf("write import attribute view failed: %s", err)
+		ret.Code = -1
+		ret.Msg = err.Error()
+		return
+	}
+	writer.Close()
+
+	formValue := func(name string) string {
+		if values := form.Value[name]; 0 < len(values) {
+			return values[0]
+		}
+		return ""
+	}
+
+	// mapping 为表头到字段 ID 的映射 JSON，映射为空字符串表示跳过该列
+	mapping := map[string]string{}
+	if mappingArg := formValue("mapping"); "" != mappingArg {
+		if err = gulu.JSON.UnmarshalJSON([]byte(mappingArg), &mapping); err != nil {
+			ret.Code = -1
+			ret.Msg = err.Error()
+			return
+		}
+	}
+
+	avID := formValue("avID")
+	primaryCol := formValue("primaryCol")
+	bindBlock := "true" == formValue("bindBlock")
+	newAvID, blockID, count, err := model.ImportAttributeView(writePath, avID, mapping, primaryCol, bindBlock)
+	if err != nil {
+		ret.Code = -1
+		ret.Msg = err.Error()
+		return
+	}
+
+	ret.Data = map[string]interface{}{
+		"avID":    newAvID,
+		"blockID": blockID,
+		"count":   count,
+	}
+}
+
 func getAttributeViewKeysByAvID(c *gin.Context) {
 	ret := gulu.Ret.NewResult()
 	defer c.JSON(http.StatusOK, ret)
@@ -93,6 +193,7 @@ func setDatabaseBlockView(c *gin.Context) {
 		ret.Msg = err.Error()
 		return
 	}
+	model.RecordBlockEditors(c, blockID)
 }
 
 func getAttributeViewPrimaryKeyValues(c *gin.Context) {
@@ -211,6 +312,11 @@ func addAttributeViewBlocks(c *gin.Context) {
 		ret.Msg = err.Error()
 		return
 	}
+	for _, src := range srcs {
+		if id, _ := src["id"].(string); "" != id {
+			model.RecordBlockEditors(c, id)
+		}
+	}
 
 	model.ReloadAttrView(avID)
 }
@@ -236,6 +342,7 @@ func removeAttributeViewBlocks(c *gin.Context) {
 		ret.Msg = err.Error()
 		return
 	}
+	model.RecordBlockEditors(c, srcIDs...)
 
 	model.ReloadAttrView(avID)
 }
@@ -558,10 +665,69 @@ func renderAttributeView(c *gin.Context) {
 		return
 	}
 
-	var views []map[string]interface{}
+	ret.Data = map[string]interface{}{
+		"name":     attrView.Name,
+		"id":       attrView.ID,
+		"viewType": view.GetType(),
+		"viewID":   view.GetID(),
+		"views":    getAttrViewViews(attrView),
+		"view":     view,
+		"isMirror": av.IsMirror(attrView.ID),
+	}
+}
+
+func renderAttributeViewCalendar(c *gin.Context) {
+	ret := gulu.Ret.NewResult()
+	defer c.JSON(http.StatusOK, ret)
+
+	arg, ok := util.JsonArg(c, ret)
+	if !ok {
+		return
+	}
+
+	id := arg["id"].(string)
+	viewIDArg := arg["viewID"]
+	var viewID string
+	if nil != viewIDArg {
+		viewID = viewIDArg.(string)
+	}
+
+	var start, end int64
+	if startArg := arg["start"]; nil != startArg {
+		start = int64(startArg.(float64))
+	}
+	if endArg := arg["end"]; nil != endArg {
+		end = int64(endArg.(float64))
+	}
+
+	query := ""
+	queryArg := arg["query"]
+	if nil != queryArg {
+		query = queryArg.(string)
+	}
+
+	view, attrView, err := model.RenderAttributeViewCalendar(id, viewID, query, start, end)
+	if err != nil {
+		ret.Code = -1
+		ret.Msg = err.Error()
+		return
+	}
+
+	ret.Data = map[string]interface{}{
+		"name":     attrView.Name,
+		"id":       attrView.ID,
+		"viewType": view.GetType(),
+		"viewID":   view.GetID(),
+		"views":    getAttrViewViews(attrView),
+		"view":     view,
+		"isMirror": av.IsMirror(attrView.ID),
+	}
+}
+
+func getAttrViewViews(attrView *av.AttributeView) (ret []map[string]interface{}) {
 	for _, v := range attrView.Views {
 		pSize := 10
-		if nil != v.Table && av.LayoutTypeTable == v.LayoutType {
+		if nil != v.Table {
 			pSize = v.Table.PageSize
 		}
 
@@ -575,18 +741,9 @@ func renderAttributeView(c *gin.Context) {
 			"pageSize":         pSize,
 		}
 
-		views = append(views, view)
-	}
-
-	ret.Data = map[string]interface{}{
-		"name":     attrView.Name,
-		"id":       attrView.ID,
-		"viewType": view.GetType(),
-		"viewID":   view.GetID(),
-		"views":    views,
-		"view":     view,
-		"isMirror": av.IsMirror(attrView.ID),
+		ret = append(ret, view)
 	}
+	return
 }
 
 func getAttributeViewKeys(c *gin.Context) {
@@ -622,6 +779,7 @@ func setAttributeViewBlockAttr(c *gin.Context) {
 		ret.Msg = err.Error()
 		return
 	}
+	model.RecordBlockEditors(c, rowID)
 
 	ret.Data = map[string]interface{}{
 		"value": updatedVal,
@@ -141,6 +141,11 @@ func getHeadingChildrenIDs(c *gin.Context) {
 	}
 
 	id := arg["id"].(string)
+	if !model.GetPublishAccess(c).IsBlockAllowed(id) {
+		ret.Data = []string{}
+		return
+	}
+
 	ids := model.GetHeadingChildrenIDs(id)
 	ret.Data = ids
 }
@@ -155,6 +160,11 @@ func getHeadingChildrenDOM(c *gin.Context) {
 	}
 
 	id := arg["id"].(string)
+	if !model.GetPublishAccess(c).IsBlockAllowed(id) {
+		ret.Data = ""
+		return
+	}
+
 	dom := model.GetHeadingChildrenDOM(id)
 	ret.Data = dom
 }
@@ -270,6 +280,12 @@ func getDocInfo(c *gin.Context) {
 	}
 
 	id := arg["id"].(string)
+	if !model.GetPublishAccess(c).IsBlockAllowed(id) {
+		ret.Code = -1
+		ret.Msg = fmt.Sprintf(model.Conf.Language(15), id)
+		return
+	}
+
 	info := model.GetDocInfo(id)
 	if nil == info {
 		ret.Code = -1
@@ -289,8 +305,11 @@ func getDocsInfo(c *gin.Context) {
 	}
 	idsArg := arg["ids"].([]interface{})
 	var ids []string
+	access := model.GetPublishAccess(c)
 	for _, id := range idsArg {
-		ids = append(ids, id.(string))
+		if access.IsBlockAllowed(id.(string)) {
+			ids = append(ids, id.(string))
+		}
 	}
 	queryRefCount := arg["refCount"].(bool)
 	queryAv := arg["av"].(bool)
@@ -308,7 +327,7 @@ func getRecentUpdatedBlocks(c *gin.Context) {
 	defer c.JSON(http.StatusOK, ret)
 
 	blocks := model.RecentUpdatedBlocks()
-	ret.Data = blocks
+	ret.Data = model.FilterPublishBlocks(c, blocks)
 }
 
 func getContentWordCount(c *gin.Context) {
@@ -381,7 +400,10 @@ func getRefText(c *gin.Context) {
 		return
 	}
 
-	refText := model.GetBlockRefText(id)
+	var refText string
+	if model.GetPublishAccess(c).IsBlockAllowed(id) {
+		refText = model.GetBlockRefText(id)
+	}
 	if "" == refText {
 		// 空块返回 id https://github.com/siyuan-note/siyuan/issues/10259
 		refText = id
@@ -476,6 +498,12 @@ func getBlockBreadcrumb(c *gin.Context) {
 		}
 	}
 
+	if !model.GetPublishAccess(c).IsBlockAllowed(id) {
+		ret.Code = -1
+		ret.Msg = fmt.Sprintf(model.Conf.Language(15), id)
+		return
+	}
+
 	blockPath, err := model.BuildBlockBreadcrumb(id, excludeTypes)
 	if err != nil {
 		ret.Code = -1
@@ -528,6 +556,11 @@ func getBlockInfo(c *gin.Context) {
 	}
 
 	id := arg["id"].(string)
+	if !model.GetPublishAccess(c).IsBlockAllowed(id) {
+		ret.Code = -1
+		ret.Msg = fmt.Sprintf(model.Conf.Language(15), id)
+		return
+	}
 
 	// 仅在此处使用带重建索引的加载函数，其他地方不要使用
 	tree, err := model.LoadTreeByBlockIDWithReindex(id)
@@ -586,7 +619,10 @@ func getBlockDOM(c *gin.Context) {
 	}
 
 	id := arg["id"].(string)
-	dom := model.GetBlockDOM(id)
+	var dom string
+	if model.GetPublishAccess(c).IsBlockAllowed(id) {
+		dom = model.GetBlockDOM(id)
+	}
 	ret.Data = map[string]string{
 		"id":  id,
 		"dom": dom,
@@ -620,7 +656,10 @@ func getBlockKramdown(c *gin.Context) {
 		}
 	}
 
-	kramdown := model.GetBlockKramdown(id, mode)
+	var kramdown string
+	if model.GetPublishAccess(c).IsBlockAllowed(id) {
+		kramdown = model.GetBlockKramdown(id, mode)
+	}
 	ret.Data = map[string]string{
 		"id":       id,
 		"kramdown": kramdown,
@@ -641,6 +680,11 @@ func getChildBlocks(c *gin.Context) {
 		return
 	}
 
+	if !model.GetPublishAccess(c).IsBlockAllowed(id) {
+		ret.Data = []*model.ChildBlock{}
+		return
+	}
+
 	ret.Data = model.GetChildBlocks(id)
 }
 
@@ -667,5 +711,10 @@ func getTailChildBlocks(c *gin.Context) {
 		n = 7
 	}
 
+	if !model.GetPublishAccess(c).IsBlockAllowed(id) {
+		ret.Data = []*model.ChildBlock{}
+		return
+	}
+
 	ret.Data = model.GetTailChildBlocks(id, n)
 }
@@ -60,6 +60,7 @@ func moveOutlineHeading(c *gin.Context) {
 
 	transactions := []*model.Transaction{
 		{
+			Username: model.GetGinContextUsername(c),
 			DoOperations: []*model.Operation{
 				{
 					Action:     "moveOutlineHeading",
@@ -114,6 +115,7 @@ func appendDailyNoteBlock(c *gin.Context) {
 	parentID := util.GetTreeID(p)
 	transactions := []*model.Transaction{
 		{
+			Username: model.GetGinContextUsername(c),
 			DoOperations: []*model.Operation{
 				{
 					Action:   "appendInsert",
@@ -167,6 +169,7 @@ func prependDailyNoteBlock(c *gin.Context) {
 	parentID := util.GetTreeID(p)
 	transactions := []*model.Transaction{
 		{
+			Username: model.GetGinContextUsername(c),
 			DoOperations: []*model.Operation{
 				{
 					Action:   "prependInsert",
@@ -215,6 +218,7 @@ func unfoldBlock(c *gin.Context) {
 	if "h" == bt.Type {
 		transactions = []*model.Transaction{
 			{
+				Username: model.GetGinContextUsername(c),
 				DoOperations: []*model.Operation{
 					{
 						Action: "unfoldHeading",
@@ -227,6 +231,7 @@ func unfoldBlock(c *gin.Context) {
 		data, _ := gulu.JSON.MarshalJSON(map[string]interface{}{"fold": ""})
 		transactions = []*model.Transaction{
 			{
+				Username: model.GetGinContextUsername(c),
 				DoOperations: []*model.Operation{
 					{
 						Action: "setAttrs",
@@ -275,6 +280,7 @@ func foldBlock(c *gin.Context) {
 	if "h" == bt.Type {
 		transactions = []*model.Transaction{
 			{
+				Username: model.GetGinContextUsername(c),
 				DoOperations: []*model.Operation{
 					{
 						Action: "foldHeading",
@@ -287,6 +293,7 @@ func foldBlock(c *gin.Context) {
 		data, _ := gulu.JSON.MarshalJSON(map[string]interface{}{"fold": "1"})
 		transactions = []*model.Transaction{
 			{
+				Username: model.GetGinContextUsername(c),
 				DoOperations: []*model.Operation{
 					{
 						Action: "setAttrs",
@@ -341,6 +348,7 @@ func moveBlock(c *gin.Context) {
 
 	transactions := []*model.Transaction{
 		{
+			Username: model.GetGinContextUsername(c),
 			DoOperations: []*model.Operation{
 				{
 					Action:     "move",
@@ -387,6 +395,7 @@ func appendBlock(c *gin.Context) {
 
 	transactions := []*model.Transaction{
 		{
+			Username: model.GetGinContextUsername(c),
 			DoOperations: []*model.Operation{
 				{
 					Action:   "appendInsert",
@@ -432,6 +441,7 @@ func prependBlock(c *gin.Context) {
 
 	transactions := []*model.Transaction{
 		{
+			Username: model.GetGinContextUsername(c),
 			DoOperations: []*model.Operation{
 				{
 					Action:   "prependInsert",
@@ -493,6 +503,7 @@ func insertBlock(c *gin.Context) {
 
 	transactions := []*model.Transaction{
 		{
+			Username: model.GetGinContextUsername(c),
 			DoOperations: []*model.Operation{
 				{
 					Action:     "insert",
@@ -585,6 +596,7 @@ func updateBlock(c *gin.Context) {
 		data = luteEngine.Tree2BlockDOM(tree, luteEngine.RenderOptions)
 		transactions = []*model.Transaction{
 			{
+				Username: model.GetGinContextUsername(c),
 				DoOperations: []*model.Operation{
 					{
 						Action: "update",
@@ -619,6 +631,7 @@ func deleteBlock(c *gin.Context) {
 
 	transactions := []*model.Transaction{
 		{
+			Username: model.GetGinContextUsername(c),
 			DoOperations: []*model.Operation{
 				{
 					Action: "delete",
@@ -17,6 +17,7 @@
 package api
 
 import (
+	"fmt"
 	"io"
 	"net/http"
 	"os"
@@ -601,6 +602,12 @@ func exportPreview(c *gin.Context) {
 	}
 
 	id := arg["id"].(string)
+	if !model.GetPublishAccess(c).IsBlockAllowed(id) {
+		ret.Code = -1
+		ret.Msg = fmt.Sprintf(model.Conf.Language(15), id)
+		return
+	}
+
 	stdHTML := model.Preview(id)
 	ret.Data = map[string]interface{}{
 		"html": stdHTML,
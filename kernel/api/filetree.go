@@ -46,6 +46,32 @@ func listDocTree(c *gin.Context) {
 		return
 	}
 
+	if savedQueryArg := arg["savedQuery"]; nil != savedQueryArg {
+		// 保存的查询作为虚拟文件夹，子文档为查询命中块所在的文档
+		savedQuery := model.GetSavedQuery(savedQueryArg.(string))
+		if nil == savedQuery {
+			ret.Code = -1
+			ret.Msg = model.ErrSavedQueryNotFound.Error()
+			return
+		}
+
+		docs, err := model.RunSavedQuery(savedQuery.ID, savedQueryParamsArg(arg))
+		if err != nil {
+			ret.Code = -1
+			ret.Msg = err.Error()
+			return
+		}
+
+		folder := &DocFile{ID: savedQuery.ID, Name: savedQuery.Name, Virtual: true, Children: []*DocFile{}}
+		for _, doc := range docs {
+			folder.Children = append(folder.Children, &DocFile{ID: doc.ID})
+		}
+		ret.Data = map[string]interface{}{
+			"tree": []*DocFile{folder},
+		}
+		return
+	}
+
 	notebook := arg["notebook"].(string)
 	if util.InvalidIDPattern(notebook, ret) {
 		return
@@ -98,6 +124,8 @@ func listDocTree(c *gin.Context) {
 
 type DocFile struct {
 	ID       string     `json:"id"`
+	Name     string     `json:"name,omitempty"`    // 虚拟文件夹名称
+	Virtual  bool       `json:"virtual,omitempty"` // 是否是保存的查询生成的虚拟文件夹
 	Children []*DocFile `json:"children,omitempty"`
 }
 
@@ -196,6 +224,7 @@ func doc2Heading(c *gin.Context) {
 		ret.Data = map[string]interface{}{"closeTimeout": 5000}
 		return
 	}
+	model.RecordBlockEditors(c, targetID)
 
 	ret.Data = map[string]interface{}{
 		"srcTreeBox":  srcTreeBox,
@@ -239,6 +268,8 @@ func heading2Doc(c *gin.Context) {
 		return
 	}
 
+	model.RecordDocEditors(c, tree.Root.ID, srcRootBlockID)
+
 	name := path.Base(targetPath)
 	box := model.Conf.Box(targetNotebook)
 	files, _, _ := model.ListDocTree(targetNotebook, path.Dir(targetPath), util.SortModeUnassigned, false, false, model.Conf.FileTree.MaxListCount)
@@ -291,6 +322,8 @@ func li2Doc(c *gin.Context) {
 		return
 	}
 
+	model.RecordDocEditors(c, tree.Root.ID, srcRootBlockID)
+
 	name := path.Base(targetPath)
 	box := model.Conf.Box(targetNotebook)
 	files, _, _ := model.ListDocTree(targetNotebook, path.Dir(targetPath), util.SortModeUnassigned, false, false, model.Conf.FileTree.MaxListCount)
@@ -369,6 +402,12 @@ func getHPathByID(c *gin.Context) {
 		return
 	}
 
+	if !model.GetPublishAccess(c).IsBlockAllowed(id) {
+		ret.Code = -1
+		ret.Msg = fmt.Sprintf(model.Conf.Language(15), id)
+		return
+	}
+
 	hPath, err := model.GetHPathByID(id)
 	if err != nil {
 		ret.Code = -1
@@ -480,6 +519,9 @@ func moveDocs(c *gin.Context) {
 		ret.Data = map[string]interface{}{"closeTimeout": 7000}
 		return
 	}
+	for _, fromPath := range fromPaths {
+		model.RecordDocEditors(c, util.GetTreeID(fromPath))
+	}
 }
 
 func moveDocsByID(c *gin.Context) {
@@ -535,6 +577,7 @@ func moveDocsByID(c *gin.Context) {
 		ret.Data = map[string]interface{}{"closeTimeout": 7000}
 		return
 	}
+	model.RecordBlockEditors(c, fromIDs...)
 }
 
 func removeDoc(c *gin.Context) {
@@ -620,6 +663,7 @@ func renameDoc(c *gin.Context) {
 		ret.Msg = err.Error()
 		return
 	}
+	model.RecordDocEditors(c, util.GetTreeID(p))
 	return
 }
 
@@ -656,6 +700,7 @@ func renameDocByID(c *gin.Context) {
 		ret.Msg = err.Error()
 		return
 	}
+	model.RecordDocEditors(c, tree.ID)
 }
 
 func duplicateDoc(c *gin.Context) {
@@ -679,6 +724,7 @@ func duplicateDoc(c *gin.Context) {
 	notebook := tree.Box
 	box := model.Conf.Box(notebook)
 	model.DuplicateDoc(tree)
+	model.RecordDocEditors(c, tree.ID)
 	pushCreate(box, tree.Path, tree.ID, arg)
 
 	ret.Data = map[string]interface{}{
@@ -718,6 +764,7 @@ func createDoc(c *gin.Context) {
 		return
 	}
 
+	model.RecordDocEditors(c, tree.Root.ID)
 	model.FlushTxQueue()
 	box := model.Conf.Box(notebook)
 	pushCreate(box, p, tree.Root.ID, arg)
@@ -759,6 +806,8 @@ func createDailyNote(c *gin.Context) {
 	}
 
 	if !existed {
+		model.RecordDocEditors(c, tree.Root.ID)
+
 		// 只有创建的情况才推送，已经存在的情况不推送
 		// Creating a dailynote existed no longer expands the doc tree https://github.com/siyuan-note/siyuan/issues/9959
 		appArg := arg["app"]
@@ -851,6 +900,7 @@ func createDocWithMd(c *gin.Context) {
 		return
 	}
 	ret.Data = id
+	model.RecordDocEditors(c, id)
 
 	model.FlushTxQueue()
 	box := model.Conf.Box(notebook)
@@ -1000,7 +1050,17 @@ func searchDocs(c *gin.Context) {
 	}
 
 	k := arg["k"].(string)
-	ret.Data = model.SearchDocsByKeyword(k, flashcard)
+	docs := model.SearchDocsByKeyword(k, flashcard)
+	if access := model.GetPublishAccess(c); nil != access {
+		var allowedDocs []map[string]string
+		for _, doc := range docs {
+			if access.IsDocAllowed(doc["box"], doc["path"]) {
+				allowedDocs = append(allowedDocs, doc)
+			}
+		}
+		docs = allowedDocs
+	}
+	ret.Data = docs
 }
 
 func listDocsByPath(c *gin.Context) {
@@ -1036,12 +1096,28 @@ func listDocsByPath(c *gin.Context) {
 		showHidden = arg["showHidden"].(bool)
 	}
 
+	access := model.GetPublishAccess(c)
+	if !access.IsBoxAllowed(notebook) {
+		ret.Code = -1
+		ret.Msg = model.Conf.Language(0)
+		return
+	}
+
 	files, totals, err := model.ListDocTree(notebook, p, sortMode, flashcard, showHidden, maxListCount)
 	if err != nil {
 		ret.Code = -1
 		ret.Msg = err.Error()
 		return
 	}
+	if nil != access {
+		var allowedFiles []*model.File
+		for _, file := range files {
+			if access.IsDocAllowed(notebook, file.Path) {
+				allowedFiles = append(allowedFiles, file)
+			}
+		}
+		files = allowedFiles
+	}
 	if maxListCount < totals {
 		// API `listDocsByPath` add an optional parameter `ignoreMaxListHint` https://github.com/siyuan-note/siyuan/issues/10290
 		ignoreMaxListHintArg := arg["ignoreMaxListHint"]
@@ -1120,6 +1196,11 @@ func getDoc(c *gin.Context) {
 		highlight = highlightArg.(bool)
 	}
 
+	if !model.GetPublishAccess(c).IsBlockAllowed(id) {
+		ret.Code = 3
+		return
+	}
+
 	blockCount, content, parentID, parent2ID, rootID, typ, eof, scroll, boxID, docPath, isBacklinkExpand, keywords, err :=
 		model.GetDoc(startID, endID, id, index, query, queryTypes, queryMethod, mode, size, isBacklink, highlight)
 	if model.ErrBlockNotFound == err {
@@ -81,6 +81,7 @@ func getGraph(c *gin.Context) {
 	model.Conf.Save()
 
 	boxID, nodes, links := model.BuildGraph(query)
+	nodes, links = model.FilterPublishGraph(c, nodes, links)
 	ret.Data = map[string]interface{}{
 		"nodes": nodes,
 		"links": links,
@@ -127,6 +128,7 @@ func getLocalGraph(c *gin.Context) {
 	model.Conf.Save()
 
 	boxID, nodes, links := model.BuildTreeGraph(id, keyword)
+	nodes, links = model.FilterPublishGraph(c, nodes, links)
 	ret.Data = map[string]interface{}{
 		"id":    id,
 		"box":   boxID,
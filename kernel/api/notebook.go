@@ -421,6 +421,16 @@ func lsNotebooks(c *gin.Context) {
 		}
 	}
 
+	if access := model.GetPublishAccess(c); nil != access {
+		var allowedNotebooks []*model.Box
+		for _, notebook := range notebooks {
+			if access.IsBoxAllowed(notebook.ID) {
+				allowedNotebooks = append(allowedNotebooks, notebook)
+			}
+		}
+		notebooks = allowedNotebooks
+	}
+
 	ret.Data = map[string]interface{}{
 		"notebooks": notebooks,
 	}
@@ -17,6 +17,7 @@
 package api
 
 import (
+	"fmt"
 	"net/http"
 
 	"github.com/88250/gulu"
@@ -44,6 +45,12 @@ func getDocOutline(c *gin.Context) {
 	}
 
 	rootID := arg["id"].(string)
+	if !model.GetPublishAccess(c).IsBlockAllowed(rootID) {
+		ret.Code = 1
+		ret.Msg = fmt.Sprintf(model.Conf.Language(15), rootID)
+		return
+	}
+
 	headings, err := model.Outline(rootID, preview)
 	if err != nil {
 		ret.Code = 1
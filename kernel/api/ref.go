@@ -60,6 +60,12 @@ func getBackmentionDoc(c *gin.Context) {
 	if val, ok := arg["highlight"]; ok {
 		highlight = val.(bool)
 	}
+	access := model.GetPublishAccess(c)
+	if !access.IsBlockAllowed(defID) || !access.IsBlockAllowed(refTreeID) {
+		ret.Data = map[string]interface{}{"backmentions": []*model.Backlink{}, "keywords": []string{}}
+		return
+	}
+
 	backlinks, keywords := model.GetBackmentionDoc(defID, refTreeID, keyword, containChildren, highlight)
 	ret.Data = map[string]interface{}{
 		"backmentions": backlinks,
@@ -87,6 +93,12 @@ func getBacklinkDoc(c *gin.Context) {
 	if val, ok := arg["highlight"]; ok {
 		highlight = val.(bool)
 	}
+	access := model.GetPublishAccess(c)
+	if !access.IsBlockAllowed(defID) || !access.IsBlockAllowed(refTreeID) {
+		ret.Data = map[string]interface{}{"backlinks": []*model.Backlink{}, "keywords": []string{}}
+		return
+	}
+
 	backlinks, keywords := model.GetBacklinkDoc(defID, refTreeID, keyword, containChildren, highlight)
 	ret.Data = map[string]interface{}{
 		"backlinks": backlinks,
@@ -124,7 +136,21 @@ func getBacklink2(c *gin.Context) {
 	if val, ok := arg["containChildren"]; ok {
 		containChildren = val.(bool)
 	}
+	if !model.GetPublishAccess(c).IsBlockAllowed(id) {
+		return
+	}
+
 	boxID, backlinks, backmentions, linkRefsCount, mentionsCount := model.GetBacklink2(id, keyword, mentionKeyword, sort, mentionSort, containChildren)
+	if nil != model.GetPublishAccess(c) {
+		backlinks, backmentions = model.FilterPublishPaths(c, backlinks), model.FilterPublishPaths(c, backmentions)
+		linkRefsCount, mentionsCount = 0, 0
+		for _, backlink := range backlinks {
+			linkRefsCount += backlink.Count
+		}
+		for _, backmention := range backmentions {
+			mentionsCount += backmention.Count
+		}
+	}
 	ret.Data = map[string]interface{}{
 		"backlinks":     backlinks,
 		"linkRefsCount": linkRefsCount,
@@ -160,7 +186,21 @@ func getBacklink(c *gin.Context) {
 	if val, ok := arg["containChildren"]; ok {
 		containChildren = val.(bool)
 	}
+	if !model.GetPublishAccess(c).IsBlockAllowed(id) {
+		return
+	}
+
 	boxID, backlinks, backmentions, linkRefsCount, mentionsCount := model.GetBacklink(id, keyword, mentionKeyword, beforeLen, containChildren)
+	if nil != model.GetPublishAccess(c) {
+		backlinks, backmentions = model.FilterPublishPaths(c, backlinks), model.FilterPublishPaths(c, backmentions)
+		linkRefsCount, mentionsCount = 0, 0
+		for _, backlink := range backlinks {
+			linkRefsCount += backlink.Count
+		}
+		for _, backmention := range backmentions {
+			mentionsCount += backmention.Count
+		}
+	}
 	ret.Data = map[string]interface{}{
 		"backlinks":     backlinks,
 		"linkRefsCount": linkRefsCount,
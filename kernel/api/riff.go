@@ -314,6 +314,7 @@ func removeRiffCards(c *gin.Context) {
 
 	transactions := []*model.Transaction{
 		{
+			Username: model.GetGinContextUsername(c),
 			DoOperations: []*model.Operation{
 				{
 					Action:   "removeFlashcards",
@@ -352,6 +353,7 @@ func addRiffCards(c *gin.Context) {
 
 	transactions := []*model.Transaction{
 		{
+			Username: model.GetGinContextUsername(c),
 			DoOperations: []*model.Operation{
 				{
 					Action:   "addFlashcards",
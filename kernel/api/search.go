@@ -22,7 +22,9 @@ import (
 
 	"github.com/88250/gulu"
 	"github.com/gin-gonic/gin"
+	"github.com/siyuan-note/siyuan/kernel/cache"
 	"github.com/siyuan-note/siyuan/kernel/model"
+	"github.com/siyuan-note/siyuan/kernel/sql"
 	"github.com/siyuan-note/siyuan/kernel/util"
 )
 
@@ -159,7 +161,17 @@ func searchAsset(c *gin.Context) {
 		}
 	}
 
-	ret.Data = model.SearchAssetsByName(k, exts)
+	assets := model.SearchAssetsByName(k, exts)
+	if access := model.GetPublishAccess(c); nil != access {
+		allowed := []*cache.Asset{}
+		for _, asset := range assets {
+			if access.IsAssetAllowed(asset.Path) {
+				allowed = append(allowed, asset)
+			}
+		}
+		assets = allowed
+	}
+	ret.Data = assets
 	return
 }
 
@@ -263,6 +275,7 @@ func getEmbedBlock(c *gin.Context) {
 	}
 
 	blocks := model.GetEmbedBlock(embedBlockID, includeIDs, headingMode, breadcrumb)
+	blocks = filterPublishEmbedBlocks(c, blocks)
 	ret.Data = map[string]interface{}{
 		"blocks": blocks,
 	}
@@ -297,6 +310,13 @@ func searchEmbedBlock(c *gin.Context) {
 		return
 	}
 
+	if nil != model.GetPublishAccess(c) {
+		// SQL 查询可以读取任意表，无法按访问范围过滤，受限的请求不允许执行
+		ret.Code = -1
+		ret.Msg = http.StatusText(http.StatusForbidden)
+		return
+	}
+
 	embedBlockID := arg["embedBlockID"].(string)
 	stmt := arg["stmt"].(string)
 	excludeIDsArg := arg["excludeIDs"].([]interface{})
@@ -316,6 +336,7 @@ func searchEmbedBlock(c *gin.Context) {
 	}
 
 	blocks := model.SearchEmbedBlock(embedBlockID, stmt, excludeIDs, headingMode, breadcrumb)
+	blocks = filterPublishEmbedBlocks(c, blocks)
 	ret.Data = map[string]interface{}{
 		"blocks": blocks,
 	}
@@ -350,7 +371,12 @@ func searchRefBlock(c *gin.Context) {
 	id := arg["id"].(string)
 	keyword := arg["k"].(string)
 	beforeLen := int(arg["beforeLen"].(float64))
-	blocks, newDoc := model.SearchRefBlock(id, rootID, keyword, beforeLen, isSquareBrackets, isDatabase)
+	fuzzy := false
+	if fuzzyArg := arg["fuzzy"]; nil != fuzzyArg {
+		fuzzy = fuzzyArg.(bool)
+	}
+	blocks, newDoc := model.SearchRefBlock(id, rootID, keyword, beforeLen, isSquareBrackets, isDatabase, fuzzy)
+	blocks = model.FilterPublishBlocks(c, blocks)
 	ret.Data = map[string]interface{}{
 		"blocks": blocks,
 		"newDoc": newDoc,
@@ -369,7 +395,19 @@ func fullTextSearchBlock(c *gin.Context) {
 	}
 
 	page, pageSize, query, paths, boxes, types, method, orderBy, groupBy := parseSearchBlockArgs(arg)
+	if 2 == method && nil != model.GetPublishAccess(c) {
+		// SQL 查询可以读取任意表，无法按访问范围过滤，受限的请求不允许执行
+		ret.Code = -1
+		ret.Msg = http.StatusText(http.StatusForbidden)
+		return
+	}
+
 	blocks, matchedBlockCount, matchedRootCount, pageCount, docMode := model.FullTextSearchBlock(query, boxes, paths, types, method, orderBy, groupBy, page, pageSize)
+	blocks = model.FilterPublishBlocks(c, blocks)
+	if explainArg, _ := arg["explain"].(bool); !explainArg {
+		// 仅在需要调试排序时返回得分明细
+		model.ClearSearchScores(blocks)
+	}
 	ret.Data = map[string]interface{}{
 		"blocks":            blocks,
 		"matchedBlockCount": matchedBlockCount,
@@ -426,13 +464,13 @@ func parseSearchBlockArgs(arg map[string]interface{}) (page, pageSize int, query
 		}
 	}
 
-	// method：0：关键字，1：查询语法，2：SQL，3：正则表达式
+	// method：0：关键字，1：查询语法，2：SQL，3：正则表达式，4：语义，5：模糊
 	methodArg := arg["method"]
 	if nil != methodArg {
 		method = int(methodArg.(float64))
 	}
 
-	// orderBy：0：按块类型（默认），1：按创建时间升序，2：按创建时间降序，3：按更新时间升序，4：按更新时间降序，5：按内容顺序（仅在按文档分组时），6：按相关度升序，7：按相关度降序
+	// orderBy：0：按块类型（默认），1：按创建时间升序，2：按创建时间降序，3：按更新时间升序，4：按更新时间降序，5：按内容顺序（仅在按文档分组时），6：按相关度升序，7：按相关度降序，8：按综合得分降序
 	orderByArg := arg["orderBy"]
 	if nil != orderByArg {
 		orderBy = int(orderByArg.(float64))
@@ -476,7 +514,7 @@ func parseSearchAssetContentArgs(arg map[string]interface{}) (page, pageSize int
 		}
 	}
 
-	// method：0：关键字，1：查询语法，2：SQL，3：正则表达式
+	// method：0：关键字，1：查询语法，2：SQL，3：正则表达式，4：语义，5：模糊
 	methodArg := arg["method"]
 	if nil != methodArg {
 		method = int(methodArg.(float64))
@@ -489,3 +527,29 @@ func parseSearchAssetContentArgs(arg map[string]interface{}) (page, pageSize int
 	}
 	return
 }
+
+func filterPublishEmbedBlocks(c *gin.Context, blocks []*model.EmbedBlock) (ret []*model.EmbedBlock) {
+	access := model.GetPublishAccess(c)
+	if nil == access {
+		return blocks
+	}
+
+	ret = []*model.EmbedBlock{}
+	for _, block := range blocks {
+		if access.IsBlockAllowed(block.Block.ID) {
+			ret = append(ret, block)
+		}
+	}
+	return
+}
+
+func getEmbeddingStatus(c *gin.Context) {
+	ret := gulu.Ret.NewResult()
+	defer c.JSON(http.StatusOK, ret)
+
+	embedded, pending := sql.CountEmbeddings()
+	ret.Data = map[string]interface{}{
+		"embedded": embedded,
+		"pending":  pending,
+	}
+}
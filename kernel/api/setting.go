@@ -204,8 +204,16 @@ func setAI(c *gin.Context) {
 		ai.OpenAI.APIMaxContexts = 7
 	}
 
+	if nil == ai.Embedding {
+		ai.Embedding = model.Conf.AI.Embedding
+	}
+	if 1 > ai.Embedding.APITimeout || 600 < ai.Embedding.APITimeout {
+		ai.Embedding.APITimeout = 30
+	}
+
 	model.Conf.AI = ai
 	model.Conf.Save()
+	model.InitEmbeddingProvider()
 
 	ret.Data = ai
 }
@@ -578,6 +586,97 @@ func setPublish(c *gin.Context) {
 	}
 }
 
+func setDAV(c *gin.Context) {
+	ret := gulu.Ret.NewResult()
+	defer c.JSON(http.StatusOK, ret)
+
+	arg, ok := util.JsonArg(c, ret)
+	if !ok {
+		return
+	}
+
+	param, err := gulu.JSON.MarshalJSON(arg)
+	if err != nil {
+		ret.Code = -1
+		ret.Msg = err.Error()
+		return
+	}
+
+	dav := conf.NewDAV()
+	if err = gulu.JSON.UnmarshalJSON(param, dav); err != nil {
+		ret.Code = -1
+		ret.Msg = err.Error()
+		return
+	}
+	if 0 > dav.Quota {
+		dav.Quota = 0
+	}
+	if nil == dav.ReadOnlyNotebooks {
+		dav.ReadOnlyNotebooks = []string{}
+	}
+
+	model.Conf.DAV = dav
+	model.Conf.Save()
+
+	ret.Data = model.Conf.DAV
+}
+
+func setOIDC(c *gin.Context) {
+	ret := gulu.Ret.NewResult()
+	defer c.JSON(http.StatusOK, ret)
+
+	arg, ok := util.JsonArg(c, ret)
+	if !ok {
+		return
+	}
+
+	param, err := gulu.JSON.MarshalJSON(arg)
+	if err != nil {
+		ret.Code = -1
+		ret.Msg = err.Error()
+		return
+	}
+
+	oidc := conf.NewOIDC()
+	if err = gulu.JSON.UnmarshalJSON(param, oidc); err != nil {
+		ret.Code = -1
+		ret.Msg = err.Error()
+		return
+	}
+	if model.MaskedAccessAuthCode == oidc.ClientSecret {
+		// 前端拿到的是脱敏后的密钥，未修改时保留原密钥
+		oidc.ClientSecret = model.Conf.OIDC.ClientSecret
+	}
+	if nil == oidc.Roles {
+		oidc.Roles = []*conf.OIDCRoleRule{}
+	}
+	if oidc.Enable {
+		if err = model.CheckOIDCRedirectURL(oidc.RedirectURL); err != nil {
+			ret.Code = -1
+			ret.Msg = err.Error()
+			return
+		}
+	}
+	for _, rule := range oidc.Roles {
+		if !conf.IsMemberRole(rule.Role) {
+			ret.Code = -1
+			ret.Msg = model.ErrInvalidMemberRole.Error()
+			return
+		}
+	}
+
+	model.Conf.OIDC = oidc
+	model.Conf.Save()
+
+	masked := *oidc
+	if "" != masked.ClientSecret {
+		masked.ClientSecret = model.MaskedAccessAuthCode
+	}
+	ret.Data = map[string]any{
+		"oidc": &masked,
+	}
+}
+
 func getPublish(c *gin.Context) {
 	ret := gulu.Ret.NewResult()
 	defer c.JSON(http.StatusOK, ret)
@@ -45,7 +45,27 @@ func SQL(c *gin.Context) {
 	}
 
 	stmt := arg["stmt"].(string)
-	result, err := sql.Query(stmt, model.Conf.Search.Limit)
+	var args []interface{}
+	if argsArg, ok := arg["args"].([]interface{}); ok {
+		for _, a := range argsArg {
+			switch v := a.(type) {
+			case nil, string, bool:
+				args = append(args, v)
+			case float64:
+				if v == float64(int64(v)) {
+					args = append(args, int64(v))
+				} else {
+					args = append(args, v)
+				}
+			default:
+				ret.Code = -1
+				ret.Msg = "args only supports string, number, boolean and null"
+				return
+			}
+		}
+	}
+
+	result, err := sql.Query(stmt, model.Conf.Search.Limit, args...)
 	if err != nil {
 		ret.Code = 1
 		ret.Msg = err.Error()
@@ -17,6 +17,7 @@
 package api
 
 import (
+	"fmt"
 	"net/http"
 
 	"github.com/88250/gulu"
@@ -180,3 +181,106 @@ func getLocalStorage(c *gin.Context) {
 	data := model.GetLocalStorage()
 	ret.Data = data
 }
+
+func setSavedQuery(c *gin.Context) {
+	ret := gulu.Ret.NewResult()
+	defer c.JSON(http.StatusOK, ret)
+
+	arg, ok := util.JsonArg(c, ret)
+	if !ok {
+		return
+	}
+
+	param, err := gulu.JSON.MarshalJSON(arg["savedQuery"])
+	if err != nil {
+		ret.Code = -1
+		ret.Msg = err.Error()
+		return
+	}
+
+	savedQuery := &model.SavedQuery{}
+	if err = gulu.JSON.UnmarshalJSON(param, savedQuery); err != nil {
+		ret.Code = -1
+		ret.Msg = err.Error()
+		return
+	}
+
+	err = model.SetSavedQuery(savedQuery)
+	if err != nil {
+		ret.Code = -1
+		ret.Msg = err.Error()
+		return
+	}
+	ret.Data = savedQuery
+}
+
+func removeSavedQuery(c *gin.Context) {
+	ret := gulu.Ret.NewResult()
+	defer c.JSON(http.StatusOK, ret)
+
+	arg, ok := util.JsonArg(c, ret)
+	if !ok {
+		return
+	}
+
+	id := arg["id"].(string)
+	err := model.RemoveSavedQuery(id)
+	if err != nil {
+		ret.Code = -1
+		ret.Msg = err.Error()
+		return
+	}
+}
+
+func getSavedQueries(c *gin.Context) {
+	ret := gulu.Ret.NewResult()
+	defer c.JSON(http.StatusOK, ret)
+
+	ret.Data = model.GetSavedQueries()
+}
+
+func runSavedQuery(c *gin.Context) {
+	ret := gulu.Ret.NewResult()
+	defer c.JSON(http.StatusOK, ret)
+
+	arg, ok := util.JsonArg(c, ret)
+	if !ok {
+		return
+	}
+
+	id := arg["id"].(string)
+	if savedQuery := model.GetSavedQuery(id); nil != savedQuery && 2 == savedQuery.Method && nil != model.GetPublishAccess(c) {
+		// SQL 查询可以读取任意表，无法按访问范围过滤，受限的请求不允许执行
+		ret.Code = -1
+		ret.Msg = http.StatusText(http.StatusForbidden)
+		return
+	}
+
+	docs, err := model.RunSavedQuery(id, savedQueryParamsArg(arg))
+	if err != nil {
+		ret.Code = -1
+		ret.Msg = err.Error()
+		return
+	}
+	ret.Data = model.FilterPublishBlocks(c, docs)
+}
+
+// savedQueryParamsArg 解析查询参数，如 {"currentDocID": "20240101120000-abcdefg"}。
+func savedQueryParamsArg(arg map[string]interface{}) (ret map[string]string) {
+	ret = map[string]string{}
+	paramsArg, ok := arg["params"].(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	for k, v := range paramsArg {
+		switch val := v.(type) {
+		case string:
+			ret[k] = val
+		case nil:
+		default:
+			ret[k] = fmt.Sprint(val)
+		}
+	}
+	return
+}
@@ -724,3 +724,38 @@ func exit(c *gin.Context) {
 		ret.Data = map[string]interface{}{"closeTimeout": 0}
 	}
 }
+
+func getAuditLog(c *gin.Context) {
+	ret := gulu.Ret.NewResult()
+	defer c.JSON(http.StatusOK, ret)
+
+	arg, ok := util.JsonArg(c, ret)
+	if !ok {
+		return
+	}
+
+	query := &model.AuditLogQuery{}
+	query.Route, _ = arg["route"].(string)
+	query.User, _ = arg["user"].(string)
+	query.ID, _ = arg["id"].(string)
+	query.IP, _ = arg["ip"].(string)
+	query.Failed, _ = arg["failed"].(bool)
+	if from, ok := arg["from"].(float64); ok {
+		query.From = int64(from)
+	}
+	if to, ok := arg["to"].(float64); ok {
+		query.To = int64(to)
+	}
+	if page, ok := arg["page"].(float64); ok {
+		query.Page = int(page)
+	}
+	if pageSize, ok := arg["pageSize"].(float64); ok {
+		query.PageSize = int(pageSize)
+	}
+
+	logs, total := model.GetAuditLogs(query)
+	ret.Data = map[string]interface{}{
+		"logs":  logs,
+		"total": total,
+	}
+}
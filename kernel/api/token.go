@@ -0,0 +1,124 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/88250/gulu"
+	"github.com/gin-gonic/gin"
+	"github.com/siyuan-note/siyuan/kernel/model"
+	"github.com/siyuan-note/siyuan/kernel/util"
+)
+
+func listAPITokens(c *gin.Context) {
+	ret := gulu.Ret.NewResult()
+	defer c.JSON(http.StatusOK, ret)
+
+	ret.Data = map[string]interface{}{
+		"tokens": model.GetAPITokens(),
+	}
+}
+
+func createAPIToken(c *gin.Context) {
+	ret := gulu.Ret.NewResult()
+	defer c.JSON(http.StatusOK, ret)
+
+	arg, ok := util.JsonArg(c, ret)
+	if !ok {
+		return
+	}
+
+	name, role, routes, notebooks, expired := parseAPITokenArgs(arg)
+	token, plain, err := model.CreateAPIToken(name, role, routes, notebooks, expired)
+	if err != nil {
+		ret.Code = -1
+		ret.Msg = err.Error()
+		return
+	}
+
+	ret.Data = map[string]interface{}{
+		"token": token,
+		"plain": plain, // 明文 token 仅在签发时返回一次
+	}
+}
+
+func updateAPIToken(c *gin.Context) {
+	ret := gulu.Ret.NewResult()
+	defer c.JSON(http.StatusOK, ret)
+
+	arg, ok := util.JsonArg(c, ret)
+	if !ok {
+		return
+	}
+
+	id, _ := arg["id"].(string)
+	name, role, routes, notebooks, expired := parseAPITokenArgs(arg)
+	if err := model.UpdateAPIToken(id, name, role, routes, notebooks, expired); err != nil {
+		ret.Code = -1
+		ret.Msg = err.Error()
+		return
+	}
+
+	ret.Data = map[string]interface{}{
+		"tokens": model.GetAPITokens(),
+	}
+}
+
+func revokeAPIToken(c *gin.Context) {
+	ret := gulu.Ret.NewResult()
+	defer c.JSON(http.StatusOK, ret)
+
+	arg, ok := util.JsonArg(c, ret)
+	if !ok {
+		return
+	}
+
+	id, _ := arg["id"].(string)
+	if err := model.RevokeAPIToken(id); err != nil {
+		ret.Code = -1
+		ret.Msg = err.Error()
+		return
+	}
+
+	ret.Data = map[string]interface{}{
+		"tokens": model.GetAPITokens(),
+	}
+}
+
+func parseAPITokenArgs(arg map[string]interface{}) (name, role string, routes, notebooks []string, expired int64) {
+	name, _ = arg["name"].(string)
+	role, _ = arg["role"].(string)
+	if routesArg, ok := arg["routes"].([]interface{}); ok {
+		for _, route := range routesArg {
+			if r, ok := route.(string); ok {
+				routes = append(routes, r)
+			}
+		}
+	}
+	if notebooksArg, ok := arg["notebooks"].([]interface{}); ok {
+		for _, notebook := range notebooksArg {
+			if n, ok := notebook.(string); ok {
+				notebooks = append(notebooks, n)
+			}
+		}
+	}
+	if expiredArg, ok := arg["expired"].(float64); ok {
+		expired = int64(expiredArg)
+	}
+	return
+}
@@ -60,8 +60,10 @@ func performTransactions(c *gin.Context) {
 		ret.Msg = "parses request failed"
 		return
 	}
+	username := model.GetGinContextUsername(c)
 	for _, transaction := range transactions {
 		transaction.Timestamp = timestamp
+		transaction.Username = username
 	}
 
 	model.PerformTransactions(&transactions)
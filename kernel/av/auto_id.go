@@ -0,0 +1,129 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package av
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/88250/lute/ast"
+	"github.com/siyuan-note/siyuan/kernel/util"
+)
+
+// AutoID 描述了自动编号字段的设置。
+type AutoID struct {
+	Prefix string `json:"prefix"` // 编号前缀，比如 TASK-
+	Next   int64  `json:"next"`   // 下一个待分配的编号，只增不减，删除行后编号不会被复用
+}
+
+// ValueAutoID 描述了自动编号字段值。
+type ValueAutoID struct {
+	Content          int64  `json:"content"`          // 编号
+	FormattedContent string `json:"formattedContent"` // 带前缀的编号，比如 TASK-42
+}
+
+func FormatAutoID(prefix string, id int64) string {
+	return prefix + strconv.FormatInt(id, 10)
+}
+
+// FillAutoIDs 为自动编号字段分配编号。
+//
+// 按行的添加顺序为还没有编号的行分配编号；同步合并后可能出现重复编号，这时保留创建最早的值，其余值重新分配新的编号。
+func (av *AttributeView) FillAutoIDs() {
+	blockValues := av.GetBlockKeyValues()
+	if nil == blockValues {
+		return
+	}
+
+	now := util.CurrentTimeMillis()
+	for _, kv := range av.KeyValues {
+		if KeyTypeAutoID != kv.Key.Type {
+			continue
+		}
+
+		if nil == kv.Key.AutoID {
+			kv.Key.AutoID = &AutoID{Next: 1}
+		}
+		autoID := kv.Key.AutoID
+
+		var numbered []*Value
+		var maxID int64
+		rowValues := map[string]*Value{}
+		for _, v := range kv.Values {
+			rowValues[v.BlockID] = v
+			if nil != v.AutoID && 0 < v.AutoID.Content {
+				numbered = append(numbered, v)
+				maxID = max(maxID, v.AutoID.Content)
+			}
+		}
+		if autoID.Next <= maxID {
+			autoID.Next = maxID + 1
+		}
+		if 1 > autoID.Next {
+			autoID.Next = 1
+		}
+
+		// 处理重复编号
+		sort.SliceStable(numbered, func(i, j int) bool {
+			if numbered[i].AutoID.Content != numbered[j].AutoID.Content {
+				return numbered[i].AutoID.Content < numbered[j].AutoID.Content
+			}
+			if numbered[i].CreatedAt != numbered[j].CreatedAt {
+				return numbered[i].CreatedAt < numbered[j].CreatedAt
+			}
+			return numbered[i].ID < numbered[j].ID
+		})
+		used := map[int64]bool{}
+		var duplicated []*Value
+		for _, v := range numbered {
+			if used[v.AutoID.Content] {
+				duplicated = append(duplicated, v)
+				continue
+			}
+			used[v.AutoID.Content] = true
+		}
+		for _, v := range duplicated {
+			v.AutoID.Content = autoID.Next
+			autoID.Next++
+		}
+
+		// 为类型修改过来的值分配编号
+		for _, v := range kv.Values {
+			if nil == v.AutoID || 1 > v.AutoID.Content {
+				v.AutoID = &ValueAutoID{Content: autoID.Next}
+				autoID.Next++
+			}
+		}
+
+		// 为新增的行分配编号
+		for _, blockValue := range blockValues.Values {
+			if nil != rowValues[blockValue.BlockID] {
+				continue
+			}
+
+			v := &Value{ID: ast.NewNodeID(), KeyID: kv.Key.ID, BlockID: blockValue.BlockID, Type: KeyTypeAutoID, CreatedAt: now, UpdatedAt: now + 1000, AutoID: &ValueAutoID{Content: autoID.Next}}
+			autoID.Next++
+			kv.Values = append(kv.Values, v)
+			rowValues[blockValue.BlockID] = v
+		}
+
+		for _, v := range kv.Values {
+			v.Type = KeyTypeAutoID
+			v.AutoID.FormattedContent = FormatAutoID(autoID.Prefix, v.AutoID.Content)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package av
+
+import (
+	"testing"
+)
+
+func TestFillAutoIDs(t *testing.T) {
+	autoIDValue := func(blockID string, id, createdAt int64) *Value {
+		return &Value{ID: blockID + "-v", BlockID: blockID, Type: KeyTypeAutoID, CreatedAt: createdAt, AutoID: &ValueAutoID{Content: id}}
+	}
+
+	cases := []struct {
+		name     string
+		rows     []string
+		values   []*Value
+		next     int64
+		want     map[string]string
+		wantNext int64
+	}{
+		{"new rows", []string{"a", "b"}, nil, 1, map[string]string{"a": "T-1", "b": "T-2"}, 3},
+		{"keep existing", []string{"a", "b"}, []*Value{autoIDValue("a", 5, 1)}, 1, map[string]string{"a": "T-5", "b": "T-6"}, 7},
+		{"not reuse deleted", []string{"b"}, []*Value{autoIDValue("b", 2, 1)}, 10, map[string]string{"b": "T-2"}, 10},
+		{"duplicated after sync", []string{"a", "b"}, []*Value{autoIDValue("a", 3, 2), autoIDValue("b", 3, 1)}, 4, map[string]string{"a": "T-4", "b": "T-3"}, 5},
+	}
+
+	for _, c := range cases {
+		blockKey := &Key{ID: "block", Type: KeyTypeBlock}
+		blockValues := &KeyValues{Key: blockKey}
+		for _, row := range c.rows {
+			blockValues.Values = append(blockValues.Values, &Value{BlockID: row, Type: KeyTypeBlock, Block: &ValueBlock{ID: row}})
+		}
+		autoIDKey := &Key{ID: "id", Type: KeyTypeAutoID, AutoID: &AutoID{Prefix: "T-", Next: c.next}}
+		attrView := &AttributeView{KeyValues: []*KeyValues{blockValues, {Key: autoIDKey, Values: c.values}}}
+
+		attrView.FillAutoIDs()
+		got := map[string]string{}
+		for _, v := range attrView.KeyValues[1].Values {
+			got[v.BlockID] = v.AutoID.FormattedContent
+		}
+		for row, want := range c.want {
+			if want != got[row] {
+				t.Errorf("case [%s]: row [%s] got %s, want %s", c.name, row, got[row], want)
+			}
+		}
+		if c.wantNext != autoIDKey.AutoID.Next {
+			t.Errorf("case [%s]: next got %d, want %d", c.name, autoIDKey.AutoID.Next, c.wantNext)
+		}
+	}
+}
@@ -41,6 +41,8 @@ type AttributeView struct {
 	KeyIDs    []string     `json:"keyIDs"`    // 属性视图属性键 ID，用于排序
 	ViewID    string       `json:"viewID"`    // 当前视图 ID
 	Views     []*View      `json:"views"`     // 视图
+
+	AddressBook bool `json:"addressBook,omitempty"` // 是否作为 CardDAV 通讯录提供
 }
 
 // KeyValues 描述了属性视图属性列值的结构。
@@ -99,6 +101,8 @@ const (
 	KeyTypeRelation   KeyType = "relation"
 	KeyTypeRollup     KeyType = "rollup"
 	KeyTypeLineNumber KeyType = "lineNumber"
+	KeyTypeFormula    KeyType = "formula"
+	KeyTypeAutoID     KeyType = "autoID"
 )
 
 // Key 描述了属性视图属性字段的基础结构。
@@ -128,6 +132,12 @@ type Key struct {
 
 	// 日期
 	Date *Date `json:"date,omitempty"` // 日期设置
+
+	// 公式
+	Formula string `json:"formula,omitempty"` // 公式内容
+
+	// 自动编号
+	AutoID *AutoID `json:"autoID,omitempty"` // 自动编号设置
 }
 
 func NewKey(id, name, icon string, keyType KeyType) *Key {
@@ -150,7 +160,8 @@ func (k *Key) GetOption(name string) (ret *SelectOption) {
 }
 
 type Date struct {
-	AutoFillNow bool `json:"autoFillNow"` // 是否自动填充当前时间 The database date field supports filling the current time by default https://github.com/siyuan-note/siyuan/issues/10823
+	AutoFillNow bool `json:"autoFillNow"`        // 是否自动填充当前时间 The database date field supports filling the current time by default https://github.com/siyuan-note/siyuan/issues/10823
+	Calendar    bool `json:"calendar,omitempty"` // 是否通过 CalDAV 笔记日历提供该字段的日程
 }
 
 type Rollup struct {
@@ -184,15 +195,30 @@ type View struct {
 	HideAttrViewName bool   `json:"hideAttrViewName"` // 是否隐藏属性视图名称
 	Desc             string `json:"desc"`             // 视图描述
 
-	LayoutType LayoutType   `json:"type"`            // 当前布局类型
-	Table      *LayoutTable `json:"table,omitempty"` // 表格布局
+	LayoutType LayoutType      `json:"type"`               // 当前布局类型
+	Table      *LayoutTable    `json:"table,omitempty"`    // 表格布局，看板、日历和画廊视图也使用该布局保存字段、过滤、排序和分页设置
+	Kanban     *LayoutKanban   `json:"kanban,omitempty"`   // 看板布局
+	Calendar   *LayoutCalendar `json:"calendar,omitempty"` // 日历布局
+	Gallery    *LayoutGallery  `json:"gallery,omitempty"`  // 画廊布局
+}
+
+// HasLayoutTable 判断视图是否使用 Table 保存字段、过滤、排序和分页设置，表格、看板、日历和画廊视图都使用该布局。
+func (view *View) HasLayoutTable() bool {
+	switch view.LayoutType {
+	case LayoutTypeTable, LayoutTypeKanban, LayoutTypeCalendar, LayoutTypeGallery:
+		return true
+	}
+	return false
 }
 
 // LayoutType 描述了视图布局的类型。
 type LayoutType string
 
 const (
-	LayoutTypeTable LayoutType = "table" // 属性视图类型 - 表格
+	LayoutTypeTable    LayoutType = "table"    // 属性视图类型 - 表格
+	LayoutTypeKanban   LayoutType = "kanban"   // 属性视图类型 - 看板
+	LayoutTypeCalendar LayoutType = "calendar" // 属性视图类型 - 日历
+	LayoutTypeGallery  LayoutType = "gallery"  // 属性视图类型 - 画廊
 )
 
 func NewTableView() (ret *View) {
@@ -211,6 +237,44 @@ func NewTableView() (ret *View) {
 	return
 }
 
+func NewKanbanView() (ret *View) {
+	ret = NewTableView()
+	ret.Name = getI18nName("kanban")
+	ret.LayoutType = LayoutTypeKanban
+	ret.Kanban = &LayoutKanban{
+		Spec:    0,
+		ID:      ast.NewNodeID(),
+		Columns: []*ViewKanbanColumn{},
+	}
+	return
+}
+
+func NewCalendarView() (ret *View) {
+	ret = NewTableView()
+	ret.Name = getI18nName("calendar")
+	ret.LayoutType = LayoutTypeCalendar
+	ret.Calendar = &LayoutCalendar{
+		Spec:           0,
+		ID:             ast.NewNodeID(),
+		Mode:           CalendarModeMonth,
+		FirstDayOfWeek: 1,
+	}
+	return
+}
+
+func NewGalleryView() (ret *View) {
+	ret = NewTableView()
+	ret.Name = getI18nName("gallery")
+	ret.LayoutType = LayoutTypeGallery
+	ret.Gallery = &LayoutGallery{
+		Spec:      0,
+		ID:        ast.NewNodeID(),
+		CoverFrom: CoverFromContentImage,
+		CardSize:  CardSizeMedium,
+	}
+	return
+}
+
 func NewTableViewWithBlockKey(blockKeyID string) (view *View, blockKey, selectKey *Key) {
 	name := getI18nName("table")
 	view = &View{
@@ -367,6 +431,9 @@ func SaveAttributeView(av *AttributeView) (err error) {
 	// 做一些数据兼容和订正处理
 	UpgradeSpec(av)
 
+	// 分配自动编号
+	av.FillAutoIDs()
+
 	// 值去重
 	blockValues := av.GetBlockKeyValues()
 	blockIDs := map[string]bool{}
@@ -548,6 +615,19 @@ func (av *AttributeView) ShallowClone() (ret *AttributeView) {
 		oldKeyIDs = append(oldKeyIDs, kv.Key.ID)
 		kv.Key.ID = newID
 		kv.Values = []*Value{}
+		if nil != kv.Key.AutoID {
+			// 新数据库中没有行，编号重新开始
+			kv.Key.AutoID.Next = 1
+		}
+	}
+
+	// 公式中通过字段 ID 引用其他字段，需要替换为新的字段 ID
+	for _, kv := range ret.KeyValues {
+		if KeyTypeFormula == kv.Key.Type {
+			for oldID, newID := range keyIDMap {
+				kv.Key.Formula = strings.ReplaceAll(kv.Key.Formula, oldID, newID)
+			}
+		}
 	}
 
 	oldKeyIDs = gulu.Str.RemoveDuplicatedElem(oldKeyIDs)
@@ -566,13 +646,40 @@ func (av *AttributeView) ShallowClone() (ret *AttributeView) {
 			column.ID = keyIDMap[column.ID]
 		}
 		view.Table.RowIDs = []string{}
+		if nil != view.Kanban {
+			view.Kanban.ID = ast.NewNodeID()
+			view.Kanban.GroupKeyID = keyIDMap[view.Kanban.GroupKeyID]
+			for _, column := range view.Kanban.Columns {
+				column.CardIDs = []string{}
+			}
+		}
+		if nil != view.Calendar {
+			view.Calendar.ID = ast.NewNodeID()
+			view.Calendar.DateKeyID = keyIDMap[view.Calendar.DateKeyID]
+		}
+		if nil != view.Gallery {
+			view.Gallery.ID = ast.NewNodeID()
+			view.Gallery.CoverFromAssetKeyID = keyIDMap[view.Gallery.CoverFromAssetKeyID]
+		}
 
-		for _, f := range view.Table.Filters {
+		for _, f := range view.Table.GetFilter().GetLeaves() {
 			f.Column = keyIDMap[f.Column]
 		}
 		for _, s := range view.Table.Sorts {
 			s.Column = keyIDMap[s.Column]
 		}
+		if nil != view.Table.Group {
+			view.Table.Group.Column = keyIDMap[view.Table.Group.Column]
+		}
+		for _, f := range view.Table.Formats {
+			f.ID = ast.NewNodeID()
+			if "" != f.Column {
+				f.Column = keyIDMap[f.Column]
+			}
+			for _, leaf := range f.Condition.GetLeaves() {
+				leaf.Column = keyIDMap[leaf.Column]
+			}
+		}
 	}
 	ret.ViewID = ret.Views[0].ID
 
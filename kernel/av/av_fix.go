@@ -25,6 +25,22 @@ import (
 
 func UpgradeSpec(av *AttributeView) {
 	upgradeSpec1(av)
+	upgradeSpec2(av)
+}
+
+func upgradeSpec2(av *AttributeView) {
+	if 2 <= av.Spec {
+		return
+	}
+
+	// 过滤组从 filters 中移到 filterGroups 中
+	for _, view := range av.Views {
+		if nil != view.Table {
+			view.Table.SetFilters(view.Table.Filters)
+		}
+	}
+
+	av.Spec = 2
 }
 
 func upgradeSpec1(av *AttributeView) {
@@ -105,8 +121,7 @@ func upgradeSpec1(av *AttributeView) {
 				}
 
 				for _, view := range av.Views {
-					switch view.LayoutType {
-					case LayoutTypeTable:
+					if view.HasLayoutTable() {
 						for _, column := range view.Table.Columns {
 							if "" == column.ID {
 								column.ID = kv.Key.ID
@@ -0,0 +1,206 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package av
+
+import (
+	"sort"
+	"time"
+)
+
+// LayoutCalendar 描述了日历布局的结构。
+//
+// 日历视图的字段、过滤和排序设置复用视图上的表格布局 View.Table，这里仅保存日历特有的设置。
+type LayoutCalendar struct {
+	Spec int    `json:"spec"` // 布局格式版本
+	ID   string `json:"id"`   // 布局 ID
+
+	DateKeyID      string       `json:"dateKeyID"`      // 日期字段 ID
+	Mode           CalendarMode `json:"mode"`           // 显示模式
+	FirstDayOfWeek int          `json:"firstDayOfWeek"` // 每周第一天：0 周日、1 周一
+}
+
+// CalendarMode 描述了日历的显示模式。
+type CalendarMode string
+
+const (
+	CalendarModeMonth    CalendarMode = "month"    // 月视图
+	CalendarModeWeek     CalendarMode = "week"     // 周视图
+	CalendarModeTimeline CalendarMode = "timeline" // 时间线（甘特图），默认显示所有日程所在的时间范围
+)
+
+func IsCalendarMode(mode string) bool {
+	switch CalendarMode(mode) {
+	case CalendarModeMonth, CalendarModeWeek, CalendarModeTimeline:
+		return true
+	}
+	return false
+}
+
+// GetCalendarDateKey 获取日历日期字段，未设置日期字段或者日期字段类型已经被修改时使用第一个日期字段。
+func GetCalendarDateKey(attrView *AttributeView, layout *LayoutCalendar) (ret *Key) {
+	if nil != layout {
+		if key, _ := attrView.GetKey(layout.DateKeyID); nil != key && KeyTypeDate == key.Type {
+			return key
+		}
+	}
+
+	for _, kv := range attrView.KeyValues {
+		if KeyTypeDate == kv.Key.Type {
+			return kv.Key
+		}
+	}
+	return
+}
+
+// Calendar 描述了日历实例的结构。
+//
+// 日历基于表格渲染：先按表格进行过滤和排序，然后调用 FillEvents 生成时间窗口内的日程。
+type Calendar struct {
+	*Table
+
+	DateKey        *TableColumn     `json:"dateKey"`        // 日期字段
+	Mode           CalendarMode     `json:"mode"`           // 显示模式
+	FirstDayOfWeek int              `json:"firstDayOfWeek"` // 每周第一天
+	Start          int64            `json:"start"`          // 时间窗口开始时间（包含）
+	End            int64            `json:"end"`            // 时间窗口结束时间（不包含）
+	Events         []*CalendarEvent `json:"events"`         // 时间窗口内的日程
+	NoDateCount    int              `json:"noDateCount"`    // 没有填写日期的行数
+
+	rows []*TableRow // 过滤和排序后的所有行，用于按不同时间窗口生成日程
+}
+
+// CalendarEvent 描述了日程的结构。
+type CalendarEvent struct {
+	ID     string    `json:"id"`     // 行 ID
+	Start  int64     `json:"start"`  // 开始时间
+	End    int64     `json:"end"`    // 结束时间，没有结束日期时和开始时间相同
+	AllDay bool      `json:"allDay"` // 是否全天（日期不包含时间）
+	Row    *TableRow `json:"row"`    // 行
+}
+
+func (calendar *Calendar) GetType() LayoutType {
+	return LayoutTypeCalendar
+}
+
+// CalcCols 日历不显示字段计算结果。
+func (calendar *Calendar) CalcCols() {
+}
+
+// FillEvents 生成时间窗口 [start, end) 内的日程，start 和 end 为 0 时使用当前时间所在的默认窗口。
+func (calendar *Calendar) FillEvents(start, end int64) {
+	if nil == calendar.rows {
+		calendar.rows = calendar.Rows
+		calendar.Table.Rows = []*TableRow{}
+	}
+
+	if 0 >= start || 0 >= end || start >= end {
+		startTime, endTime := GetCalendarDefaultWindow(time.Now(), calendar.Mode, calendar.FirstDayOfWeek)
+		if CalendarModeTimeline == calendar.Mode {
+			startTime, endTime = calendar.timelineWindow(startTime, endTime)
+		}
+		start, end = startTime.UnixMilli(), endTime.UnixMilli()
+	}
+	calendar.Start, calendar.End = start, end
+
+	calendar.Events = []*CalendarEvent{}
+	calendar.NoDateCount = 0
+	if nil == calendar.DateKey {
+		calendar.NoDateCount = len(calendar.rows)
+		return
+	}
+
+	for _, row := range calendar.rows {
+		event := newCalendarEvent(row, row.GetValue(calendar.DateKey.ID))
+		if nil == event {
+			calendar.NoDateCount++
+			continue
+		}
+
+		if event.Start < end && event.End >= start {
+			calendar.Events = append(calendar.Events, event)
+		}
+	}
+
+	sort.SliceStable(calendar.Events, func(i, j int) bool {
+		return calendar.Events[i].Start < calendar.Events[j].Start
+	})
+}
+
+// timelineWindow 时间线默认显示所有日程所在的时间范围（按天对齐），没有日程时使用 start 和 end。
+func (calendar *Calendar) timelineWindow(start, end time.Time) (time.Time, time.Time) {
+	if nil == calendar.DateKey {
+		return start, end
+	}
+
+	var minStart, maxEnd int64
+	for _, row := range calendar.rows {
+		event := newCalendarEvent(row, row.GetValue(calendar.DateKey.ID))
+		if nil == event {
+			continue
+		}
+		if 0 == minStart || event.Start < minStart {
+			minStart = event.Start
+		}
+		if event.End > maxEnd {
+			maxEnd = event.End
+		}
+	}
+	if 0 == minStart {
+		return start, end
+	}
+
+	startTime, endTime := time.UnixMilli(minStart), time.UnixMilli(maxEnd)
+	start = time.Date(startTime.Year(), startTime.Month(), startTime.Day(), 0, 0, 0, 0, time.Local)
+	end = time.Date(endTime.Year(), endTime.Month(), endTime.Day(), 0, 0, 0, 0, time.Local).AddDate(0, 0, 1)
+	return start, end
+}
+
+func newCalendarEvent(row *TableRow, value *Value) (ret *CalendarEvent) {
+	if nil == value || nil == value.Date || !value.Date.IsNotEmpty {
+		return
+	}
+
+	ret = &CalendarEvent{ID: row.ID, Start: value.Date.Content, End: value.Date.Content, AllDay: value.Date.IsNotTime, Row: row}
+	if value.Date.HasEndDate && value.Date.IsNotEmpty2 && value.Date.Content2 > value.Date.Content {
+		ret.End = value.Date.Content2
+	}
+	if ret.AllDay {
+		// 全天日程的结束时间取结束日期当天的最后时刻
+		endTime := time.UnixMilli(ret.End)
+		ret.End = time.Date(endTime.Year(), endTime.Month(), endTime.Day(), 23, 59, 59, 999999999, time.Local).UnixMilli()
+	}
+	return
+}
+
+// GetCalendarDefaultWindow 获取显示模式下包含 now 的默认时间窗口。
+func GetCalendarDefaultWindow(now time.Time, mode CalendarMode, firstDayOfWeek int) (start, end time.Time) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	switch mode {
+	case CalendarModeWeek:
+		offset := (int(today.Weekday()) - firstDayOfWeek + 7) % 7
+		start = today.AddDate(0, 0, -offset)
+		end = start.AddDate(0, 0, 7)
+	case CalendarModeTimeline:
+		// 没有日程时时间线显示当前季度
+		start = time.Date(now.Year(), now.Month()-(now.Month()-1)%3, 1, 0, 0, 0, 0, time.Local)
+		end = start.AddDate(0, 3, 0)
+	default:
+		start = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
+		end = start.AddDate(0, 1, 0)
+	}
+	return
+}
@@ -20,6 +20,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/88250/gulu"
 	"github.com/88250/lute/ast"
 	"github.com/siyuan-note/siyuan/kernel/util"
 )
@@ -28,12 +29,110 @@ type Filterable interface {
 	FilterRows(attrView *AttributeView)
 }
 
+// ViewFilter 描述了过滤规则树的节点。
+//
+// Conjunction 为空时是过滤条件（叶子节点），否则是过滤组，Children 中的子规则按 Conjunction 进行组合，过滤组可以任意嵌套。
 type ViewFilter struct {
 	Column        string         `json:"column"`
 	Operator      FilterOperator `json:"operator"`
 	Value         *Value         `json:"value"`
 	RelativeDate  *RelativeDate  `json:"relativeDate"`
 	RelativeDate2 *RelativeDate  `json:"relativeDate2"`
+
+	Conjunction FilterConjunction `json:"conjunction,omitempty"` // 过滤组的组合方式
+	Children    []*ViewFilter     `json:"children,omitempty"`    // 过滤组的子规则
+	Not         bool              `json:"not,omitempty"`         // 是否对结果取反
+}
+
+// FilterConjunction 描述了过滤组中子规则的组合方式。
+type FilterConjunction string
+
+const (
+	FilterConjunctionAnd FilterConjunction = "and"
+	FilterConjunctionOr  FilterConjunction = "or"
+)
+
+// NewViewFilterGroup 创建一个 AND 过滤组。
+func NewViewFilterGroup(children ...*ViewFilter) *ViewFilter {
+	if nil == children {
+		children = []*ViewFilter{}
+	}
+	return &ViewFilter{Conjunction: FilterConjunctionAnd, Children: children}
+}
+
+func (filter *ViewFilter) IsGroup() bool {
+	return "" != filter.Conjunction
+}
+
+// IsEmpty 判断过滤规则树中是否没有任何过滤条件。
+func (filter *ViewFilter) IsEmpty() bool {
+	return nil == filter || 1 > len(filter.GetLeaves())
+}
+
+// GetLeaves 获取过滤规则树中的所有过滤条件。
+func (filter *ViewFilter) GetLeaves() (ret []*ViewFilter) {
+	if nil == filter {
+		return
+	}
+
+	if !filter.IsGroup() {
+		return []*ViewFilter{filter}
+	}
+	for _, child := range filter.Children {
+		ret = append(ret, child.GetLeaves()...)
+	}
+	return
+}
+
+// GetAffectLeaves 获取所有行都必须满足的过滤条件，即仅经过未取反的 AND 过滤组就能到达的过滤条件，用于给新添加的行设置默认值。
+func (filter *ViewFilter) GetAffectLeaves() (ret []*ViewFilter) {
+	if nil == filter || filter.Not {
+		return
+	}
+
+	if !filter.IsGroup() {
+		return []*ViewFilter{filter}
+	}
+	if FilterConjunctionAnd != filter.Conjunction && 1 < len(filter.Children) {
+		return
+	}
+	for _, child := range filter.Children {
+		ret = append(ret, child.GetAffectLeaves()...)
+	}
+	return
+}
+
+// RemoveLeaves 移除过滤规则树中满足 remove 的过滤条件。
+func (filter *ViewFilter) RemoveLeaves(remove func(leaf *ViewFilter) bool) {
+	if nil == filter || !filter.IsGroup() {
+		return
+	}
+
+	children := []*ViewFilter{}
+	for _, child := range filter.Children {
+		if !child.IsGroup() && remove(child) {
+			continue
+		}
+		child.RemoveLeaves(remove)
+		children = append(children, child)
+	}
+	filter.Children = children
+}
+
+func (filter *ViewFilter) Clone() (ret *ViewFilter) {
+	if nil == filter {
+		return
+	}
+
+	data, err := gulu.JSON.MarshalJSON(filter)
+	if err != nil {
+		return
+	}
+	err = gulu.JSON.UnmarshalJSON(data, &ret)
+	if err != nil {
+		return
+	}
+	return
 }
 
 type RelativeDateUnit int
@@ -84,6 +183,10 @@ func (value *Value) Filter(filter *ViewFilter, attrView *AttributeView, rowID st
 		return true
 	}
 
+	if KeyTypeFormula == value.Type && nil != value.Formula {
+		return value.filterFormula(filter, attrView, rowID, attrViewCache)
+	}
+
 	if nil != filter.Value && value.Type != filter.Value.Type {
 		// 由于字段类型被用户编辑过导致和过滤器值类型不匹配，该情况下不过滤
 		return true
@@ -215,6 +318,23 @@ func (value *Value) Filter(filter *ViewFilter, attrView *AttributeView, rowID st
 	return value.filter(filter.Value, filter.RelativeDate, filter.RelativeDate2, filter.Operator)
 }
 
+// filterFormula 将公式计算结果转换为结果类型的值后再过滤，结果类型和过滤值类型不一致时按文本过滤。
+func (value *Value) filterFormula(filter *ViewFilter, attrView *AttributeView, rowID string, attrViewCache *map[string]*AttributeView) bool {
+	val := value.Formula.ToValue()
+	f := *filter
+	if nil != f.Value {
+		if KeyTypeFormula == f.Value.Type && nil != f.Value.Formula {
+			f.Value = f.Value.Formula.ToValue()
+		}
+
+		if val.Type != f.Value.Type {
+			val = &Value{Type: KeyTypeText, Text: &ValueText{Content: val.String(false)}}
+			f.Value = &Value{Type: KeyTypeText, Text: &ValueText{Content: f.Value.String(false)}}
+		}
+	}
+	return val.Filter(&f, attrView, rowID, attrViewCache)
+}
+
 func (value *Value) filter(other *Value, relativeDate, relativeDate2 *RelativeDate, operator FilterOperator) bool {
 	switch value.Type {
 	case KeyTypeBlock:
@@ -555,6 +675,37 @@ func (value *Value) filter(other *Value, relativeDate, relativeDate2 *RelativeDa
 				return "" != strings.TrimSpace(value.Template.Content)
 			}
 		}
+	case KeyTypeAutoID:
+		if nil != value.AutoID && nil != other && nil != other.AutoID {
+			switch operator {
+			case FilterOperatorIsEqual:
+				if 1 > other.AutoID.Content {
+					return true
+				}
+				return value.AutoID.Content == other.AutoID.Content
+			case FilterOperatorIsNotEqual:
+				if 1 > other.AutoID.Content {
+					return true
+				}
+				return value.AutoID.Content != other.AutoID.Content
+			case FilterOperatorIsGreater:
+				return value.AutoID.Content > other.AutoID.Content
+			case FilterOperatorIsGreaterOrEqual:
+				return value.AutoID.Content >= other.AutoID.Content
+			case FilterOperatorIsLess:
+				return value.AutoID.Content < other.AutoID.Content
+			case FilterOperatorIsLessOrEqual:
+				return value.AutoID.Content <= other.AutoID.Content
+			case FilterOperatorContains:
+				return strings.Contains(value.AutoID.FormattedContent, other.AutoID.FormattedContent)
+			case FilterOperatorDoesNotContain:
+				return !strings.Contains(value.AutoID.FormattedContent, other.AutoID.FormattedContent)
+			case FilterOperatorStartsWith:
+				return strings.HasPrefix(value.AutoID.FormattedContent, other.AutoID.FormattedContent)
+			case FilterOperatorEndsWith:
+				return strings.HasSuffix(value.AutoID.FormattedContent, other.AutoID.FormattedContent)
+			}
+		}
 	case KeyTypeCheckbox:
 		if nil != value.Checkbox {
 			switch operator {
@@ -716,7 +867,7 @@ func calcRelativeTimeRegion(count int, unit RelativeDateUnit, direction Relative
 
 func (filter *ViewFilter) GetAffectValue(key *Key, defaultVal *Value) (ret *Value) {
 	if nil != filter.Value {
-		if KeyTypeRelation == filter.Value.Type || KeyTypeTemplate == filter.Value.Type || KeyTypeRollup == filter.Value.Type || KeyTypeUpdated == filter.Value.Type || KeyTypeCreated == filter.Value.Type {
+		if KeyTypeRelation == filter.Value.Type || KeyTypeTemplate == filter.Value.Type || KeyTypeRollup == filter.Value.Type || KeyTypeUpdated == filter.Value.Type || KeyTypeCreated == filter.Value.Type || KeyTypeFormula == filter.Value.Type || KeyTypeAutoID == filter.Value.Type {
 			// 所有生成的数据都不设置默认值
 			return nil
 		}
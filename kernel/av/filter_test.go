@@ -0,0 +1,112 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package av
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/88250/gulu"
+)
+
+func textFilter(column, content string) *ViewFilter {
+	return &ViewFilter{Column: column, Operator: FilterOperatorIsEqual, Value: &Value{Type: KeyTypeText, Text: &ValueText{Content: content}}}
+}
+
+func TestFilterRows(t *testing.T) {
+	rows := [][2]string{{"a", "x"}, {"a", "y"}, {"b", "x"}, {"b", "y"}}
+	newTable := func(filters ...*ViewFilter) *Table {
+		table := &Table{Filters: filters, Columns: []*TableColumn{{ID: "c1"}, {ID: "c2"}}}
+		for i, r := range rows {
+			row := &TableRow{ID: string(rune('0' + i))}
+			row.Cells = append(row.Cells,
+				&TableCell{ValueType: KeyTypeText, Value: &Value{Type: KeyTypeText, Text: &ValueText{Content: r[0]}}},
+				&TableCell{ValueType: KeyTypeText, Value: &Value{Type: KeyTypeText, Text: &ValueText{Content: r[1]}}})
+			table.Rows = append(table.Rows, row)
+		}
+		return table
+	}
+
+	cases := []struct {
+		name    string
+		filters []*ViewFilter
+		want    []string
+	}{
+		{"empty", nil, []string{"0", "1", "2", "3"}},
+		{"and", []*ViewFilter{textFilter("c1", "a"), textFilter("c2", "x")}, []string{"0"}},
+		{"or group", []*ViewFilter{{Conjunction: FilterConjunctionOr, Children: []*ViewFilter{textFilter("c1", "a"), textFilter("c2", "x")}}}, []string{"0", "1", "2"}},
+		{"not group", []*ViewFilter{{Conjunction: FilterConjunctionAnd, Not: true, Children: []*ViewFilter{textFilter("c1", "a"), textFilter("c2", "x")}}}, []string{"1", "2", "3"}},
+		{"nested", []*ViewFilter{textFilter("c2", "y"), {Conjunction: FilterConjunctionOr, Children: []*ViewFilter{textFilter("c1", "b"), {Conjunction: FilterConjunctionAnd, Children: []*ViewFilter{textFilter("c1", "a"), textFilter("c2", "x")}}}}}, []string{"3"}},
+		{"removed column", []*ViewFilter{textFilter("c3", "a")}, []string{"0", "1", "2", "3"}},
+	}
+
+	attrView := &AttributeView{ID: "av"}
+	for _, c := range cases {
+		table := newTable(c.filters...)
+		table.FilterRows(attrView)
+		var got []string
+		for _, row := range table.Rows {
+			got = append(got, row.ID)
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("case [%s]: got %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestLayoutTableFiltersJSON(t *testing.T) {
+	// 旧版数据是平铺的过滤条件列表，需要能够直接读取
+	var layout LayoutTable
+	legacy := `{"filters":[{"column":"c1","operator":"=","value":{"type":"text","text":{"content":"a"}}}]}`
+	if err := gulu.JSON.UnmarshalJSON([]byte(legacy), &layout); err != nil {
+		t.Fatal(err)
+	}
+	if 1 != len(layout.Filters) || "c1" != layout.Filters[0].Column || layout.Filters[0].IsGroup() {
+		t.Fatalf("unexpected legacy filters %+v", layout.Filters)
+	}
+
+	// 过滤组单独保存在 filterGroups 中，filters 中只有旧版本能够识别的过滤条件
+	layout.SetFilters(append(layout.Filters, &ViewFilter{Conjunction: FilterConjunctionOr, Children: []*ViewFilter{textFilter("c2", "x")}}))
+	data, err := gulu.JSON.MarshalJSON(layout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"filters":[{"column":"c1"`) || !strings.Contains(string(data), `"filterGroups":[{`) {
+		t.Fatalf("unexpected json %s", data)
+	}
+
+	var decoded LayoutTable
+	if err = gulu.JSON.UnmarshalJSON(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if 1 != len(decoded.Filters) || 1 != len(decoded.FilterGroups) || 2 != len(decoded.GetFilter().Children) || 2 != len(decoded.GetFilter().GetLeaves()) {
+		t.Fatalf("unexpected decoded filters %+v", decoded)
+	}
+}
+
+func TestUpgradeSpec2(t *testing.T) {
+	// 之前的版本将过滤组保存在 filters 中
+	group := &ViewFilter{Conjunction: FilterConjunctionOr, Children: []*ViewFilter{textFilter("c1", "a"), textFilter("c2", "x")}}
+	attrView := &AttributeView{Spec: 1, Views: []*View{{Table: &LayoutTable{Filters: []*ViewFilter{textFilter("c1", "b"), group}}}}}
+	upgradeSpec2(attrView)
+
+	table := attrView.Views[0].Table
+	if 2 != attrView.Spec || 1 != len(table.Filters) || "c1" != table.Filters[0].Column || 1 != len(table.FilterGroups) || group != table.FilterGroups[0] {
+		t.Fatalf("unexpected upgraded filters %+v", table)
+	}
+}
@@ -0,0 +1,1263 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package av
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/dgraph-io/ristretto"
+)
+
+// 公式是一个只能读取当前行字段值的表达式，不能访问文件、网络或者其他任何外部资源。
+//
+// 语法：
+//   - 字面量：数字 1.5、字符串 "abc" 或者 'abc'、布尔值 true 和 false
+//   - 运算符：+ - * / % ^、== != < <= > >=、&& || !，其中 + 两边有字符串时进行字符串拼接
+//   - 字段引用：prop("字段 ID")，找不到字段 ID 时按字段名查找；关联和汇总字段返回列表
+//   - 函数调用：if(条件, 值1, 值2)、round(x, 2)、dateAdd(now(), 1, "days") 等，完整列表见 formulaFuncs
+//
+// 为了防止恶意或者错误的公式拖慢渲染，公式的长度、嵌套深度和求值步数都有上限。
+
+const (
+	formulaMaxLength = 4096    // 公式最大长度
+	formulaMaxDepth  = 64      // 语法树最大嵌套深度
+	formulaMaxSteps  = 10000   // 单次求值最大步数
+	formulaMaxString = 1 << 16 // 字符串结果最大长度
+)
+
+// formulaDate 描述了公式中的日期值。
+type formulaDate struct {
+	time      time.Time
+	isNotTime bool
+}
+
+// formulaNode 描述了公式语法树的节点。
+type formulaNode struct {
+	kind  formulaNodeKind
+	op    string
+	num   float64
+	str   string
+	args  []*formulaNode
+	token int // 节点在公式中的位置，用于报错
+}
+
+type formulaNodeKind int
+
+const (
+	formulaNodeNumber formulaNodeKind = iota
+	formulaNodeString
+	formulaNodeBool
+	formulaNodeCall
+	formulaNodeUnary
+	formulaNodeBinary
+)
+
+// ParseFormula 解析公式，返回的语法树可以多次求值。
+func ParseFormula(expr string) (ret *Formula, err error) {
+	if formulaMaxLength < len(expr) {
+		err = fmt.Errorf("formula is too long")
+		return
+	}
+
+	tokens, err := lexFormula(expr)
+	if err != nil {
+		return
+	}
+
+	p := &formulaParser{tokens: tokens}
+	root, err := p.parseExpr(0)
+	if err != nil {
+		return
+	}
+	if p.peek().kind != formulaTokenEOF {
+		err = fmt.Errorf("unexpected [%s] at %d", p.peek().text, p.peek().pos)
+		return
+	}
+	ret = &Formula{root: root}
+	return
+}
+
+// Formula 描述了解析后的公式。
+type Formula struct {
+	root *formulaNode
+}
+
+// formulaCacheMaxCount 缓存的公式数量上限。
+const formulaCacheMaxCount = 1024
+
+// formulaCache 缓存解析后的公式，避免渲染每一行时都重新解析。解析结果只和公式表达式有关，超过上限后淘汰不常用的公式。
+var formulaCache, _ = ristretto.NewCache[string, *Formula](&ristretto.Config[string, *Formula]{
+	NumCounters: formulaCacheMaxCount * 10,
+	MaxCost:     formulaCacheMaxCount,
+	BufferItems: 64,
+})
+
+func getFormula(expr string) (ret *Formula, err error) {
+	if cached, ok := formulaCache.Get(expr); ok {
+		return cached, nil
+	}
+
+	ret, err = ParseFormula(expr)
+	if err != nil {
+		return
+	}
+	formulaCache.Set(expr, ret, 1)
+	return
+}
+
+// FormulaContext 描述了公式求值时可以访问的行数据，同一行的公式共享一个上下文，公式字段之间可以互相引用。
+type FormulaContext struct {
+	keys   map[string]*Key
+	names  map[string]*Key
+	values map[string]*Value
+
+	results    map[string]*ValueFormula
+	evaluating map[string]bool
+}
+
+// NewFormulaContext 使用属性视图的字段和某一行的字段值创建公式求值上下文。
+func NewFormulaContext(attrView *AttributeView, rowValues []*KeyValues) (ret *FormulaContext) {
+	ret = &FormulaContext{
+		keys:       map[string]*Key{},
+		names:      map[string]*Key{},
+		values:     map[string]*Value{},
+		results:    map[string]*ValueFormula{},
+		evaluating: map[string]bool{},
+	}
+	for _, kv := range attrView.KeyValues {
+		ret.keys[kv.Key.ID] = kv.Key
+		if _, ok := ret.names[kv.Key.Name]; !ok {
+			ret.names[kv.Key.Name] = kv.Key
+		}
+	}
+	for _, kv := range rowValues {
+		if nil != kv.Key && 0 < len(kv.Values) {
+			ret.values[kv.Key.ID] = kv.Values[0]
+		}
+	}
+	return
+}
+
+// SetValue 设置字段值，已经渲染过的值（比如模板列）通过这里覆盖原始值。
+func (ctx *FormulaContext) SetValue(value *Value) {
+	if nil == value || "" == value.KeyID {
+		return
+	}
+	ctx.values[value.KeyID] = value
+}
+
+// Render 计算公式字段的值。
+func (ctx *FormulaContext) Render(key *Key) (ret *ValueFormula) {
+	if ret = ctx.results[key.ID]; nil != ret {
+		return
+	}
+
+	if ctx.evaluating[key.ID] {
+		return &ValueFormula{Type: KeyTypeText, Text: &ValueText{}, Error: "circular reference"}
+	}
+	ctx.evaluating[key.ID] = true
+	defer delete(ctx.evaluating, key.ID)
+
+	ret = ctx.eval(key)
+	if KeyTypeNumber == ret.Type && nil != ret.Number {
+		ret.Number.Format = key.NumberFormat
+		ret.Number.FormatNumber()
+	}
+	ctx.results[key.ID] = ret
+	return
+}
+
+func (ctx *FormulaContext) eval(key *Key) (ret *ValueFormula) {
+	if "" == strings.TrimSpace(key.Formula) {
+		return &ValueFormula{Type: KeyTypeText, Text: &ValueText{}}
+	}
+
+	formula, err := getFormula(key.Formula)
+	if err != nil {
+		return &ValueFormula{Type: KeyTypeText, Text: &ValueText{}, Error: err.Error()}
+	}
+
+	e := &formulaEvaluator{ctx: ctx}
+	val, err := e.eval(formula.root)
+	if err != nil {
+		return &ValueFormula{Type: KeyTypeText, Text: &ValueText{}, Error: err.Error()}
+	}
+	return newValueFormula(val)
+}
+
+func newValueFormula(val interface{}) (ret *ValueFormula) {
+	switch v := val.(type) {
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return &ValueFormula{Type: KeyTypeNumber, Number: &ValueNumber{}}
+		}
+		return &ValueFormula{Type: KeyTypeNumber, Number: NewFormattedValueNumber(v, NumberFormatNone)}
+	case bool:
+		return &ValueFormula{Type: KeyTypeCheckbox, Checkbox: &ValueCheckbox{Checked: v}}
+	case *formulaDate:
+		return &ValueFormula{Type: KeyTypeDate, Date: NewFormattedValueDate(v.time.UnixMilli(), 0, DateFormatNone, v.isNotTime, false)}
+	case nil:
+		return &ValueFormula{Type: KeyTypeText, Text: &ValueText{}}
+	default:
+		return &ValueFormula{Type: KeyTypeText, Text: &ValueText{Content: formulaToString(val)}}
+	}
+}
+
+// 词法分析
+
+type formulaTokenKind int
+
+const (
+	formulaTokenEOF formulaTokenKind = iota
+	formulaTokenNumber
+	formulaTokenString
+	formulaTokenIdent
+	formulaTokenOp
+)
+
+type formulaToken struct {
+	kind formulaTokenKind
+	text string
+	pos  int
+}
+
+func lexFormula(expr string) (ret []*formulaToken, err error) {
+	i := 0
+	for i < len(expr) {
+		r, size := utf8.DecodeRuneInString(expr[i:])
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+		case '0' <= r && '9' >= r || ('.' == r && i+1 < len(expr) && '0' <= expr[i+1] && '9' >= expr[i+1]):
+			start := i
+			for i < len(expr) && ('0' <= expr[i] && '9' >= expr[i] || '.' == expr[i]) {
+				i++
+			}
+			ret = append(ret, &formulaToken{kind: formulaTokenNumber, text: expr[start:i], pos: start})
+		case '"' == r || '\'' == r:
+			start := i
+			i++
+			var buf strings.Builder
+			closed := false
+			for i < len(expr) {
+				c := expr[i]
+				if '\\' == c && i+1 < len(expr) {
+					buf.WriteByte(expr[i+1])
+					i += 2
+					continue
+				}
+				if rune(c) == r {
+					closed = true
+					i++
+					break
+				}
+				buf.WriteByte(c)
+				i++
+			}
+			if !closed {
+				err = fmt.Errorf("unterminated string at %d", start)
+				return
+			}
+			ret = append(ret, &formulaToken{kind: formulaTokenString, text: buf.String(), pos: start})
+		case unicode.IsLetter(r) || '_' == r:
+			start := i
+			for i < len(expr) {
+				c, s := utf8.DecodeRuneInString(expr[i:])
+				if !unicode.IsLetter(c) && !unicode.IsDigit(c) && '_' != c {
+					break
+				}
+				i += s
+			}
+			ret = append(ret, &formulaToken{kind: formulaTokenIdent, text: expr[start:i], pos: start})
+		default:
+			op := ""
+			for _, candidate := range []string{"==", "!=", "<=", ">=", "&&", "||", "+", "-", "*", "/", "%", "^", "<", ">", "!", "(", ")", ","} {
+				if strings.HasPrefix(expr[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if "" == op {
+				err = fmt.Errorf("unexpected character [%c] at %d", r, i)
+				return
+			}
+			ret = append(ret, &formulaToken{kind: formulaTokenOp, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	ret = append(ret, &formulaToken{kind: formulaTokenEOF, pos: len(expr)})
+	return
+}
+
+// 语法分析
+
+type formulaParser struct {
+	tokens []*formulaToken
+	pos    int
+}
+
+func (p *formulaParser) peek() *formulaToken {
+	return p.tokens[p.pos]
+}
+
+func (p *formulaParser) next() *formulaToken {
+	ret := p.tokens[p.pos]
+	if formulaTokenEOF != ret.kind {
+		p.pos++
+	}
+	return ret
+}
+
+func (p *formulaParser) expectOp(op string) error {
+	t := p.next()
+	if formulaTokenOp != t.kind || op != t.text {
+		return fmt.Errorf("expected [%s] at %d", op, t.pos)
+	}
+	return nil
+}
+
+// formulaBinaryPrecedences 二元运算符优先级，数值越大优先级越高。
+var formulaBinaryPrecedences = map[string]int{
+	"||": 1,
+	"&&": 2,
+	"==": 3, "!=": 3,
+	"<": 4, "<=": 4, ">": 4, ">=": 4,
+	"+": 5, "-": 5,
+	"*": 6, "/": 6, "%": 6,
+	"^": 8,
+}
+
+func (p *formulaParser) parseExpr(depth int) (ret *formulaNode, err error) {
+	return p.parseBinary(depth, 1)
+}
+
+func (p *formulaParser) parseBinary(depth, minPrec int) (ret *formulaNode, err error) {
+	if formulaMaxDepth < depth {
+		err = errors.New("formula is nested too deeply")
+		return
+	}
+
+	ret, err = p.parseUnary(depth + 1)
+	if err != nil {
+		return
+	}
+
+	for {
+		t := p.peek()
+		prec, ok := formulaBinaryPrecedences[t.text]
+		if formulaTokenOp != t.kind || !ok || prec < minPrec {
+			return
+		}
+		p.next()
+
+		nextMinPrec := prec + 1
+		if "^" == t.text {
+			nextMinPrec = prec // 乘方右结合
+		}
+		var right *formulaNode
+		right, err = p.parseBinary(depth+1, nextMinPrec)
+		if err != nil {
+			return
+		}
+		ret = &formulaNode{kind: formulaNodeBinary, op: t.text, args: []*formulaNode{ret, right}, token: t.pos}
+	}
+}
+
+func (p *formulaParser) parseUnary(depth int) (ret *formulaNode, err error) {
+	if formulaMaxDepth < depth {
+		err = errors.New("formula is nested too deeply")
+		return
+	}
+
+	t := p.peek()
+	if formulaTokenOp == t.kind && ("-" == t.text || "!" == t.text) {
+		p.next()
+		var operand *formulaNode
+		// 一元负号的优先级低于乘方：-2^2 = -4
+		if "-" == t.text {
+			operand, err = p.parseBinary(depth+1, formulaBinaryPrecedences["^"])
+		} else {
+			operand, err = p.parseUnary(depth + 1)
+		}
+		if err != nil {
+			return
+		}
+		ret = &formulaNode{kind: formulaNodeUnary, op: t.text, args: []*formulaNode{operand}, token: t.pos}
+		return
+	}
+	return p.parsePrimary(depth + 1)
+}
+
+func (p *formulaParser) parsePrimary(depth int) (ret *formulaNode, err error) {
+	t := p.next()
+	switch t.kind {
+	case formulaTokenNumber:
+		num, parseErr := strconv.ParseFloat(t.text, 64)
+		if nil != parseErr {
+			err = fmt.Errorf("invalid number [%s] at %d", t.text, t.pos)
+			return
+		}
+		ret = &formulaNode{kind: formulaNodeNumber, num: num, token: t.pos}
+	case formulaTokenString:
+		ret = &formulaNode{kind: formulaNodeString, str: t.text, token: t.pos}
+	case formulaTokenIdent:
+		switch t.text {
+		case "true", "false":
+			ret = &formulaNode{kind: formulaNodeBool, str: t.text, token: t.pos}
+			return
+		}
+
+		if _, ok := formulaFuncs[t.text]; !ok {
+			err = fmt.Errorf("unknown function [%s] at %d", t.text, t.pos)
+			return
+		}
+		if err = p.expectOp("("); err != nil {
+			return
+		}
+
+		ret = &formulaNode{kind: formulaNodeCall, str: t.text, token: t.pos}
+		if next := p.peek(); formulaTokenOp == next.kind && ")" == next.text {
+			p.next()
+			return
+		}
+		for {
+			var arg *formulaNode
+			arg, err = p.parseExpr(depth + 1)
+			if err != nil {
+				return
+			}
+			ret.args = append(ret.args, arg)
+
+			next := p.next()
+			if formulaTokenOp == next.kind && ")" == next.text {
+				return
+			}
+			if formulaTokenOp != next.kind || "," != next.text {
+				err = fmt.Errorf("expected [,] or [)] at %d", next.pos)
+				return
+			}
+		}
+	case formulaTokenOp:
+		if "(" == t.text {
+			ret, err = p.parseExpr(depth + 1)
+			if err != nil {
+				return
+			}
+			err = p.expectOp(")")
+			return
+		}
+		err = fmt.Errorf("unexpected [%s] at %d", t.text, t.pos)
+	default:
+		err = errors.New("unexpected end of formula")
+	}
+	return
+}
+
+// 求值
+
+type formulaEvaluator struct {
+	ctx   *FormulaContext
+	steps int
+}
+
+func (e *formulaEvaluator) eval(node *formulaNode) (ret interface{}, err error) {
+	e.steps++
+	if formulaMaxSteps < e.steps {
+		err = errors.New("formula is too complex")
+		return
+	}
+
+	switch node.kind {
+	case formulaNodeNumber:
+		return node.num, nil
+	case formulaNodeString:
+		return node.str, nil
+	case formulaNodeBool:
+		return "true" == node.str, nil
+	case formulaNodeUnary:
+		var operand interface{}
+		if operand, err = e.eval(node.args[0]); err != nil {
+			return
+		}
+		if "!" == node.op {
+			return !formulaToBool(operand), nil
+		}
+		num, ok := formulaToNumber(operand)
+		if !ok {
+			return nil, nil
+		}
+		return -num, nil
+	case formulaNodeBinary:
+		return e.evalBinary(node)
+	case formulaNodeCall:
+		fn := formulaFuncs[node.str]
+		if 0 <= fn.minArgs && len(node.args) < fn.minArgs || 0 <= fn.maxArgs && len(node.args) > fn.maxArgs {
+			err = fmt.Errorf("wrong number of arguments for [%s] at %d", node.str, node.token)
+			return
+		}
+		if nil != fn.lazy {
+			return fn.lazy(e, node.args)
+		}
+
+		var args []interface{}
+		for _, arg := range node.args {
+			var val interface{}
+			if val, err = e.eval(arg); err != nil {
+				return
+			}
+			args = append(args, val)
+		}
+		ret, err = fn.call(e, args)
+		if s, ok := ret.(string); ok && formulaMaxString < len(s) {
+			err = errors.New("formula result is too long")
+		}
+		return
+	}
+	return
+}
+
+func (e *formulaEvaluator) evalBinary(node *formulaNode) (ret interface{}, err error) {
+	left, err := e.eval(node.args[0])
+	if err != nil {
+		return
+	}
+
+	// 逻辑运算短路求值
+	switch node.op {
+	case "&&":
+		if !formulaToBool(left) {
+			return false, nil
+		}
+		right, evalErr := e.eval(node.args[1])
+		return formulaToBool(right), evalErr
+	case "||":
+		if formulaToBool(left) {
+			return true, nil
+		}
+		right, evalErr := e.eval(node.args[1])
+		return formulaToBool(right), evalErr
+	}
+
+	right, err := e.eval(node.args[1])
+	if err != nil {
+		return
+	}
+
+	switch node.op {
+	case "==":
+		return 0 == formulaCompare(left, right), nil
+	case "!=":
+		return 0 != formulaCompare(left, right), nil
+	case "<":
+		return 0 > formulaCompare(left, right), nil
+	case "<=":
+		return 0 >= formulaCompare(left, right), nil
+	case ">":
+		return 0 < formulaCompare(left, right), nil
+	case ">=":
+		return 0 <= formulaCompare(left, right), nil
+	case "+":
+		_, ls := left.(string)
+		_, rs := right.(string)
+		if ls || rs {
+			ret = formulaToString(left) + formulaToString(right)
+			if formulaMaxString < len(ret.(string)) {
+				err = errors.New("formula result is too long")
+			}
+			return
+		}
+	}
+
+	l, lok := formulaToNumber(left)
+	r, rok := formulaToNumber(right)
+	if !lok || !rok {
+		return nil, nil
+	}
+
+	switch node.op {
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	case "/":
+		if 0 == r {
+			return nil, nil
+		}
+		return l / r, nil
+	case "%":
+		if 0 == r {
+			return nil, nil
+		}
+		return math.Mod(l, r), nil
+	case "^":
+		return math.Pow(l, r), nil
+	}
+	err = fmt.Errorf("unknown operator [%s] at %d", node.op, node.token)
+	return
+}
+
+// prop 读取当前行的字段值。
+func (e *formulaEvaluator) prop(id string) (ret interface{}, err error) {
+	key := e.ctx.keys[id]
+	if nil == key {
+		key = e.ctx.names[id]
+	}
+	if nil == key {
+		err = fmt.Errorf("field [%s] not found", id)
+		return
+	}
+
+	if KeyTypeFormula == key.Type {
+		result := e.ctx.Render(key)
+		if "" != result.Error {
+			err = fmt.Errorf("field [%s]: %s", key.Name, result.Error)
+			return
+		}
+		return valueToFormula(result.ToValue()), nil
+	}
+	return valueToFormula(e.ctx.values[key.ID]), nil
+}
+
+// valueToFormula 将字段值转换为公式中的值：数字为 float64，勾选框为 bool，日期为 *formulaDate，多值字段为 []interface{}，其他为 string。
+func valueToFormula(value *Value) interface{} {
+	if nil == value {
+		return nil
+	}
+
+	switch value.Type {
+	case KeyTypeNumber:
+		if nil == value.Number || !value.Number.IsNotEmpty {
+			return nil
+		}
+		return value.Number.Content
+	case KeyTypeCheckbox:
+		return nil != value.Checkbox && value.Checkbox.Checked
+	case KeyTypeDate:
+		if nil == value.Date || !value.Date.IsNotEmpty {
+			return nil
+		}
+		return &formulaDate{time: time.UnixMilli(value.Date.Content), isNotTime: value.Date.IsNotTime}
+	case KeyTypeCreated:
+		if nil == value.Created || !value.Created.IsNotEmpty {
+			return nil
+		}
+		return &formulaDate{time: time.UnixMilli(value.Created.Content)}
+	case KeyTypeUpdated:
+		if nil == value.Updated || !value.Updated.IsNotEmpty {
+			return nil
+		}
+		return &formulaDate{time: time.UnixMilli(value.Updated.Content)}
+	case KeyTypeMSelect:
+		ret := []interface{}{}
+		for _, opt := range value.MSelect {
+			ret = append(ret, opt.Content)
+		}
+		return ret
+	case KeyTypeMAsset:
+		ret := []interface{}{}
+		for _, asset := range value.MAsset {
+			ret = append(ret, asset.Content)
+		}
+		return ret
+	case KeyTypeRelation:
+		ret := []interface{}{}
+		if nil != value.Relation {
+			for _, content := range value.Relation.Contents {
+				ret = append(ret, valueToFormula(content))
+			}
+		}
+		return ret
+	case KeyTypeRollup:
+		ret := []interface{}{}
+		if nil != value.Rollup {
+			for _, content := range value.Rollup.Contents {
+				ret = append(ret, valueToFormula(content))
+			}
+		}
+		return ret
+	case KeyTypeFormula:
+		if nil == value.Formula {
+			return nil
+		}
+		return valueToFormula(value.Formula.ToValue())
+	default:
+		return value.String(false)
+	}
+}
+
+func formulaToNumber(val interface{}) (float64, bool) {
+	switch v := val.(type) {
+	case float64:
+		return v, true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	case string:
+		num, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return num, nil == err
+	case *formulaDate:
+		return float64(v.time.UnixMilli()), true
+	case []interface{}:
+		if 1 == len(v) {
+			return formulaToNumber(v[0])
+		}
+	}
+	return 0, false
+}
+
+func formulaToBool(val interface{}) bool {
+	switch v := val.(type) {
+	case bool:
+		return v
+	case float64:
+		return 0 != v
+	case string:
+		return "" != v
+	case []interface{}:
+		return 0 < len(v)
+	case *formulaDate:
+		return true
+	}
+	return false
+}
+
+func formulaToString(val interface{}) string {
+	switch v := val.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case *formulaDate:
+		if v.isNotTime {
+			return v.time.Format("2006-01-02")
+		}
+		return v.time.Format("2006-01-02 15:04")
+	case []interface{}:
+		var items []string
+		for _, item := range v {
+			items = append(items, formulaToString(item))
+		}
+		return strings.Join(items, ", ")
+	}
+	return fmt.Sprint(val)
+}
+
+func formulaToDate(val interface{}) (*formulaDate, bool) {
+	switch v := val.(type) {
+	case *formulaDate:
+		return v, true
+	case float64:
+		return &formulaDate{time: time.UnixMilli(int64(v))}, true
+	case string:
+		return parseFormulaDate(v)
+	case []interface{}:
+		if 1 == len(v) {
+			return formulaToDate(v[0])
+		}
+	}
+	return nil, false
+}
+
+func parseFormulaDate(s string) (*formulaDate, bool) {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{"2006-01-02", "2006/01/02", "20060102"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); nil == err {
+			return &formulaDate{time: t, isNotTime: true}, true
+		}
+	}
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02T15:04:05", "20060102150405", time.RFC3339} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); nil == err {
+			return &formulaDate{time: t}, true
+		}
+	}
+	return nil, false
+}
+
+// formulaCompare 比较两个值，数字和日期按数值比较，其他按字符串比较。
+func formulaCompare(a, b interface{}) int {
+	if nil == a || nil == b {
+		switch {
+		case nil == a && nil == b:
+			return 0
+		case nil == a:
+			if "" == formulaToString(b) {
+				return 0
+			}
+			return -1
+		default:
+			if "" == formulaToString(a) {
+				return 0
+			}
+			return 1
+		}
+	}
+
+	_, aIsStr := a.(string)
+	_, bIsStr := b.(string)
+	if !aIsStr || !bIsStr {
+		an, aok := formulaToNumber(a)
+		bn, bok := formulaToNumber(b)
+		if aok && bok {
+			switch {
+			case an < bn:
+				return -1
+			case an > bn:
+				return 1
+			}
+			return 0
+		}
+	}
+	return strings.Compare(formulaToString(a), formulaToString(b))
+}
+
+func formulaFlatten(args []interface{}) (ret []interface{}) {
+	for _, arg := range args {
+		if list, ok := arg.([]interface{}); ok {
+			ret = append(ret, formulaFlatten(list)...)
+			continue
+		}
+		ret = append(ret, arg)
+	}
+	return
+}
+
+func formulaNumbers(args []interface{}) (ret []float64) {
+	for _, arg := range formulaFlatten(args) {
+		if nil == arg {
+			continue
+		}
+		if num, ok := formulaToNumber(arg); ok {
+			ret = append(ret, num)
+		}
+	}
+	return
+}
+
+func formulaInt(val interface{}) int {
+	num, _ := formulaToNumber(val)
+	return int(num)
+}
+
+// 函数
+
+type formulaFunc struct {
+	minArgs, maxArgs int // 参数数量范围，-1 表示不限制
+	call             func(e *formulaEvaluator, args []interface{}) (interface{}, error)
+	lazy             func(e *formulaEvaluator, args []*formulaNode) (interface{}, error) // 需要延迟求值参数的函数
+}
+
+var formulaFuncs map[string]*formulaFunc
+
+func init() {
+	math1 := func(f func(float64) float64) *formulaFunc {
+		return &formulaFunc{minArgs: 1, maxArgs: 1, call: func(e *formulaEvaluator, args []interface{}) (interface{}, error) {
+			num, ok := formulaToNumber(args[0])
+			if !ok {
+				return nil, nil
+			}
+			return f(num), nil
+		}}
+	}
+	str1 := func(f func(string) interface{}) *formulaFunc {
+		return &formulaFunc{minArgs: 1, maxArgs: 1, call: func(e *formulaEvaluator, args []interface{}) (interface{}, error) {
+			return f(formulaToString(args[0])), nil
+		}}
+	}
+	date1 := func(f func(time.Time) float64) *formulaFunc {
+		return &formulaFunc{minArgs: 1, maxArgs: 1, call: func(e *formulaEvaluator, args []interface{}) (interface{}, error) {
+			d, ok := formulaToDate(args[0])
+			if !ok {
+				return nil, nil
+			}
+			return f(d.time), nil
+		}}
+	}
+
+	formulaFuncs = map[string]*formulaFunc{
+		// 字段引用
+		"prop": {minArgs: 1, maxArgs: 1, call: func(e *formulaEvaluator, args []interface{}) (interface{}, error) {
+			return e.prop(formulaToString(args[0]))
+		}},
+
+		// 逻辑
+		"if": {minArgs: 2, maxArgs: 3, lazy: func(e *formulaEvaluator, args []*formulaNode) (interface{}, error) {
+			cond, err := e.eval(args[0])
+			if err != nil {
+				return nil, err
+			}
+			if formulaToBool(cond) {
+				return e.eval(args[1])
+			}
+			if 3 == len(args) {
+				return e.eval(args[2])
+			}
+			return nil, nil
+		}},
+		"and": {minArgs: 1, maxArgs: -1, lazy: func(e *formulaEvaluator, args []*formulaNode) (interface{}, error) {
+			for _, arg := range args {
+				val, err := e.eval(arg)
+				if err != nil || !formulaToBool(val) {
+					return false, err
+				}
+			}
+			return true, nil
+		}},
+		"or": {minArgs: 1, maxArgs: -1, lazy: func(e *formulaEvaluator, args []*formulaNode) (interface{}, error) {
+			for _, arg := range args {
+				val, err := e.eval(arg)
+				if err != nil || formulaToBool(val) {
+					return nil == err, err
+				}
+			}
+			return false, nil
+		}},
+		"not": {minArgs: 1, maxArgs: 1, call: func(e *formulaEvaluator, args []interface{}) (interface{}, error) {
+			return !formulaToBool(args[0]), nil
+		}},
+		"empty": {minArgs: 1, maxArgs: 1, call: func(e *formulaEvaluator, args []interface{}) (interface{}, error) {
+			switch v := args[0].(type) {
+			case nil:
+				return true, nil
+			case string:
+				return "" == v, nil
+			case []interface{}:
+				return 0 == len(v), nil
+			}
+			return false, nil
+		}},
+
+		// 数学
+		"abs":   math1(math.Abs),
+		"floor": math1(math.Floor),
+		"ceil":  math1(math.Ceil),
+		"sqrt":  math1(math.Sqrt),
+		"round": {minArgs: 1, maxArgs: 2, call: func(e *formulaEvaluator, args []interface{}) (interface{}, error) {
+			num, ok := formulaToNumber(args[0])
+			if !ok {
+				return nil, nil
+			}
+			precision := 0
+			if 2 == len(args) {
+				precision = formulaInt(args[1])
+			}
+			return Round(num, precision), nil
+		}},
+		"pow": {minArgs: 2, maxArgs: 2, call: func(e *formulaEvaluator, args []interface{}) (interface{}, error) {
+			x, xok := formulaToNumber(args[0])
+			y, yok := formulaToNumber(args[1])
+			if !xok || !yok {
+				return nil, nil
+			}
+			return math.Pow(x, y), nil
+		}},
+		"min": {minArgs: 1, maxArgs: -1, call: func(e *formulaEvaluator, args []interface{}) (interface{}, error) {
+			nums := formulaNumbers(args)
+			if 1 > len(nums) {
+				return nil, nil
+			}
+			ret := nums[0]
+			for _, num := range nums[1:] {
+				ret = math.Min(ret, num)
+			}
+			return ret, nil
+		}},
+		"max": {minArgs: 1, maxArgs: -1, call: func(e *formulaEvaluator, args []interface{}) (interface{}, error) {
+			nums := formulaNumbers(args)
+			if 1 > len(nums) {
+				return nil, nil
+			}
+			ret := nums[0]
+			for _, num := range nums[1:] {
+				ret = math.Max(ret, num)
+			}
+			return ret, nil
+		}},
+		"sum": {minArgs: 1, maxArgs: -1, call: func(e *formulaEvaluator, args []interface{}) (interface{}, error) {
+			ret := 0.0
+			for _, num := range formulaNumbers(args) {
+				ret += num
+			}
+			return ret, nil
+		}},
+		"avg": {minArgs: 1, maxArgs: -1, call: func(e *formulaEvaluator, args []interface{}) (interface{}, error) {
+			nums := formulaNumbers(args)
+			if 1 > len(nums) {
+				return nil, nil
+			}
+			ret := 0.0
+			for _, num := range nums {
+				ret += num
+			}
+			return ret / float64(len(nums)), nil
+		}},
+		"toNumber": {minArgs: 1, maxArgs: 1, call: func(e *formulaEvaluator, args []interface{}) (interface{}, error) {
+			num, ok := formulaToNumber(args[0])
+			if !ok {
+				return nil, nil
+			}
+			return num, nil
+		}},
+
+		// 字符串
+		"concat": {minArgs: 1, maxArgs: -1, call: func(e *formulaEvaluator, args []interface{}) (interface{}, error) {
+			var buf strings.Builder
+			for _, arg := range args {
+				buf.WriteString(formulaToString(arg))
+			}
+			return buf.String(), nil
+		}},
+		"format": str1(func(s string) interface{} { return s }),
+		"lower":  str1(func(s string) interface{} { return strings.ToLower(s) }),
+		"upper":  str1(func(s string) interface{} { return strings.ToUpper(s) }),
+		"trim":   str1(func(s string) interface{} { return strings.TrimSpace(s) }),
+		"length": {minArgs: 1, maxArgs: 1, call: func(e *formulaEvaluator, args []interface{}) (interface{}, error) {
+			if list, ok := args[0].([]interface{}); ok {
+				return float64(len(list)), nil
+			}
+			return float64(utf8.RuneCountInString(formulaToString(args[0]))), nil
+		}},
+		"contains": {minArgs: 2, maxArgs: 2, call: func(e *formulaEvaluator, args []interface{}) (interface{}, error) {
+			if list, ok := args[0].([]interface{}); ok {
+				for _, item := range list {
+					if 0 == formulaCompare(item, args[1]) {
+						return true, nil
+					}
+				}
+				return false, nil
+			}
+			return strings.Contains(formulaToString(args[0]), formulaToString(args[1])), nil
+		}},
+		"startsWith": {minArgs: 2, maxArgs: 2, call: func(e *formulaEvaluator, args []interface{}) (interface{}, error) {
+			return strings.HasPrefix(formulaToString(args[0]), formulaToString(args[1])), nil
+		}},
+		"endsWith": {minArgs: 2, maxArgs: 2, call: func(e *formulaEvaluator, args []interface{}) (interface{}, error) {
+			return strings.HasSuffix(formulaToString(args[0]), formulaToString(args[1])), nil
+		}},
+		"replace": {minArgs: 3, maxArgs: 3, call: func(e *formulaEvaluator, args []interface{}) (interface{}, error) {
+			s, old, n := formulaToString(args[0]), formulaToString(args[1]), formulaToString(args[2])
+			if "" == old {
+				return s, nil
+			}
+			if 0 < len(n)-len(old) && formulaMaxString < len(s)+strings.Count(s, old)*(len(n)-len(old)) {
+				return nil, errors.New("formula result is too long")
+			}
+			return strings.ReplaceAll(s, old, n), nil
+		}},
+		"slice": {minArgs: 2, maxArgs: 3, call: func(e *formulaEvaluator, args []interface{}) (interface{}, error) {
+			runes := []rune(formulaToString(args[0]))
+			start, end := formulaInt(args[1]), len(runes)
+			if 3 == len(args) {
+				end = formulaInt(args[2])
+			}
+			start = max(0, min(start, len(runes)))
+			end = max(start, min(end, len(runes)))
+			return string(runes[start:end]), nil
+		}},
+		"join": {minArgs: 1, maxArgs: 2, call: func(e *formulaEvaluator, args []interface{}) (interface{}, error) {
+			sep := ", "
+			if 2 == len(args) {
+				sep = formulaToString(args[1])
+			}
+			var items []string
+			for _, item := range formulaFlatten(args[:1]) {
+				items = append(items, formulaToString(item))
+			}
+			return strings.Join(items, sep), nil
+		}},
+
+		// 列表
+		"first": {minArgs: 1, maxArgs: 1, call: func(e *formulaEvaluator, args []interface{}) (interface{}, error) {
+			if list, ok := args[0].([]interface{}); ok {
+				if 0 < len(list) {
+					return list[0], nil
+				}
+				return nil, nil
+			}
+			return args[0], nil
+		}},
+		"last": {minArgs: 1, maxArgs: 1, call: func(e *formulaEvaluator, args []interface{}) (interface{}, error) {
+			if list, ok := args[0].([]interface{}); ok {
+				if 0 < len(list) {
+					return list[len(list)-1], nil
+				}
+				return nil, nil
+			}
+			return args[0], nil
+		}},
+		"at": {minArgs: 2, maxArgs: 2, call: func(e *formulaEvaluator, args []interface{}) (interface{}, error) {
+			list, ok := args[0].([]interface{})
+			i := formulaInt(args[1])
+			if !ok || 0 > i || len(list) <= i {
+				return nil, nil
+			}
+			return list[i], nil
+		}},
+
+		// 日期
+		"now": {minArgs: 0, maxArgs: 0, call: func(e *formulaEvaluator, args []interface{}) (interface{}, error) {
+			return &formulaDate{time: time.Now()}, nil
+		}},
+		"today": {minArgs: 0, maxArgs: 0, call: func(e *formulaEvaluator, args []interface{}) (interface{}, error) {
+			now := time.Now()
+			return &formulaDate{time: time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local), isNotTime: true}, nil
+		}},
+		"date": {minArgs: 1, maxArgs: 3, call: func(e *formulaEvaluator, args []interface{}) (interface{}, error) {
+			if 1 == len(args) {
+				if d, ok := formulaToDate(args[0]); ok {
+					return d, nil
+				}
+				return nil, nil
+			}
+			day := 1
+			if 3 == len(args) {
+				day = formulaInt(args[2])
+			}
+			return &formulaDate{time: time.Date(formulaInt(args[0]), time.Month(formulaInt(args[1])), day, 0, 0, 0, 0, time.Local), isNotTime: true}, nil
+		}},
+		"dateAdd": {minArgs: 3, maxArgs: 3, call: func(e *formulaEvaluator, args []interface{}) (interface{}, error) {
+			return formulaDateAdd(args[0], formulaInt(args[1]), formulaToString(args[2]))
+		}},
+		"dateSubtract": {minArgs: 3, maxArgs: 3, call: func(e *formulaEvaluator, args []interface{}) (interface{}, error) {
+			return formulaDateAdd(args[0], -formulaInt(args[1]), formulaToString(args[2]))
+		}},
+		"dateBetween": {minArgs: 3, maxArgs: 3, call: func(e *formulaEvaluator, args []interface{}) (interface{}, error) {
+			a, aok := formulaToDate(args[0])
+			b, bok := formulaToDate(args[1])
+			if !aok || !bok {
+				return nil, nil
+			}
+			return formulaDateBetween(a.time, b.time, formulaToString(args[2]))
+		}},
+		"formatDate": {minArgs: 2, maxArgs: 2, call: func(e *formulaEvaluator, args []interface{}) (interface{}, error) {
+			d, ok := formulaToDate(args[0])
+			if !ok {
+				return nil, nil
+			}
+			return formatFormulaDate(d.time, formulaToString(args[1])), nil
+		}},
+		"timestamp": date1(func(t time.Time) float64 { return float64(t.UnixMilli()) }),
+		"year":      date1(func(t time.Time) float64 { return float64(t.Year()) }),
+		"month":     date1(func(t time.Time) float64 { return float64(t.Month()) }),
+		"day":       date1(func(t time.Time) float64 { return float64(t.Day()) }),
+		"hour":      date1(func(t time.Time) float64 { return float64(t.Hour()) }),
+		"minute":    date1(func(t time.Time) float64 { return float64(t.Minute()) }),
+		"weekday":   date1(func(t time.Time) float64 { return float64(t.Weekday()) }),
+	}
+}
+
+func formulaDateAdd(val interface{}, n int, unit string) (interface{}, error) {
+	d, ok := formulaToDate(val)
+	if !ok {
+		return nil, nil
+	}
+
+	t := d.time
+	switch strings.TrimSuffix(unit, "s") {
+	case "year":
+		t = t.AddDate(n, 0, 0)
+	case "month":
+		t = t.AddDate(0, n, 0)
+	case "week":
+		t = t.AddDate(0, 0, 7*n)
+	case "day":
+		t = t.AddDate(0, 0, n)
+	case "hour":
+		t = t.Add(time.Duration(n) * time.Hour)
+	case "minute":
+		t = t.Add(time.Duration(n) * time.Minute)
+	default:
+		return nil, fmt.Errorf("unknown date unit [%s]", unit)
+	}
+	return &formulaDate{time: t, isNotTime: d.isNotTime}, nil
+}
+
+func formulaDateBetween(a, b time.Time, unit string) (interface{}, error) {
+	switch strings.TrimSuffix(unit, "s") {
+	case "year":
+		return float64(formulaMonthsBetween(a, b) / 12), nil
+	case "month":
+		return float64(formulaMonthsBetween(a, b)), nil
+	case "week":
+		return math.Trunc(a.Sub(b).Hours() / 24 / 7), nil
+	case "day":
+		return math.Trunc(a.Sub(b).Hours() / 24), nil
+	case "hour":
+		return math.Trunc(a.Sub(b).Hours()), nil
+	case "minute":
+		return math.Trunc(a.Sub(b).Minutes()), nil
+	}
+	return nil, fmt.Errorf("unknown date unit [%s]", unit)
+}
+
+// formulaMonthsBetween 计算 a 和 b 之间相差的整月数，不足一个月的部分舍去。
+func formulaMonthsBetween(a, b time.Time) (ret int) {
+	ret = (a.Year()-b.Year())*12 + int(a.Month()) - int(b.Month())
+	if 0 < ret && b.AddDate(0, ret, 0).After(a) {
+		ret--
+	} else if 0 > ret && b.AddDate(0, ret, 0).Before(a) {
+		ret++
+	}
+	return
+}
+
+// formatFormulaDate 使用 YYYY、MM、DD、HH、mm、ss 占位符格式化日期，其他字符原样输出。
+func formatFormulaDate(t time.Time, layout string) string {
+	placeholders := []struct {
+		token string
+		value string
+	}{
+		{"YYYY", fmt.Sprintf("%04d", t.Year())},
+		{"MM", fmt.Sprintf("%02d", t.Month())},
+		{"DD", fmt.Sprintf("%02d", t.Day())},
+		{"HH", fmt.Sprintf("%02d", t.Hour())},
+		{"mm", fmt.Sprintf("%02d", t.Minute())},
+		{"ss", fmt.Sprintf("%02d", t.Second())},
+	}
+
+	var buf strings.Builder
+	for i := 0; i < len(layout); {
+		matched := false
+		for _, p := range placeholders {
+			if strings.HasPrefix(layout[i:], p.token) {
+				buf.WriteString(p.value)
+				i += len(p.token)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			buf.WriteByte(layout[i])
+			i++
+		}
+	}
+	return buf.String()
+}
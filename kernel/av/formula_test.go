@@ -0,0 +1,111 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package av
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestFormulaCache(t *testing.T) {
+	formulaCache.Clear()
+
+	first, err := getFormula("1 + 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	formulaCache.Wait()
+	second, err := getFormula("1 + 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != second {
+		t.Fatal("formula is not cached")
+	}
+
+	// 解析失败的公式不缓存
+	if _, err = getFormula("1 +"); nil == err {
+		t.Fatal("invalid formula parsed")
+	}
+	formulaCache.Wait()
+	if _, ok := formulaCache.Get("1 +"); ok {
+		t.Fatal("invalid formula cached")
+	}
+
+	// 缓存数量有上限
+	for i := 0; i < formulaCacheMaxCount*4; i++ {
+		if _, err = getFormula(strconv.Itoa(i) + " * 2"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	formulaCache.Wait()
+	cached := 0
+	for i := 0; i < formulaCacheMaxCount*4; i++ {
+		if _, ok := formulaCache.Get(strconv.Itoa(i) + " * 2"); ok {
+			cached++
+		}
+	}
+	if cached > formulaCacheMaxCount {
+		t.Fatalf("cached [%d] formulas, limit is [%d]", cached, formulaCacheMaxCount)
+	}
+}
+
+func TestFormulaRender(t *testing.T) {
+	numKey := &Key{ID: "n", Name: "Num", Type: KeyTypeNumber}
+	formulaKey := &Key{ID: "f", Name: "F", Type: KeyTypeFormula}
+	attrView := &AttributeView{KeyValues: []*KeyValues{{Key: numKey}, {Key: formulaKey}}}
+	row := []*KeyValues{{Key: numKey, Values: []*Value{{KeyID: "n", Type: KeyTypeNumber, Number: &ValueNumber{Content: 4, IsNotEmpty: true}}}}}
+
+	cases := []struct {
+		formula string
+		typ     KeyType
+		want    string
+	}{
+		{`prop("n") * 2`, KeyTypeNumber, "8"},
+		{`prop("Num") + 1`, KeyTypeNumber, "5"},
+		{`"a" + prop("n")`, KeyTypeText, "a4"},
+		{`prop("n") > 3`, KeyTypeCheckbox, "true"},
+		{`if(prop("n") > 5, "big", "small")`, KeyTypeText, "small"},
+	}
+
+	for _, c := range cases {
+		// 修改公式后使用新的公式计算，缓存按公式内容区分
+		formulaKey.Formula = c.formula
+		ret := NewFormulaContext(attrView, row).Render(formulaKey)
+		if "" != ret.Error {
+			t.Errorf("formula [%s] failed: %s", c.formula, ret.Error)
+			continue
+		}
+		if c.typ != ret.Type {
+			t.Errorf("formula [%s]: got type %s, want %s", c.formula, ret.Type, c.typ)
+			continue
+		}
+
+		var got string
+		switch ret.Type {
+		case KeyTypeNumber:
+			got = strconv.FormatFloat(ret.Number.Content, 'f', -1, 64)
+		case KeyTypeCheckbox:
+			got = strconv.FormatBool(ret.Checkbox.Checked)
+		default:
+			got = ret.Text.Content
+		}
+		if c.want != got {
+			t.Errorf("formula [%s]: got %s, want %s", c.formula, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package av
+
+// LayoutGallery 描述了画廊布局的结构。
+//
+// 画廊视图的字段（包括字段是否显示）、过滤、排序和分页设置复用视图上的表格布局 View.Table，这里仅保存画廊特有的设置。
+type LayoutGallery struct {
+	Spec int    `json:"spec"` // 布局格式版本
+	ID   string `json:"id"`   // 布局 ID
+
+	CoverFrom           CoverFrom `json:"coverFrom"`           // 封面来源
+	CoverFromAssetKeyID string    `json:"coverFromAssetKeyID"` // 封面来源为资源字段时的字段 ID
+	CardSize            CardSize  `json:"cardSize"`            // 卡片大小
+	FitImage            bool      `json:"fitImage"`            // 封面图片是否适应卡片（不裁剪）
+}
+
+// CoverFrom 描述了卡片封面的来源。
+type CoverFrom int
+
+const (
+	CoverFromNone         CoverFrom = iota // 无封面
+	CoverFromContentImage                  // 绑定块内容中的第一张图片
+	CoverFromAssetField                    // 资源字段中的第一张图片
+)
+
+// CardSize 描述了卡片的大小。
+type CardSize string
+
+const (
+	CardSizeSmall  CardSize = "small"
+	CardSizeMedium CardSize = "medium"
+	CardSizeLarge  CardSize = "large"
+)
+
+func IsCardSize(size string) bool {
+	switch CardSize(size) {
+	case CardSizeSmall, CardSizeMedium, CardSizeLarge:
+		return true
+	}
+	return false
+}
+
+// Gallery 描述了画廊实例的结构。
+//
+// 画廊基于表格渲染：先按表格进行过滤、排序和分页，然后调用 FillCards 生成卡片。
+type Gallery struct {
+	*Table
+
+	CoverFrom           CoverFrom      `json:"coverFrom"`           // 封面来源
+	CoverFromAssetKeyID string         `json:"coverFromAssetKeyID"` // 封面来源为资源字段时的字段 ID
+	CardSize            CardSize       `json:"cardSize"`            // 卡片大小
+	FitImage            bool           `json:"fitImage"`            // 封面图片是否适应卡片
+	Cards               []*GalleryCard `json:"cards"`               // 卡片
+	CardCount           int            `json:"cardCount"`           // 卡片总数
+
+	ContentCovers map[string]string `json:"-"` // 封面来源为块内容时的封面图片地址，块 ID -> 图片地址
+}
+
+// GalleryCard 描述了画廊卡片的结构。
+type GalleryCard struct {
+	ID       string       `json:"id"`       // 卡片 ID（行 ID）
+	CoverURL string       `json:"coverURL"` // 封面图片地址
+	Values   []*TableCell `json:"values"`   // 卡片上显示的字段值
+}
+
+func (gallery *Gallery) GetType() LayoutType {
+	return LayoutTypeGallery
+}
+
+// CalcCols 画廊不显示字段计算结果。
+func (gallery *Gallery) CalcCols() {
+}
+
+// FillCards 使用当前页的行生成卡片，卡片上仅包含未隐藏的字段。
+//
+// 封面来源为块内容时需要调用方先加载块并填充 ContentCovers。
+func (gallery *Gallery) FillCards() {
+	gallery.Cards = []*GalleryCard{}
+	for _, row := range gallery.Rows {
+		card := &GalleryCard{ID: row.ID, Values: []*TableCell{}}
+		for i, cell := range row.Cells {
+			if i < len(gallery.Columns) && gallery.Columns[i].Hidden {
+				continue
+			}
+			card.Values = append(card.Values, cell)
+		}
+
+		if CoverFromAssetField == gallery.CoverFrom {
+			card.CoverURL = GetValueCoverURL(row.GetValue(gallery.CoverFromAssetKeyID))
+		} else if CoverFromContentImage == gallery.CoverFrom {
+			if blockVal := row.GetBlockValue(); nil != blockVal {
+				card.CoverURL = gallery.ContentCovers[blockVal.BlockID]
+			}
+		}
+		gallery.Cards = append(gallery.Cards, card)
+	}
+	gallery.Table.Rows = []*TableRow{}
+}
+
+// GetValueCoverURL 获取资源字段值中的第一张图片地址。
+func GetValueCoverURL(value *Value) string {
+	if nil == value {
+		return ""
+	}
+
+	for _, asset := range value.MAsset {
+		if AssetTypeImage == asset.Type && "" != asset.Content {
+			return asset.Content
+		}
+	}
+	return ""
+}
@@ -0,0 +1,390 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package av
+
+import (
+	"sort"
+
+	"github.com/88250/gulu"
+	"github.com/siyuan-note/siyuan/kernel/util"
+)
+
+// LayoutKanban 描述了看板布局的结构。
+//
+// 看板视图的字段、过滤、排序和分页设置复用视图上的表格布局 View.Table，这里仅保存看板特有的设置。
+type LayoutKanban struct {
+	Spec int    `json:"spec"` // 布局格式版本
+	ID   string `json:"id"`   // 布局 ID
+
+	GroupKeyID string              `json:"groupKeyID"` // 分组字段 ID，支持单选、多选和复选框字段
+	Columns    []*ViewKanbanColumn `json:"columns"`    // 看板列，用于自定义列排序和列内卡片排序
+}
+
+// ViewKanbanColumn 描述了看板列的结构。
+type ViewKanbanColumn struct {
+	ID      string   `json:"id"`      // 列 ID，即分组值：单选/多选为选项名称，复选框为 KanbanColumnChecked/KanbanColumnUnchecked，空值为 ""
+	Folded  bool     `json:"folded"`  // 是否折叠
+	CardIDs []string `json:"cardIds"` // 卡片 ID（行 ID），用于列内自定义排序
+}
+
+const (
+	KanbanColumnChecked   = "checked"   // 复选框字段已勾选列
+	KanbanColumnUnchecked = "unchecked" // 复选框字段未勾选列
+)
+
+func (kanban *LayoutKanban) GetColumn(id string) *ViewKanbanColumn {
+	for _, column := range kanban.Columns {
+		if column.ID == id {
+			return column
+		}
+	}
+	return nil
+}
+
+// RenameColumn 重命名看板列，如果新列已经存在则将卡片合并到新列中。
+func (kanban *LayoutKanban) RenameColumn(oldID, newID string) {
+	oldColumn := kanban.GetColumn(oldID)
+	if nil == oldColumn {
+		return
+	}
+
+	if newColumn := kanban.GetColumn(newID); nil != newColumn {
+		newColumn.CardIDs = gulu.Str.RemoveDuplicatedElem(append(newColumn.CardIDs, oldColumn.CardIDs...))
+		kanban.RemoveColumn(oldID)
+		return
+	}
+	oldColumn.ID = newID
+}
+
+func (kanban *LayoutKanban) RemoveColumn(id string) {
+	for i, column := range kanban.Columns {
+		if column.ID == id {
+			kanban.Columns = append(kanban.Columns[:i], kanban.Columns[i+1:]...)
+			return
+		}
+	}
+}
+
+// RemoveCard 将卡片从所有看板列中移除。
+func (kanban *LayoutKanban) RemoveCard(cardID string) {
+	for _, column := range kanban.Columns {
+		column.CardIDs = gulu.Str.RemoveElem(column.CardIDs, cardID)
+	}
+}
+
+// ReplaceCard 替换看板列中的卡片 ID，用于行绑定的块发生变化时保持列内排序。
+func (kanban *LayoutKanban) ReplaceCard(oldCardID, newCardID string) {
+	for _, column := range kanban.Columns {
+		for i, cardID := range column.CardIDs {
+			if cardID == oldCardID {
+				column.CardIDs[i] = newCardID
+				break
+			}
+		}
+	}
+}
+
+// IsKanbanGroupKeyType 判断字段类型是否可以用于看板分组。
+func IsKanbanGroupKeyType(keyType KeyType) bool {
+	return KeyTypeSelect == keyType || KeyTypeMSelect == keyType || KeyTypeCheckbox == keyType
+}
+
+// Kanban 描述了看板实例的结构。
+//
+// 看板基于表格渲染：先按表格进行过滤和排序，然后调用 GroupCards 将行分组到看板列中。
+type Kanban struct {
+	*Table
+
+	GroupKey      *TableColumn    `json:"groupKey"`      // 分组字段
+	KanbanColumns []*KanbanColumn `json:"kanbanColumns"` // 看板列
+}
+
+// KanbanColumn 描述了看板列实例的结构。
+type KanbanColumn struct {
+	ID        string      `json:"id"`        // 列 ID，即分组值
+	Name      string      `json:"name"`      // 列名
+	Color     string      `json:"color"`     // 列颜色，仅单选/多选分组时存在
+	Folded    bool        `json:"folded"`    // 是否折叠
+	Cards     []*TableRow `json:"cards"`     // 卡片
+	CardCount int         `json:"cardCount"` // 卡片总数
+}
+
+func (kanban *Kanban) GetType() LayoutType {
+	return LayoutTypeKanban
+}
+
+func (kanban *Kanban) GetKanbanColumn(id string) *KanbanColumn {
+	for _, column := range kanban.KanbanColumns {
+		if column.ID == id {
+			return column
+		}
+	}
+	return nil
+}
+
+// CalcCols 看板不显示字段计算结果。
+func (kanban *Kanban) CalcCols() {
+}
+
+// GroupCards 按分组字段将过滤和排序后的行分组到看板列中。
+func (kanban *Kanban) GroupCards(layout *LayoutKanban) {
+	if nil == layout {
+		layout = &LayoutKanban{}
+	}
+
+	kanban.KanbanColumns = []*KanbanColumn{}
+	if nil == kanban.GroupKey {
+		// 没有可用的分组字段时所有卡片都放在一列中
+		column := &KanbanColumn{ID: "", Cards: kanban.Rows}
+		column.CardCount = len(column.Cards)
+		kanban.KanbanColumns = append(kanban.KanbanColumns, column)
+		kanban.Rows = []*TableRow{}
+		return
+	}
+
+	// 按选项生成默认列
+	var columns []*KanbanColumn
+	for _, columnID := range getKanbanDefaultColumnIDs(kanban.GroupKey.Type, kanban.GroupKey.Options) {
+		column := &KanbanColumn{ID: columnID, Name: columnID}
+		for _, opt := range kanban.GroupKey.Options {
+			if opt.Name == columnID {
+				column.Color = opt.Color
+				break
+			}
+		}
+		columns = append(columns, column)
+	}
+
+	// 按布局中保存的列顺序排列，未保存过的列保持默认顺序排在后面
+	var sortedColumns []*KanbanColumn
+	for _, viewColumn := range layout.Columns {
+		for _, column := range columns {
+			if column.ID == viewColumn.ID {
+				column.Folded = viewColumn.Folded
+				sortedColumns = append(sortedColumns, column)
+				break
+			}
+		}
+	}
+	for _, column := range columns {
+		if nil == layout.GetColumn(column.ID) {
+			sortedColumns = append(sortedColumns, column)
+		}
+	}
+	kanban.KanbanColumns = sortedColumns
+
+	// 分组
+	for _, row := range kanban.Rows {
+		for _, columnID := range GetKanbanCardColumnIDs(row.GetValue(kanban.GroupKey.ID), kanban.GroupKey.Type) {
+			column := kanban.GetKanbanColumn(columnID)
+			if nil == column {
+				// 值中的选项已经不在字段选项中，归入空值列
+				column = kanban.GetKanbanColumn("")
+			}
+			if nil == column {
+				continue
+			}
+			column.Cards = append(column.Cards, row)
+		}
+	}
+
+	// 没有设置排序规则时使用列内自定义排序
+	if 1 > len(kanban.Sorts) {
+		for _, column := range kanban.KanbanColumns {
+			viewColumn := layout.GetColumn(column.ID)
+			if nil == viewColumn || 1 > len(viewColumn.CardIDs) {
+				continue
+			}
+
+			cardIndexes := map[string]int{}
+			for i, cardID := range viewColumn.CardIDs {
+				cardIndexes[cardID] = i
+			}
+
+			var sortedCards, unsortedCards []*TableRow
+			for _, card := range column.Cards {
+				if _, ok := cardIndexes[card.ID]; ok {
+					sortedCards = append(sortedCards, card)
+				} else {
+					unsortedCards = append(unsortedCards, card)
+				}
+			}
+			sort.SliceStable(sortedCards, func(i, j int) bool {
+				return cardIndexes[sortedCards[i].ID] < cardIndexes[sortedCards[j].ID]
+			})
+			column.Cards = append(sortedCards, unsortedCards...)
+		}
+	}
+
+	for _, column := range kanban.KanbanColumns {
+		if nil == column.Cards {
+			column.Cards = []*TableRow{}
+		}
+		column.CardCount = len(column.Cards)
+	}
+	kanban.Rows = []*TableRow{}
+}
+
+// GetKanbanGroupKey 获取看板分组字段，未设置分组字段或者分组字段类型已经被修改时使用第一个单选字段。
+func GetKanbanGroupKey(attrView *AttributeView, layout *LayoutKanban) (ret *Key) {
+	if nil != layout {
+		if key, _ := attrView.GetKey(layout.GroupKeyID); nil != key && IsKanbanGroupKeyType(key.Type) {
+			return key
+		}
+	}
+
+	for _, kv := range attrView.KeyValues {
+		if KeyTypeSelect == kv.Key.Type {
+			return kv.Key
+		}
+	}
+	return
+}
+
+// FillColumns 将分组字段对应的所有列补全到布局中，用于自定义列排序和列内卡片排序。
+func (kanban *LayoutKanban) FillColumns(groupKey *Key) {
+	for _, columnID := range getKanbanDefaultColumnIDs(groupKey.Type, groupKey.Options) {
+		if nil == kanban.GetColumn(columnID) {
+			kanban.Columns = append(kanban.Columns, &ViewKanbanColumn{ID: columnID, CardIDs: []string{}})
+		}
+	}
+}
+
+// MoveCard 将卡片移动到目标列中 previousCardID 之后，previousCardID 为空时移动到列首。
+//
+// 调用前分组字段值应该已经更新为目标列对应的值。
+func (kanban *LayoutKanban) MoveCard(attrView *AttributeView, view *View, groupKey *Key, cardID, fromColumnID, toColumnID, previousCardID string) {
+	kanban.FillColumns(groupKey)
+	toColumn := kanban.GetColumn(toColumnID)
+	if nil == toColumn {
+		return
+	}
+
+	// 列内卡片排序可能只保存了部分卡片，这里先按当前显示顺序补全
+	toColumn.CardIDs = kanban.getColumnCardIDs(attrView, view, groupKey, toColumn)
+
+	if KeyTypeMSelect == groupKey.Type {
+		// 多选值的卡片可能同时位于多个列中，仅从来源列中移除
+		if fromColumn := kanban.GetColumn(fromColumnID); nil != fromColumn {
+			fromColumn.CardIDs = gulu.Str.RemoveElem(fromColumn.CardIDs, cardID)
+		}
+	} else {
+		kanban.RemoveCard(cardID)
+	}
+	toColumn.CardIDs = gulu.Str.RemoveElem(toColumn.CardIDs, cardID)
+
+	index := 0
+	for i, id := range toColumn.CardIDs {
+		if id == previousCardID {
+			index = i + 1
+			break
+		}
+	}
+	toColumn.CardIDs = util.InsertElem(toColumn.CardIDs, index, cardID)
+}
+
+func (kanban *LayoutKanban) getColumnCardIDs(attrView *AttributeView, view *View, groupKey *Key, column *ViewKanbanColumn) (ret []string) {
+	blockValues := attrView.GetBlockKeyValues()
+	if nil == blockValues {
+		return
+	}
+
+	for _, blockValue := range blockValues.Values {
+		columnIDs := GetKanbanCardColumnIDs(attrView.GetValue(groupKey.ID, blockValue.BlockID), groupKey.Type)
+		for _, columnID := range columnIDs {
+			if KeyTypeCheckbox != groupKey.Type && nil == groupKey.GetOption(columnID) {
+				columnID = ""
+			}
+			if columnID == column.ID {
+				ret = append(ret, blockValue.BlockID)
+				break
+			}
+		}
+	}
+
+	cardIndexes := map[string]int{}
+	for i, cardID := range column.CardIDs {
+		cardIndexes[cardID] = i
+	}
+	rowIndexes := map[string]int{}
+	if nil != view.Table {
+		for i, rowID := range view.Table.RowIDs {
+			rowIndexes[rowID] = i
+		}
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		ci, iok := cardIndexes[ret[i]]
+		cj, jok := cardIndexes[ret[j]]
+		if iok && jok {
+			return ci < cj
+		}
+		if iok != jok {
+			return iok
+		}
+		if rowIndexes[ret[i]] == rowIndexes[ret[j]] {
+			return ret[i] < ret[j]
+		}
+		return rowIndexes[ret[i]] < rowIndexes[ret[j]]
+	})
+	return
+}
+
+// getKanbanDefaultColumnIDs 获取看板默认列，单选/多选分组时空值列放在最前面。
+func getKanbanDefaultColumnIDs(groupKeyType KeyType, options []*SelectOption) (ret []string) {
+	switch groupKeyType {
+	case KeyTypeSelect, KeyTypeMSelect:
+		ret = append(ret, "")
+		for _, opt := range options {
+			ret = append(ret, opt.Name)
+		}
+	case KeyTypeCheckbox:
+		ret = append(ret, KanbanColumnUnchecked, KanbanColumnChecked)
+	default:
+		ret = append(ret, "")
+	}
+	return
+}
+
+// GetKanbanCardColumnIDs 获取值所在的看板列 ID，多选值可能同时位于多个列中。
+func GetKanbanCardColumnIDs(value *Value, groupKeyType KeyType) (ret []string) {
+	switch groupKeyType {
+	case KeyTypeSelect, KeyTypeMSelect:
+		if nil == value || 1 > len(value.MSelect) {
+			return []string{""}
+		}
+		for _, opt := range value.MSelect {
+			if "" == opt.Content {
+				continue
+			}
+			ret = append(ret, opt.Content)
+			if KeyTypeSelect == groupKeyType {
+				break
+			}
+		}
+		if 1 > len(ret) {
+			ret = []string{""}
+		}
+	case KeyTypeCheckbox:
+		if nil != value && nil != value.Checkbox && value.Checkbox.Checked {
+			return []string{KanbanColumnChecked}
+		}
+		return []string{KanbanColumnUnchecked}
+	default:
+		return []string{""}
+	}
+	return
+}
@@ -271,6 +271,26 @@ func (value *Value) Compare(other *Value, attrView *AttributeView) int {
 			}
 			return 1
 		}
+	case KeyTypeFormula:
+		if nil != value.Formula && nil != other.Formula {
+			// 结果类型相同时按该类型比较，否则按文本比较
+			v1, v2 := value.Formula.ToValue(), other.Formula.ToValue()
+			if v1.Type != v2.Type {
+				v1 = &Value{Type: KeyTypeText, Text: &ValueText{Content: v1.String(false)}}
+				v2 = &Value{Type: KeyTypeText, Text: &ValueText{Content: v2.String(false)}}
+			}
+			return v1.Compare(v2, attrView)
+		}
+	case KeyTypeAutoID:
+		if nil != value.AutoID && nil != other.AutoID {
+			if value.AutoID.Content > other.AutoID.Content {
+				return 1
+			}
+			if value.AutoID.Content < other.AutoID.Content {
+				return -1
+			}
+			return 0
+		}
 	case KeyTypeCheckbox:
 		if nil != value.Checkbox && nil != other.Checkbox {
 			if value.Checkbox.Checked && !other.Checkbox.Checked {
@@ -25,11 +25,36 @@ type LayoutTable struct {
 	Spec int    `json:"spec"` // 布局格式版本
 	ID   string `json:"id"`   // 布局 ID
 
-	Columns  []*ViewTableColumn `json:"columns"`  // 表格列
-	RowIDs   []string           `json:"rowIds"`   // 行 ID，用于自定义排序
-	Filters  []*ViewFilter      `json:"filters"`  // 过滤规则
-	Sorts    []*ViewSort        `json:"sorts"`    // 排序规则
-	PageSize int                `json:"pageSize"` // 每页行数
+	Columns      []*ViewTableColumn `json:"columns"`                // 表格列
+	RowIDs       []string           `json:"rowIds"`                 // 行 ID，用于自定义排序
+	Filters      []*ViewFilter      `json:"filters"`                // 过滤条件，各条件之间为 AND
+	FilterGroups []*ViewFilter      `json:"filterGroups,omitempty"` // 嵌套的过滤组，和过滤条件之间为 AND
+	Sorts        []*ViewSort        `json:"sorts"`                  // 排序规则
+	Group        *ViewGroup         `json:"group,omitempty"`        // 分组规则
+	Formats      []*ViewFormat      `json:"formats,omitempty"`      // 条件格式规则
+	PageSize     int                `json:"pageSize"`               // 每页行数
+}
+
+// GetFilter 将过滤条件和过滤组组装为 AND 过滤组，过滤组的子规则和 Filters、FilterGroups 共用同一批过滤规则。
+func (table *LayoutTable) GetFilter() *ViewFilter {
+	children := make([]*ViewFilter, 0, len(table.Filters)+len(table.FilterGroups))
+	children = append(children, table.Filters...)
+	children = append(children, table.FilterGroups...)
+	return NewViewFilterGroup(children...)
+}
+
+// SetFilters 设置过滤规则，各规则之间为 AND。
+//
+// 过滤组单独保存在 filterGroups 中，旧版本只能识别 filters 中的过滤条件，会把过滤组当作列不存在的过滤条件删除。
+func (table *LayoutTable) SetFilters(filters []*ViewFilter) {
+	table.Filters, table.FilterGroups = []*ViewFilter{}, nil
+	for _, filter := range filters {
+		if filter.IsGroup() {
+			table.FilterGroups = append(table.FilterGroups, filter)
+		} else {
+			table.Filters = append(table.Filters, filter)
+		}
+	}
 }
 
 type ViewTableColumn struct {
@@ -52,8 +77,11 @@ type Table struct {
 	HideAttrViewName bool           `json:"hideAttrViewName"` // 是否隐藏属性视图名称
 	Filters          []*ViewFilter  `json:"filters"`          // 过滤规则
 	Sorts            []*ViewSort    `json:"sorts"`            // 排序规则
+	Group            *ViewGroup     `json:"group"`            // 分组规则
+	Formats          []*ViewFormat  `json:"formats"`          // 条件格式规则
 	Columns          []*TableColumn `json:"columns"`          // 表格列
 	Rows             []*TableRow    `json:"rows"`             // 表格行
+	Groups           []*TableGroup  `json:"groups,omitempty"` // 表格分组，设置了分组规则时行按分组返回
 	RowCount         int            `json:"rowCount"`         // 表格总行数
 	PageSize         int            `json:"pageSize"`         // 每页行数
 }
@@ -78,6 +106,8 @@ type TableColumn struct {
 	Relation     *Relation       `json:"relation,omitempty"` // 关联列
 	Rollup       *Rollup         `json:"rollup,omitempty"`   // 汇总列
 	Date         *Date           `json:"date,omitempty"`     // 日期设置
+	Formula      string          `json:"formula,omitempty"`  // 公式
+	AutoID       *AutoID         `json:"autoID,omitempty"`   // 自动编号
 }
 
 type TableCell struct {
@@ -86,11 +116,19 @@ type TableCell struct {
 	ValueType KeyType `json:"valueType"`
 	Color     string  `json:"color"`
 	BgColor   string  `json:"bgColor"`
+	Icon      string  `json:"icon,omitempty"` // 条件格式设置的图标
+	Bold      bool    `json:"bold,omitempty"` // 条件格式设置的加粗
 }
 
 type TableRow struct {
 	ID    string       `json:"id"`
 	Cells []*TableCell `json:"cells"`
+
+	// 以下为条件格式设置的整行样式
+	Color   string `json:"color,omitempty"`
+	BgColor string `json:"bgColor,omitempty"`
+	Icon    string `json:"icon,omitempty"`
+	Bold    bool   `json:"bold,omitempty"`
 }
 
 func (row *TableRow) GetBlockValue() (ret *Value) {
@@ -244,50 +282,70 @@ func (table *Table) SortRows(attrView *AttributeView) {
 }
 
 func (table *Table) FilterRows(attrView *AttributeView) {
-	if 1 > len(table.Filters) {
+	filter := NewViewFilterGroup(table.Filters...)
+	if filter.IsEmpty() {
 		return
 	}
 
-	var colIndexes []int
-	for _, f := range table.Filters {
-		for i, c := range table.Columns {
-			if c.ID == f.Column {
-				colIndexes = append(colIndexes, i)
-				break
-			}
-		}
+	colIndexes := map[string]int{}
+	for i, c := range table.Columns {
+		colIndexes[c.ID] = i
 	}
 
 	rows := []*TableRow{}
 	attrViewCache := map[string]*AttributeView{}
 	attrViewCache[attrView.ID] = attrView
 	for _, row := range table.Rows {
-		pass := true
-		for j, index := range colIndexes {
-			operator := table.Filters[j].Operator
-
-			if nil == row.Cells[index].Value {
-				if FilterOperatorIsNotEmpty == operator {
-					pass = false
-				} else if FilterOperatorIsEmpty == operator {
-					pass = true
-					break
-				}
+		if table.matchFilter(filter, row, colIndexes, attrView, &attrViewCache) {
+			rows = append(rows, row)
+		}
+	}
+	table.Rows = rows
+}
+
+func (table *Table) matchFilter(filter *ViewFilter, row *TableRow, colIndexes map[string]int, attrView *AttributeView, attrViewCache *map[string]*AttributeView) (ret bool) {
+	if filter.IsGroup() {
+		ret = true
+		if FilterConjunctionOr == filter.Conjunction && 0 < len(filter.Children) {
+			ret = false
+		}
 
-				if KeyTypeText != row.Cells[index].ValueType {
-					pass = false
+		for _, child := range filter.Children {
+			pass := table.matchFilter(child, row, colIndexes, attrView, attrViewCache)
+			if FilterConjunctionOr == filter.Conjunction {
+				if pass {
+					ret = true
+					break
 				}
+			} else if !pass {
+				ret = false
 				break
 			}
+		}
+	} else {
+		index, ok := colIndexes[filter.Column]
+		if !ok {
+			// 字段已经被删除的过滤条件不参与过滤
+			return true
+		}
 
-			if !row.Cells[index].Value.Filter(table.Filters[j], attrView, row.ID, &attrViewCache) {
-				pass = false
-				break
+		cell := row.Cells[index]
+		if nil == cell.Value {
+			switch filter.Operator {
+			case FilterOperatorIsNotEmpty:
+				ret = false
+			case FilterOperatorIsEmpty:
+				ret = true
+			default:
+				ret = KeyTypeText == cell.ValueType
 			}
-		}
-		if pass {
-			rows = append(rows, row)
+		} else {
+			ret = cell.Value.Filter(filter, attrView, row.ID, attrViewCache)
 		}
 	}
-	table.Rows = rows
+
+	if filter.Not {
+		ret = !ret
+	}
+	return
 }
@@ -66,6 +66,10 @@ func (table *Table) CalcCols() {
 			table.calcColRelation(col, i)
 		case KeyTypeRollup:
 			table.calcColRollup(col, i)
+		case KeyTypeFormula:
+			table.calcColFormula(col, i)
+		case KeyTypeAutoID:
+			table.calcColAutoID(col, i)
 		}
 	}
 }
@@ -1663,3 +1667,68 @@ func (table *Table) calcColRollup(col *TableColumn, colIndex int) {
 		}
 	}
 }
+
+// calcColFormula 按公式结果类型进行计算，所有非空结果的类型相同时使用该类型的计算逻辑，否则按文本计算。
+func (table *Table) calcColFormula(col *TableColumn, colIndex int) {
+	var resultType KeyType
+	var values []*Value
+	for _, row := range table.Rows {
+		val := &Value{Type: KeyTypeText, Text: &ValueText{}}
+		if nil != row.Cells[colIndex] && nil != row.Cells[colIndex].Value && nil != row.Cells[colIndex].Value.Formula {
+			val = row.Cells[colIndex].Value.Formula.ToValue()
+		}
+		values = append(values, val)
+
+		if val.IsEmpty() {
+			continue
+		}
+		if "" == resultType {
+			resultType = val.Type
+		} else if resultType != val.Type {
+			resultType = KeyTypeText
+		}
+	}
+	if "" == resultType {
+		resultType = KeyTypeText
+	}
+
+	resultCol := &TableColumn{ID: col.ID, Type: resultType, NumberFormat: col.NumberFormat, Calc: &ColumnCalc{Operator: col.Calc.Operator}}
+	resultTable := &Table{Columns: []*TableColumn{resultCol}}
+	for i, row := range table.Rows {
+		val := values[i]
+		if resultType != val.Type {
+			val = &Value{Type: resultType}
+			switch resultType {
+			case KeyTypeNumber:
+				val.Number = &ValueNumber{}
+			case KeyTypeDate:
+				val.Date = &ValueDate{}
+			case KeyTypeCheckbox:
+				val.Checkbox = &ValueCheckbox{}
+			default:
+				val.Text = &ValueText{}
+				if !values[i].IsEmpty() {
+					val.Text.Content = values[i].String(false)
+				}
+			}
+		}
+		resultTable.Rows = append(resultTable.Rows, &TableRow{ID: row.ID, Cells: []*TableCell{{Value: val, ValueType: resultType}}})
+	}
+	resultTable.CalcCols()
+	col.Calc.Result = resultCol.Calc.Result
+}
+
+// calcColAutoID 将自动编号转换为数字后计算。
+func (table *Table) calcColAutoID(col *TableColumn, colIndex int) {
+	numberCol := &TableColumn{ID: col.ID, Type: KeyTypeNumber, Calc: &ColumnCalc{Operator: col.Calc.Operator}}
+	numberTable := &Table{Columns: []*TableColumn{numberCol}}
+	for _, row := range table.Rows {
+		val := &Value{Type: KeyTypeNumber, Number: &ValueNumber{}}
+		if nil != row.Cells[colIndex] && nil != row.Cells[colIndex].Value && nil != row.Cells[colIndex].Value.AutoID && 0 < row.Cells[colIndex].Value.AutoID.Content {
+			val.Number = NewFormattedValueNumber(float64(row.Cells[colIndex].Value.AutoID.Content), NumberFormatNone)
+		}
+		numberTable.Rows = append(numberTable.Rows, &TableRow{ID: row.ID, Cells: []*TableCell{{Value: val, ValueType: KeyTypeNumber}}})
+	}
+	numberTable.CalcCols()
+	col.Calc.Result = numberCol.Calc.Result
+}
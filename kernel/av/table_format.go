@@ -0,0 +1,118 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package av
+
+// ViewFormat 描述了条件格式规则的结构。
+//
+// 条件复用过滤规则，可以是单个过滤条件，也可以是条件组。
+type ViewFormat struct {
+	ID        string       `json:"id"`                // 规则 ID
+	Condition *ViewFilter  `json:"condition"`         // 条件
+	Target    FormatTarget `json:"target"`            // 作用范围
+	Column    string       `json:"column,omitempty"`  // 作用范围为单元格时的列 ID
+	Color     string       `json:"color,omitempty"`   // 文字颜色
+	BgColor   string       `json:"bgColor,omitempty"` // 背景颜色
+	Icon      string       `json:"icon,omitempty"`    // 图标
+	Bold      bool         `json:"bold,omitempty"`    // 是否加粗
+}
+
+// FormatTarget 描述了条件格式的作用范围。
+type FormatTarget string
+
+const (
+	FormatTargetCell FormatTarget = "cell" // 单元格
+	FormatTargetRow  FormatTarget = "row"  // 整行
+)
+
+func IsFormatTarget(target string) bool {
+	switch FormatTarget(target) {
+	case FormatTargetCell, FormatTargetRow:
+		return true
+	}
+	return false
+}
+
+// FormatRows 按条件格式规则设置行和单元格的颜色、图标和加粗。
+//
+// 规则按顺序匹配，同一个样式属性以先匹配的规则为准，条件为空的规则不生效。
+func (table *Table) FormatRows(attrView *AttributeView) {
+	if 1 > len(table.Formats) {
+		return
+	}
+
+	colIndexes := map[string]int{}
+	for i, c := range table.Columns {
+		colIndexes[c.ID] = i
+	}
+
+	attrViewCache := map[string]*AttributeView{}
+	attrViewCache[attrView.ID] = attrView
+	for _, format := range table.Formats {
+		if format.Condition.IsEmpty() {
+			continue
+		}
+
+		cellIndex := -1
+		if FormatTargetCell == format.Target {
+			index, ok := colIndexes[format.Column]
+			if !ok {
+				continue
+			}
+			cellIndex = index
+		}
+
+		for _, row := range table.Rows {
+			if !table.matchFilter(format.Condition, row, colIndexes, attrView, &attrViewCache) {
+				continue
+			}
+
+			if 0 > cellIndex {
+				row.applyFormat(format)
+				continue
+			}
+			if cellIndex < len(row.Cells) {
+				row.Cells[cellIndex].applyFormat(format)
+			}
+		}
+	}
+}
+
+func (row *TableRow) applyFormat(format *ViewFormat) {
+	if "" == row.Color {
+		row.Color = format.Color
+	}
+	if "" == row.BgColor {
+		row.BgColor = format.BgColor
+	}
+	if "" == row.Icon {
+		row.Icon = format.Icon
+	}
+	row.Bold = row.Bold || format.Bold
+}
+
+func (cell *TableCell) applyFormat(format *ViewFormat) {
+	if "" == cell.Color {
+		cell.Color = format.Color
+	}
+	if "" == cell.BgColor {
+		cell.BgColor = format.BgColor
+	}
+	if "" == cell.Icon {
+		cell.Icon = format.Icon
+	}
+	cell.Bold = cell.Bold || format.Bold
+}
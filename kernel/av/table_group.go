@@ -0,0 +1,267 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package av
+
+import (
+	"sort"
+	"time"
+)
+
+// ViewGroup 描述了分组规则的结构。
+type ViewGroup struct {
+	Column string            `json:"column"`           // 分组列 ID
+	Method GroupMethod       `json:"method,omitempty"` // 分组方式，仅日期列使用
+	Groups []*ViewTableGroup `json:"groups,omitempty"` // 分组状态
+}
+
+type ViewTableGroup struct {
+	ID     string `json:"id"`     // 分组 ID，未填写值的分组 ID 为空字符串
+	Folded bool   `json:"folded"` // 是否折叠
+}
+
+// GroupMethod 描述了日期列的分组方式。
+type GroupMethod string
+
+const (
+	GroupMethodDay   GroupMethod = "day"   // 按天
+	GroupMethodWeek  GroupMethod = "week"  // 按周
+	GroupMethodMonth GroupMethod = "month" // 按月
+)
+
+const (
+	GroupChecked   = "checked"   // 复选框列已勾选分组
+	GroupUnchecked = "unchecked" // 复选框列未勾选分组
+)
+
+func IsGroupKeyType(typ KeyType) bool {
+	switch typ {
+	case KeyTypeSelect, KeyTypeMSelect, KeyTypeCheckbox, KeyTypeDate, KeyTypeRelation, KeyTypeTemplate:
+		return true
+	}
+	return false
+}
+
+func IsGroupMethod(method string) bool {
+	switch GroupMethod(method) {
+	case GroupMethodDay, GroupMethodWeek, GroupMethodMonth:
+		return true
+	}
+	return false
+}
+
+// GetGroup 获取分组状态。
+func (group *ViewGroup) GetGroup(id string) *ViewTableGroup {
+	for _, g := range group.Groups {
+		if g.ID == id {
+			return g
+		}
+	}
+	return nil
+}
+
+// RenameGroup 分组列选项名称变更后同步分组状态。
+func (group *ViewGroup) RenameGroup(oldID, newID string) {
+	if nil != group.GetGroup(newID) {
+		group.RemoveGroup(oldID)
+		return
+	}
+
+	if g := group.GetGroup(oldID); nil != g {
+		g.ID = newID
+	}
+}
+
+func (group *ViewGroup) RemoveGroup(id string) {
+	for i, g := range group.Groups {
+		if g.ID == id {
+			group.Groups = append(group.Groups[:i], group.Groups[i+1:]...)
+			return
+		}
+	}
+}
+
+// TableGroup 描述了表格分组实例的结构。
+type TableGroup struct {
+	ID       string        `json:"id"`       // 分组 ID
+	Name     string        `json:"name"`     // 分组名称
+	Color    string        `json:"color"`    // 分组颜色，仅选项分组使用
+	Folded   bool          `json:"folded"`   // 是否折叠
+	Rows     []*TableRow   `json:"rows"`     // 分组内的行
+	RowCount int           `json:"rowCount"` // 分组内的总行数
+	Calcs    []*ColumnCalc `json:"calcs"`    // 分组内各列的计算结果，和表格列一一对应
+}
+
+// GroupRows 按分组规则对过滤和排序后的行进行分组，并计算每个分组的列计算结果。
+//
+// 多选和关联列的行可能同时出现在多个分组中，未填写值的行归入 ID 为空字符串的分组并放在最后。
+func (table *Table) GroupRows() {
+	if nil == table.Group {
+		return
+	}
+
+	colIndex := -1
+	for i, col := range table.Columns {
+		if col.ID == table.Group.Column {
+			colIndex = i
+			break
+		}
+	}
+	if 0 > colIndex || !IsGroupKeyType(table.Columns[colIndex].Type) {
+		return
+	}
+	col := table.Columns[colIndex]
+
+	groups := map[string]*TableGroup{}
+	var groupIDs []string
+	for _, row := range table.Rows {
+		var value *Value
+		if colIndex < len(row.Cells) && nil != row.Cells[colIndex] {
+			value = row.Cells[colIndex].Value
+		}
+
+		for _, g := range getRowGroups(value, col.Type, table.Group.Method) {
+			group := groups[g.ID]
+			if nil == group {
+				group = g
+				groups[g.ID] = group
+				groupIDs = append(groupIDs, g.ID)
+			}
+			group.Rows = append(group.Rows, row)
+		}
+	}
+
+	sortGroupIDs(groupIDs, groups, col)
+
+	table.Groups = []*TableGroup{}
+	for _, id := range groupIDs {
+		group := groups[id]
+		group.RowCount = len(group.Rows)
+		if state := table.Group.GetGroup(id); nil != state {
+			group.Folded = state.Folded
+		}
+		group.Calcs = table.calcGroupCols(group.Rows)
+		table.Groups = append(table.Groups, group)
+	}
+}
+
+// calcGroupCols 使用分组内的行计算各列的计算结果，不影响表格本身的计算结果。
+func (table *Table) calcGroupCols(rows []*TableRow) (ret []*ColumnCalc) {
+	groupTable := &Table{Rows: rows}
+	for _, col := range table.Columns {
+		groupCol := *col
+		if nil != col.Calc {
+			groupCol.Calc = &ColumnCalc{Operator: col.Calc.Operator}
+		}
+		groupTable.Columns = append(groupTable.Columns, &groupCol)
+	}
+	groupTable.CalcCols()
+
+	for _, col := range groupTable.Columns {
+		ret = append(ret, col.Calc)
+	}
+	return
+}
+
+func getRowGroups(value *Value, typ KeyType, method GroupMethod) (ret []*TableGroup) {
+	switch typ {
+	case KeyTypeSelect, KeyTypeMSelect:
+		if nil != value {
+			for _, opt := range value.MSelect {
+				if "" == opt.Content {
+					continue
+				}
+				ret = append(ret, &TableGroup{ID: opt.Content, Name: opt.Content, Color: opt.Color})
+				if KeyTypeSelect == typ {
+					break
+				}
+			}
+		}
+	case KeyTypeCheckbox:
+		if nil != value && nil != value.Checkbox && value.Checkbox.Checked {
+			return []*TableGroup{{ID: GroupChecked, Name: GroupChecked}}
+		}
+		return []*TableGroup{{ID: GroupUnchecked, Name: GroupUnchecked}}
+	case KeyTypeDate:
+		if nil != value && nil != value.Date && value.Date.IsNotEmpty {
+			id := getDateGroupID(time.UnixMilli(value.Date.Content), method)
+			ret = append(ret, &TableGroup{ID: id, Name: id})
+		}
+	case KeyTypeRelation:
+		if nil != value && nil != value.Relation {
+			for i, blockID := range value.Relation.BlockIDs {
+				name := blockID
+				if i < len(value.Relation.Contents) && nil != value.Relation.Contents[i] {
+					name = value.Relation.Contents[i].String(false)
+				}
+				ret = append(ret, &TableGroup{ID: blockID, Name: name})
+			}
+		}
+	case KeyTypeTemplate:
+		if nil != value && nil != value.Template && "" != value.Template.Content {
+			ret = append(ret, &TableGroup{ID: value.Template.Content, Name: value.Template.Content})
+		}
+	}
+
+	if 1 > len(ret) {
+		ret = []*TableGroup{{ID: "", Name: ""}}
+	}
+	return
+}
+
+// getDateGroupID 获取日期所在分组的 ID：按天为 2006-01-02，按周为该周周一的日期，按月为 2006-01。
+func getDateGroupID(t time.Time, method GroupMethod) string {
+	switch method {
+	case GroupMethodWeek:
+		offset := (int(t.Weekday()) + 6) % 7
+		return t.AddDate(0, 0, -offset).Format("2006-01-02")
+	case GroupMethodMonth:
+		return t.Format("2006-01")
+	default:
+		return t.Format("2006-01-02")
+	}
+}
+
+// sortGroupIDs 对分组进行排序：选项分组按选项顺序，复选框已勾选在前，日期升序，其他按名称排序，空值分组放在最后。
+func sortGroupIDs(groupIDs []string, groups map[string]*TableGroup, col *TableColumn) {
+	optionIndexes := map[string]int{}
+	for i, opt := range col.Options {
+		optionIndexes[opt.Name] = i
+	}
+
+	sort.SliceStable(groupIDs, func(i, j int) bool {
+		a, b := groupIDs[i], groupIDs[j]
+		if "" == a || "" == b {
+			return "" != a
+		}
+
+		switch col.Type {
+		case KeyTypeSelect, KeyTypeMSelect:
+			ia, oka := optionIndexes[a]
+			ib, okb := optionIndexes[b]
+			if oka && okb {
+				return ia < ib
+			}
+			return oka
+		case KeyTypeCheckbox:
+			return GroupChecked == a
+		case KeyTypeDate:
+			return a < b
+		default:
+			return groups[a].Name < groups[b].Name
+		}
+	})
+}
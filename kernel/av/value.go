@@ -56,6 +56,8 @@ type Value struct {
 	Checkbox *ValueCheckbox `json:"checkbox,omitempty"`
 	Relation *ValueRelation `json:"relation,omitempty"`
 	Rollup   *ValueRollup   `json:"rollup,omitempty"`
+	Formula  *ValueFormula  `json:"formula,omitempty"`
+	AutoID   *ValueAutoID   `json:"autoID,omitempty"`
 }
 
 func (value *Value) SetUpdatedAt(mills int64) {
@@ -174,6 +176,19 @@ func (value *Value) String(format bool) string {
 			ret = append(ret, v.String(format))
 		}
 		return strings.TrimSpace(strings.Join(ret, ", "))
+	case KeyTypeFormula:
+		if nil == value.Formula {
+			return ""
+		}
+		return value.Formula.ToValue().String(format)
+	case KeyTypeAutoID:
+		if nil == value.AutoID {
+			return ""
+		}
+		if "" != value.AutoID.FormattedContent {
+			return value.AutoID.FormattedContent
+		}
+		return strconv.FormatInt(value.AutoID.Content, 10)
 	default:
 		return ""
 	}
@@ -290,6 +305,13 @@ func (value *Value) IsEmpty() bool {
 		return 1 > len(value.Relation.Contents)
 	case KeyTypeRollup:
 		return 1 > len(value.Rollup.Contents)
+	case KeyTypeFormula:
+		if nil == value.Formula {
+			return true
+		}
+		return value.Formula.ToValue().IsEmpty()
+	case KeyTypeAutoID:
+		return nil == value.AutoID || 1 > value.AutoID.Content
 	}
 	return false
 }
@@ -328,6 +350,10 @@ func (value *Value) SetValByType(typ KeyType, val interface{}) {
 		value.Relation = val.(*ValueRelation)
 	case KeyTypeRollup:
 		value.Rollup = val.(*ValueRollup)
+	case KeyTypeFormula:
+		value.Formula = val.(*ValueFormula)
+	case KeyTypeAutoID:
+		value.AutoID = val.(*ValueAutoID)
 	}
 }
 
@@ -365,6 +391,10 @@ func (value *Value) GetValByType(typ KeyType) (ret interface{}) {
 		return value.Relation
 	case KeyTypeRollup:
 		return value.Rollup
+	case KeyTypeFormula:
+		return value.Formula
+	case KeyTypeAutoID:
+		return value.AutoID
 	}
 	return
 }
@@ -670,6 +700,41 @@ type ValueRelation struct {
 	Contents []*Value `json:"contents"`
 }
 
+// ValueFormula 描述了公式计算结果，结果类型可以是文本、数字、日期或者勾选框。
+type ValueFormula struct {
+	Type     KeyType        `json:"type"`               // 结果类型
+	Text     *ValueText     `json:"text,omitempty"`     // 文本结果
+	Number   *ValueNumber   `json:"number,omitempty"`   // 数字结果
+	Date     *ValueDate     `json:"date,omitempty"`     // 日期结果
+	Checkbox *ValueCheckbox `json:"checkbox,omitempty"` // 勾选框结果
+	Error    string         `json:"error,omitempty"`    // 公式错误
+}
+
+// ToValue 将公式计算结果转换为对应结果类型的值，以便复用该类型的过滤、排序和计算逻辑。
+func (f *ValueFormula) ToValue() (ret *Value) {
+	ret = &Value{Type: f.Type, Text: f.Text, Number: f.Number, Date: f.Date, Checkbox: f.Checkbox}
+	switch f.Type {
+	case KeyTypeNumber:
+		if nil == ret.Number {
+			ret.Number = &ValueNumber{}
+		}
+	case KeyTypeDate:
+		if nil == ret.Date {
+			ret.Date = &ValueDate{}
+		}
+	case KeyTypeCheckbox:
+		if nil == ret.Checkbox {
+			ret.Checkbox = &ValueCheckbox{}
+		}
+	default:
+		ret.Type = KeyTypeText
+		if nil == ret.Text {
+			ret.Text = &ValueText{}
+		}
+	}
+	return
+}
+
 type ValueRollup struct {
 	Contents []*Value `json:"contents"`
 }
@@ -966,6 +1031,10 @@ func GetAttributeViewDefaultValue(valueID, keyID, blockID string, typ KeyType) (
 		ret.Relation = &ValueRelation{}
 	case KeyTypeRollup:
 		ret.Rollup = &ValueRollup{}
+	case KeyTypeFormula:
+		ret.Formula = &ValueFormula{Type: KeyTypeText, Text: &ValueText{}}
+	case KeyTypeAutoID:
+		ret.AutoID = &ValueAutoID{}
 	}
 	return
 }
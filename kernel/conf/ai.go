@@ -26,7 +26,8 @@ import (
 )
 
 type AI struct {
-	OpenAI *OpenAI `json:"openAI"`
+	OpenAI    *OpenAI    `json:"openAI"`
+	Embedding *Embedding `json:"embedding"`
 }
 
 type OpenAI struct {
@@ -43,6 +44,23 @@ type OpenAI struct {
 	APIVersion     string  `json:"apiVersion"`  // Azure API version
 }
 
+// Embedding 描述了语义搜索使用的向量生成服务。
+type Embedding struct {
+	Enable     bool   `json:"enable"`
+	Provider   string `json:"provider"`   // OpenAI：使用 OpenAI 配置调用 /embeddings 接口，HTTP：调用本地兼容 OpenAI /embeddings 格式的服务
+	Model      string `json:"model"`      // 模型名称
+	URL        string `json:"url"`        // HTTP 服务地址，如 http://127.0.0.1:11434/v1/embeddings
+	APITimeout int    `json:"apiTimeout"` // 超时时间，单位：秒
+}
+
+func NewEmbedding() *Embedding {
+	return &Embedding{
+		Provider:   "OpenAI",
+		Model:      string(openai.SmallEmbedding3),
+		APITimeout: 30,
+	}
+}
+
 func NewAI() *AI {
 	openAI := &OpenAI{
 		APITemperature: 1.0,
@@ -95,5 +113,5 @@ func NewAI() *AI {
 	if userAgent := os.Getenv("SIYUAN_OPENAI_API_USER_AGENT"); "" != userAgent {
 		openAI.APIUserAgent = userAgent
 	}
-	return &AI{OpenAI: openAI}
+	return &AI{OpenAI: openAI, Embedding: NewEmbedding()}
 }
@@ -19,11 +19,26 @@ package conf
 import "github.com/88250/gulu"
 
 type API struct {
-	Token string `json:"token"`
+	Token  string      `json:"token"`  // 全局 API token，拥有管理员权限
+	Tokens []*APIToken `json:"tokens"` // 按需签发的 API token
+}
+
+// APIToken 描述了按需签发的 API token，每个集成使用单独的 token，可以单独吊销。
+type APIToken struct {
+	ID        string   `json:"id"`        // ID
+	Name      string   `json:"name"`      // 名称，比如 Web Clipper
+	Hash      string   `json:"hash"`      // token 的 SHA-256 哈希，不保存明文
+	Role      string   `json:"role"`      // 角色：admin、editor、reader
+	Routes    []string `json:"routes"`    // 允许访问的路由，以 * 或者 / 结尾时按前缀匹配，比如 /api/block/* 和 /api/query/sql，为空表示不限制
+	Notebooks []string `json:"notebooks"` // 允许访问的笔记本 ID，为空表示不限制
+	Expired   int64    `json:"expired"`   // 过期时间，毫秒时间戳，0 表示不过期
+	Created   int64    `json:"created"`   // 创建时间，毫秒时间戳
+	LastUsed  int64    `json:"lastUsed"`  // 最近使用时间，毫秒时间戳
 }
 
 func NewAPI() *API {
 	return &API{
-		Token: gulu.Rand.String(16),
+		Token:  gulu.Rand.String(16),
+		Tokens: []*APIToken{},
 	}
 }
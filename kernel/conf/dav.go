@@ -0,0 +1,29 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package conf
+
+// DAV 描述了内核 WebDAV 服务（/webdav/）的配置。
+type DAV struct {
+	Quota             int64    `json:"quota"`             // 工作空间容量配额，单位字节，超出后拒绝通过 WebDAV 写入，0 表示不限制
+	ReadOnlyNotebooks []string `json:"readOnlyNotebooks"` // 通过 WebDAV 只读的笔记本 ID
+}
+
+func NewDAV() *DAV {
+	return &DAV{
+		ReadOnlyNotebooks: []string{},
+	}
+}
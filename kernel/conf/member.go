@@ -0,0 +1,41 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package conf
+
+// Member 描述了工作空间的用户帐号，用于多人协作访问。
+//
+// 社区帐号使用 User 结构，这里使用 Member 以示区分。
+type Member struct {
+	Username string `json:"username"` // 用户名
+	Password string `json:"password"` // 密码的 bcrypt 哈希，不保存明文
+	Role     string `json:"role"`     // 角色：admin、editor、reader
+	Memo     string `json:"memo"`     // 备注
+}
+
+const (
+	MemberRoleAdmin  = "admin"  // 管理员
+	MemberRoleEditor = "editor" // 编辑者
+	MemberRoleReader = "reader" // 读者
+)
+
+func IsMemberRole(role string) bool {
+	switch role {
+	case MemberRoleAdmin, MemberRoleEditor, MemberRoleReader:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package conf
+
+// OIDC 描述了 OpenID Connect 登录配置。
+type OIDC struct {
+	Enable       bool            `json:"enable"`       // 是否启用 OpenID Connect 登录
+	Issuer       string          `json:"issuer"`       // 签发者地址，通过 {issuer}/.well-known/openid-configuration 获取端点
+	ClientID     string          `json:"clientID"`     // 客户端 ID
+	ClientSecret string          `json:"clientSecret"` // 客户端密钥
+	RedirectURL  string          `json:"redirectURL"`  // 回调地址，比如 https://example.com/auth/oidc/callback，需要和签发者中登记的一致
+	Scopes       []string        `json:"scopes"`       // 请求的 scope，为空时使用 openid email profile
+	GroupsClaim  string          `json:"groupsClaim"`  // ID token 中用户组的声明名称，为空时使用 groups
+	Roles        []*OIDCRoleRule `json:"roles"`        // 角色映射规则，按顺序匹配，均不匹配时拒绝登录
+}
+
+// OIDCRoleRule 描述了邮箱或者用户组到角色的映射规则。
+type OIDCRoleRule struct {
+	Emails []string `json:"emails"` // 邮箱，以 @ 开头时匹配整个域名，比如 @example.com
+	Groups []string `json:"groups"` // 用户组
+	Role   string   `json:"role"`   // 角色：admin、editor、reader
+}
+
+func NewOIDC() *OIDC {
+	return &OIDC{
+		Scopes: []string{"openid", "email", "profile"},
+		Roles:  []*OIDCRoleRule{},
+	}
+}
@@ -31,6 +31,24 @@ type BasicAuthAccount struct {
 	Username string `json:"username"` // 用户名
 	Password string `json:"password"` // 密码
 	Memo     string `json:"memo"`     // 备注
+
+	Notebooks []string                    `json:"notebooks,omitempty"` // 允许访问的笔记本 ID，为空表示不限制
+	Paths     []string                    `json:"paths,omitempty"`     // 允许访问的文档路径前缀，可以是文档数据路径（/20240101120000-abcdefg）或者可读路径（/Project），为空表示不限制
+	Attrs     []*BasicAuthAccountAttrRule `json:"attrs,omitempty"`     // 文档属性规则
+}
+
+// BasicAuthAccountAttrRule 描述了按文档属性限制访问的规则。
+//
+// 存在允许规则时文档需要至少匹配一条允许规则，匹配任意一条拒绝规则的文档不能访问。
+type BasicAuthAccountAttrRule struct {
+	Name  string `json:"name"`           // 属性名，比如 custom-publish
+	Value string `json:"value"`          // 属性值，为空表示只要存在该属性即匹配
+	Deny  bool   `json:"deny,omitempty"` // 是否为拒绝规则
+}
+
+// IsRestricted 判断账户是否设置了访问范围。
+func (account *BasicAuthAccount) IsRestricted() bool {
+	return 0 < len(account.Notebooks) || 0 < len(account.Paths) || 0 < len(account.Attrs)
 }
 
 func NewPublish() *Publish {
@@ -0,0 +1,41 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package conf
+
+// TOTP 描述了访问授权码登录的两步验证配置。
+type TOTP struct {
+	Enable        bool          `json:"enable"`        // 是否启用两步验证
+	Secret        string        `json:"secret"`        // Base32 编码的密钥
+	PendingSecret string        `json:"pendingSecret"` // 绑定过程中尚未确认的密钥
+	RecoveryCodes []string      `json:"recoveryCodes"` // 恢复码的 SHA-256 哈希，使用后移除
+	LastStep      int64         `json:"lastStep"`      // 最近一次验证通过的时间步，用于防止验证码重放
+	Devices       []*TOTPDevice `json:"devices"`       // 记住的设备
+}
+
+// TOTPDevice 描述了登录时选择记住的设备，在有效期内登录时免两步验证。
+type TOTPDevice struct {
+	Hash    string `json:"hash"`    // 设备 Cookie 的 SHA-256 哈希，不保存明文
+	Created int64  `json:"created"` // 创建时间，毫秒时间戳
+	Expired int64  `json:"expired"` // 过期时间，毫秒时间戳
+}
+
+func NewTOTP() *TOTP {
+	return &TOTP{
+		RecoveryCodes: []string{},
+		Devices:       []*TOTPDevice{},
+	}
+}
@@ -38,6 +38,7 @@ func main() {
 	sql.InitAssetContentDatabase(false)
 	sql.SetCaseSensitive(model.Conf.Search.CaseSensitive)
 	sql.SetIndexAssetPath(model.Conf.Search.IndexAssetPath)
+	model.InitEmbeddingProvider()
 
 	model.BootSyncData()
 	model.InitBoxes()
@@ -0,0 +1,303 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package model
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/88250/lute/ast"
+	"github.com/gin-gonic/gin"
+	"github.com/siyuan-note/siyuan/kernel/conf"
+	"github.com/siyuan-note/siyuan/kernel/util"
+)
+
+const (
+	APITokenContextKey = "apiToken"
+
+	apiTokenPrefix = "sy_"
+)
+
+var (
+	ErrInvalidAPITokenName = errors.New("invalid token name")
+	ErrInvalidAPITokenRole = errors.New("invalid token role")
+	ErrAPITokenNotFound    = errors.New("token not found")
+)
+
+// apiTokenLock 保护 Conf.Api.Tokens，Conf.Save 序列化配置时也会持有该锁，所以持有该锁时不能调用 Conf.Save。
+var apiTokenLock = sync.Mutex{}
+
+// GetAPITokens 获取签发的 API token 列表，返回的 token 不包含哈希。
+func GetAPITokens() (ret []*conf.APIToken) {
+	apiTokenLock.Lock()
+	defer apiTokenLock.Unlock()
+
+	ret = []*conf.APIToken{}
+	for _, token := range Conf.Api.Tokens {
+		t := *token
+		t.Hash = ""
+		ret = append(ret, &t)
+	}
+	return
+}
+
+// CreateAPIToken 签发 API token，返回的明文 token 仅在签发时可见。
+func CreateAPIToken(name, role string, routes, notebooks []string, expired int64) (ret *conf.APIToken, plain string, err error) {
+	if err = checkAPITokenArgs(name, role); err != nil {
+		return
+	}
+
+	secret, err := randomToken(32)
+	if err != nil {
+		return
+	}
+
+	plain = apiTokenPrefix + secret
+	token := &conf.APIToken{
+		ID:        ast.NewNodeID(),
+		Name:      strings.TrimSpace(name),
+		Hash:      hashAPIToken(plain),
+		Role:      role,
+		Routes:    normalizeAPITokenRoutes(routes),
+		Notebooks: notebooks,
+		Expired:   expired,
+		Created:   util.CurrentTimeMillis(),
+	}
+	if nil == token.Notebooks {
+		token.Notebooks = []string{}
+	}
+
+	apiTokenLock.Lock()
+	Conf.Api.Tokens = append(Conf.Api.Tokens, token)
+	apiTokenLock.Unlock()
+	Conf.Save()
+
+	t := *token
+	t.Hash = ""
+	ret = &t
+	return
+}
+
+// UpdateAPIToken 更新 API token 的名称、角色和访问范围，token 本身不变。
+func UpdateAPIToken(id, name, role string, routes, notebooks []string, expired int64) (err error) {
+	if err = checkAPITokenArgs(name, role); err != nil {
+		return
+	}
+
+	apiTokenLock.Lock()
+	token := getAPITokenByID(id)
+	if nil == token {
+		apiTokenLock.Unlock()
+		return ErrAPITokenNotFound
+	}
+
+	token.Name = strings.TrimSpace(name)
+	token.Role = role
+	token.Routes = normalizeAPITokenRoutes(routes)
+	token.Notebooks = notebooks
+	if nil == token.Notebooks {
+		token.Notebooks = []string{}
+	}
+	token.Expired = expired
+	apiTokenLock.Unlock()
+	Conf.Save()
+	return
+}
+
+// RevokeAPIToken 吊销 API token，其他 token 不受影响。
+func RevokeAPIToken(id string) (err error) {
+	apiTokenLock.Lock()
+	for i, token := range Conf.Api.Tokens {
+		if token.ID == id {
+			Conf.Api.Tokens = append(Conf.Api.Tokens[:i], Conf.Api.Tokens[i+1:]...)
+			apiTokenLock.Unlock()
+			Conf.Save()
+			return
+		}
+	}
+	apiTokenLock.Unlock()
+	return ErrAPITokenNotFound
+}
+
+// GetGinContextAPIToken 获取当前请求使用的签发 API token，使用全局 API token 或者未使用 API token 时返回 nil。
+func GetGinContextAPIToken(c *gin.Context) *conf.APIToken {
+	if nil == c {
+		return nil
+	}
+
+	if token, exists := c.Get(APITokenContextKey); exists {
+		return token.(*conf.APIToken)
+	}
+	return nil
+}
+
+// authAPIToken 使用签发的 API token 认证，认证通过时在请求上下文中设置角色。
+//
+// 返回 false 时已经中止请求：token 无效或者过期时返回 401，请求的路由或者笔记本超出 token 访问范围时返回 403。
+func authAPIToken(c *gin.Context, plain string) bool {
+	hash := hashAPIToken(plain)
+	now := util.CurrentTimeMillis()
+
+	apiTokenLock.Lock()
+	var token *conf.APIToken
+	for _, t := range Conf.Api.Tokens {
+		if 1 == subtle.ConstantTimeCompare([]byte(t.Hash), []byte(hash)) {
+			token = t
+			break
+		}
+	}
+	if nil == token || (0 < token.Expired && token.Expired < now) {
+		apiTokenLock.Unlock()
+		c.JSON(http.StatusUnauthorized, map[string]interface{}{"code": -1, "msg": "Auth failed [token]"})
+		c.Abort()
+		return false
+	}
+
+	// 最近使用时间每分钟最多持久化一次，同步保存避免多个保存协程并发写配置文件
+	needSave := 60*1000 < now-token.LastUsed
+	token.LastUsed = now
+	apiTokenLock.Unlock()
+	if needSave {
+		Conf.Save()
+	}
+
+	if !isAPITokenRouteAllowed(token, c.Request.URL.Path) {
+		c.JSON(http.StatusForbidden, map[string]interface{}{"code": -1, "msg": "Forbidden [token route]"})
+		c.Abort()
+		return false
+	}
+
+	// 限制了笔记本范围的 token 只能调用会按访问范围检查参数和过滤结果的接口
+	if 0 < len(token.Notebooks) && !isAPITokenScopedRouteAllowed(c.Request.URL.Path) {
+		c.JSON(http.StatusForbidden, map[string]interface{}{"code": -1, "msg": "Forbidden [token notebook]"})
+		c.Abort()
+		return false
+	}
+
+	c.Set(APITokenContextKey, token)
+	c.Set(RoleContextKey, getRoleByName(token.Role))
+	return true
+}
+
+func isAPITokenRouteAllowed(token *conf.APIToken, p string) bool {
+	if 1 > len(token.Routes) {
+		return true
+	}
+
+	for _, route := range token.Routes {
+		if strings.HasSuffix(route, "*") || strings.HasSuffix(route, "/") {
+			if strings.HasPrefix(p, strings.TrimSuffix(route, "*")) {
+				return true
+			}
+			continue
+		}
+		if p == route {
+			return true
+		}
+	}
+	return false
+}
+
+// apiTokenScopedRoutes 是限制了笔记本范围的 token 可以调用的接口，这些接口会按访问范围检查参数和过滤结果。
+//
+// 凭据和用户帐号管理等其他接口一律拒绝，新增接口需要实现访问范围检查后才能加入。
+var apiTokenScopedRoutes = map[string]bool{
+	"/api/notebook/lsNotebooks":         true,
+	"/api/filetree/searchDocs":          true,
+	"/api/filetree/listDocsByPath":      true,
+	"/api/filetree/getDoc":              true,
+	"/api/block/getBlockDOM":            true,
+	"/api/block/getBlockKramdown":       true,
+	"/api/search/searchRefBlock":        true,
+	"/api/search/fullTextSearchBlock":   true,
+	"/api/search/getEmbedBlock":         true,
+	"/api/ref/getBacklink":              true,
+	"/api/ref/getBacklink2":             true,
+	"/api/ref/getBacklinkDoc":           true,
+	"/api/ref/getBackmentionDoc":        true,
+	"/api/graph/getGraph":               true,
+	"/api/graph/getLocalGraph":          true,
+	"/api/storage/runSavedQuery":        true,
+	"/api/filetree/getHPathByID":        true,
+	"/api/outline/getDocOutline":        true,
+	"/api/search/searchAsset":           true,
+	"/api/block/getBlockInfo":           true,
+	"/api/block/getChildBlocks":         true,
+	"/api/block/getTailChildBlocks":     true,
+	"/api/block/getBlockBreadcrumb":     true,
+	"/api/block/getRefText":             true,
+	"/api/block/getBlocksWordCount":     true,
+	"/api/block/getRecentUpdatedBlocks": true,
+	"/api/block/getDocInfo":             true,
+	"/api/block/getHeadingChildrenIDs":  true,
+	"/api/block/getHeadingChildrenDOM":  true,
+	"/api/attr/getBlockAttrs":           true,
+	"/api/attr/batchGetBlockAttrs":      true,
+	"/api/export/preview":               true,
+}
+
+func isAPITokenScopedRouteAllowed(p string) bool {
+	if strings.HasPrefix(p, "/assets/") {
+		// 资源文件在 CheckReadRole 中按访问范围检查
+		return true
+	}
+	return apiTokenScopedRoutes[p]
+}
+
+func normalizeAPITokenRoutes(routes []string) (ret []string) {
+	ret = []string{}
+	for _, route := range routes {
+		route = strings.TrimSpace(route)
+		if "" == route {
+			continue
+		}
+		if !strings.HasPrefix(route, "/") {
+			route = "/" + route
+		}
+		ret = append(ret, route)
+	}
+	return
+}
+
+func getAPITokenByID(id string) *conf.APIToken {
+	for _, token := range Conf.Api.Tokens {
+		if token.ID == id {
+			return token
+		}
+	}
+	return nil
+}
+
+func checkAPITokenArgs(name, role string) error {
+	if "" == strings.TrimSpace(name) {
+		return ErrInvalidAPITokenName
+	}
+	if !conf.IsMemberRole(role) {
+		return ErrInvalidAPITokenRole
+	}
+	return nil
+}
+
+func hashAPIToken(plain string) string {
+	hash := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(hash[:])
+}
@@ -97,8 +97,7 @@ func AppendAttributeViewDetachedBlocksWithValues(avID string, blocksValues [][]*
 	}
 
 	for _, v := range attrView.Views {
-		switch v.LayoutType {
-		case av.LayoutTypeTable:
+		if v.HasLayoutTable() {
 			for _, addingBlockID := range blockIDs {
 				v.Table.RowIDs = append(v.Table.RowIDs, addingBlockID)
 			}
@@ -258,9 +257,8 @@ func GetAttributeViewFilterSort(avID, blockID string) (filters []*av.ViewFilter,
 
 	filters = []*av.ViewFilter{}
 	sorts = []*av.ViewSort{}
-	switch view.LayoutType {
-	case av.LayoutTypeTable:
-		filters = view.Table.Filters
+	if view.HasLayoutTable() {
+		filters = view.Table.GetFilter().Children
 		sorts = view.Table.Sorts
 	}
 	return
@@ -277,7 +275,7 @@ func SearchAttributeViewNonRelationKey(avID, keyword string) (ret []*av.Key) {
 	}
 
 	for _, keyValues := range attrView.KeyValues {
-		if av.KeyTypeRelation != keyValues.Key.Type && av.KeyTypeRollup != keyValues.Key.Type && av.KeyTypeTemplate != keyValues.Key.Type && av.KeyTypeCreated != keyValues.Key.Type && av.KeyTypeUpdated != keyValues.Key.Type && av.KeyTypeLineNumber != keyValues.Key.Type {
+		if av.KeyTypeRelation != keyValues.Key.Type && av.KeyTypeRollup != keyValues.Key.Type && av.KeyTypeTemplate != keyValues.Key.Type && av.KeyTypeCreated != keyValues.Key.Type && av.KeyTypeUpdated != keyValues.Key.Type && av.KeyTypeLineNumber != keyValues.Key.Type && av.KeyTypeFormula != keyValues.Key.Type {
 			if strings.Contains(strings.ToLower(keyValues.Key.Name), strings.ToLower(keyword)) {
 				ret = append(ret, keyValues.Key)
 			}
@@ -538,6 +536,8 @@ func GetBlockAttributeViewKeys(blockID string) (ret []*BlockAttributeViewKeys) {
 				kValues.Values = append(kValues.Values, &av.Value{ID: ast.NewNodeID(), KeyID: kValues.Key.ID, BlockID: blockID, Type: av.KeyTypeCreated})
 			case av.KeyTypeUpdated:
 				kValues.Values = append(kValues.Values, &av.Value{ID: ast.NewNodeID(), KeyID: kValues.Key.ID, BlockID: blockID, Type: av.KeyTypeUpdated})
+			case av.KeyTypeFormula:
+				kValues.Values = append(kValues.Values, &av.Value{ID: ast.NewNodeID(), KeyID: kValues.Key.ID, BlockID: blockID, Type: av.KeyTypeFormula})
 			case av.KeyTypeNumber:
 				for _, v := range kValues.Values {
 					if nil != v.Number {
@@ -687,6 +687,14 @@ func GetBlockAttributeViewKeys(blockID string) (ret []*BlockAttributeViewKeys) {
 			util.PushErrMsg(fmt.Sprintf(Conf.Language(44), util.EscapeHTML(renderTemplateErr.Error())), 30000)
 		}
 
+		// 最后计算公式
+		formulaCtx := av.NewFormulaContext(attrView, keyValues)
+		for _, kv := range keyValues {
+			if av.KeyTypeFormula == kv.Key.Type && 0 < len(kv.Values) {
+				kv.Values[0].Formula = formulaCtx.Render(kv.Key)
+			}
+		}
+
 		// 字段排序
 		refreshAttrViewKeyIDs(attrView, true)
 		sorts := map[string]int{}
@@ -884,16 +892,14 @@ func renderAttributeView(attrView *av.AttributeView, viewID, query string, page,
 	// 做一些数据兼容和订正处理，保存的时候也会做 av.SaveAttributeView()
 	upgradeAttributeViewSpec(attrView)
 
-	switch view.LayoutType {
-	case av.LayoutTypeTable:
+	if view.HasLayoutTable() {
 		// 列删除以后需要删除设置的过滤和排序
-		tmpFilters := []*av.ViewFilter{}
-		for _, f := range view.Table.Filters {
-			if k, _ := attrView.GetKey(f.Column); nil != k {
-				tmpFilters = append(tmpFilters, f)
-			}
-		}
-		view.Table.Filters = tmpFilters
+		filter := view.Table.GetFilter()
+		filter.RemoveLeaves(func(leaf *av.ViewFilter) bool {
+			k, _ := attrView.GetKey(leaf.Column)
+			return nil == k
+		})
+		view.Table.SetFilters(filter.Children)
 
 		tmpSorts := []*av.ViewSort{}
 		for _, s := range view.Table.Sorts {
@@ -903,7 +909,37 @@ func renderAttributeView(attrView *av.AttributeView, viewID, query string, page,
 		}
 		view.Table.Sorts = tmpSorts
 
-		viewable = sql.RenderAttributeViewTable(attrView, view, query)
+		if nil != view.Table.Group {
+			if k, _ := attrView.GetKey(view.Table.Group.Column); nil == k || !av.IsGroupKeyType(k.Type) {
+				view.Table.Group = nil
+			}
+		}
+
+		tmpFormats := []*av.ViewFormat{}
+		for _, f := range view.Table.Formats {
+			if av.FormatTargetCell == f.Target {
+				if k, _ := attrView.GetKey(f.Column); nil == k {
+					continue
+				}
+			}
+			f.Condition.RemoveLeaves(func(leaf *av.ViewFilter) bool {
+				k, _ := attrView.GetKey(leaf.Column)
+				return nil == k
+			})
+			tmpFormats = append(tmpFormats, f)
+		}
+		view.Table.Formats = tmpFormats
+
+		switch view.LayoutType {
+		case av.LayoutTypeKanban:
+			viewable = sql.RenderAttributeViewKanban(attrView, view, query)
+		case av.LayoutTypeCalendar:
+			viewable = sql.RenderAttributeViewCalendar(attrView, view, query)
+		case av.LayoutTypeGallery:
+			viewable = sql.RenderAttributeViewGallery(attrView, view, query)
+		default:
+			viewable = sql.RenderAttributeViewTable(attrView, view, query)
+		}
 	}
 
 	viewable.FilterRows(attrView)
@@ -923,13 +959,156 @@ func renderAttributeView(attrView *av.AttributeView, viewID, query string, page,
 			pageSize = table.PageSize
 		}
 
+		if nil != table.Group {
+			table.GroupRows()
+
+			// 表格分组后每个分组单独分页
+			start := (page - 1) * pageSize
+			for _, group := range table.Groups {
+				end := start + pageSize
+				if len(group.Rows) < end {
+					end = len(group.Rows)
+				}
+				if len(group.Rows) < start {
+					group.Rows = []*av.TableRow{}
+					continue
+				}
+				group.Rows = group.Rows[start:end]
+			}
+			table.Rows = []*av.TableRow{}
+			break
+		}
+
 		start := (page - 1) * pageSize
 		end := start + pageSize
 		if len(table.Rows) < end {
 			end = len(table.Rows)
 		}
 		table.Rows = table.Rows[start:end]
+	case av.LayoutTypeKanban:
+		kanban := viewable.(*av.Kanban)
+		kanban.RowCount = len(kanban.Rows)
+		if 1 > view.Table.PageSize {
+			view.Table.PageSize = 50
+		}
+		kanban.PageSize = view.Table.PageSize
+		if 1 > pageSize {
+			pageSize = kanban.PageSize
+		}
+
+		kanban.GroupCards(view.Kanban)
+
+		// 看板每列单独分页
+		start := (page - 1) * pageSize
+		for _, column := range kanban.KanbanColumns {
+			end := start + pageSize
+			if len(column.Cards) < end {
+				end = len(column.Cards)
+			}
+			if len(column.Cards) < start {
+				column.Cards = []*av.TableRow{}
+				continue
+			}
+			column.Cards = column.Cards[start:end]
+		}
+	case av.LayoutTypeCalendar:
+		// 日历不分页，按时间窗口生成日程
+		calendar := viewable.(*av.Calendar)
+		calendar.RowCount = len(calendar.Rows)
+		calendar.PageSize = view.Table.PageSize
+		calendar.FillEvents(0, 0)
+	case av.LayoutTypeGallery:
+		gallery := viewable.(*av.Gallery)
+		gallery.RowCount = len(gallery.Rows)
+		gallery.CardCount = gallery.RowCount
+		if 1 > view.Table.PageSize {
+			view.Table.PageSize = 50
+		}
+		gallery.PageSize = view.Table.PageSize
+		if 1 > pageSize {
+			pageSize = gallery.PageSize
+		}
+
+		start := (page - 1) * pageSize
+		end := start + pageSize
+		if len(gallery.Rows) < end {
+			end = len(gallery.Rows)
+		}
+		gallery.Rows = gallery.Rows[start:end]
+		fillGalleryContentCovers(gallery)
+		gallery.FillCards()
+	}
+	return
+}
+
+// fillGalleryContentCovers 封面来源为块内容时使用绑定块中的第一张图片作为卡片封面，文档块优先使用题头图。
+//
+// 需要在 FillCards 之前调用。
+func fillGalleryContentCovers(gallery *av.Gallery) {
+	if av.CoverFromContentImage != gallery.CoverFrom {
+		return
+	}
+
+	var blockIDs []string
+	for _, row := range gallery.Rows {
+		blockVal := row.GetBlockValue()
+		if nil == blockVal || blockVal.IsDetached {
+			continue
+		}
+		blockIDs = append(blockIDs, blockVal.BlockID)
+	}
+	if 1 > len(blockIDs) {
+		return
+	}
+
+	covers := map[string]string{}
+	trees := filesys.LoadTrees(blockIDs)
+	for _, blockID := range blockIDs {
+		tree := trees[blockID]
+		if nil == tree {
+			continue
+		}
+
+		node := treenode.GetNodeInTree(tree, blockID)
+		if nil == node {
+			continue
+		}
+
+		if ast.NodeDocument == node.Type {
+			if titleImg := treenode.GetDocTitleImgPath(node); "" != titleImg {
+				covers[blockID] = titleImg
+				continue
+			}
+		}
+
+		ast.Walk(node, func(n *ast.Node, entering bool) ast.WalkStatus {
+			if !entering || ast.NodeImage != n.Type {
+				return ast.WalkContinue
+			}
+
+			if dest := n.ChildByType(ast.NodeLinkDest); nil != dest {
+				covers[blockID] = dest.TokensStr()
+				return ast.WalkStop
+			}
+			return ast.WalkContinue
+		})
+	}
+	gallery.ContentCovers = covers
+}
+
+// RenderAttributeViewCalendar 渲染日历视图时间窗口 [start, end) 内的日程，时间单位为毫秒。
+func RenderAttributeViewCalendar(avID, viewID, query string, start, end int64) (viewable av.Viewable, attrView *av.AttributeView, err error) {
+	viewable, attrView, err = RenderAttributeView(avID, viewID, query, 1, -1)
+	if err != nil {
+		return
 	}
+
+	calendar, ok := viewable.(*av.Calendar)
+	if !ok {
+		err = fmt.Errorf("view [%s] is not a calendar", viewable.GetID())
+		return
+	}
+	calendar.FillEvents(start, end)
 	return
 }
 
@@ -986,8 +1165,7 @@ func unbindAttributeViewBlock(operation *Operation, tx *Transaction) (err error)
 
 	replacedRowID := false
 	for _, v := range attrView.Views {
-		switch v.LayoutType {
-		case av.LayoutTypeTable:
+		if v.HasLayoutTable() {
 			for i, rowID := range v.Table.RowIDs {
 				if rowID == operation.ID {
 					v.Table.RowIDs[i] = operation.NextID
@@ -999,6 +1177,10 @@ func unbindAttributeViewBlock(operation *Operation, tx *Transaction) (err error)
 			if !replacedRowID {
 				v.Table.RowIDs = append(v.Table.RowIDs, operation.NextID)
 			}
+
+			if nil != v.Kanban {
+				v.Kanban.ReplaceCard(operation.ID, operation.NextID)
+			}
 		}
 	}
 
@@ -1041,6 +1223,36 @@ func setAttributeViewColDate(operation *Operation) (err error) {
 	return
 }
 
+func (tx *Transaction) doSetAttrViewColDateCalendar(operation *Operation) (ret *TxErr) {
+	err := setAttributeViewColDateCalendar(operation)
+	if err != nil {
+		return &TxErr{code: TxErrWriteAttributeView, id: operation.AvID, msg: err.Error()}
+	}
+	return
+}
+
+func setAttributeViewColDateCalendar(operation *Operation) (err error) {
+	attrView, err := av.ParseAttributeView(operation.AvID)
+	if err != nil {
+		return
+	}
+
+	keyID := operation.ID
+	key, _ := attrView.GetKey(keyID)
+	if nil == key || av.KeyTypeDate != key.Type {
+		return
+	}
+
+	if nil == key.Date {
+		key.Date = &av.Date{}
+	}
+
+	key.Date.Calendar = operation.Data.(bool)
+
+	err = av.SaveAttributeView(attrView)
+	return
+}
+
 func (tx *Transaction) doHideAttrViewName(operation *Operation) (ret *TxErr) {
 	err := hideAttrViewName(operation)
 	if err != nil {
@@ -1217,71 +1429,748 @@ func updateAttributeViewColRelation(operation *Operation) (err error) {
 		}
 	}
 
-	if !destAdded && operation.IsTwoWay {
-		// 新建双向关联目标字段
-		name := strings.TrimSpace(operation.Name)
-		if "" == name {
-			name = srcAv.Name + " " + operation.Format
-			name = strings.TrimSpace(name)
-		}
+	if !destAdded && operation.IsTwoWay {
+		// 新建双向关联目标字段
+		name := strings.TrimSpace(operation.Name)
+		if "" == name {
+			name = srcAv.Name + " " + operation.Format
+			name = strings.TrimSpace(name)
+		}
+
+		destKeyValues := &av.KeyValues{
+			Key: &av.Key{
+				ID:       operation.BackRelationKeyID,
+				Name:     name,
+				Type:     av.KeyTypeRelation,
+				Relation: &av.Relation{AvID: operation.AvID, IsTwoWay: operation.IsTwoWay, BackKeyID: operation.KeyID},
+			},
+		}
+		destAv.KeyValues = append(destAv.KeyValues, destKeyValues)
+
+		for _, v := range destAv.Views {
+			if v.HasLayoutTable() {
+				v.Table.Columns = append(v.Table.Columns, &av.ViewTableColumn{ID: operation.BackRelationKeyID})
+			}
+		}
+
+		now := time.Now().UnixMilli()
+		// 和现有值进行关联
+		for _, keyValues := range srcAv.KeyValues {
+			if keyValues.Key.ID != operation.KeyID {
+				continue
+			}
+
+			for _, srcVal := range keyValues.Values {
+				for _, blockID := range srcVal.Relation.BlockIDs {
+					destVal := destAv.GetValue(destKeyValues.Key.ID, blockID)
+					if nil == destVal {
+						destVal = &av.Value{ID: ast.NewNodeID(), KeyID: destKeyValues.Key.ID, BlockID: blockID, Type: keyValues.Key.Type, Relation: &av.ValueRelation{}, CreatedAt: now, UpdatedAt: now + 1000}
+					} else {
+						destVal.Type = keyValues.Key.Type
+						if nil == destVal.Relation {
+							destVal.Relation = &av.ValueRelation{}
+						}
+						destVal.UpdatedAt = now
+					}
+					destVal.Relation.BlockIDs = append(destVal.Relation.BlockIDs, srcVal.BlockID)
+					destVal.Relation.BlockIDs = gulu.Str.RemoveDuplicatedElem(destVal.Relation.BlockIDs)
+					destKeyValues.Values = append(destKeyValues.Values, destVal)
+				}
+			}
+		}
+	}
+
+	err = av.SaveAttributeView(srcAv)
+	if err != nil {
+		return
+	}
+	if !isSameAv {
+		err = av.SaveAttributeView(destAv)
+		ReloadAttrView(destAv.ID)
+	}
+
+	av.UpsertAvBackRel(srcAv.ID, destAv.ID)
+	if operation.IsTwoWay && !isSameAv {
+		av.UpsertAvBackRel(destAv.ID, srcAv.ID)
+	}
+	return
+}
+
+func (tx *Transaction) doSetAttrViewKanbanGroup(operation *Operation) (ret *TxErr) {
+	err := setAttributeViewKanbanGroup(operation)
+	if err != nil {
+		return &TxErr{code: TxErrWriteAttributeView, id: operation.AvID, msg: err.Error()}
+	}
+	return
+}
+
+func setAttributeViewKanbanGroup(operation *Operation) (err error) {
+	attrView, err := av.ParseAttributeView(operation.AvID)
+	if err != nil {
+		return
+	}
+
+	view, err := getAttrViewViewByBlockID(attrView, operation.BlockID)
+	if err != nil {
+		return
+	}
+
+	kanban := getAttrViewKanbanLayout(view)
+	if nil == kanban {
+		return
+	}
+
+	key, err := attrView.GetKey(operation.KeyID)
+	if err != nil {
+		return
+	}
+
+	if !av.IsKanbanGroupKeyType(key.Type) {
+		err = fmt.Errorf("key [%s] type [%s] can't be used to group kanban", key.Name, key.Type)
+		return
+	}
+
+	if kanban.GroupKeyID != key.ID {
+		// 不同分组字段的列不同，需要清空之前的列设置
+		kanban.GroupKeyID = key.ID
+		kanban.Columns = []*av.ViewKanbanColumn{}
+	}
+
+	err = av.SaveAttributeView(attrView)
+	return
+}
+
+func (tx *Transaction) doSortAttrViewKanbanColumn(operation *Operation) (ret *TxErr) {
+	err := sortAttributeViewKanbanColumn(operation)
+	if err != nil {
+		return &TxErr{code: TxErrWriteAttributeView, id: operation.AvID, msg: err.Error()}
+	}
+	return
+}
+
+func sortAttributeViewKanbanColumn(operation *Operation) (err error) {
+	attrView, err := av.ParseAttributeView(operation.AvID)
+	if err != nil {
+		return
+	}
+
+	view, err := getAttrViewViewByBlockID(attrView, operation.BlockID)
+	if err != nil {
+		return
+	}
+
+	kanban := getAttrViewKanbanLayout(view)
+	if nil == kanban {
+		return
+	}
+
+	groupKey := av.GetKanbanGroupKey(attrView, kanban)
+	if nil == groupKey {
+		return
+	}
+	kanban.GroupKeyID = groupKey.ID
+	kanban.FillColumns(groupKey)
+
+	columnID := operation.ID
+	previousColumnID := operation.PreviousID
+	if columnID == previousColumnID {
+		return
+	}
+
+	var column *av.ViewKanbanColumn
+	var index, previousIndex int
+	for i, c := range kanban.Columns {
+		if c.ID == columnID {
+			column = c
+			index = i
+			break
+		}
+	}
+	if nil == column {
+		return
+	}
+
+	kanban.Columns = append(kanban.Columns[:index], kanban.Columns[index+1:]...)
+	for i, c := range kanban.Columns {
+		if c.ID == previousColumnID {
+			previousIndex = i + 1
+			break
+		}
+	}
+	kanban.Columns = util.InsertElem(kanban.Columns, previousIndex, column)
+
+	err = av.SaveAttributeView(attrView)
+	return
+}
+
+func (tx *Transaction) doFoldAttrViewKanbanColumn(operation *Operation) (ret *TxErr) {
+	err := foldAttributeViewKanbanColumn(operation)
+	if err != nil {
+		return &TxErr{code: TxErrWriteAttributeView, id: operation.AvID, msg: err.Error()}
+	}
+	return
+}
+
+func foldAttributeViewKanbanColumn(operation *Operation) (err error) {
+	attrView, err := av.ParseAttributeView(operation.AvID)
+	if err != nil {
+		return
+	}
+
+	view, err := getAttrViewViewByBlockID(attrView, operation.BlockID)
+	if err != nil {
+		return
+	}
+
+	kanban := getAttrViewKanbanLayout(view)
+	if nil == kanban {
+		return
+	}
+
+	groupKey := av.GetKanbanGroupKey(attrView, kanban)
+	if nil == groupKey {
+		return
+	}
+	kanban.GroupKeyID = groupKey.ID
+	kanban.FillColumns(groupKey)
+
+	if column := kanban.GetColumn(operation.ID); nil != column {
+		column.Folded = operation.Data.(bool)
+	}
+
+	err = av.SaveAttributeView(attrView)
+	return
+}
+
+func (tx *Transaction) doMoveAttrViewKanbanCard(operation *Operation) (ret *TxErr) {
+	err := moveAttributeViewKanbanCard(operation, tx)
+	if err != nil {
+		return &TxErr{code: TxErrWriteAttributeView, id: operation.AvID, msg: err.Error()}
+	}
+	return
+}
+
+// moveAttributeViewKanbanCard 移动看板卡片。
+//
+// operation.ID 为卡片（行）ID，operation.ParentID 为目标列 ID，operation.PreviousID 为目标列中的前一张卡片 ID，
+// operation.Data 为来源列 ID。跨列移动时通过 UpdateAttributeViewCell 更新分组字段值。
+func moveAttributeViewKanbanCard(operation *Operation, tx *Transaction) (err error) {
+	avID := operation.AvID
+	attrView, err := av.ParseAttributeView(avID)
+	if err != nil {
+		return
+	}
+
+	view, err := getAttrViewViewByBlockID(attrView, operation.BlockID)
+	if err != nil {
+		return
+	}
+
+	kanban := getAttrViewKanbanLayout(view)
+	if nil == kanban {
+		return
+	}
+
+	groupKey := av.GetKanbanGroupKey(attrView, kanban)
+	if nil == groupKey {
+		err = fmt.Errorf("kanban [%s] has no group key", view.ID)
+		return
+	}
+
+	rowID := operation.ID
+	toColumnID := operation.ParentID
+	var fromColumnID string
+	if nil != operation.Data {
+		fromColumnID, _ = operation.Data.(string)
+	}
+
+	if fromColumnID != toColumnID {
+		valueData := getKanbanCardValueData(groupKey, attrView.GetValue(groupKey.ID, rowID), fromColumnID, toColumnID)
+		if _, err = UpdateAttributeViewCell(tx, avID, groupKey.ID, rowID, valueData); err != nil {
+			return
+		}
+
+		// 更新单元格时属性视图已经被保存，这里需要重新解析
+		viewID := view.ID
+		attrView, err = av.ParseAttributeView(avID)
+		if err != nil {
+			return
+		}
+		view = attrView.GetView(viewID)
+		if nil == view {
+			err = av.ErrViewNotFound
+			return
+		}
+		kanban = getAttrViewKanbanLayout(view)
+		groupKey, err = attrView.GetKey(groupKey.ID)
+		if err != nil {
+			return
+		}
+	}
+
+	kanban.GroupKeyID = groupKey.ID
+	kanban.MoveCard(attrView, view, groupKey, rowID, fromColumnID, toColumnID, operation.PreviousID)
+
+	err = av.SaveAttributeView(attrView)
+	return
+}
+
+func getKanbanCardValueData(groupKey *av.Key, value *av.Value, fromColumnID, toColumnID string) (ret map[string]interface{}) {
+	switch groupKey.Type {
+	case av.KeyTypeCheckbox:
+		ret = map[string]interface{}{"checkbox": &av.ValueCheckbox{Checked: av.KanbanColumnChecked == toColumnID}}
+	case av.KeyTypeSelect:
+		mSelect := []*av.ValueSelect{}
+		if "" != toColumnID {
+			mSelect = append(mSelect, &av.ValueSelect{Content: toColumnID})
+		}
+		ret = map[string]interface{}{"mSelect": mSelect}
+	case av.KeyTypeMSelect:
+		// 多选值仅替换来源列对应的选项，保留其他选项
+		mSelect := []*av.ValueSelect{}
+		if nil != value {
+			for _, opt := range value.MSelect {
+				if opt.Content != fromColumnID && opt.Content != toColumnID {
+					mSelect = append(mSelect, opt)
+				}
+			}
+		}
+		if "" != toColumnID {
+			mSelect = append(mSelect, &av.ValueSelect{Content: toColumnID})
+		}
+		ret = map[string]interface{}{"mSelect": mSelect}
+	}
+	return
+}
+
+func getAttrViewKanbanLayout(view *av.View) *av.LayoutKanban {
+	if av.LayoutTypeKanban != view.LayoutType {
+		return nil
+	}
+
+	if nil == view.Kanban {
+		view.Kanban = &av.LayoutKanban{ID: ast.NewNodeID(), Columns: []*av.ViewKanbanColumn{}}
+	}
+	return view.Kanban
+}
+
+func (tx *Transaction) doSetAttrViewCalendarDateKey(operation *Operation) (ret *TxErr) {
+	err := setAttributeViewCalendarDateKey(operation)
+	if err != nil {
+		return &TxErr{code: TxErrWriteAttributeView, id: operation.AvID, msg: err.Error()}
+	}
+	return
+}
+
+func setAttributeViewCalendarDateKey(operation *Operation) (err error) {
+	attrView, err := av.ParseAttributeView(operation.AvID)
+	if err != nil {
+		return
+	}
+
+	view, err := getAttrViewViewByBlockID(attrView, operation.BlockID)
+	if err != nil {
+		return
+	}
+
+	calendar := getAttrViewCalendarLayout(view)
+	if nil == calendar {
+		return
+	}
+
+	key, err := attrView.GetKey(operation.KeyID)
+	if err != nil {
+		return
+	}
+
+	if av.KeyTypeDate != key.Type {
+		err = fmt.Errorf("key [%s] type [%s] can't be used as calendar date", key.Name, key.Type)
+		return
+	}
+
+	calendar.DateKeyID = key.ID
+	err = av.SaveAttributeView(attrView)
+	return
+}
+
+func (tx *Transaction) doSetAttrViewCalendarMode(operation *Operation) (ret *TxErr) {
+	err := setAttributeViewCalendarMode(operation)
+	if err != nil {
+		return &TxErr{code: TxErrWriteAttributeView, id: operation.AvID, msg: err.Error()}
+	}
+	return
+}
+
+func setAttributeViewCalendarMode(operation *Operation) (err error) {
+	attrView, err := av.ParseAttributeView(operation.AvID)
+	if err != nil {
+		return
+	}
+
+	view, err := getAttrViewViewByBlockID(attrView, operation.BlockID)
+	if err != nil {
+		return
+	}
+
+	calendar := getAttrViewCalendarLayout(view)
+	if nil == calendar {
+		return
+	}
+
+	mode := operation.Data.(string)
+	if !av.IsCalendarMode(mode) {
+		err = fmt.Errorf("invalid calendar mode [%s]", mode)
+		return
+	}
+
+	calendar.Mode = av.CalendarMode(mode)
+	err = av.SaveAttributeView(attrView)
+	return
+}
+
+func (tx *Transaction) doSetAttrViewCalendarFirstDayOfWeek(operation *Operation) (ret *TxErr) {
+	err := setAttributeViewCalendarFirstDayOfWeek(operation)
+	if err != nil {
+		return &TxErr{code: TxErrWriteAttributeView, id: operation.AvID, msg: err.Error()}
+	}
+	return
+}
+
+func setAttributeViewCalendarFirstDayOfWeek(operation *Operation) (err error) {
+	attrView, err := av.ParseAttributeView(operation.AvID)
+	if err != nil {
+		return
+	}
+
+	view, err := getAttrViewViewByBlockID(attrView, operation.BlockID)
+	if err != nil {
+		return
+	}
+
+	calendar := getAttrViewCalendarLayout(view)
+	if nil == calendar {
+		return
+	}
+
+	firstDayOfWeek := int(operation.Data.(float64))
+	if 0 > firstDayOfWeek || 6 < firstDayOfWeek {
+		err = fmt.Errorf("invalid first day of week [%d]", firstDayOfWeek)
+		return
+	}
+
+	calendar.FirstDayOfWeek = firstDayOfWeek
+	err = av.SaveAttributeView(attrView)
+	return
+}
+
+func (tx *Transaction) doMoveAttrViewCalendarEvent(operation *Operation) (ret *TxErr) {
+	err := moveAttributeViewCalendarEvent(operation, tx)
+	if err != nil {
+		return &TxErr{code: TxErrWriteAttributeView, id: operation.AvID, msg: err.Error()}
+	}
+	return
+}
+
+// moveAttributeViewCalendarEvent 拖拽日程后改写日期字段值。
+//
+// operation.ID 为日程（行）ID，operation.Data 为 {start, end}，时间单位为毫秒，没有结束日期的日程忽略 end。
+func moveAttributeViewCalendarEvent(operation *Operation, tx *Transaction) (err error) {
+	attrView, err := av.ParseAttributeView(operation.AvID)
+	if err != nil {
+		return
+	}
+
+	view, err := getAttrViewViewByBlockID(attrView, operation.BlockID)
+	if err != nil {
+		return
+	}
+
+	calendar := getAttrViewCalendarLayout(view)
+	if nil == calendar {
+		return
+	}
+
+	dateKey := av.GetCalendarDateKey(attrView, calendar)
+	if nil == dateKey {
+		err = fmt.Errorf("calendar [%s] has no date key", view.ID)
+		return
+	}
+
+	data := operation.Data.(map[string]interface{})
+	start := int64(data["start"].(float64))
+	var end int64
+	if nil != data["end"] {
+		end = int64(data["end"].(float64))
+	}
+
+	date := &av.ValueDate{Content: start, IsNotEmpty: true}
+	if value := attrView.GetValue(dateKey.ID, operation.ID); nil != value && nil != value.Date {
+		date.IsNotTime = value.Date.IsNotTime
+		date.HasEndDate = value.Date.HasEndDate
+	}
+	if 0 < end && end > start {
+		date.HasEndDate = true
+	}
+	if date.HasEndDate {
+		if end < start {
+			end = start
+		}
+		date.Content2 = end
+		date.IsNotEmpty2 = true
+	}
+
+	_, err = UpdateAttributeViewCell(tx, operation.AvID, dateKey.ID, operation.ID, map[string]interface{}{"date": date})
+	return
+}
+
+func getAttrViewCalendarLayout(view *av.View) *av.LayoutCalendar {
+	if av.LayoutTypeCalendar != view.LayoutType {
+		return nil
+	}
+
+	if nil == view.Calendar {
+		view.Calendar = &av.LayoutCalendar{ID: ast.NewNodeID(), Mode: av.CalendarModeMonth, FirstDayOfWeek: 1}
+	}
+	return view.Calendar
+}
+
+func (tx *Transaction) doSetAttrViewCoverFrom(operation *Operation) (ret *TxErr) {
+	err := setAttributeViewCoverFrom(operation)
+	if err != nil {
+		return &TxErr{code: TxErrWriteAttributeView, id: operation.AvID, msg: err.Error()}
+	}
+	return
+}
+
+func setAttributeViewCoverFrom(operation *Operation) (err error) {
+	attrView, err := av.ParseAttributeView(operation.AvID)
+	if err != nil {
+		return
+	}
+
+	view, err := getAttrViewViewByBlockID(attrView, operation.BlockID)
+	if err != nil {
+		return
+	}
+
+	gallery := getAttrViewGalleryLayout(view)
+	if nil == gallery {
+		return
+	}
+
+	coverFrom := av.CoverFrom(operation.Data.(float64))
+	switch coverFrom {
+	case av.CoverFromNone, av.CoverFromContentImage, av.CoverFromAssetField:
+	default:
+		err = fmt.Errorf("invalid cover from [%d]", coverFrom)
+		return
+	}
+
+	gallery.CoverFrom = coverFrom
+	err = av.SaveAttributeView(attrView)
+	return
+}
+
+func (tx *Transaction) doSetAttrViewCoverFromAssetKeyID(operation *Operation) (ret *TxErr) {
+	err := setAttributeViewCoverFromAssetKeyID(operation)
+	if err != nil {
+		return &TxErr{code: TxErrWriteAttributeView, id: operation.AvID, msg: err.Error()}
+	}
+	return
+}
+
+func setAttributeViewCoverFromAssetKeyID(operation *Operation) (err error) {
+	attrView, err := av.ParseAttributeView(operation.AvID)
+	if err != nil {
+		return
+	}
+
+	view, err := getAttrViewViewByBlockID(attrView, operation.BlockID)
+	if err != nil {
+		return
+	}
+
+	gallery := getAttrViewGalleryLayout(view)
+	if nil == gallery {
+		return
+	}
+
+	key, err := attrView.GetKey(operation.KeyID)
+	if err != nil {
+		return
+	}
+
+	if av.KeyTypeMAsset != key.Type {
+		err = fmt.Errorf("key [%s] type [%s] can't be used as gallery cover", key.Name, key.Type)
+		return
+	}
+
+	gallery.CoverFrom = av.CoverFromAssetField
+	gallery.CoverFromAssetKeyID = key.ID
+	err = av.SaveAttributeView(attrView)
+	return
+}
+
+func (tx *Transaction) doSetAttrViewCardSize(operation *Operation) (ret *TxErr) {
+	err := setAttributeViewCardSize(operation)
+	if err != nil {
+		return &TxErr{code: TxErrWriteAttributeView, id: operation.AvID, msg: err.Error()}
+	}
+	return
+}
+
+func setAttributeViewCardSize(operation *Operation) (err error) {
+	attrView, err := av.ParseAttributeView(operation.AvID)
+	if err != nil {
+		return
+	}
+
+	view, err := getAttrViewViewByBlockID(attrView, operation.BlockID)
+	if err != nil {
+		return
+	}
+
+	gallery := getAttrViewGalleryLayout(view)
+	if nil == gallery {
+		return
+	}
+
+	size := operation.Data.(string)
+	if !av.IsCardSize(size) {
+		err = fmt.Errorf("invalid card size [%s]", size)
+		return
+	}
+
+	gallery.CardSize = av.CardSize(size)
+	err = av.SaveAttributeView(attrView)
+	return
+}
+
+func (tx *Transaction) doSetAttrViewFitImage(operation *Operation) (ret *TxErr) {
+	err := setAttributeViewFitImage(operation)
+	if err != nil {
+		return &TxErr{code: TxErrWriteAttributeView, id: operation.AvID, msg: err.Error()}
+	}
+	return
+}
+
+func setAttributeViewFitImage(operation *Operation) (err error) {
+	attrView, err := av.ParseAttributeView(operation.AvID)
+	if err != nil {
+		return
+	}
+
+	view, err := getAttrViewViewByBlockID(attrView, operation.BlockID)
+	if err != nil {
+		return
+	}
+
+	gallery := getAttrViewGalleryLayout(view)
+	if nil == gallery {
+		return
+	}
+
+	gallery.FitImage = operation.Data.(bool)
+	err = av.SaveAttributeView(attrView)
+	return
+}
+
+func getAttrViewGalleryLayout(view *av.View) *av.LayoutGallery {
+	if av.LayoutTypeGallery != view.LayoutType {
+		return nil
+	}
+
+	if nil == view.Gallery {
+		view.Gallery = &av.LayoutGallery{ID: ast.NewNodeID(), CoverFrom: av.CoverFromContentImage, CardSize: av.CardSizeMedium}
+	}
+	return view.Gallery
+}
+
+func (tx *Transaction) doSetAttrViewGroup(operation *Operation) (ret *TxErr) {
+	err := setAttributeViewGroup(operation)
+	if err != nil {
+		return &TxErr{code: TxErrWriteAttributeView, id: operation.AvID, msg: err.Error()}
+	}
+	return
+}
+
+// setAttributeViewGroup 设置表格视图的分组规则，KeyID 为空时取消分组，日期列通过 Data 指定分组方式。
+func setAttributeViewGroup(operation *Operation) (err error) {
+	attrView, err := av.ParseAttributeView(operation.AvID)
+	if err != nil {
+		return
+	}
+
+	view, err := getAttrViewViewByBlockID(attrView, operation.BlockID)
+	if err != nil {
+		return
+	}
+
+	if av.LayoutTypeTable != view.LayoutType {
+		return
+	}
+
+	if "" == operation.KeyID {
+		view.Table.Group = nil
+		err = av.SaveAttributeView(attrView)
+		return
+	}
+
+	key, err := attrView.GetKey(operation.KeyID)
+	if err != nil {
+		return
+	}
+
+	if !av.IsGroupKeyType(key.Type) {
+		err = fmt.Errorf("key [%s] type [%s] can't be used to group", key.Name, key.Type)
+		return
+	}
 
-		destKeyValues := &av.KeyValues{
-			Key: &av.Key{
-				ID:       operation.BackRelationKeyID,
-				Name:     name,
-				Type:     av.KeyTypeRelation,
-				Relation: &av.Relation{AvID: operation.AvID, IsTwoWay: operation.IsTwoWay, BackKeyID: operation.KeyID},
-			},
+	var method av.GroupMethod
+	if av.KeyTypeDate == key.Type {
+		method = av.GroupMethodDay
+		if m, ok := operation.Data.(string); ok && av.IsGroupMethod(m) {
+			method = av.GroupMethod(m)
 		}
-		destAv.KeyValues = append(destAv.KeyValues, destKeyValues)
+	}
 
-		for _, v := range destAv.Views {
-			switch v.LayoutType {
-			case av.LayoutTypeTable:
-				v.Table.Columns = append(v.Table.Columns, &av.ViewTableColumn{ID: operation.BackRelationKeyID})
-			}
-		}
+	if nil == view.Table.Group || view.Table.Group.Column != key.ID || view.Table.Group.Method != method {
+		view.Table.Group = &av.ViewGroup{Column: key.ID, Method: method}
+	}
+	err = av.SaveAttributeView(attrView)
+	return
+}
 
-		now := time.Now().UnixMilli()
-		// 和现有值进行关联
-		for _, keyValues := range srcAv.KeyValues {
-			if keyValues.Key.ID != operation.KeyID {
-				continue
-			}
+func (tx *Transaction) doFoldAttrViewGroup(operation *Operation) (ret *TxErr) {
+	err := foldAttributeViewGroup(operation)
+	if err != nil {
+		return &TxErr{code: TxErrWriteAttributeView, id: operation.AvID, msg: err.Error()}
+	}
+	return
+}
 
-			for _, srcVal := range keyValues.Values {
-				for _, blockID := range srcVal.Relation.BlockIDs {
-					destVal := destAv.GetValue(destKeyValues.Key.ID, blockID)
-					if nil == destVal {
-						destVal = &av.Value{ID: ast.NewNodeID(), KeyID: destKeyValues.Key.ID, BlockID: blockID, Type: keyValues.Key.Type, Relation: &av.ValueRelation{}, CreatedAt: now, UpdatedAt: now + 1000}
-					} else {
-						destVal.Type = keyValues.Key.Type
-						if nil == destVal.Relation {
-							destVal.Relation = &av.ValueRelation{}
-						}
-						destVal.UpdatedAt = now
-					}
-					destVal.Relation.BlockIDs = append(destVal.Relation.BlockIDs, srcVal.BlockID)
-					destVal.Relation.BlockIDs = gulu.Str.RemoveDuplicatedElem(destVal.Relation.BlockIDs)
-					destKeyValues.Values = append(destKeyValues.Values, destVal)
-				}
-			}
-		}
+func foldAttributeViewGroup(operation *Operation) (err error) {
+	attrView, err := av.ParseAttributeView(operation.AvID)
+	if err != nil {
+		return
 	}
 
-	err = av.SaveAttributeView(srcAv)
+	view, err := getAttrViewViewByBlockID(attrView, operation.BlockID)
 	if err != nil {
 		return
 	}
-	if !isSameAv {
-		err = av.SaveAttributeView(destAv)
-		ReloadAttrView(destAv.ID)
+
+	if av.LayoutTypeTable != view.LayoutType || nil == view.Table.Group {
+		return
 	}
 
-	av.UpsertAvBackRel(srcAv.ID, destAv.ID)
-	if operation.IsTwoWay && !isSameAv {
-		av.UpsertAvBackRel(destAv.ID, srcAv.ID)
+	group := view.Table.Group.GetGroup(operation.ID)
+	if nil == group {
+		group = &av.ViewTableGroup{ID: operation.ID}
+		view.Table.Group.Groups = append(view.Table.Group.Groups, group)
 	}
+	group.Folded = operation.Data.(bool)
+	err = av.SaveAttributeView(attrView)
 	return
 }
 
@@ -1465,15 +2354,11 @@ func (tx *Transaction) doDuplicateAttrViewView(operation *Operation) (ret *TxErr
 		})
 	}
 
-	for _, filter := range masterView.Table.Filters {
-		view.Table.Filters = append(view.Table.Filters, &av.ViewFilter{
-			Column:        filter.Column,
-			Operator:      filter.Operator,
-			Value:         filter.Value,
-			RelativeDate:  filter.RelativeDate,
-			RelativeDate2: filter.RelativeDate2,
-		})
+	var filters []*av.ViewFilter
+	for _, filter := range masterView.Table.GetFilter().Children {
+		filters = append(filters, filter.Clone())
 	}
+	view.Table.SetFilters(filters)
 
 	for _, s := range masterView.Table.Sorts {
 		view.Table.Sorts = append(view.Table.Sorts, &av.ViewSort{
@@ -1482,9 +2367,66 @@ func (tx *Transaction) doDuplicateAttrViewView(operation *Operation) (ret *TxErr
 		})
 	}
 
+	if nil != masterView.Table.Group {
+		view.Table.Group = &av.ViewGroup{
+			Column: masterView.Table.Group.Column,
+			Method: masterView.Table.Group.Method,
+		}
+		for _, g := range masterView.Table.Group.Groups {
+			view.Table.Group.Groups = append(view.Table.Group.Groups, &av.ViewTableGroup{ID: g.ID, Folded: g.Folded})
+		}
+	}
+
+	for _, f := range masterView.Table.Formats {
+		view.Table.Formats = append(view.Table.Formats, &av.ViewFormat{
+			ID:        ast.NewNodeID(),
+			Condition: f.Condition.Clone(),
+			Target:    f.Target,
+			Column:    f.Column,
+			Color:     f.Color,
+			BgColor:   f.BgColor,
+			Icon:      f.Icon,
+			Bold:      f.Bold,
+		})
+	}
+
 	view.Table.PageSize = masterView.Table.PageSize
 	view.Table.RowIDs = masterView.Table.RowIDs
 
+	if nil != masterView.Kanban {
+		view.Kanban = &av.LayoutKanban{
+			ID:         ast.NewNodeID(),
+			GroupKeyID: masterView.Kanban.GroupKeyID,
+			Columns:    []*av.ViewKanbanColumn{},
+		}
+		for _, column := range masterView.Kanban.Columns {
+			view.Kanban.Columns = append(view.Kanban.Columns, &av.ViewKanbanColumn{
+				ID:      column.ID,
+				Folded:  column.Folded,
+				CardIDs: column.CardIDs,
+			})
+		}
+	}
+
+	if nil != masterView.Calendar {
+		view.Calendar = &av.LayoutCalendar{
+			ID:             ast.NewNodeID(),
+			DateKeyID:      masterView.Calendar.DateKeyID,
+			Mode:           masterView.Calendar.Mode,
+			FirstDayOfWeek: masterView.Calendar.FirstDayOfWeek,
+		}
+	}
+
+	if nil != masterView.Gallery {
+		view.Gallery = &av.LayoutGallery{
+			ID:                  ast.NewNodeID(),
+			CoverFrom:           masterView.Gallery.CoverFrom,
+			CoverFromAssetKeyID: masterView.Gallery.CoverFromAssetKeyID,
+			CardSize:            masterView.Gallery.CardSize,
+			FitImage:            masterView.Gallery.FitImage,
+		}
+	}
+
 	if err = av.SaveAttributeView(attrView); err != nil {
 		logging.LogErrorf("save attribute view [%s] failed: %s", avID, err)
 		return &TxErr{code: TxErrWriteAttributeView, msg: err.Error(), id: avID}
@@ -1526,7 +2468,17 @@ func (tx *Transaction) doAddAttrViewView(operation *Operation) (ret *TxErr) {
 		return
 	}
 
-	view := av.NewTableView()
+	var view *av.View
+	switch av.LayoutType(operation.Typ) {
+	case av.LayoutTypeKanban:
+		view = av.NewKanbanView()
+	case av.LayoutTypeCalendar:
+		view = av.NewCalendarView()
+	case av.LayoutTypeGallery:
+		view = av.NewGalleryView()
+	default:
+		view = av.NewTableView()
+	}
 	view.ID = operation.ID
 	attrView.Views = append(attrView.Views, view)
 	attrView.ViewID = view.ID
@@ -1646,6 +2598,25 @@ func (tx *Transaction) setAttributeViewName(operation *Operation) (err error) {
 	return
 }
 
+func (tx *Transaction) doSetAttrViewAddressBook(operation *Operation) (ret *TxErr) {
+	err := setAttributeViewAddressBook(operation)
+	if err != nil {
+		return &TxErr{code: TxErrWriteAttributeView, id: operation.AvID, msg: err.Error()}
+	}
+	return
+}
+
+func setAttributeViewAddressBook(operation *Operation) (err error) {
+	attrView, err := av.ParseAttributeView(operation.AvID)
+	if err != nil {
+		return
+	}
+
+	attrView.AddressBook = operation.Data.(bool)
+	err = av.SaveAttributeView(attrView)
+	return
+}
+
 func getAvNames(avIDs string) (ret string) {
 	if "" == avIDs {
 		return
@@ -1724,17 +2695,92 @@ func setAttributeViewFilters(operation *Operation) (err error) {
 		return
 	}
 
-	operationData := operation.Data.([]interface{})
-	data, err := gulu.JSON.MarshalJSON(operationData)
+	data, err := gulu.JSON.MarshalJSON(operation.Data)
 	if err != nil {
 		return
 	}
 
-	switch view.LayoutType {
-	case av.LayoutTypeTable:
-		if err = gulu.JSON.UnmarshalJSON(data, &view.Table.Filters); err != nil {
-			return
+	// 传入过滤规则列表（各规则之间为 AND）或者一个过滤组
+	filter := av.NewViewFilterGroup()
+	if _, ok := operation.Data.([]interface{}); ok {
+		err = gulu.JSON.UnmarshalJSON(data, &filter.Children)
+	} else if nil != operation.Data {
+		err = gulu.JSON.UnmarshalJSON(data, &filter)
+	}
+	if err != nil {
+		return
+	}
+
+	if !filter.IsGroup() {
+		filter = av.NewViewFilterGroup(filter)
+	}
+
+	if view.HasLayoutTable() {
+		if av.FilterConjunctionAnd == filter.Conjunction && !filter.Not {
+			view.Table.SetFilters(filter.Children)
+		} else {
+			view.Table.SetFilters([]*av.ViewFilter{filter})
+		}
+	}
+
+	err = av.SaveAttributeView(attrView)
+	return
+}
+
+func (tx *Transaction) doSetAttrViewFormats(operation *Operation) (ret *TxErr) {
+	err := setAttributeViewFormats(operation)
+	if err != nil {
+		return &TxErr{code: TxErrWriteAttributeView, id: operation.AvID, msg: err.Error()}
+	}
+	return
+}
+
+func setAttributeViewFormats(operation *Operation) (err error) {
+	attrView, err := av.ParseAttributeView(operation.AvID)
+	if err != nil {
+		return
+	}
+
+	view, err := getAttrViewViewByBlockID(attrView, operation.BlockID)
+	if err != nil {
+		return
+	}
+
+	data, err := gulu.JSON.MarshalJSON(operation.Data)
+	if err != nil {
+		return
+	}
+
+	formats := []*av.ViewFormat{}
+	if err = gulu.JSON.UnmarshalJSON(data, &formats); err != nil {
+		return
+	}
+
+	var tmp []*av.ViewFormat
+	for _, f := range formats {
+		if nil == f || nil == f.Condition || !av.IsFormatTarget(string(f.Target)) {
+			continue
+		}
+		if av.FormatTargetCell == f.Target {
+			if k, _ := attrView.GetKey(f.Column); nil == k {
+				continue
+			}
+		} else {
+			f.Column = ""
+		}
+
+		// 单个条件也包装为条件组，和过滤规则保持一致
+		if !f.Condition.IsGroup() {
+			f.Condition = av.NewViewFilterGroup(f.Condition)
 		}
+		if "" == f.ID {
+			f.ID = ast.NewNodeID()
+		}
+		tmp = append(tmp, f)
+	}
+
+	if view.HasLayoutTable() {
+		view.Table.Formats = tmp
 	}
 
 	err = av.SaveAttributeView(attrView)
@@ -1766,8 +2812,7 @@ func setAttributeViewSorts(operation *Operation) (err error) {
 		return
 	}
 
-	switch view.LayoutType {
-	case av.LayoutTypeTable:
+	if view.HasLayoutTable() {
 		if err = gulu.JSON.UnmarshalJSON(data, &view.Table.Sorts); err != nil {
 			return
 		}
@@ -1796,8 +2841,7 @@ func setAttributeViewPageSize(operation *Operation) (err error) {
 		return
 	}
 
-	switch view.LayoutType {
-	case av.LayoutTypeTable:
+	if view.HasLayoutTable() {
 		view.Table.PageSize = int(operation.Data.(float64))
 	}
 
@@ -1831,8 +2875,7 @@ func setAttributeViewColumnCalc(operation *Operation) (err error) {
 	}
 
 	calc := &av.ColumnCalc{}
-	switch view.LayoutType {
-	case av.LayoutTypeTable:
+	if view.HasLayoutTable() {
 		if err = gulu.JSON.UnmarshalJSON(data, calc); err != nil {
 			return
 		}
@@ -1948,7 +2991,7 @@ func addAttributeViewBlock(now int64, avID, blockID, previousBlockID, addingBloc
 
 	// 如果存在过滤条件，则将过滤条件应用到新添加的块上
 	view, _ := getAttrViewViewByBlockID(attrView, blockID)
-	if nil != view && 0 < len(view.Table.Filters) && !ignoreFillFilter {
+	if nil != view && !view.Table.GetFilter().IsEmpty() && !ignoreFillFilter {
 		viewable := sql.RenderAttributeViewTable(attrView, view, "")
 		viewable.FilterRows(attrView)
 		viewable.SortRows(attrView)
@@ -1972,7 +3015,7 @@ func addAttributeViewBlock(now int64, avID, blockID, previousBlockID, addingBloc
 		sameKeyFilterSort := false // 是否在同一个字段上同时存在过滤和排序
 		if 0 < len(viewable.Sorts) {
 			filterKeys, sortKeys := map[string]bool{}, map[string]bool{}
-			for _, f := range view.Table.Filters {
+			for _, f := range view.Table.GetFilter().GetLeaves() {
 				filterKeys[f.Column] = true
 			}
 			for _, s := range view.Table.Sorts {
@@ -1989,7 +3032,7 @@ func addAttributeViewBlock(now int64, avID, blockID, previousBlockID, addingBloc
 
 		if !sameKeyFilterSort {
 			// 如果在同一个字段上仅存在过滤条件，则将过滤条件应用到新添加的块上
-			for _, filter := range view.Table.Filters {
+			for _, filter := range view.Table.GetFilter().GetAffectLeaves() {
 				for _, keyValues := range attrView.KeyValues {
 					if keyValues.Key.ID == filter.Column {
 						var defaultVal *av.Value
@@ -2037,8 +3080,7 @@ func addAttributeViewBlock(now int64, avID, blockID, previousBlockID, addingBloc
 	}
 
 	for _, v := range attrView.Views {
-		switch v.LayoutType {
-		case av.LayoutTypeTable:
+		if v.HasLayoutTable() {
 			if "" != previousBlockID {
 				changed := false
 				for i, id := range v.Table.RowIDs {
@@ -2111,6 +3153,9 @@ func removeAttributeViewBlock(srcIDs []string, avID string, tx *Transaction) (er
 	for _, view := range attrView.Views {
 		for _, blockID := range srcIDs {
 			view.Table.RowIDs = gulu.Str.RemoveElem(view.Table.RowIDs, blockID)
+			if nil != view.Kanban {
+				view.Kanban.RemoveCard(blockID)
+			}
 		}
 	}
 
@@ -2192,12 +3237,15 @@ func duplicateAttributeViewKey(operation *Operation) (err error) {
 	}
 	copyKey.ID = operation.NextID
 	copyKey.Name = util.GetDuplicateName(key.Name)
+	if nil != key.AutoID {
+		// 复制的自动编号字段重新开始编号
+		copyKey.AutoID = &av.AutoID{Prefix: key.AutoID.Prefix, Next: 1}
+	}
 
 	attrView.KeyValues = append(attrView.KeyValues, &av.KeyValues{Key: copyKey})
 
 	for _, view := range attrView.Views {
-		switch view.LayoutType {
-		case av.LayoutTypeTable:
+		if view.HasLayoutTable() {
 			for i, column := range view.Table.Columns {
 				if column.ID == key.ID {
 					view.Table.Columns = append(view.Table.Columns[:i+1], append([]*av.ViewTableColumn{
@@ -2239,8 +3287,7 @@ func setAttributeViewColWidth(operation *Operation) (err error) {
 		return
 	}
 
-	switch view.LayoutType {
-	case av.LayoutTypeTable:
+	if view.HasLayoutTable() {
 		for _, column := range view.Table.Columns {
 			if column.ID == operation.ID {
 				column.Width = operation.Data.(string)
@@ -2272,8 +3319,7 @@ func setAttributeViewColWrap(operation *Operation) (err error) {
 		return
 	}
 
-	switch view.LayoutType {
-	case av.LayoutTypeTable:
+	if view.HasLayoutTable() {
 		for _, column := range view.Table.Columns {
 			if column.ID == operation.ID {
 				column.Wrap = operation.Data.(bool)
@@ -2305,8 +3351,7 @@ func setAttributeViewColHidden(operation *Operation) (err error) {
 		return
 	}
 
-	switch view.LayoutType {
-	case av.LayoutTypeTable:
+	if view.HasLayoutTable() {
 		for _, column := range view.Table.Columns {
 			if column.ID == operation.ID {
 				column.Hidden = operation.Data.(bool)
@@ -2338,8 +3383,7 @@ func setAttributeViewColPin(operation *Operation) (err error) {
 		return
 	}
 
-	switch view.LayoutType {
-	case av.LayoutTypeTable:
+	if view.HasLayoutTable() {
 		for _, column := range view.Table.Columns {
 			if column.ID == operation.ID {
 				column.Pin = operation.Data.(bool)
@@ -2441,8 +3485,7 @@ func sortAttributeViewRow(operation *Operation) (err error) {
 		idx = len(view.Table.RowIDs) - 1
 	}
 
-	switch view.LayoutType {
-	case av.LayoutTypeTable:
+	if view.HasLayoutTable() {
 		view.Table.RowIDs = append(view.Table.RowIDs[:idx], view.Table.RowIDs[idx+1:]...)
 		for i, r := range view.Table.RowIDs {
 			if r == operation.PreviousID {
@@ -2481,8 +3524,7 @@ func SortAttributeViewViewKey(avID, blockID, keyID, previousKeyID string) (err e
 		return
 	}
 
-	switch view.LayoutType {
-	case av.LayoutTypeTable:
+	if view.HasLayoutTable() {
 		var col *av.ViewTableColumn
 		var index, previousIndex int
 		for i, column := range view.Table.Columns {
@@ -2607,7 +3649,7 @@ func AddAttributeViewKey(avID, keyID, keyName, keyType, keyIcon, previousKeyID s
 	switch keyTyp {
 	case av.KeyTypeText, av.KeyTypeNumber, av.KeyTypeDate, av.KeyTypeSelect, av.KeyTypeMSelect, av.KeyTypeURL, av.KeyTypeEmail,
 		av.KeyTypePhone, av.KeyTypeMAsset, av.KeyTypeTemplate, av.KeyTypeCreated, av.KeyTypeUpdated, av.KeyTypeCheckbox,
-		av.KeyTypeRelation, av.KeyTypeRollup, av.KeyTypeLineNumber:
+		av.KeyTypeRelation, av.KeyTypeRollup, av.KeyTypeLineNumber, av.KeyTypeFormula, av.KeyTypeAutoID:
 
 		key := av.NewKey(keyID, keyName, keyIcon, keyTyp)
 		if av.KeyTypeRollup == keyTyp {
@@ -2617,11 +3659,10 @@ func AddAttributeViewKey(avID, keyID, keyName, keyType, keyIcon, previousKeyID s
 		attrView.KeyValues = append(attrView.KeyValues, &av.KeyValues{Key: key})
 
 		for _, view := range attrView.Views {
-			switch view.LayoutType {
-			case av.LayoutTypeTable:
+			if view.HasLayoutTable() {
 				if "" == previousKeyID {
 					view.Table.Columns = append([]*av.ViewTableColumn{{ID: key.ID}}, view.Table.Columns...)
-					break
+					continue
 				}
 
 				added := false
@@ -2672,6 +3713,69 @@ func updateAttributeViewColTemplate(operation *Operation) (err error) {
 	return
 }
 
+func (tx *Transaction) doUpdateAttrViewColFormula(operation *Operation) (ret *TxErr) {
+	err := updateAttributeViewColFormula(operation)
+	if err != nil {
+		return &TxErr{code: TxErrWriteAttributeView, id: operation.AvID, msg: err.Error()}
+	}
+	return
+}
+
+func updateAttributeViewColFormula(operation *Operation) (err error) {
+	attrView, err := av.ParseAttributeView(operation.AvID)
+	if err != nil {
+		return
+	}
+
+	formula := strings.TrimSpace(operation.Data.(string))
+	if "" != formula {
+		if _, err = av.ParseFormula(formula); err != nil {
+			return
+		}
+	}
+
+	for _, keyValues := range attrView.KeyValues {
+		if keyValues.Key.ID == operation.ID && av.KeyTypeFormula == keyValues.Key.Type {
+			keyValues.Key.Formula = formula
+			break
+		}
+	}
+
+	err = av.SaveAttributeView(attrView)
+	return
+}
+
+func (tx *Transaction) doUpdateAttrViewColAutoID(operation *Operation) (ret *TxErr) {
+	err := updateAttributeViewColAutoID(operation)
+	if err != nil {
+		return &TxErr{code: TxErrWriteAttributeView, id: operation.AvID, msg: err.Error()}
+	}
+	return
+}
+
+func updateAttributeViewColAutoID(operation *Operation) (err error) {
+	attrView, err := av.ParseAttributeView(operation.AvID)
+	if err != nil {
+		return
+	}
+
+	prefix, _ := operation.Data.(string)
+	prefix = strings.TrimSpace(prefix)
+	for _, keyValues := range attrView.KeyValues {
+		if keyValues.Key.ID == operation.ID && av.KeyTypeAutoID == keyValues.Key.Type {
+			if nil == keyValues.Key.AutoID {
+				keyValues.Key.AutoID = &av.AutoID{Next: 1}
+			}
+			// 保存时会使用新的前缀格式化所有编号
+			keyValues.Key.AutoID.Prefix = prefix
+			break
+		}
+	}
+
+	err = av.SaveAttributeView(attrView)
+	return
+}
+
 func (tx *Transaction) doUpdateAttrViewColNumberFormat(operation *Operation) (ret *TxErr) {
 	err := updateAttributeViewColNumberFormat(operation)
 	if err != nil {
@@ -2688,9 +3792,9 @@ func updateAttributeViewColNumberFormat(operation *Operation) (err error) {
 
 	colType := av.KeyType(operation.Typ)
 	switch colType {
-	case av.KeyTypeNumber:
+	case av.KeyTypeNumber, av.KeyTypeFormula:
 		for _, keyValues := range attrView.KeyValues {
-			if keyValues.Key.ID == operation.ID && av.KeyTypeNumber == keyValues.Key.Type {
+			if keyValues.Key.ID == operation.ID && colType == keyValues.Key.Type {
 				keyValues.Key.NumberFormat = av.NumberFormat(operation.Format)
 				break
 			}
@@ -2719,7 +3823,7 @@ func updateAttributeViewColumn(operation *Operation) (err error) {
 	switch colType {
 	case av.KeyTypeBlock, av.KeyTypeText, av.KeyTypeNumber, av.KeyTypeDate, av.KeyTypeSelect, av.KeyTypeMSelect, av.KeyTypeURL, av.KeyTypeEmail,
 		av.KeyTypePhone, av.KeyTypeMAsset, av.KeyTypeTemplate, av.KeyTypeCreated, av.KeyTypeUpdated, av.KeyTypeCheckbox,
-		av.KeyTypeRelation, av.KeyTypeRollup, av.KeyTypeLineNumber:
+		av.KeyTypeRelation, av.KeyTypeRollup, av.KeyTypeLineNumber, av.KeyTypeFormula, av.KeyTypeAutoID:
 		for _, keyValues := range attrView.KeyValues {
 			if keyValues.Key.ID == operation.ID {
 				keyValues.Key.Name = strings.TrimSpace(operation.Name)
@@ -2793,8 +3897,7 @@ func RemoveAttributeViewKey(avID, keyID string, removeRelationDest bool) (err er
 
 				if removeRelationDest {
 					for _, view := range destAv.Views {
-						switch view.LayoutType {
-						case av.LayoutTypeTable:
+						if view.HasLayoutTable() {
 							for i, column := range view.Table.Columns {
 								if column.ID == removedKey.Relation.BackKeyID {
 									view.Table.Columns = append(view.Table.Columns[:i], view.Table.Columns[i+1:]...)
@@ -2828,8 +3931,7 @@ func RemoveAttributeViewKey(avID, keyID string, removeRelationDest bool) (err er
 	}
 
 	for _, view := range attrView.Views {
-		switch view.LayoutType {
-		case av.LayoutTypeTable:
+		if view.HasLayoutTable() {
 			for i, column := range view.Table.Columns {
 				if column.ID == keyID {
 					view.Table.Columns = append(view.Table.Columns[:i], view.Table.Columns[i+1:]...)
@@ -2923,8 +4025,7 @@ func replaceAttributeViewBlock(operation *Operation, tx *Transaction) (err error
 
 	replacedRowID := false
 	for _, v := range attrView.Views {
-		switch v.LayoutType {
-		case av.LayoutTypeTable:
+		if v.HasLayoutTable() {
 			for i, rowID := range v.Table.RowIDs {
 				if rowID == operation.PreviousID {
 					v.Table.RowIDs[i] = operation.NextID
@@ -2936,6 +4037,10 @@ func replaceAttributeViewBlock(operation *Operation, tx *Transaction) (err error
 			if !replacedRowID {
 				v.Table.RowIDs = append(v.Table.RowIDs, operation.NextID)
 			}
+
+			if nil != v.Kanban {
+				v.Kanban.ReplaceCard(operation.PreviousID, operation.NextID)
+			}
 		}
 	}
 
@@ -3005,6 +4110,11 @@ func UpdateAttributeViewCell(tx *Transaction, avID, keyID, rowID string, valueDa
 		break
 	}
 
+	if av.KeyTypeAutoID == val.Type {
+		// 自动编号由系统分配，不允许修改
+		return
+	}
+
 	isUpdatingBlockKey := av.KeyTypeBlock == val.Type
 	oldBoundBlockID := val.BlockID
 	var oldRelationBlockIDs []string
@@ -3390,6 +4500,15 @@ func removeAttributeViewColumnOption(operation *Operation) (err error) {
 		}
 	}
 
+	for _, view := range attrView.Views {
+		if nil != view.Kanban && view.Kanban.GroupKeyID == key.ID {
+			view.Kanban.RemoveColumn(optName)
+		}
+		if nil != view.Table && nil != view.Table.Group && view.Table.Group.Column == key.ID {
+			view.Table.Group.RemoveGroup(optName)
+		}
+	}
+
 	for _, keyValues := range attrView.KeyValues {
 		if keyValues.Key.ID != operation.ID {
 			continue
@@ -3511,10 +4630,9 @@ func updateAttributeViewColumnOption(operation *Operation) (err error) {
 	// 如果存在选项对应的过滤器，需要更新过滤器中设置的选项值
 	// Database select field filters follow option editing changes https://github.com/siyuan-note/siyuan/issues/10881
 	for _, view := range attrView.Views {
-		switch view.LayoutType {
-		case av.LayoutTypeTable:
+		if view.HasLayoutTable() {
 			table := view.Table
-			for _, filter := range table.Filters {
+			for _, filter := range table.GetFilter().GetLeaves() {
 				if filter.Column != key.ID {
 					continue
 				}
@@ -3530,6 +4648,14 @@ func updateAttributeViewColumnOption(operation *Operation) (err error) {
 				}
 			}
 		}
+
+		// 看板分组列跟随选项名称变更
+		if rename && nil != view.Kanban && view.Kanban.GroupKeyID == key.ID {
+			view.Kanban.RenameColumn(oldName, newName)
+		}
+		if rename && nil != view.Table && nil != view.Table.Group && view.Table.Group.Column == key.ID {
+			view.Table.Group.RenameGroup(oldName, newName)
+		}
 	}
 
 	err = av.SaveAttributeView(attrView)
@@ -3579,6 +4705,38 @@ func getAttrViewViewByBlockID(attrView *av.AttributeView, blockID string) (ret *
 	return attrView.GetCurrentView(viewID)
 }
 
+// getAttributeViews 遍历所有数据库，返回满足过滤条件的数据库。
+func getAttributeViews(filter func(attrView *av.AttributeView) bool) (ret []*av.AttributeView) {
+	avDir := filepath.Join(util.DataDir, "storage", "av")
+	entries, err := os.ReadDir(avDir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logging.LogErrorf("read directory [%s] failed: %s", avDir, err)
+		}
+		return
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+
+		id := strings.TrimSuffix(entry.Name(), ".json")
+		if !ast.IsNodeIDPattern(id) {
+			continue
+		}
+
+		attrView, parseErr := av.ParseAttributeView(id)
+		if nil != parseErr || nil == attrView {
+			continue
+		}
+		if filter(attrView) {
+			ret = append(ret, attrView)
+		}
+	}
+	return
+}
+
 func getAttrViewName(attrView *av.AttributeView) string {
 	ret := strings.TrimSpace(attrView.Name)
 	if "" == ret {
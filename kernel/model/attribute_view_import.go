@@ -0,0 +1,527 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package model
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/mail"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/88250/gulu"
+	"github.com/88250/lute/ast"
+	"github.com/siyuan-note/logging"
+	"github.com/siyuan-note/siyuan/kernel/av"
+	"github.com/siyuan-note/siyuan/kernel/sql"
+	"github.com/siyuan-note/siyuan/kernel/treenode"
+	"github.com/siyuan-note/siyuan/kernel/util"
+	"github.com/xuri/excelize/v2"
+)
+
+// ImportAttributeView 将 CSV、TSV 或者 XLSX 文件中的数据导入到数据库中。
+//
+//   - avID 为空时新建数据库，新建的数据库需要调用方使用返回的 blockID 插入数据库块
+//   - mapping 为表头到字段 ID 的映射，映射为空字符串表示跳过该列；没有映射的列按名称匹配已有字段，匹配不到时根据数据推断字段类型并新建字段
+//   - primaryCol 为主键列的表头，为空时使用第一列
+//   - bindBlock 为 true 时主键值是块 ID 或者唯一的文档标题时将行绑定到该块，否则创建游离行
+func ImportAttributeView(filePath, avID string, mapping map[string]string, primaryCol string, bindBlock bool) (retAvID, retBlockID string, count int, err error) {
+	header, records, err := readAttributeViewImportFile(filePath)
+	if err != nil {
+		return
+	}
+	if 1 > len(header) {
+		err = errors.New("no data found")
+		return
+	}
+
+	primaryIndex := 0
+	if "" != primaryCol {
+		primaryIndex = -1
+		for i, h := range header {
+			if h == primaryCol {
+				primaryIndex = i
+				break
+			}
+		}
+		if 0 > primaryIndex {
+			err = fmt.Errorf("primary column [%s] not found", primaryCol)
+			return
+		}
+	}
+
+	var attrView *av.AttributeView
+	isNew := "" == avID
+	if isNew {
+		avID = ast.NewNodeID()
+		attrView = newImportAttributeView(avID, header[primaryIndex], filePath)
+	} else {
+		attrView, err = av.ParseAttributeView(avID)
+		if err != nil {
+			return
+		}
+	}
+
+	keys, err := getImportColumnKeys(attrView, header, records, mapping, primaryIndex)
+	if err != nil {
+		return
+	}
+
+	now := util.CurrentTimeMillis()
+	blockValues := attrView.GetBlockKeyValues()
+	var addingBlockIDs, bindingBlockIDs []string
+	for _, record := range records {
+		if isEmptyImportRecord(record) {
+			continue
+		}
+
+		primary := strings.TrimSpace(record[primaryIndex])
+		blockID := ""
+		if bindBlock {
+			blockID = getImportBindBlockID(primary)
+		}
+		isDetached := "" == blockID
+		if isDetached {
+			blockID = ast.NewNodeID()
+		}
+
+		var blockValue *av.Value
+		for _, v := range blockValues.Values {
+			if v.BlockID == blockID {
+				blockValue = v
+				break
+			}
+		}
+		if nil == blockValue {
+			blockValue = &av.Value{ID: ast.NewNodeID(), KeyID: blockValues.Key.ID, BlockID: blockID, Type: av.KeyTypeBlock, IsDetached: isDetached, CreatedAt: now, UpdatedAt: now,
+				Block: &av.ValueBlock{ID: blockID, Content: primary, Created: now, Updated: now}}
+			if !isDetached {
+				if node, _, _ := getNodeByBlockID(nil, blockID); nil != node {
+					blockValue.Block.Icon, blockValue.Block.Content = getNodeAvBlockText(node)
+				}
+			}
+			blockValues.Values = append(blockValues.Values, blockValue)
+			addingBlockIDs = append(addingBlockIDs, blockID)
+		}
+		if !isDetached {
+			bindingBlockIDs = append(bindingBlockIDs, blockID)
+		}
+
+		for i, key := range keys {
+			if nil == key || i == primaryIndex || i >= len(record) {
+				continue
+			}
+
+			keyValues, _ := attrView.GetKeyValues(key.ID)
+			val := parseImportValue(key, record[i])
+			if nil == val {
+				continue
+			}
+			val.KeyID, val.BlockID, val.Type, val.IsDetached = key.ID, blockID, key.Type, isDetached
+			val.CreatedAt, val.UpdatedAt = now, now
+
+			replaced := false
+			for j, v := range keyValues.Values {
+				if v.BlockID == blockID {
+					val.ID, val.CreatedAt = v.ID, v.CreatedAt
+					keyValues.Values[j] = val
+					replaced = true
+					break
+				}
+			}
+			if !replaced {
+				val.ID = ast.NewNodeID()
+				keyValues.Values = append(keyValues.Values, val)
+			}
+		}
+		count++
+	}
+
+	for _, view := range attrView.Views {
+		if view.HasLayoutTable() {
+			view.Table.RowIDs = append(view.Table.RowIDs, addingBlockIDs...)
+		}
+	}
+
+	if err = av.SaveAttributeView(attrView); err != nil {
+		logging.LogErrorf("save attribute view [%s] failed: %s", avID, err)
+		return
+	}
+
+	for _, blockID := range gulu.Str.RemoveDuplicatedElem(bindingBlockIDs) {
+		bindBlockAv(nil, avID, blockID)
+	}
+
+	retAvID = avID
+	if isNew {
+		retBlockID = ast.NewNodeID()
+		av.UpsertBlockRel(avID, retBlockID)
+	} else {
+		ReloadAttrView(avID)
+	}
+	return
+}
+
+func newImportAttributeView(avID, primaryName, filePath string) (ret *av.AttributeView) {
+	view, blockKey, _ := av.NewTableViewWithBlockKey(ast.NewNodeID())
+	if "" != primaryName {
+		blockKey.Name = primaryName
+	}
+	view.Table.Columns = []*av.ViewTableColumn{{ID: blockKey.ID}}
+
+	name := strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath))
+	ret = &av.AttributeView{
+		Spec:      0,
+		ID:        avID,
+		Name:      name,
+		KeyValues: []*av.KeyValues{{Key: blockKey}},
+		ViewID:    view.ID,
+		Views:     []*av.View{view},
+	}
+	return
+}
+
+// getImportColumnKeys 获取各列对应的字段，跳过的列和无法导入的列对应 nil。
+func getImportColumnKeys(attrView *av.AttributeView, header []string, records [][]string, mapping map[string]string, primaryIndex int) (ret []*av.Key, err error) {
+	blockKey := attrView.GetBlockKeyValues().Key
+	for i, h := range header {
+		if i == primaryIndex {
+			ret = append(ret, blockKey)
+			continue
+		}
+
+		var key *av.Key
+		if keyID, ok := mapping[h]; ok {
+			if "" == keyID {
+				ret = append(ret, nil)
+				continue
+			}
+
+			key, _ = attrView.GetKey(keyID)
+			if nil == key {
+				err = fmt.Errorf("key [%s] not found", keyID)
+				return
+			}
+		} else {
+			for _, kv := range attrView.KeyValues {
+				if av.KeyTypeBlock != kv.Key.Type && strings.EqualFold(strings.TrimSpace(kv.Key.Name), h) {
+					key = kv.Key
+					break
+				}
+			}
+		}
+
+		if nil == key {
+			var column []string
+			for _, record := range records {
+				if i < len(record) {
+					column = append(column, record[i])
+				}
+			}
+
+			name := h
+			if "" == name {
+				name = fmt.Sprintf("Column %d", i+1)
+			}
+			key = av.NewKey(ast.NewNodeID(), name, "", av.KeyTypeText)
+			inferImportKeyType(key, column)
+			attrView.KeyValues = append(attrView.KeyValues, &av.KeyValues{Key: key})
+			for _, view := range attrView.Views {
+				if view.HasLayoutTable() {
+					view.Table.Columns = append(view.Table.Columns, &av.ViewTableColumn{ID: key.ID})
+				}
+			}
+		}
+
+		if av.KeyTypeBlock == key.Type || !isImportableKeyType(key.Type) {
+			key = nil
+		}
+		ret = append(ret, key)
+	}
+	return
+}
+
+func isImportableKeyType(typ av.KeyType) bool {
+	switch typ {
+	case av.KeyTypeBlock, av.KeyTypeText, av.KeyTypeNumber, av.KeyTypeDate, av.KeyTypeSelect, av.KeyTypeMSelect, av.KeyTypeURL,
+		av.KeyTypeEmail, av.KeyTypePhone, av.KeyTypeMAsset, av.KeyTypeCheckbox:
+		return true
+	}
+	return false
+}
+
+// inferImportKeyType 根据列数据推断字段类型：所有非空值都能解析为数字、日期、复选框、链接或者邮箱时使用该类型，
+// 重复值较多的文本列推断为单选并生成选项。
+func inferImportKeyType(key *av.Key, column []string) {
+	var values []string
+	for _, v := range column {
+		if v = strings.TrimSpace(v); "" != v {
+			values = append(values, v)
+		}
+	}
+	if 1 > len(values) {
+		return
+	}
+
+	allMatch := func(match func(string) bool) bool {
+		for _, v := range values {
+			if !match(v) {
+				return false
+			}
+		}
+		return true
+	}
+
+	switch {
+	case allMatch(func(v string) bool { _, ok := parseImportNumber(v); return ok }):
+		key.Type = av.KeyTypeNumber
+		if allMatch(func(v string) bool { return strings.HasSuffix(v, "%") }) {
+			key.NumberFormat = av.NumberFormatPercent
+		}
+	case allMatch(func(v string) bool { _, _, ok := parseImportDate(v); return ok }):
+		key.Type = av.KeyTypeDate
+	case allMatch(func(v string) bool { _, ok := parseImportCheckbox(v); return ok }):
+		key.Type = av.KeyTypeCheckbox
+	case allMatch(func(v string) bool { return strings.HasPrefix(v, "http://") || strings.HasPrefix(v, "https://") }):
+		key.Type = av.KeyTypeURL
+	case allMatch(isImportEmail):
+		key.Type = av.KeyTypeEmail
+	default:
+		var options []string
+		for _, v := range values {
+			if !gulu.Str.Contains(v, options) {
+				options = append(options, v)
+			}
+		}
+		if len(options) <= 16 && len(options)*2 <= len(values) {
+			key.Type = av.KeyTypeSelect
+			for i, opt := range options {
+				key.Options = append(key.Options, &av.SelectOption{Name: opt, Color: strconv.Itoa(i%14 + 1)})
+			}
+		}
+	}
+}
+
+// parseImportValue 按字段类型解析单元格文本，无法解析时返回 nil。
+func parseImportValue(key *av.Key, content string) (ret *av.Value) {
+	content = strings.TrimSpace(content)
+	ret = &av.Value{}
+	switch key.Type {
+	case av.KeyTypeBlock:
+		ret.Block = &av.ValueBlock{Content: content}
+	case av.KeyTypeText:
+		ret.Text = &av.ValueText{Content: content}
+	case av.KeyTypeNumber:
+		ret.Number = &av.ValueNumber{}
+		if "" != content {
+			num, ok := parseImportNumber(content)
+			if !ok {
+				return nil
+			}
+			ret.Number = av.NewFormattedValueNumber(num, key.NumberFormat)
+		}
+	case av.KeyTypeDate:
+		ret.Date = &av.ValueDate{}
+		if "" != content {
+			t, isNotTime, ok := parseImportDate(content)
+			if !ok {
+				return nil
+			}
+			ret.Date = &av.ValueDate{Content: t.UnixMilli(), IsNotEmpty: true, IsNotTime: isNotTime}
+		}
+	case av.KeyTypeCheckbox:
+		checked, _ := parseImportCheckbox(content)
+		ret.Checkbox = &av.ValueCheckbox{Checked: checked}
+	case av.KeyTypeURL:
+		ret.URL = &av.ValueURL{Content: content}
+	case av.KeyTypeEmail:
+		ret.Email = &av.ValueEmail{Content: content}
+	case av.KeyTypePhone:
+		ret.Phone = &av.ValuePhone{Content: content}
+	case av.KeyTypeSelect, av.KeyTypeMSelect:
+		ret.MSelect = []*av.ValueSelect{}
+		var names []string
+		if av.KeyTypeSelect == key.Type {
+			names = []string{content}
+		} else {
+			names = strings.FieldsFunc(content, func(r rune) bool { return ',' == r || '，' == r || ';' == r })
+		}
+		for _, name := range names {
+			if name = strings.TrimSpace(name); "" == name {
+				continue
+			}
+
+			opt := key.GetOption(name)
+			if nil == opt {
+				opt = &av.SelectOption{Name: name, Color: strconv.Itoa(len(key.Options)%14 + 1)}
+				key.Options = append(key.Options, opt)
+			}
+			ret.MSelect = append(ret.MSelect, &av.ValueSelect{Content: opt.Name, Color: opt.Color})
+		}
+	case av.KeyTypeMAsset:
+		ret.MAsset = []*av.ValueAsset{}
+		for _, dest := range strings.Fields(strings.ReplaceAll(content, ",", " ")) {
+			asset := &av.ValueAsset{Type: av.AssetTypeFile, Name: path.Base(dest), Content: dest}
+			if gulu.Str.Contains(strings.ToLower(path.Ext(dest)), util.SiYuanAssetsImage) {
+				asset.Type = av.AssetTypeImage
+			}
+			ret.MAsset = append(ret.MAsset, asset)
+		}
+	default:
+		return nil
+	}
+	return
+}
+
+func parseImportNumber(content string) (ret float64, ok bool) {
+	s := strings.ReplaceAll(strings.TrimSpace(content), ",", "")
+	percent := strings.HasSuffix(s, "%")
+	s = strings.TrimSuffix(s, "%")
+	ret, err := strconv.ParseFloat(s, 64)
+	if nil != err {
+		return
+	}
+	if percent {
+		ret /= 100
+	}
+	ok = true
+	return
+}
+
+var importDateLayouts = []string{
+	time.RFC3339, "2006-01-02 15:04:05", "2006-01-02 15:04", "2006/01/02 15:04:05", "2006/01/02 15:04", "2006/1/2 15:04",
+	"2006-01-02", "2006/01/02", "2006/1/2", "2006.01.02", "2006年1月2日", "1/2/2006", "1/2/06",
+}
+
+// parseImportDate 解析日期文本，isNotTime 为 true 表示文本中不包含时间。
+func parseImportDate(content string) (ret time.Time, isNotTime, ok bool) {
+	content = strings.TrimSpace(content)
+	for _, layout := range importDateLayouts {
+		t, err := time.ParseInLocation(layout, content, time.Local)
+		if nil != err {
+			continue
+		}
+		return t, !strings.Contains(layout, "15"), true
+	}
+	return
+}
+
+func parseImportCheckbox(content string) (checked, ok bool) {
+	switch strings.ToLower(strings.TrimSpace(content)) {
+	case "true", "yes", "√", "✓", "✔", "☑", "checked":
+		return true, true
+	case "false", "no", "☐", "unchecked":
+		return false, true
+	}
+	return
+}
+
+func isImportEmail(content string) bool {
+	addr, err := mail.ParseAddress(content)
+	return nil == err && addr.Address == content
+}
+
+// getImportBindBlockID 获取主键值对应的块：主键值为已经存在的块 ID 或者唯一的文档标题。
+func getImportBindBlockID(primary string) string {
+	if "" == primary {
+		return ""
+	}
+
+	if ast.IsNodeIDPattern(primary) {
+		if nil != treenode.GetBlockTree(primary) {
+			return primary
+		}
+		return ""
+	}
+
+	if ids := sql.QueryDocIDsByTitle(primary); 1 == len(ids) {
+		return ids[0]
+	}
+	return ""
+}
+
+func isEmptyImportRecord(record []string) bool {
+	for _, v := range record {
+		if "" != strings.TrimSpace(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// readAttributeViewImportFile 读取导入文件，返回表头和数据行，数据行的列数和表头对齐。
+func readAttributeViewImportFile(filePath string) (header []string, records [][]string, err error) {
+	var rows [][]string
+	switch ext := strings.ToLower(filepath.Ext(filePath)); ext {
+	case ".csv", ".tsv":
+		var data []byte
+		data, err = os.ReadFile(filePath)
+		if err != nil {
+			logging.LogErrorf("read [%s] failed: %s", filePath, err)
+			return
+		}
+		data = bytes.TrimPrefix(data, []byte("\xEF\xBB\xBF")) // 去掉 Microsoft Excel 写入的 UTF-8 BOM
+
+		reader := csv.NewReader(bytes.NewReader(data))
+		if ".tsv" == ext {
+			reader.Comma = '\t'
+		}
+		reader.FieldsPerRecord = -1
+		reader.LazyQuotes = true
+		if rows, err = reader.ReadAll(); err != nil {
+			logging.LogErrorf("parse [%s] failed: %s", filePath, err)
+			return
+		}
+	case ".xlsx":
+		var x *excelize.File
+		x, err = excelize.OpenFile(filePath)
+		if err != nil {
+			logging.LogErrorf("open [%s] failed: %s", filePath, err)
+			return
+		}
+		defer x.Close()
+
+		// 仅导入第一个工作表
+		if rows, err = x.GetRows(x.GetSheetName(0)); err != nil {
+			logging.LogErrorf("get rows from [%s] failed: %s", filePath, err)
+			return
+		}
+	default:
+		err = fmt.Errorf("unsupported file type [%s]", ext)
+		return
+	}
+
+	if 1 > len(rows) {
+		return
+	}
+
+	for _, h := range rows[0] {
+		header = append(header, strings.TrimSpace(h))
+	}
+	for _, row := range rows[1:] {
+		record := make([]string, len(header))
+		copy(record, row)
+		records = append(records, record)
+	}
+	return
+}
@@ -0,0 +1,391 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package model
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/88250/gulu"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/siyuan-note/logging"
+	"github.com/siyuan-note/siyuan/kernel/util"
+)
+
+// AuditLog 描述了一条审计日志，审计日志以 JSON Lines 格式追加写入 temp/audit/ 下。
+type AuditLog struct {
+	Created   int64    `json:"created"`           // 请求时间，毫秒时间戳
+	Elapsed   int64    `json:"elapsed"`           // 耗时，毫秒
+	Method    string   `json:"method"`            // 请求方法
+	Route     string   `json:"route"`             // 请求路径
+	Auth      string   `json:"auth"`              // 认证方式：accessAuthCode、user、apiToken、publish、anonymous
+	User      string   `json:"user,omitempty"`    // 用户帐号、OpenID Connect 用户或者发布服务账户
+	Role      string   `json:"role"`              // 角色：admin、editor、reader、visitor
+	TokenID   string   `json:"tokenID,omitempty"` // 签发的 API token ID
+	IP        string   `json:"ip"`                // 客户端 IP
+	UserAgent string   `json:"userAgent"`         // 客户端 User-Agent
+	IDs       []string `json:"ids,omitempty"`     // 请求中涉及的块、文档、笔记本等 ID
+	Path      string   `json:"path,omitempty"`    // 请求中涉及的文件路径
+	Status    int      `json:"status"`            // HTTP 状态码
+	Code      int      `json:"code"`              // 接口返回的 code
+	Msg       string   `json:"msg,omitempty"`     // 接口返回的错误信息
+}
+
+const (
+	auditContextKey       = "audit"
+	auditLogFileName      = "audit.log"
+	auditLogMaxSize       = 8 * 1024 * 1024 // 单个日志文件超过该大小后轮转
+	auditLogMaxFiles      = 10              // 保留的轮转日志文件数
+	auditBodyMaxSize      = 4 * 1024 * 1024 // 超过该大小的请求体不解析 ID
+	auditResponseMaxSize  = 4 * 1024
+	auditIDsMaxCount      = 64
+	auditLogQueryMaxCount = 1024
+)
+
+// auditRoutePrefixes 需要记录审计日志的路由，此外所有使用 CheckReadonly 的写入接口也会被记录。
+var auditRoutePrefixes = []string{
+	"/api/transactions",
+	"/api/file/putFile",
+	"/api/file/removeFile",
+	"/api/filetree/removeDoc",
+	"/api/repo/",
+	"/api/system/loginAuth",
+}
+
+var (
+	auditLogLock    = sync.Mutex{}
+	auditIDRegexp   = regexp.MustCompile(`\d{14}-[0-9a-z]{7}`)
+	auditCodeRegexp = regexp.MustCompile(`"code":\s*(-?\d+)`)
+)
+
+// Audit 记录写入接口的调用者、目标和结果。
+func Audit(c *gin.Context) {
+	if http.MethodPost != c.Request.Method || !strings.HasPrefix(c.Request.URL.Path, "/api/") {
+		c.Next()
+		return
+	}
+
+	var body []byte
+	if strings.Contains(c.ContentType(), "json") && 0 <= c.Request.ContentLength && auditBodyMaxSize >= c.Request.ContentLength {
+		var err error
+		if body, err = io.ReadAll(io.LimitReader(c.Request.Body, auditBodyMaxSize)); err == nil {
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+	}
+
+	writer := &auditResponseWriter{ResponseWriter: c.Writer}
+	c.Writer = writer
+	start := time.Now()
+	c.Next()
+
+	if !c.GetBool(auditContextKey) && !isAuditRoute(c.Request.URL.Path) {
+		return
+	}
+
+	auditLog := &AuditLog{
+		Created:   start.UnixMilli(),
+		Elapsed:   time.Since(start).Milliseconds(),
+		Method:    c.Request.Method,
+		Route:     c.Request.URL.Path,
+		IP:        util.GetRemoteAddr(c.Request),
+		UserAgent: c.GetHeader("User-Agent"),
+		Status:    writer.Status(),
+	}
+	setAuditCaller(c, auditLog)
+	setAuditTargets(c, body, auditLog)
+	setAuditOutcome(writer.body.Bytes(), auditLog)
+	writeAuditLog(auditLog)
+}
+
+// markAudit 标记当前请求需要记录审计日志。
+func markAudit(c *gin.Context) {
+	c.Set(auditContextKey, true)
+}
+
+func isAuditRoute(route string) bool {
+	for _, prefix := range auditRoutePrefixes {
+		if strings.HasPrefix(route, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func setAuditCaller(c *gin.Context, auditLog *AuditLog) {
+	role := GetGinContextRole(c)
+	switch role {
+	case RoleAdministrator:
+		auditLog.Role = "admin"
+	case RoleEditor:
+		auditLog.Role = "editor"
+	case RoleReader:
+		auditLog.Role = "reader"
+	default:
+		auditLog.Role = "visitor"
+	}
+
+	if token := GetGinContextAPIToken(c); nil != token {
+		auditLog.Auth = "apiToken"
+		auditLog.TokenID = token.ID
+		auditLog.User = token.Name
+		return
+	}
+
+	if claims, exists := c.Get(ClaimsContextKey); exists {
+		auditLog.Auth = "publish"
+		auditLog.User, _ = claims.(jwt.MapClaims)["jti"].(string)
+		return
+	}
+
+	if username := GetGinContextUsername(c); "" != username {
+		auditLog.Auth = "user"
+		auditLog.User = username
+		return
+	}
+
+	if RoleVisitor == role {
+		auditLog.Auth = "anonymous"
+		return
+	}
+	auditLog.Auth = "accessAuthCode"
+}
+
+func setAuditTargets(c *gin.Context, body []byte, auditLog *AuditLog) {
+	if 0 < len(body) {
+		auditLog.IDs = gulu.Str.RemoveDuplicatedElem(auditIDRegexp.FindAllString(string(body), -1))
+		if auditIDsMaxCount < len(auditLog.IDs) {
+			auditLog.IDs = auditLog.IDs[:auditIDsMaxCount]
+		}
+
+		arg := map[string]interface{}{}
+		if err := gulu.JSON.UnmarshalJSON(body, &arg); err == nil {
+			auditLog.Path, _ = arg["path"].(string)
+		}
+		return
+	}
+
+	if nil != c.Request.MultipartForm {
+		if paths := c.Request.MultipartForm.Value["path"]; 0 < len(paths) {
+			auditLog.Path = paths[0]
+		}
+	}
+}
+
+func setAuditOutcome(body []byte, auditLog *AuditLog) {
+	result := &struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+	}{}
+	if err := gulu.JSON.UnmarshalJSON(body, result); err == nil {
+		auditLog.Code = result.Code
+		auditLog.Msg = result.Msg
+		return
+	}
+
+	// 响应内容被截断时只解析 code
+	if matches := auditCodeRegexp.FindSubmatch(body); 1 < len(matches) {
+		auditLog.Code, _ = strconv.Atoi(string(matches[1]))
+		return
+	}
+	if http.StatusOK != auditLog.Status {
+		auditLog.Code = -1
+	}
+}
+
+func writeAuditLog(auditLog *AuditLog) {
+	data, err := gulu.JSON.MarshalJSON(auditLog)
+	if err != nil {
+		logging.LogErrorf("marshal audit log failed: %s", err)
+		return
+	}
+	data = append(data, '\n')
+
+	auditLogLock.Lock()
+	defer auditLogLock.Unlock()
+
+	dir := filepath.Join(util.TempDir, "audit")
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		logging.LogErrorf("create audit log dir failed: %s", err)
+		return
+	}
+
+	logPath := filepath.Join(dir, auditLogFileName)
+	if info, statErr := os.Stat(logPath); nil == statErr && auditLogMaxSize < info.Size()+int64(len(data)) {
+		rotateAuditLog(dir, logPath)
+	}
+
+	f, err := os.OpenFile(logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		logging.LogErrorf("open audit log failed: %s", err)
+		return
+	}
+	defer f.Close()
+	if _, err = f.Write(data); err != nil {
+		logging.LogErrorf("write audit log failed: %s", err)
+	}
+}
+
+// rotateAuditLog 将当前日志文件重命名为 audit-{时间}.log，并只保留最近的 auditLogMaxFiles 个轮转文件。
+func rotateAuditLog(dir, logPath string) {
+	rotated := filepath.Join(dir, "audit-"+time.Now().Format("20060102150405")+".log")
+	if err := os.Rename(logPath, rotated); err != nil {
+		logging.LogErrorf("rotate audit log failed: %s", err)
+		return
+	}
+
+	files := getRotatedAuditLogs(dir)
+	for i := auditLogMaxFiles; i < len(files); i++ {
+		if err := os.Remove(files[i]); err != nil {
+			logging.LogErrorf("remove audit log [%s] failed: %s", files[i], err)
+		}
+	}
+}
+
+// getRotatedAuditLogs 获取轮转的日志文件，按时间倒序。
+func getRotatedAuditLogs(dir string) (ret []string) {
+	ret, _ = filepath.Glob(filepath.Join(dir, "audit-*.log"))
+	sort.Sort(sort.Reverse(sort.StringSlice(ret)))
+	return
+}
+
+// AuditLogQuery 描述了审计日志的查询条件，空值表示不限制。
+type AuditLogQuery struct {
+	Route    string `json:"route"`    // 路由前缀
+	User     string `json:"user"`     // 用户
+	ID       string `json:"id"`       // 涉及的 ID
+	IP       string `json:"ip"`       // 客户端 IP
+	Failed   bool   `json:"failed"`   // 只查询失败的请求
+	From     int64  `json:"from"`     // 开始时间，毫秒时间戳
+	To       int64  `json:"to"`       // 结束时间，毫秒时间戳
+	Page     int    `json:"page"`     // 页码，从 1 开始
+	PageSize int    `json:"pageSize"` // 每页数量
+}
+
+// GetAuditLogs 按时间倒序查询审计日志。
+func GetAuditLogs(query *AuditLogQuery) (ret []*AuditLog, total int) {
+	ret = []*AuditLog{}
+	if 1 > query.Page {
+		query.Page = 1
+	}
+	if 1 > query.PageSize || auditLogQueryMaxCount < query.PageSize {
+		query.PageSize = 32
+	}
+
+	auditLogLock.Lock()
+	defer auditLogLock.Unlock()
+
+	dir := filepath.Join(util.TempDir, "audit")
+	files := append([]string{filepath.Join(dir, auditLogFileName)}, getRotatedAuditLogs(dir)...)
+	start, end := (query.Page-1)*query.PageSize, query.Page*query.PageSize
+	for _, file := range files {
+		logs := readAuditLogs(file)
+		for i := len(logs) - 1; 0 <= i; i-- {
+			if !query.match(logs[i]) {
+				continue
+			}
+
+			if start <= total && total < end {
+				ret = append(ret, logs[i])
+			}
+			total++
+		}
+	}
+	return
+}
+
+func (query *AuditLogQuery) match(auditLog *AuditLog) bool {
+	if "" != query.Route && !strings.HasPrefix(auditLog.Route, query.Route) {
+		return false
+	}
+	if "" != query.User && query.User != auditLog.User {
+		return false
+	}
+	if "" != query.ID && !gulu.Str.Contains(query.ID, auditLog.IDs) && !strings.Contains(auditLog.Path, query.ID) {
+		return false
+	}
+	if "" != query.IP && query.IP != auditLog.IP {
+		return false
+	}
+	if query.Failed && 0 == auditLog.Code && http.StatusOK == auditLog.Status {
+		return false
+	}
+	if 0 < query.From && auditLog.Created < query.From {
+		return false
+	}
+	if 0 < query.To && auditLog.Created > query.To {
+		return false
+	}
+	return true
+}
+
+func readAuditLogs(file string) (ret []*AuditLog) {
+	f, err := os.Open(file)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logging.LogErrorf("open audit log [%s] failed: %s", file, err)
+		}
+		return
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		auditLog := &AuditLog{}
+		if err = gulu.JSON.UnmarshalJSON(scanner.Bytes(), auditLog); err != nil {
+			continue
+		}
+		ret = append(ret, auditLog)
+	}
+	return
+}
+
+// auditResponseWriter 记录响应内容的开头部分，用于解析接口返回的 code。
+type auditResponseWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *auditResponseWriter) Write(data []byte) (int, error) {
+	w.capture(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *auditResponseWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *auditResponseWriter) capture(data []byte) {
+	if remain := auditResponseMaxSize - w.body.Len(); 0 < remain {
+		if len(data) > remain {
+			data = data[:remain]
+		}
+		w.body.Write(data)
+	}
+}
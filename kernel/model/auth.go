@@ -18,6 +18,7 @@ package model
 
 import (
 	"crypto/rand"
+	"encoding/hex"
 	"net/http"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -96,6 +97,17 @@ func InitJWT() {
 	}
 }
 
+// randomToken 使用安全随机数生成 size 字节的随机串，返回其十六进制编码。
+func randomToken(size int) (ret string, err error) {
+	b := make([]byte, size)
+	if _, err = rand.Read(b); err != nil {
+		logging.LogErrorf("generate random token failed: %s", err)
+		return
+	}
+	ret = hex.EncodeToString(b)
+	return
+}
+
 func ParseJWT(tokenString string) (*jwt.Token, error) {
 	// REF: https://golang-jwt.github.io/jwt/usage/parse/
 	return jwt.Parse(
@@ -68,6 +68,8 @@ type Block struct {
 
 	RiffCardID string    `json:"riffCardID"`
 	RiffCard   *RiffCard `json:"riffCard"`
+
+	Score *SearchScore `json:"score,omitempty"` // 按综合得分排序时的得分明细
 }
 
 type RiffCard struct {
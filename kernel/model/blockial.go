@@ -74,7 +74,7 @@ func SetBlockReminder(id string, timed string) (err error) {
 		return
 	}
 
-	attrName := "custom-reminder-wechat"
+	attrName := BlockReminderAttrName
 	if "0" == timed {
 		delete(attrs, attrName)
 		old := node.IALAttr(attrName)
@@ -679,6 +679,7 @@ func fullReindex() {
 	for _, openedBox := range openedBoxes {
 		index(openedBox.ID)
 	}
+	indexAttributeViews()
 	LoadFlashcards()
 	debug.FreeOSMemory()
 }
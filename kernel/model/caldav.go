@@ -667,6 +667,10 @@ func (b *CalDavBackend) CalendarHomeSetPath(ctx context.Context) (string, error)
 func (b *CalDavBackend) CreateCalendar(ctx context.Context, calendar *caldav.Calendar) (err error) {
 	// logging.LogDebugf("CalDAV CreateCalendar -> calendar: %#v", calendar)
 	calendar.Path = PathCleanWithSlash(calendar.Path)
+	if IsCalDavNotesCalendarPath(calendar.Path) {
+		err = ErrorCalDavCalendarPathInvalid
+		return
+	}
 
 	if err = calendars.Load(); err != nil {
 		return
@@ -684,6 +688,7 @@ func (b *CalDavBackend) ListCalendars(ctx context.Context) (calendars_ []caldav.
 	}
 
 	calendars_, err = calendars.ListCalendars()
+	calendars_ = append(calendars_, notesCalendar)
 	// logging.LogDebugf("CalDAV ListCalendars <- calendars: %#v, err: %s", calendars_, err)
 	return
 }
@@ -691,6 +696,10 @@ func (b *CalDavBackend) ListCalendars(ctx context.Context) (calendars_ []caldav.
 func (b *CalDavBackend) GetCalendar(ctx context.Context, calendarPath string) (calendar *caldav.Calendar, err error) {
 	// logging.LogDebugf("CalDAV GetCalendar -> calendarPath: %s", calendarPath)
 	calendarPath = PathCleanWithSlash(calendarPath)
+	if CalDavNotesCalendarPath == calendarPath {
+		calendar = &notesCalendar
+		return
+	}
 
 	if err = calendars.Load(); err != nil {
 		return
@@ -704,6 +713,10 @@ func (b *CalDavBackend) GetCalendar(ctx context.Context, calendarPath string) (c
 func (b *CalDavBackend) DeleteCalendar(ctx context.Context, calendarPath string) (err error) {
 	// logging.LogDebugf("CalDAV DeleteCalendar -> calendarPath: %s", calendarPath)
 	calendarPath = PathCleanWithSlash(calendarPath)
+	if IsCalDavNotesCalendarPath(calendarPath) {
+		err = ErrorCalDavNotesCalendarReadOnly
+		return
+	}
 
 	if err = calendars.Load(); err != nil {
 		return
@@ -717,6 +730,10 @@ func (b *CalDavBackend) DeleteCalendar(ctx context.Context, calendarPath string)
 func (b *CalDavBackend) PutCalendarObject(ctx context.Context, objectPath string, calendar *ical.Calendar, opts *caldav.PutCalendarObjectOptions) (calendarObject *caldav.CalendarObject, err error) {
 	// logging.LogDebugf("CalDAV PutCalendarObject -> objectPath: %s, opts: %#v", objectPath, opts)
 	objectPath = PathCleanWithSlash(objectPath)
+	if IsCalDavNotesCalendarPath(objectPath) {
+		calendarObject, err = putNotesCalendarObject(objectPath, calendar)
+		return
+	}
 
 	if err = calendars.Load(); err != nil {
 		return
@@ -730,6 +747,10 @@ func (b *CalDavBackend) PutCalendarObject(ctx context.Context, objectPath string
 func (b *CalDavBackend) ListCalendarObjects(ctx context.Context, calendarPath string, req *caldav.CalendarCompRequest) (calendarObjects []caldav.CalendarObject, err error) {
 	// logging.LogDebugf("CalDAV ListCalendarObjects -> calendarPath: %s, req: %#v", calendarPath, req)
 	calendarPath = PathCleanWithSlash(calendarPath)
+	if CalDavNotesCalendarPath == calendarPath {
+		calendarObjects = listNotesCalendarObjects()
+		return
+	}
 
 	if err = calendars.Load(); err != nil {
 		return
@@ -743,6 +764,10 @@ func (b *CalDavBackend) ListCalendarObjects(ctx context.Context, calendarPath st
 func (b *CalDavBackend) GetCalendarObject(ctx context.Context, objectPath string, req *caldav.CalendarCompRequest) (calendarObject *caldav.CalendarObject, err error) {
 	// logging.LogDebugf("CalDAV GetCalendarObject -> objectPath: %s, req: %#v", objectPath, req)
 	objectPath = PathCleanWithSlash(objectPath)
+	if IsCalDavNotesCalendarPath(objectPath) {
+		calendarObject, err = getNotesCalendarObject(objectPath)
+		return
+	}
 
 	if err = calendars.Load(); err != nil {
 		return
@@ -756,6 +781,10 @@ func (b *CalDavBackend) GetCalendarObject(ctx context.Context, objectPath string
 func (b *CalDavBackend) QueryCalendarObjects(ctx context.Context, calendarPath string, query *caldav.CalendarQuery) (calendarObjects []caldav.CalendarObject, err error) {
 	// logging.LogDebugf("CalDAV QueryCalendarObjects -> calendarPath: %s, query: %#v", calendarPath, query)
 	calendarPath = PathCleanWithSlash(calendarPath)
+	if CalDavNotesCalendarPath == calendarPath {
+		calendarObjects, err = caldav.Filter(query, listNotesCalendarObjects())
+		return
+	}
 
 	if err = calendars.Load(); err != nil {
 		return
@@ -769,6 +798,10 @@ func (b *CalDavBackend) QueryCalendarObjects(ctx context.Context, calendarPath s
 func (b *CalDavBackend) DeleteCalendarObject(ctx context.Context, objectPath string) (err error) {
 	// logging.LogDebugf("CalDAV DeleteCalendarObject -> objectPath: %s", objectPath)
 	objectPath = PathCleanWithSlash(objectPath)
+	if IsCalDavNotesCalendarPath(objectPath) {
+		err = deleteNotesCalendarObject(objectPath)
+		return
+	}
 
 	if err = calendars.Load(); err != nil {
 		return
@@ -0,0 +1,635 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package model
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/88250/gulu"
+	"github.com/88250/lute/ast"
+	"github.com/88250/lute/parse"
+	"github.com/emersion/go-ical"
+	"github.com/emersion/go-webdav/caldav"
+	"github.com/siyuan-note/logging"
+	"github.com/siyuan-note/siyuan/kernel/av"
+	"github.com/siyuan-note/siyuan/kernel/sql"
+	"github.com/siyuan-note/siyuan/kernel/treenode"
+	"github.com/siyuan-note/siyuan/kernel/util"
+)
+
+// 笔记日历是一个虚拟日历，日历对象由笔记内容实时生成，不在 CalDAV 目录下保存文件：
+//
+//   - 设置了提醒（custom-reminder-wechat）的块生成 VEVENT，任务列表项生成 VTODO
+//   - 内容中带有日期（yyyy-MM-dd [HH:mm]）的任务列表项生成 VTODO
+//   - 开启了日历的数据库日期字段，每个有值的单元格生成 VEVENT
+//
+// 客户端修改日程时间写回提醒属性、任务列表项内容中的日期或者数据库日期字段的值，完成待办时勾选任务列表项。
+
+const (
+	CalDavNotesCalendarPath = CalDavHomeSetPath + "/siyuan" // 3 resourceTypeCalendar
+	CalDavNotesCalendarName = "SiYuan"
+
+	BlockReminderAttrName = "custom-reminder-wechat"
+
+	blockReminderTimeLayout = "20060102150405"
+	notesCalendarProductID  = "-//b3log.org//SiYuan//EN"
+)
+
+var (
+	notesCalendar = caldav.Calendar{
+		Path:                  CalDavNotesCalendarPath,
+		Name:                  CalDavNotesCalendarName,
+		Description:           "Reminders, tasks and database dates in notes",
+		MaxResourceSize:       calendarMaxResourceSize,
+		SupportedComponentSet: calendarSupportedComponentSet,
+	}
+	notesCalendarLock = sync.Mutex{}
+
+	// 数据库 ID 到该数据库生成的日历对象，数据库文件未修改时不重新解析，需要持有 notesCalendarLock
+	notesCalendarAttrViewObjects = map[string]*notesCalendarAttrViewObject{}
+
+	ErrorCalDavNotesCalendarReadOnly = errors.New("CalDAV: calendar objects of notes can not be created or deleted")
+
+	taskDateRegexp    = regexp.MustCompile(`(\d{4}-\d{2}-\d{2})(?:[ T](\d{2}:\d{2}))?`)
+	taskCheckedRegexp = regexp.MustCompile(`^\s*(?:[*+-]|\d+[.)])\s+\[[xX]]`)
+)
+
+// IsCalDavNotesCalendarPath 判断日历路径或者日历对象路径是否位于笔记日历下。
+func IsCalDavNotesCalendarPath(p string) bool {
+	return CalDavNotesCalendarPath == p || strings.HasPrefix(p, CalDavNotesCalendarPath+"/")
+}
+
+func listNotesCalendarObjects() (ret []caldav.CalendarObject) {
+	notesCalendarLock.Lock()
+	defer notesCalendarLock.Unlock()
+
+	blocks := map[string]*sql.Block{}
+	for _, block := range sql.QueryReminderBlocks() {
+		blocks[block.ID] = block
+	}
+	for _, block := range sql.QueryDatedTaskBlocks() {
+		blocks[block.ID] = block
+	}
+
+	ret = []caldav.CalendarObject{}
+	for _, block := range blocks {
+		if object := newBlockCalendarObject(block); nil != object {
+			ret = append(ret, *object)
+		}
+	}
+
+	ret = append(ret, listAttrViewCalendarObjects()...)
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Path < ret[j].Path })
+	return
+}
+
+type notesCalendarAttrViewObject struct {
+	modTime time.Time
+	size    int64
+	objects []caldav.CalendarObject
+}
+
+// listAttrViewCalendarObjects 列出数据库日期字段生成的日历对象，调用方需要持有 notesCalendarLock。
+func listAttrViewCalendarObjects() (ret []caldav.CalendarObject) {
+	avDir := filepath.Join(util.DataDir, "storage", "av")
+	entries, err := os.ReadDir(avDir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logging.LogErrorf("read directory [%s] failed: %s", avDir, err)
+		}
+		return
+	}
+
+	cached := map[string]*notesCalendarAttrViewObject{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+
+		id := strings.TrimSuffix(entry.Name(), ".json")
+		if !ast.IsNodeIDPattern(id) {
+			continue
+		}
+
+		info, infoErr := entry.Info()
+		if nil != infoErr {
+			continue
+		}
+
+		c := notesCalendarAttrViewObjects[id]
+		if nil == c || !c.modTime.Equal(info.ModTime()) || c.size != info.Size() {
+			c = &notesCalendarAttrViewObject{modTime: info.ModTime(), size: info.Size(), objects: newAttrViewCalendarObjects(id)}
+		}
+		cached[id] = c
+		ret = append(ret, c.objects...)
+	}
+	notesCalendarAttrViewObjects = cached
+	return
+}
+
+func newAttrViewCalendarObjects(avID string) (ret []caldav.CalendarObject) {
+	attrView, err := av.ParseAttributeView(avID)
+	if nil != err || nil == attrView {
+		return
+	}
+
+	for _, kv := range attrView.KeyValues {
+		if !isCalendarDateKey(kv.Key) {
+			continue
+		}
+
+		for _, value := range kv.Values {
+			if object := newAttrViewCalendarObject(attrView, kv.Key, value); nil != object {
+				ret = append(ret, *object)
+			}
+		}
+	}
+	return
+}
+
+func getNotesCalendarObject(objectPath string) (ret *caldav.CalendarObject, err error) {
+	notesCalendarLock.Lock()
+	defer notesCalendarLock.Unlock()
+
+	return getNotesCalendarObject0(objectPath)
+}
+
+func getNotesCalendarObject0(objectPath string) (ret *caldav.CalendarObject, err error) {
+	objectID, err := parseNotesCalendarObjectPath(objectPath)
+	if err != nil {
+		return
+	}
+
+	if avID, keyID, rowID, ok := parseAttrViewCalendarObjectID(objectID); ok {
+		attrView, parseErr := av.ParseAttributeView(avID)
+		if nil != parseErr || nil == attrView {
+			err = ErrorCalDavCalendarObjectNotFound
+			return
+		}
+
+		key, _ := attrView.GetKey(keyID)
+		if nil == key || !isCalendarDateKey(key) {
+			err = ErrorCalDavCalendarObjectNotFound
+			return
+		}
+		ret = newAttrViewCalendarObject(attrView, key, attrView.GetValue(keyID, rowID))
+	} else {
+		ret = newBlockCalendarObject(sql.GetBlock(objectID))
+	}
+	if nil == ret {
+		err = ErrorCalDavCalendarObjectNotFound
+	}
+	return
+}
+
+// putNotesCalendarObject 将客户端对日历对象的修改写回笔记，笔记日历不支持新建日历对象。
+func putNotesCalendarObject(objectPath string, calendarData *ical.Calendar) (ret *caldav.CalendarObject, err error) {
+	notesCalendarLock.Lock()
+	defer notesCalendarLock.Unlock()
+
+	objectID, err := parseNotesCalendarObjectPath(objectPath)
+	if err != nil {
+		return
+	}
+
+	var comp *ical.Component
+	for _, child := range calendarData.Children {
+		if ical.CompEvent == child.Name || ical.CompToDo == child.Name {
+			comp = child
+			break
+		}
+	}
+	if nil == comp {
+		err = ErrorCalDavCalendarObjectPathInvalid
+		return
+	}
+
+	if avID, keyID, rowID, ok := parseAttrViewCalendarObjectID(objectID); ok {
+		err = putAttrViewCalendarObject(avID, keyID, rowID, comp)
+	} else {
+		err = putBlockCalendarObject(objectID, comp)
+	}
+	if err != nil {
+		return
+	}
+
+	sql.FlushQueue()
+	ret, err = getNotesCalendarObject0(objectPath)
+	return
+}
+
+// deleteNotesCalendarObject 删除日历对象时移除块提醒或者清空数据库日期字段的值，仅通过内容日期生成的待办不能删除。
+func deleteNotesCalendarObject(objectPath string) (err error) {
+	notesCalendarLock.Lock()
+	defer notesCalendarLock.Unlock()
+
+	objectID, err := parseNotesCalendarObjectPath(objectPath)
+	if err != nil {
+		return
+	}
+
+	if avID, keyID, rowID, ok := parseAttrViewCalendarObjectID(objectID); ok {
+		_, err = UpdateAttributeViewCell(nil, avID, keyID, rowID, map[string]interface{}{"date": &av.ValueDate{}})
+		if err != nil {
+			return
+		}
+		ReloadAttrView(avID)
+		return
+	}
+
+	block := sql.GetBlock(objectID)
+	if nil == block {
+		return ErrorCalDavCalendarObjectNotFound
+	}
+	if "" == getBlockIALAttr(block, BlockReminderAttrName) {
+		return ErrorCalDavNotesCalendarReadOnly
+	}
+	return SetBlockAttrs(objectID, map[string]string{BlockReminderAttrName: ""})
+}
+
+func putBlockCalendarObject(id string, comp *ical.Component) (err error) {
+	block := sql.GetBlock(id)
+	if nil == block {
+		return ErrorCalDavNotesCalendarReadOnly
+	}
+
+	// 客户端没有传日期时不修改日程时间
+	timeProp := comp.Props.Get(ical.PropDue)
+	if nil == timeProp {
+		timeProp = comp.Props.Get(ical.PropDateTimeStart)
+	}
+	if nil != timeProp {
+		newTime, timeErr := timeProp.DateTime(time.Local)
+		if nil != timeErr {
+			return timeErr
+		}
+
+		if oldTime, _ := getBlockCalendarTime(block); !newTime.IsZero() && !newTime.Equal(oldTime) {
+			if "" != getBlockIALAttr(block, BlockReminderAttrName) {
+				// 改期写回提醒属性，仅在本地保存，不会同步到云端提醒
+				err = SetBlockAttrs(id, map[string]string{BlockReminderAttrName: newTime.In(time.Local).Format(blockReminderTimeLayout)})
+			} else {
+				// 日期在任务列表项内容中时改期写回内容
+				isDate := ical.ValueDate == timeProp.ValueType() || 8 == len(timeProp.Value)
+				err = setTaskListItemDate(id, newTime, isDate)
+			}
+			if err != nil {
+				return
+			}
+		}
+	}
+
+	if !isTaskBlock(block) || ical.CompToDo != comp.Name {
+		return
+	}
+
+	status, _ := comp.Props.Text(ical.PropStatus)
+	checked := "COMPLETED" == strings.ToUpper(status) || nil != comp.Props.Get(ical.PropCompleted)
+	if checked != isTaskChecked(block) {
+		err = setTaskListItemChecked(id, checked)
+	}
+	return
+}
+
+func putAttrViewCalendarObject(avID, keyID, rowID string, comp *ical.Component) (err error) {
+	attrView, err := av.ParseAttributeView(avID)
+	if nil != err || nil == attrView {
+		return ErrorCalDavCalendarObjectNotFound
+	}
+
+	key, _ := attrView.GetKey(keyID)
+	if nil == key || !isCalendarDateKey(key) {
+		return ErrorCalDavCalendarObjectNotFound
+	}
+
+	startProp := comp.Props.Get(ical.PropDateTimeStart)
+	if nil == startProp {
+		startProp = comp.Props.Get(ical.PropDue)
+	}
+	if nil == startProp {
+		return ErrorCalDavCalendarObjectPathInvalid
+	}
+
+	start, err := startProp.DateTime(time.Local)
+	if err != nil {
+		return
+	}
+	end, err := comp.Props.DateTime(ical.PropDateTimeEnd, time.Local)
+	if err != nil {
+		return
+	}
+
+	date := &av.ValueDate{Content: start.UnixMilli(), IsNotEmpty: true}
+	date.IsNotTime = ical.ValueDate == startProp.ValueType() || 8 == len(startProp.Value)
+	if date.IsNotTime && !end.IsZero() {
+		// 全天日程的结束日期不包含在日程内
+		end = end.AddDate(0, 0, -1)
+	}
+	if !end.IsZero() && end.After(start) {
+		date.HasEndDate = true
+		date.Content2 = end.UnixMilli()
+		date.IsNotEmpty2 = true
+	}
+
+	if _, err = UpdateAttributeViewCell(nil, avID, keyID, rowID, map[string]interface{}{"date": date}); err != nil {
+		return
+	}
+	ReloadAttrView(avID)
+	return
+}
+
+func setTaskListItemChecked(id string, checked bool) (err error) {
+	FlushTxQueue()
+
+	tree, err := LoadTreeByBlockID(id)
+	if err != nil {
+		return
+	}
+
+	node := treenode.GetNodeInTree(tree, id)
+	if nil == node || ast.NodeListItem != node.Type || nil == node.FirstChild || nil == node.FirstChild.FirstChild {
+		return ErrorCalDavCalendarObjectNotFound
+	}
+
+	marker := node.FirstChild.FirstChild
+	if ast.NodeTaskListItemMarker != marker.Type {
+		return ErrorCalDavCalendarObjectNotFound
+	}
+
+	marker.TaskListItemChecked = checked
+	if err = indexWriteTreeUpsertQueue(tree); err != nil {
+		return
+	}
+	IncSync()
+	refreshProtyle(tree.ID)
+	return
+}
+
+// setTaskListItemDate 将任务列表项内容中的第一个日期修改为 t，isDate 为 true 时不带时间。
+func setTaskListItemDate(id string, t time.Time, isDate bool) (err error) {
+	FlushTxQueue()
+
+	tree, err := LoadTreeByBlockID(id)
+	if err != nil {
+		return
+	}
+
+	node := treenode.GetNodeInTree(tree, id)
+	if nil == node || ast.NodeListItem != node.Type {
+		return ErrorCalDavCalendarObjectNotFound
+	}
+
+	layout := "2006-01-02 15:04"
+	if isDate {
+		layout = "2006-01-02"
+	}
+	replaced := false
+	ast.Walk(node, func(n *ast.Node, entering bool) ast.WalkStatus {
+		if !entering || ast.NodeText != n.Type {
+			return ast.WalkContinue
+		}
+
+		loc := taskDateRegexp.FindIndex(n.Tokens)
+		if nil == loc {
+			return ast.WalkContinue
+		}
+
+		tokens := append([]byte{}, n.Tokens[:loc[0]]...)
+		tokens = append(tokens, t.In(time.Local).Format(layout)...)
+		n.Tokens = append(tokens, n.Tokens[loc[1]:]...)
+		replaced = true
+		return ast.WalkStop
+	})
+	if !replaced {
+		return ErrorCalDavNotesCalendarReadOnly
+	}
+
+	if err = indexWriteTreeUpsertQueue(tree); err != nil {
+		return
+	}
+	IncSync()
+	refreshProtyle(tree.ID)
+	return
+}
+
+func newBlockCalendarObject(block *sql.Block) (ret *caldav.CalendarObject) {
+	if nil == block {
+		return
+	}
+
+	t, isDate := getBlockCalendarTime(block)
+	if t.IsZero() {
+		return
+	}
+
+	updated, _ := time.ParseInLocation(blockReminderTimeLayout, block.Updated, time.Local)
+	summary := gulu.Str.SubStr(strings.TrimSpace(block.Content), 128)
+	if "" == summary {
+		summary = block.HPath
+	}
+
+	var comp *ical.Component
+	if isTaskBlock(block) {
+		comp = ical.NewComponent(ical.CompToDo)
+		setCalendarTimeProp(comp, ical.PropDue, t, isDate)
+		if isTaskChecked(block) {
+			comp.Props.SetText(ical.PropStatus, "COMPLETED")
+			comp.Props.SetDateTime(ical.PropCompleted, updated.UTC())
+		} else {
+			comp.Props.SetText(ical.PropStatus, "NEEDS-ACTION")
+		}
+	} else {
+		comp = ical.NewComponent(ical.CompEvent)
+		setCalendarTimeProp(comp, ical.PropDateTimeStart, t, isDate)
+		if isDate {
+			setCalendarTimeProp(comp, ical.PropDateTimeEnd, t.AddDate(0, 0, 1), true)
+		} else {
+			setCalendarTimeProp(comp, ical.PropDateTimeEnd, t, false)
+		}
+		comp.Children = append(comp.Children, newCalendarAlarm(summary))
+	}
+	comp.Props.SetText(ical.PropUID, block.ID)
+	comp.Props.SetText(ical.PropSummary, summary)
+	comp.Props.SetText(ical.PropDescription, block.HPath)
+	comp.Props.SetText(ical.PropURL, "siyuan://blocks/"+block.ID)
+	comp.Props.SetDateTime(ical.PropDateTimeStamp, updated.UTC())
+	comp.Props.SetDateTime(ical.PropLastModified, updated.UTC())
+	return newNotesCalendarObject(block.ID, comp, updated)
+}
+
+func newAttrViewCalendarObject(attrView *av.AttributeView, key *av.Key, value *av.Value) (ret *caldav.CalendarObject) {
+	if nil == value || "" == value.BlockID || nil == value.Date || !value.Date.IsNotEmpty {
+		return
+	}
+
+	summary := ""
+	if blockKey := attrView.GetBlockKey(); nil != blockKey {
+		if blockValue := attrView.GetValue(blockKey.ID, value.BlockID); nil != blockValue {
+			summary = strings.TrimSpace(blockValue.String(false))
+		}
+	}
+	if "" == summary {
+		summary = key.Name
+	}
+
+	start := time.UnixMilli(value.Date.Content)
+	end := start
+	if value.Date.HasEndDate && value.Date.IsNotEmpty2 && value.Date.Content2 > value.Date.Content {
+		end = time.UnixMilli(value.Date.Content2)
+	}
+	if value.Date.IsNotTime {
+		end = end.AddDate(0, 0, 1)
+	}
+
+	objectID := strings.Join([]string{attrView.ID, key.ID, value.BlockID}, "_")
+	updated := time.UnixMilli(value.UpdatedAt)
+	comp := ical.NewComponent(ical.CompEvent)
+	comp.Props.SetText(ical.PropUID, objectID)
+	comp.Props.SetText(ical.PropSummary, summary)
+	comp.Props.SetText(ical.PropDescription, strings.TrimSpace(attrView.Name+" "+key.Name))
+	setCalendarTimeProp(comp, ical.PropDateTimeStart, start, value.Date.IsNotTime)
+	setCalendarTimeProp(comp, ical.PropDateTimeEnd, end, value.Date.IsNotTime)
+	comp.Props.SetDateTime(ical.PropDateTimeStamp, updated.UTC())
+	comp.Props.SetDateTime(ical.PropLastModified, updated.UTC())
+	return newNotesCalendarObject(objectID, comp, updated)
+}
+
+func newNotesCalendarObject(objectID string, comp *ical.Component, modTime time.Time) (ret *caldav.CalendarObject) {
+	calendar := ical.NewCalendar()
+	calendar.Props.SetText(ical.PropProductID, notesCalendarProductID)
+	calendar.Props.SetText(ical.PropVersion, "2.0")
+	calendar.Children = append(calendar.Children, comp)
+
+	var buf bytes.Buffer
+	if err := ical.NewEncoder(&buf).Encode(calendar); err != nil {
+		logging.LogErrorf("encode calendar object [%s] failed: %s", objectID, err)
+		return
+	}
+
+	hash := fnv.New64a()
+	hash.Write(buf.Bytes())
+	ret = &caldav.CalendarObject{
+		Path:          PathJoinWithSlash(CalDavNotesCalendarPath, objectID+ICalendarFileExt),
+		ModTime:       modTime,
+		ContentLength: int64(buf.Len()),
+		ETag:          fmt.Sprintf("%x", hash.Sum64()),
+		Data:          calendar,
+	}
+	return
+}
+
+func newCalendarAlarm(description string) (ret *ical.Component) {
+	ret = ical.NewComponent(ical.CompAlarm)
+	ret.Props.SetText(ical.PropAction, "DISPLAY")
+	ret.Props.SetText(ical.PropDescription, description)
+	trigger := ical.NewProp(ical.PropTrigger)
+	trigger.SetDuration(0)
+	ret.Props.Set(trigger)
+	return
+}
+
+func setCalendarTimeProp(comp *ical.Component, name string, t time.Time, isDate bool) {
+	if isDate {
+		comp.Props.SetDate(name, t)
+		return
+	}
+	comp.Props.SetDateTime(name, t.UTC())
+}
+
+// getBlockCalendarTime 获取块的日程时间，提醒属性优先，任务列表项其次使用内容中的日期。
+func getBlockCalendarTime(block *sql.Block) (ret time.Time, isDate bool) {
+	if reminder := getBlockIALAttr(block, BlockReminderAttrName); "" != reminder {
+		if t, err := time.ParseInLocation(blockReminderTimeLayout, reminder, time.Local); nil == err {
+			return t, false
+		}
+	}
+
+	if !isTaskBlock(block) {
+		return
+	}
+
+	groups := taskDateRegexp.FindStringSubmatch(block.Content)
+	if nil == groups {
+		return
+	}
+	if "" != groups[2] {
+		ret, _ = time.ParseInLocation("2006-01-02 15:04", groups[1]+" "+groups[2], time.Local)
+		return
+	}
+	ret, _ = time.ParseInLocation("2006-01-02", groups[1], time.Local)
+	isDate = true
+	return
+}
+
+func getBlockIALAttr(block *sql.Block, name string) string {
+	return parse.IAL2Map(parse.Tokens2IAL([]byte(block.IAL)))[name]
+}
+
+func isTaskBlock(block *sql.Block) bool {
+	return "i" == block.Type && "t" == block.SubType
+}
+
+func isTaskChecked(block *sql.Block) bool {
+	return taskCheckedRegexp.MatchString(block.Markdown)
+}
+
+func isCalendarDateKey(key *av.Key) bool {
+	return av.KeyTypeDate == key.Type && nil != key.Date && key.Date.Calendar
+}
+
+func parseNotesCalendarObjectPath(objectPath string) (objectID string, err error) {
+	calendarPath, objectID, err := ParseCalendarObjectPath(objectPath)
+	if err != nil {
+		return
+	}
+	if CalDavNotesCalendarPath != calendarPath {
+		err = ErrorCalDavCalendarPathInvalid
+		return
+	}
+
+	objectID = strings.TrimSuffix(path.Base(objectID), ICalendarFileExt)
+	if _, _, _, ok := parseAttrViewCalendarObjectID(objectID); !ok && !ast.IsNodeIDPattern(objectID) {
+		err = ErrorCalDavCalendarObjectNotFound
+	}
+	return
+}
+
+// parseAttrViewCalendarObjectID 解析数据库日期字段生成的日历对象 ID，格式为 {avID}_{keyID}_{rowID}。
+func parseAttrViewCalendarObjectID(objectID string) (avID, keyID, rowID string, ok bool) {
+	parts := strings.Split(objectID, "_")
+	if 3 != len(parts) {
+		return
+	}
+
+	for _, part := range parts {
+		if !ast.IsNodeIDPattern(part) {
+			return
+		}
+	}
+	return parts[0], parts[1], parts[2], true
+}
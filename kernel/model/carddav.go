@@ -801,6 +801,7 @@ func (b *CardDavBackend) ListAddressBooks(ctx context.Context) (addressBooks []c
 	}
 
 	addressBooks, err = contacts.ListAddressBooks()
+	addressBooks = append(addressBooks, listAttrViewAddressBooks()...)
 	// logging.LogDebugf("CardDAV ListAddressBooks <- addressBooks: %#v, err: %s", addressBooks, err)
 	return
 }
@@ -808,6 +809,10 @@ func (b *CardDavBackend) ListAddressBooks(ctx context.Context) (addressBooks []c
 func (b *CardDavBackend) GetAddressBook(ctx context.Context, bookPath string) (addressBook *carddav.AddressBook, err error) {
 	// logging.LogDebugf("CardDAV GetAddressBook -> bookPath: %s", bookPath)
 	bookPath = PathCleanWithSlash(bookPath)
+	if IsAttrViewAddressBookPath(bookPath) {
+		addressBook, err = getAttrViewAddressBook(bookPath)
+		return
+	}
 
 	if err = contacts.Load(); err != nil {
 		return
@@ -821,6 +826,10 @@ func (b *CardDavBackend) GetAddressBook(ctx context.Context, bookPath string) (a
 func (b *CardDavBackend) CreateAddressBook(ctx context.Context, addressBook *carddav.AddressBook) (err error) {
 	// logging.LogDebugf("CardDAV CreateAddressBook -> addressBook: %#v", addressBook)
 	addressBook.Path = PathCleanWithSlash(addressBook.Path)
+	if IsAttrViewAddressBookPath(addressBook.Path) {
+		err = ErrorCardDavAttrViewBookReadOnly
+		return
+	}
 
 	if err = contacts.Load(); err != nil {
 		return
@@ -834,6 +843,10 @@ func (b *CardDavBackend) CreateAddressBook(ctx context.Context, addressBook *car
 func (b *CardDavBackend) DeleteAddressBook(ctx context.Context, bookPath string) (err error) {
 	// logging.LogDebugf("CardDAV DeleteAddressBook -> bookPath: %s", bookPath)
 	bookPath = PathCleanWithSlash(bookPath)
+	if IsAttrViewAddressBookPath(bookPath) {
+		err = ErrorCardDavAttrViewBookReadOnly
+		return
+	}
 
 	if err = contacts.Load(); err != nil {
 		return
@@ -847,6 +860,12 @@ func (b *CardDavBackend) DeleteAddressBook(ctx context.Context, bookPath string)
 func (b *CardDavBackend) GetAddressObject(ctx context.Context, addressPath string, req *carddav.AddressDataRequest) (addressObject *carddav.AddressObject, err error) {
 	// logging.LogDebugf("CardDAV GetAddressObject -> addressPath: %s, req: %#v", addressPath, req)
 	addressPath = PathCleanWithSlash(addressPath)
+	if IsAttrViewAddressBookPath(addressPath) {
+		if addressObject, err = getAttrViewAddressObject(addressPath); nil == err {
+			addressObject = AddressPropsFilter(addressObject, req)
+		}
+		return
+	}
 
 	if err = contacts.Load(); err != nil {
 		return
@@ -860,6 +879,10 @@ func (b *CardDavBackend) GetAddressObject(ctx context.Context, addressPath strin
 func (b *CardDavBackend) ListAddressObjects(ctx context.Context, bookPath string, req *carddav.AddressDataRequest) (addressObjects []carddav.AddressObject, err error) {
 	// logging.LogDebugf("CardDAV ListAddressObjects -> bookPath: %s, req: %#v", bookPath, req)
 	bookPath = PathCleanWithSlash(bookPath)
+	if IsAttrViewAddressBookPath(bookPath) {
+		addressObjects, err = listAttrViewAddressObjects(bookPath)
+		return
+	}
 
 	if err = contacts.Load(); err != nil {
 		return
@@ -873,12 +896,31 @@ func (b *CardDavBackend) ListAddressObjects(ctx context.Context, bookPath string
 func (b *CardDavBackend) QueryAddressObjects(ctx context.Context, urlPath string, query *carddav.AddressBookQuery) (addressObjects []carddav.AddressObject, err error) {
 	// logging.LogDebugf("CardDAV QueryAddressObjects -> urlPath: %s, query: %#v", urlPath, query)
 	urlPath = PathCleanWithSlash(urlPath)
+	if IsAttrViewAddressBookPath(urlPath) {
+		var avAddressObjects []carddav.AddressObject
+		if cardDavPathDepth_AddressBook == GetCardDavPathDepth(urlPath) {
+			avAddressObjects, err = listAttrViewAddressObjects(urlPath)
+		} else if addressObject, getErr := getAttrViewAddressObject(urlPath); nil == getErr {
+			avAddressObjects = append(avAddressObjects, *addressObject)
+		}
+		if err != nil {
+			return
+		}
+		addressObjects, err = carddav.Filter(query, avAddressObjects)
+		return
+	}
 
 	if err = contacts.Load(); err != nil {
 		return
 	}
 
 	addressObjects, err = contacts.QueryAddressObjects(urlPath, query)
+	if nil == err && cardDavPathDepth_HomeSet >= GetCardDavPathDepth(urlPath) {
+		var avAddressObjects []carddav.AddressObject
+		if avAddressObjects, err = carddav.Filter(query, listAllAttrViewAddressObjects()); nil == err {
+			addressObjects = append(addressObjects, avAddressObjects...)
+		}
+	}
 	// logging.LogDebugf("CardDAV QueryAddressObjects <- addressObjects: %#v, err: %s", addressObjects, err)
 	return
 }
@@ -886,6 +928,10 @@ func (b *CardDavBackend) QueryAddressObjects(ctx context.Context, urlPath string
 func (b *CardDavBackend) PutAddressObject(ctx context.Context, addressPath string, card vcard.Card, opts *carddav.PutAddressObjectOptions) (addressObject *carddav.AddressObject, err error) {
 	// logging.LogDebugf("CardDAV PutAddressObject -> addressPath: %s, card: %#v, opts: %#v", addressPath, card, opts)
 	addressPath = PathCleanWithSlash(addressPath)
+	if IsAttrViewAddressBookPath(addressPath) {
+		addressObject, err = putAttrViewAddressObject(addressPath, card)
+		return
+	}
 
 	if err = contacts.Load(); err != nil {
 		return
@@ -899,6 +945,10 @@ func (b *CardDavBackend) PutAddressObject(ctx context.Context, addressPath strin
 func (b *CardDavBackend) DeleteAddressObject(ctx context.Context, addressPath string) (err error) {
 	// logging.LogDebugf("CardDAV DeleteAddressObject -> addressPath: %s", addressPath)
 	addressPath = PathCleanWithSlash(addressPath)
+	if IsAttrViewAddressBookPath(addressPath) {
+		err = deleteAttrViewAddressObject(addressPath)
+		return
+	}
 
 	if err = contacts.Load(); err != nil {
 		return
@@ -0,0 +1,414 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package model
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/88250/lute/ast"
+	"github.com/emersion/go-vcard"
+	"github.com/emersion/go-webdav/carddav"
+	"github.com/siyuan-note/logging"
+	"github.com/siyuan-note/siyuan/kernel/av"
+)
+
+// 开启了通讯录的数据库作为 CardDAV 通讯录提供，路径为 /carddav/principals/main/contacts/{avID}：
+//
+//   - 每一行对应一张 vCard，行 ID 即联系人 ID
+//   - 主键映射为 FN，邮箱、电话和链接字段分别映射为 EMAIL、TEL 和 URL
+//   - 文本字段按字段名映射为 ORG、TITLE、ROLE、NICKNAME 和 NOTE，其他文本字段不提供
+//
+// 客户端的修改通过数据库事务写回，同一 vCard 属性对应多个字段时按 X-SIYUAN-KEY 参数或者字段顺序对应。
+
+const attrViewAddressKeyParam = "X-SIYUAN-KEY"
+
+var (
+	attrViewAddressBookLock = sync.Mutex{}
+
+	// 文本字段名（不区分大小写）到 vCard 属性的映射
+	attrViewAddressTextFields = map[string]string{
+		"org":          vcard.FieldOrganization,
+		"organization": vcard.FieldOrganization,
+		"company":      vcard.FieldOrganization,
+		"公司":           vcard.FieldOrganization,
+		"单位":           vcard.FieldOrganization,
+		"title":        vcard.FieldTitle,
+		"职位":           vcard.FieldTitle,
+		"职务":           vcard.FieldTitle,
+		"role":         vcard.FieldRole,
+		"角色":           vcard.FieldRole,
+		"nickname":     vcard.FieldNickname,
+		"昵称":           vcard.FieldNickname,
+		"note":         vcard.FieldNote,
+		"notes":        vcard.FieldNote,
+		"备注":           vcard.FieldNote,
+	}
+
+	ErrorCardDavAttrViewBookReadOnly = errors.New("CardDAV: address book of database can not be created or deleted")
+)
+
+// IsAttrViewAddressBookPath 判断通讯录路径或者联系人路径是否位于开启了通讯录的数据库下。
+func IsAttrViewAddressBookPath(p string) bool {
+	_, ok := parseAttrViewAddressBookPath(p)
+	if !ok {
+		bookPath, _, err := ParseAddressPath(p)
+		if err != nil {
+			return false
+		}
+		_, ok = parseAttrViewAddressBookPath(PathCleanWithSlash(bookPath))
+	}
+	return ok
+}
+
+func listAttrViewAddressBooks() (ret []carddav.AddressBook) {
+	attrViews := getAttributeViews(func(attrView *av.AttributeView) bool { return attrView.AddressBook })
+	for _, attrView := range attrViews {
+		ret = append(ret, *newAttrViewAddressBook(attrView))
+	}
+	return
+}
+
+func getAttrViewAddressBook(bookPath string) (ret *carddav.AddressBook, err error) {
+	attrView, ok := parseAttrViewAddressBookPath(bookPath)
+	if !ok {
+		err = ErrorCardDavBookNotFound
+		return
+	}
+	ret = newAttrViewAddressBook(attrView)
+	return
+}
+
+func listAttrViewAddressObjects(bookPath string) (ret []carddav.AddressObject, err error) {
+	attrView, ok := parseAttrViewAddressBookPath(bookPath)
+	if !ok {
+		err = ErrorCardDavBookNotFound
+		return
+	}
+
+	ret = []carddav.AddressObject{}
+	blockKeyValues := attrView.GetBlockKeyValues()
+	if nil == blockKeyValues {
+		return
+	}
+	for _, blockValue := range blockKeyValues.Values {
+		if object := newAttrViewAddressObject(attrView, blockValue.BlockID); nil != object {
+			ret = append(ret, *object)
+		}
+	}
+	return
+}
+
+// listAllAttrViewAddressObjects 获取所有数据库通讯录的联系人，用于查询通讯录主目录。
+func listAllAttrViewAddressObjects() (ret []carddav.AddressObject) {
+	for _, addressBook := range listAttrViewAddressBooks() {
+		objects, _ := listAttrViewAddressObjects(addressBook.Path)
+		ret = append(ret, objects...)
+	}
+	return
+}
+
+func getAttrViewAddressObject(addressPath string) (ret *carddav.AddressObject, err error) {
+	attrView, rowID, err := parseAttrViewAddressPath(addressPath)
+	if err != nil {
+		return
+	}
+
+	if ret = newAttrViewAddressObject(attrView, rowID); nil == ret {
+		err = ErrorCardDavAddressNotFound
+	}
+	return
+}
+
+// putAttrViewAddressObject 通过数据库事务写回联系人，联系人不存在时新建游离行。
+func putAttrViewAddressObject(addressPath string, card vcard.Card) (ret *carddav.AddressObject, err error) {
+	attrViewAddressBookLock.Lock()
+	defer attrViewAddressBookLock.Unlock()
+
+	attrView, rowID, err := parseAttrViewAddressPath(addressPath)
+	if err != nil {
+		return
+	}
+
+	blockKey := attrView.GetBlockKey()
+	if nil == blockKey {
+		err = ErrorCardDavBookNotFound
+		return
+	}
+
+	fn := strings.TrimSpace(card.PreferredValue(vcard.FieldFormattedName))
+	if name := card.Name(); "" == fn && nil != name {
+		fn = strings.TrimSpace(strings.Join([]string{name.GivenName, name.AdditionalName, name.FamilyName}, " "))
+	}
+
+	var ops []*Operation
+	blockValue := attrView.GetValue(blockKey.ID, rowID)
+	if nil == blockValue {
+		if !ast.IsNodeIDPattern(rowID) {
+			// 客户端生成的联系人 ID 不是块 ID 格式时使用新的行 ID，客户端通过响应中的 Location 获取新路径
+			rowID = ast.NewNodeID()
+		}
+		ops = append(ops, &Operation{
+			Action:              "insertAttrViewBlock",
+			AvID:                attrView.ID,
+			Srcs:                []map[string]interface{}{{"id": rowID, "isDetached": true, "content": fn}},
+			IgnoreFillFilterVal: true,
+		})
+	} else if blockValue.IsDetached && nil != blockValue.Block && fn != blockValue.Block.Content {
+		// 绑定块的行主键是块内容，不通过 vCard 修改
+		ops = append(ops, &Operation{
+			Action: "updateAttrViewCell",
+			AvID:   attrView.ID,
+			KeyID:  blockKey.ID,
+			RowID:  rowID,
+			Data:   map[string]interface{}{"isDetached": true, "block": map[string]interface{}{"id": rowID, "content": fn}},
+		})
+	}
+
+	keys := map[string][]*av.Key{}
+	var fields []string
+	for _, kv := range attrView.KeyValues {
+		field := getAttrViewAddressField(kv.Key)
+		if "" == field || vcard.FieldFormattedName == field {
+			continue
+		}
+		if _, ok := keys[field]; !ok {
+			fields = append(fields, field)
+		}
+		keys[field] = append(keys[field], kv.Key)
+	}
+
+	for _, field := range fields {
+		contents := matchAttrViewAddressFields(keys[field], card[field])
+		for _, key := range keys[field] {
+			oldContent := ""
+			if value := attrView.GetValue(key.ID, rowID); nil != value {
+				oldContent = strings.TrimSpace(value.String(false))
+			}
+			if contents[key.ID] == oldContent {
+				continue
+			}
+
+			ops = append(ops, &Operation{
+				Action: "updateAttrViewCell",
+				AvID:   attrView.ID,
+				KeyID:  key.ID,
+				RowID:  rowID,
+				Data:   map[string]interface{}{string(key.Type): map[string]interface{}{"content": contents[key.ID]}},
+			})
+		}
+	}
+
+	if 0 < len(ops) {
+		PerformTransactions(&[]*Transaction{{DoOperations: ops}})
+		FlushTxQueue()
+		ReloadAttrView(attrView.ID)
+	}
+
+	if attrView, err = av.ParseAttributeView(attrView.ID); err != nil {
+		return
+	}
+	if ret = newAttrViewAddressObject(attrView, rowID); nil == ret {
+		err = ErrorCardDavAddressNotFound
+	}
+	return
+}
+
+// deleteAttrViewAddressObject 通过数据库事务删除联系人对应的行，绑定的块不会被删除。
+func deleteAttrViewAddressObject(addressPath string) (err error) {
+	attrViewAddressBookLock.Lock()
+	defer attrViewAddressBookLock.Unlock()
+
+	attrView, rowID, err := parseAttrViewAddressPath(addressPath)
+	if err != nil {
+		return
+	}
+
+	blockKey := attrView.GetBlockKey()
+	if nil == blockKey || nil == attrView.GetValue(blockKey.ID, rowID) {
+		return ErrorCardDavAddressNotFound
+	}
+
+	PerformTransactions(&[]*Transaction{{DoOperations: []*Operation{{Action: "removeAttrViewBlock", AvID: attrView.ID, SrcIDs: []string{rowID}}}}})
+	FlushTxQueue()
+	ReloadAttrView(attrView.ID)
+	return
+}
+
+// matchAttrViewAddressFields 将同一 vCard 属性的多个值对应到字段上，优先使用 X-SIYUAN-KEY 参数，其余按顺序对应。
+func matchAttrViewAddressFields(keys []*av.Key, fields []*vcard.Field) (ret map[string]string) {
+	ret = map[string]string{}
+	var rest []*vcard.Field
+	for _, field := range fields {
+		keyID := field.Params.Get(attrViewAddressKeyParam)
+		if _, ok := ret[keyID]; ok || "" == keyID {
+			rest = append(rest, field)
+			continue
+		}
+
+		matched := false
+		for _, key := range keys {
+			if key.ID == keyID {
+				ret[keyID] = strings.TrimSpace(field.Value)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			rest = append(rest, field)
+		}
+	}
+
+	for _, key := range keys {
+		if _, ok := ret[key.ID]; ok {
+			continue
+		}
+		if 0 < len(rest) {
+			ret[key.ID] = strings.TrimSpace(rest[0].Value)
+			rest = rest[1:]
+		}
+	}
+	return
+}
+
+func newAttrViewAddressBook(attrView *av.AttributeView) *carddav.AddressBook {
+	return &carddav.AddressBook{
+		Path:                 PathJoinWithSlash(CardDavHomeSetPath, attrView.ID),
+		Name:                 getAttrViewName(attrView),
+		Description:          "SiYuan database",
+		MaxResourceSize:      addressBookMaxResourceSize,
+		SupportedAddressData: addressBookSupportedAddressData,
+	}
+}
+
+func newAttrViewAddressObject(attrView *av.AttributeView, rowID string) (ret *carddav.AddressObject) {
+	if nil == attrView {
+		return
+	}
+
+	blockKey := attrView.GetBlockKey()
+	if nil == blockKey || nil == attrView.GetValue(blockKey.ID, rowID) {
+		return
+	}
+
+	card := vcard.Card{}
+	card.SetValue(vcard.FieldVersion, "3.0")
+	card.SetValue(vcard.FieldUID, rowID)
+
+	fn := ""
+	var updated int64
+	for _, kv := range attrView.KeyValues {
+		field := getAttrViewAddressField(kv.Key)
+		if "" == field {
+			continue
+		}
+
+		value := attrView.GetValue(kv.Key.ID, rowID)
+		if nil == value {
+			continue
+		}
+		updated = max(updated, value.UpdatedAt, value.CreatedAt)
+
+		content := strings.TrimSpace(value.String(false))
+		if "" == content {
+			continue
+		}
+		if vcard.FieldFormattedName == field {
+			fn = content
+			continue
+		}
+		card.Add(field, &vcard.Field{Value: content, Params: vcard.Params{attrViewAddressKeyParam: []string{kv.Key.ID}}})
+	}
+	if "" == fn {
+		fn = rowID
+	}
+	card.SetValue(vcard.FieldFormattedName, fn)
+	card.SetName(&vcard.Name{GivenName: fn})
+	modTime := time.UnixMilli(updated)
+	card.SetRevision(modTime)
+
+	var buf bytes.Buffer
+	if err := vcard.NewEncoder(&buf).Encode(card); err != nil {
+		logging.LogErrorf("encode vCard [%s] failed: %s", rowID, err)
+		return
+	}
+
+	hash := fnv.New64a()
+	hash.Write(buf.Bytes())
+	ret = &carddav.AddressObject{
+		Path:          PathJoinWithSlash(CardDavHomeSetPath, attrView.ID, rowID+VCardFileExt),
+		ModTime:       modTime,
+		ContentLength: int64(buf.Len()),
+		ETag:          fmt.Sprintf("%x", hash.Sum64()),
+		Card:          card,
+	}
+	return
+}
+
+func getAttrViewAddressField(key *av.Key) string {
+	switch key.Type {
+	case av.KeyTypeBlock:
+		return vcard.FieldFormattedName
+	case av.KeyTypeEmail:
+		return vcard.FieldEmail
+	case av.KeyTypePhone:
+		return vcard.FieldTelephone
+	case av.KeyTypeURL:
+		return vcard.FieldURL
+	case av.KeyTypeText:
+		return attrViewAddressTextFields[strings.ToLower(strings.TrimSpace(key.Name))]
+	}
+	return ""
+}
+
+func parseAttrViewAddressBookPath(bookPath string) (ret *av.AttributeView, ok bool) {
+	if GetCardDavPathDepth(bookPath) != cardDavPathDepth_AddressBook {
+		return
+	}
+
+	avID := path.Base(bookPath)
+	if !ast.IsNodeIDPattern(avID) || !av.IsAttributeViewExist(avID) {
+		return
+	}
+
+	attrView, err := av.ParseAttributeView(avID)
+	if err != nil || nil == attrView || !attrView.AddressBook {
+		return
+	}
+	return attrView, true
+}
+
+func parseAttrViewAddressPath(addressPath string) (attrView *av.AttributeView, rowID string, err error) {
+	bookPath, addressID, err := ParseAddressPath(addressPath)
+	if err != nil {
+		return
+	}
+
+	attrView, ok := parseAttrViewAddressBookPath(PathCleanWithSlash(bookPath))
+	if !ok {
+		err = ErrorCardDavBookNotFound
+		return
+	}
+	rowID = strings.TrimSuffix(addressID, VCardFileExt)
+	return
+}
@@ -78,6 +78,10 @@ type AppConf struct {
 	Api            *conf.API        `json:"api"`            // API
 	Repo           *conf.Repo       `json:"repo"`           // 数据仓库
 	Publish        *conf.Publish    `json:"publish"`        // 发布服务
+	Members        []*conf.Member   `json:"members"`        // 用户帐号
+	DAV            *conf.DAV        `json:"dav"`            // WebDAV 服务
+	OIDC           *conf.OIDC       `json:"oidc"`           // OpenID Connect 登录
+	TOTP           *conf.TOTP       `json:"totp"`           // 两步验证
 	OpenHelp       bool             `json:"openHelp"`       // 启动后是否需要打开用户指南
 	ShowChangelog  bool             `json:"showChangelog"`  // 是否显示版本更新日志
 	CloudRegion    int              `json:"cloudRegion"`    // 云端区域，0：中国大陆，1：北美
@@ -368,6 +372,9 @@ func InitConf() {
 	if nil == Conf.Api {
 		Conf.Api = conf.NewAPI()
 	}
+	if nil == Conf.Api.Tokens {
+		Conf.Api.Tokens = []*conf.APIToken{}
+	}
 
 	if nil == Conf.Bazaar {
 		Conf.Bazaar = conf.NewBazaar()
@@ -376,6 +383,33 @@ func InitConf() {
 	if nil == Conf.Publish {
 		Conf.Publish = conf.NewPublish()
 	}
+	if nil == Conf.Members {
+		Conf.Members = []*conf.Member{}
+	}
+
+	if nil == Conf.DAV {
+		Conf.DAV = conf.NewDAV()
+	}
+	if nil == Conf.DAV.ReadOnlyNotebooks {
+		Conf.DAV.ReadOnlyNotebooks = []string{}
+	}
+
+	if nil == Conf.OIDC {
+		Conf.OIDC = conf.NewOIDC()
+	}
+	if nil == Conf.OIDC.Roles {
+		Conf.OIDC.Roles = []*conf.OIDCRoleRule{}
+	}
+
+	if nil == Conf.TOTP {
+		Conf.TOTP = conf.NewTOTP()
+	}
+	if nil == Conf.TOTP.RecoveryCodes {
+		Conf.TOTP.RecoveryCodes = []string{}
+	}
+	if nil == Conf.TOTP.Devices {
+		Conf.TOTP.Devices = []*conf.TOTPDevice{}
+	}
 	if Conf.OpenHelp && Conf.Publish.Enable {
 		Conf.OpenHelp = false
 	}
@@ -462,6 +496,15 @@ func InitConf() {
 	if 1 > Conf.AI.OpenAI.APIMaxContexts || 64 < Conf.AI.OpenAI.APIMaxContexts {
 		Conf.AI.OpenAI.APIMaxContexts = 7
 	}
+	if nil == Conf.AI.Embedding {
+		Conf.AI.Embedding = conf.NewEmbedding()
+	}
+	if "" == Conf.AI.Embedding.Provider {
+		Conf.AI.Embedding.Provider = "OpenAI"
+	}
+	if 1 > Conf.AI.Embedding.APITimeout {
+		Conf.AI.Embedding.APITimeout = 30
+	}
 
 	if "" != Conf.AI.OpenAI.APIKey {
 		logging.LogInfof("OpenAI API enabled\n"+
@@ -711,7 +754,10 @@ func (conf *AppConf) Save() {
 	Conf.m.Lock()
 	defer Conf.m.Unlock()
 
+	// 签发的 API token 会在认证时更新最近使用时间，序列化时需要持有 token 锁
+	apiTokenLock.Lock()
 	newData, _ := gulu.JSON.MarshalIndentJSON(Conf, "", "  ")
+	apiTokenLock.Unlock()
 	confPath := filepath.Join(util.ConfDir, "conf.json")
 	oldData, err := filelock.ReadFile(confPath)
 	if err != nil {
@@ -844,6 +890,9 @@ func InitBoxes() {
 			index(box.ID)
 		}
 	}
+	if !initialized || 1 > sql.CountAttributeViews() {
+		indexAttributeViews()
+	}
 
 	var dbSize string
 	if dbFile, err := os.Stat(util.DBPath); err == nil {
@@ -893,6 +942,18 @@ func GetMaskedConf() (ret *AppConf, err error) {
 	if "" != ret.AccessAuthCode {
 		ret.AccessAuthCode = MaskedAccessAuthCode
 	}
+	for _, member := range ret.Members {
+		member.Password = ""
+	}
+	for _, token := range ret.Api.Tokens {
+		token.Hash = ""
+	}
+	if nil != ret.OIDC && "" != ret.OIDC.ClientSecret {
+		ret.OIDC.ClientSecret = MaskedAccessAuthCode
+	}
+	if nil != ret.TOTP {
+		ret.TOTP = &conf.TOTP{Enable: ret.TOTP.Enable, RecoveryCodes: []string{}, Devices: []*conf.TOTPDevice{}}
+	}
 	return
 }
 
@@ -904,6 +965,9 @@ func HideConfSecret(c *AppConf) {
 	c.Flashcard = &conf.Flashcard{}
 	c.LocalIPs = []string{}
 	c.Publish = &conf.Publish{}
+	c.Members = []*conf.Member{}
+	c.OIDC = &conf.OIDC{}
+	c.TOTP = &conf.TOTP{}
 	c.Repo = &conf.Repo{}
 	c.Sync = &conf.Sync{}
 	c.System.AppDir = ""
@@ -0,0 +1,136 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/siyuan-note/httpclient"
+	"github.com/siyuan-note/logging"
+	"github.com/siyuan-note/siyuan/kernel/sql"
+	"github.com/siyuan-note/siyuan/kernel/util"
+)
+
+// InitEmbeddingProvider 根据配置设置语义搜索使用的向量生成服务。
+func InitEmbeddingProvider() {
+	embedding := Conf.AI.Embedding
+	if nil == embedding || !embedding.Enable {
+		sql.SetEmbeddingProvider(nil)
+		return
+	}
+
+	switch embedding.Provider {
+	case "HTTP":
+		if "" == embedding.URL {
+			logging.LogWarnf("embedding service URL is not set")
+			sql.SetEmbeddingProvider(nil)
+			return
+		}
+		sql.SetEmbeddingProvider(&httpEmbeddingProvider{model: embedding.Model, url: embedding.URL, timeout: embedding.APITimeout})
+	default:
+		if "" == Conf.AI.OpenAI.APIKey {
+			logging.LogWarnf("OpenAI API key is not set, semantic search is disabled")
+			sql.SetEmbeddingProvider(nil)
+			return
+		}
+		sql.SetEmbeddingProvider(&openAIEmbeddingProvider{model: embedding.Model, timeout: embedding.APITimeout})
+	}
+	logging.LogInfof("embedding enabled [provider=%s, model=%s]", embedding.Provider, embedding.Model)
+}
+
+// openAIEmbeddingProvider 使用 OpenAI 配置调用 /embeddings 接口。
+type openAIEmbeddingProvider struct {
+	model   string
+	timeout int
+}
+
+func (provider *openAIEmbeddingProvider) Model() string {
+	return provider.model
+}
+
+func (provider *openAIEmbeddingProvider) Embed(texts []string) (ret [][]float32, err error) {
+	client := util.NewOpenAIClient(Conf.AI.OpenAI.APIKey, Conf.AI.OpenAI.APIProxy, Conf.AI.OpenAI.APIBaseURL, Conf.AI.OpenAI.APIUserAgent, Conf.AI.OpenAI.APIVersion, Conf.AI.OpenAI.APIProvider)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(provider.timeout)*time.Second)
+	defer cancel()
+
+	resp, err := client.CreateEmbeddings(ctx, openai.EmbeddingRequestStrings{
+		Input: texts,
+		Model: openai.EmbeddingModel(provider.model),
+	})
+	if err != nil {
+		return
+	}
+
+	ret = make([][]float32, len(texts))
+	for _, data := range resp.Data {
+		if 0 > data.Index || len(ret) <= data.Index {
+			err = fmt.Errorf("invalid embedding index [%d]", data.Index)
+			return
+		}
+		ret[data.Index] = data.Embedding
+	}
+	return
+}
+
+// httpEmbeddingProvider 调用本地兼容 OpenAI /embeddings 请求和响应格式的服务。
+type httpEmbeddingProvider struct {
+	model   string
+	url     string
+	timeout int
+}
+
+func (provider *httpEmbeddingProvider) Model() string {
+	return provider.model
+}
+
+func (provider *httpEmbeddingProvider) Embed(texts []string) (ret [][]float32, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(provider.timeout)*time.Second)
+	defer cancel()
+
+	result := &struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}{}
+	resp, err := httpclient.NewBrowserRequest().
+		SetContext(ctx).
+		SetBody(map[string]interface{}{"model": provider.model, "input": texts}).
+		SetSuccessResult(result).
+		Post(provider.url)
+	if err != nil {
+		return
+	}
+	if !resp.IsSuccessState() {
+		err = errors.New("embedding service responded " + resp.Status)
+		return
+	}
+
+	ret = make([][]float32, len(texts))
+	for _, data := range result.Data {
+		if 0 > data.Index || len(ret) <= data.Index {
+			err = fmt.Errorf("invalid embedding index [%d]", data.Index)
+			return
+		}
+		ret[data.Index] = data.Embedding
+	}
+	return
+}
@@ -1481,15 +1481,17 @@ func moveDoc(fromBox *Box, fromPath string, toBox *Box, toPath string, luteEngin
 	return
 }
 
-func RemoveDoc(boxID, p string) {
+func RemoveDoc(boxID, p string) (err error) {
 	box := Conf.Box(boxID)
 	if nil == box {
-		return
+		return ErrBoxNotFound
 	}
 
 	FlushTxQueue()
 	luteEngine := util.NewLute()
-	removeDoc(box, p, luteEngine)
+	if err = removeDoc(box, p, luteEngine); nil != err {
+		return
+	}
 	IncSync()
 	return
 }
@@ -1508,9 +1510,9 @@ func RemoveDocs(paths []string) {
 	return
 }
 
-func removeDoc(box *Box, p string, luteEngine *lute.Lute) {
-	tree, _ := filesys.LoadTree(box.ID, p, luteEngine)
-	if nil == tree {
+func removeDoc(box *Box, p string, luteEngine *lute.Lute) (err error) {
+	tree, err := filesys.LoadTree(box.ID, p, luteEngine)
+	if nil != err {
 		return
 	}
 
@@ -1581,8 +1583,8 @@ func removeDoc(box *Box, p string, luteEngine *lute.Lute) {
 	box.removeSort(removeIDs)
 	RemoveRecentDoc(removeIDs)
 	if "/" != dir {
-		others, err := os.ReadDir(filepath.Join(util.DataDir, box.ID, dir))
-		if err == nil && 1 > len(others) {
+		others, readErr := os.ReadDir(filepath.Join(util.DataDir, box.ID, dir))
+		if nil == readErr && 1 > len(others) {
 			box.Remove(dir)
 		}
 	}
@@ -1595,6 +1597,7 @@ func removeDoc(box *Box, p string, luteEngine *lute.Lute) {
 
 	refreshParentDocInfo(tree)
 	task.AppendTask(task.DatabaseIndex, removeDoc0, tree, childrenDir)
+	return
 }
 
 func removeDoc0(tree *parse.Tree, childrenDir string) {
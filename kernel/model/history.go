@@ -27,6 +27,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/88250/gulu"
@@ -34,6 +35,7 @@ import (
 	"github.com/88250/lute/ast"
 	"github.com/88250/lute/parse"
 	"github.com/88250/lute/render"
+	"github.com/gin-gonic/gin"
 	"github.com/siyuan-note/eventbus"
 	"github.com/siyuan-note/filelock"
 	"github.com/siyuan-note/logging"
@@ -395,10 +397,11 @@ type History struct {
 }
 
 type HistoryItem struct {
-	Title    string `json:"title"`
-	Path     string `json:"path"`
-	Op       string `json:"op"`
-	Notebook string `json:"notebook"` // 仅用于文档历史
+	Title    string   `json:"title"`
+	Path     string   `json:"path"`
+	Op       string   `json:"op"`
+	Notebook string   `json:"notebook"`          // 仅用于文档历史
+	Editors  []string `json:"editors,omitempty"` // 修改过该文档的用户名，仅用于文档历史
 }
 
 const fileHistoryPageSize = 32
@@ -590,6 +593,18 @@ func (box *Box) generateDocHistory0() {
 		return
 	}
 
+	editors := map[string][]string{}
+	for _, file := range files {
+		if strings.HasSuffix(file, ".sy") {
+			rootID := strings.TrimSuffix(filepath.Base(file), ".sy")
+			if usernames := popDocEditors(rootID); 0 < len(usernames) {
+				editors[rootID] = usernames
+			}
+		}
+	}
+	// 先写入修改者，后面复制文件失败时不会丢失已经取出的修改者，索引历史时也能读取到
+	writeHistoryEditors(historyDir, editors)
+
 	luteEngine := util.NewLute()
 	for _, file := range files {
 		historyPath := filepath.Join(historyDir, box.ID, strings.TrimPrefix(file, filepath.Join(util.DataDir, box.ID)))
@@ -883,6 +898,7 @@ func fromSQLHistories(sqlHistories []*sql.History) (ret []*HistoryItem) {
 		return
 	}
 
+	editorsCache := map[string]map[string][]string{}
 	for _, sqlHistory := range sqlHistories {
 		item := &HistoryItem{
 			Title: sqlHistory.Title,
@@ -895,6 +911,13 @@ func fromSQLHistories(sqlHistories []*sql.History) (ret []*HistoryItem) {
 			parts := strings.Split(sqlHistory.Path, "/")
 			if 2 <= len(parts) {
 				item.Notebook = parts[1]
+
+				editors, ok := editorsCache[parts[0]]
+				if !ok {
+					editors = readHistoryEditors(filepath.Join(util.HistoryDir, parts[0]))
+					editorsCache[parts[0]] = editors
+				}
+				item.Editors = editors[sqlHistory.ID]
 			} else {
 				logging.LogWarnf("invalid doc history path [%s]", item.Path)
 			}
@@ -913,3 +936,97 @@ func subscribeSQLHistoryEvents() {
 		ReindexHistory()
 	})
 }
+
+var (
+	docEditors     = map[string][]string{} // 文档 ID 到自上次生成历史以来修改过该文档的用户名列表
+	docEditorsLock = sync.Mutex{}
+
+	historyEditorsLock = sync.Mutex{} // 同一秒内生成的多个笔记本的历史共用一个历史目录，写入修改者时需要合并
+)
+
+const historyEditorsFileName = "editors.json"
+
+// recordDocEditor 记录修改文档的用户，生成文档历史时写入历史目录。
+func recordDocEditor(rootID, username string) {
+	if "" == username {
+		return
+	}
+
+	docEditorsLock.Lock()
+	defer docEditorsLock.Unlock()
+	if !gulu.Str.Contains(username, docEditors[rootID]) {
+		docEditors[rootID] = append(docEditors[rootID], username)
+	}
+}
+
+// RecordDocEditors 记录当前请求的用户修改了这些文档，用于不通过事务 Username 记录的文档操作。
+func RecordDocEditors(c *gin.Context, rootIDs ...string) {
+	username := GetGinContextUsername(c)
+	for _, rootID := range rootIDs {
+		recordDocEditor(rootID, username)
+	}
+}
+
+// RecordBlockEditors 记录当前请求的用户修改了这些块所在的文档，不存在的块（比如游离行）忽略。
+func RecordBlockEditors(c *gin.Context, ids ...string) {
+	username := GetGinContextUsername(c)
+	for _, id := range ids {
+		if bt := treenode.GetBlockTree(id); nil != bt {
+			recordDocEditor(bt.RootID, username)
+		}
+	}
+}
+
+func popDocEditors(rootID string) (ret []string) {
+	docEditorsLock.Lock()
+	defer docEditorsLock.Unlock()
+	ret = docEditors[rootID]
+	delete(docEditors, rootID)
+	return
+}
+
+// writeHistoryEditors 将修改者合并写入历史目录下的 editors.json。
+func writeHistoryEditors(historyDir string, editors map[string][]string) {
+	if 1 > len(editors) {
+		return
+	}
+
+	historyEditorsLock.Lock()
+	defer historyEditorsLock.Unlock()
+
+	merged := readHistoryEditors(historyDir)
+	for rootID, usernames := range editors {
+		for _, username := range usernames {
+			if !gulu.Str.Contains(username, merged[rootID]) {
+				merged[rootID] = append(merged[rootID], username)
+			}
+		}
+	}
+
+	data, err := gulu.JSON.MarshalJSON(merged)
+	if err != nil {
+		logging.LogErrorf("marshal history editors failed: %s", err)
+		return
+	}
+	if err = gulu.File.WriteFileSafer(filepath.Join(historyDir, historyEditorsFileName), data, 0644); err != nil {
+		logging.LogErrorf("write history editors failed: %s", err)
+	}
+}
+
+func readHistoryEditors(historyDir string) (ret map[string][]string) {
+	ret = map[string][]string{}
+	p := filepath.Join(historyDir, historyEditorsFileName)
+	if !gulu.File.IsExist(p) {
+		return
+	}
+
+	data, err := os.ReadFile(p)
+	if err != nil {
+		logging.LogErrorf("read history editors [%s] failed: %s", p, err)
+		return
+	}
+	if err = gulu.JSON.UnmarshalJSON(data, &ret); err != nil {
+		logging.LogErrorf("unmarshal history editors [%s] failed: %s", p, err)
+	}
+	return
+}
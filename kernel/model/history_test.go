@@ -0,0 +1,38 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWriteHistoryEditors(t *testing.T) {
+	historyDir := t.TempDir()
+
+	// 同一个历史目录中依次写入两个笔记本的修改者
+	writeHistoryEditors(historyDir, map[string][]string{"20240101120000-aaaaaaa": {"alice"}})
+	writeHistoryEditors(historyDir, map[string][]string{"20240101120000-aaaaaaa": {"alice", "bob"}, "20240101120000-bbbbbbb": {"carol"}})
+
+	expected := map[string][]string{
+		"20240101120000-aaaaaaa": {"alice", "bob"},
+		"20240101120000-bbbbbbb": {"carol"},
+	}
+	if editors := readHistoryEditors(historyDir); !reflect.DeepEqual(expected, editors) {
+		t.Fatalf("unexpected editors %v", editors)
+	}
+}
@@ -20,6 +20,8 @@ import (
 	"bytes"
 	"fmt"
 	"io/fs"
+	"os"
+	"path"
 	"path/filepath"
 	"runtime"
 	"runtime/debug"
@@ -291,6 +293,43 @@ func IndexRefs() {
 	util.PushStatusBar(fmt.Sprintf(Conf.Language(55), i))
 }
 
+// indexAttributeViews 索引所有数据库的字段和值，以便通过 SQL 查询数据库。
+func indexAttributeViews() {
+	avDir := filepath.Join(util.DataDir, "storage", "av")
+	entries, err := os.ReadDir(avDir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logging.LogErrorf("read directory [%s] failed: %s", avDir, err)
+		}
+		return
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+
+		id := strings.TrimSuffix(entry.Name(), ".json")
+		if !ast.IsNodeIDPattern(id) {
+			continue
+		}
+		sql.IndexAttributeViewQueue(id)
+	}
+}
+
+// indexAttributeViewFile 数据库文件 /storage/av/{id}.json 变更后重新索引该数据库。
+func indexAttributeViewFile(p string) {
+	if !strings.HasPrefix(p, "/storage/av/") || !strings.HasSuffix(p, ".json") {
+		return
+	}
+
+	id := strings.TrimSuffix(path.Base(p), ".json")
+	if !ast.IsNodeIDPattern(id) {
+		return
+	}
+	sql.IndexAttributeViewQueue(id)
+}
+
 var indexEmbedBlockLock = sync.Mutex{}
 
 // IndexEmbedBlockJob 嵌入块支持搜索 https://github.com/siyuan-note/siyuan/issues/7112
@@ -0,0 +1,186 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package model
+
+import (
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/siyuan-note/logging"
+	"github.com/siyuan-note/siyuan/kernel/conf"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const UsernameContextKey = "username"
+
+var (
+	ErrInvalidMemberName     = errors.New("invalid username")
+	ErrInvalidMemberPassword = errors.New("invalid password")
+	ErrInvalidMemberRole     = errors.New("invalid role")
+	ErrMemberExists          = errors.New("user already exists")
+	ErrMemberNotFound        = errors.New("user not found")
+)
+
+var memberLock = sync.Mutex{}
+
+// GetMembers 获取用户帐号列表，返回的帐号不包含密码哈希。
+func GetMembers() (ret []*conf.Member) {
+	memberLock.Lock()
+	defer memberLock.Unlock()
+
+	ret = []*conf.Member{}
+	for _, member := range Conf.Members {
+		ret = append(ret, &conf.Member{Username: member.Username, Role: member.Role, Memo: member.Memo})
+	}
+	return
+}
+
+func AddMember(username, password, role, memo string) (err error) {
+	username = strings.TrimSpace(username)
+	if err = checkMemberArgs(username, role); err != nil {
+		return
+	}
+	if "" == password {
+		return ErrInvalidMemberPassword
+	}
+
+	memberLock.Lock()
+	defer memberLock.Unlock()
+
+	if nil != getMember(username) {
+		return ErrMemberExists
+	}
+
+	hash, err := hashMemberPassword(password)
+	if err != nil {
+		return
+	}
+	Conf.Members = append(Conf.Members, &conf.Member{Username: username, Password: hash, Role: role, Memo: memo})
+	Conf.Save()
+	return
+}
+
+// UpdateMember 更新用户帐号，password 为空时不修改密码。
+func UpdateMember(username, password, role, memo string) (err error) {
+	if err = checkMemberArgs(username, role); err != nil {
+		return
+	}
+
+	memberLock.Lock()
+	defer memberLock.Unlock()
+
+	member := getMember(username)
+	if nil == member {
+		return ErrMemberNotFound
+	}
+
+	if "" != password {
+		var hash string
+		if hash, err = hashMemberPassword(password); err != nil {
+			return
+		}
+		member.Password = hash
+	}
+	member.Role = role
+	member.Memo = memo
+	Conf.Save()
+	return
+}
+
+func RemoveMember(username string) (err error) {
+	memberLock.Lock()
+	defer memberLock.Unlock()
+
+	for i, member := range Conf.Members {
+		if member.Username == username {
+			Conf.Members = append(Conf.Members[:i], Conf.Members[i+1:]...)
+			Conf.Save()
+			return
+		}
+	}
+	return ErrMemberNotFound
+}
+
+// AuthMember 校验用户名和密码，校验通过时返回对应的用户帐号。
+func AuthMember(username, password string) *conf.Member {
+	memberLock.Lock()
+	member := getMember(username)
+	memberLock.Unlock()
+	if nil == member {
+		return nil
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(member.Password), []byte(password)); err != nil {
+		return nil
+	}
+	return member
+}
+
+// GetGinContextUsername 获取当前请求的用户名，通过访问授权码或者 API token 访问时为空。
+func GetGinContextUsername(c *gin.Context) string {
+	if nil == c {
+		return ""
+	}
+	return c.GetString(UsernameContextKey)
+}
+
+// setMemberContext 在请求上下文中设置用户帐号对应的用户名和角色。
+func setMemberContext(c *gin.Context, member *conf.Member) {
+	c.Set(UsernameContextKey, member.Username)
+	c.Set(RoleContextKey, getRoleByName(member.Role))
+}
+
+func getSessionMember(username string) *conf.Member {
+	if "" == username {
+		return nil
+	}
+
+	memberLock.Lock()
+	defer memberLock.Unlock()
+	return getMember(username)
+}
+
+func getMember(username string) *conf.Member {
+	for _, member := range Conf.Members {
+		if member.Username == username {
+			return member
+		}
+	}
+	return nil
+}
+
+func checkMemberArgs(username, role string) error {
+	if "" == username || strings.ContainsAny(username, ": \t\r\n") {
+		return ErrInvalidMemberName
+	}
+	if !conf.IsMemberRole(role) {
+		return ErrInvalidMemberRole
+	}
+	return nil
+}
+
+func hashMemberPassword(password string) (ret string, err error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		logging.LogErrorf("hash password failed: %s", err)
+		return
+	}
+	ret = string(hash)
+	return
+}
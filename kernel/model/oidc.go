@@ -0,0 +1,480 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package model
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/88250/gulu"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/siyuan-note/httpclient"
+	"github.com/siyuan-note/logging"
+	"github.com/siyuan-note/siyuan/kernel/conf"
+	"github.com/siyuan-note/siyuan/kernel/util"
+)
+
+const (
+	OIDCLoginPath    = "/auth/oidc/login"
+	OIDCCallbackPath = "/auth/oidc/callback"
+)
+
+var (
+	ErrOIDCDisabled     = errors.New("OpenID Connect login is disabled")
+	ErrOIDCInvalidState = errors.New("invalid OpenID Connect state")
+	ErrOIDCRoleNotFound = errors.New("no role is mapped to the OpenID Connect user")
+
+	ErrOIDCInvalidRedirectURL = errors.New("OpenID Connect redirect URL must be an absolute http(s) URL")
+)
+
+// oidcProvider 是通过 {issuer}/.well-known/openid-configuration 发现的签发者元数据。
+type oidcProvider struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksURI               string `json:"jwks_uri"`
+
+	keys    map[string]any // kid -> 公钥
+	fetched time.Time
+}
+
+type oidcJWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+var (
+	oidcProviderCache *oidcProvider
+	oidcProviderLock  = sync.Mutex{}
+)
+
+// OIDCLogin 跳转到签发者的授权页面。
+func OIDCLogin(c *gin.Context) {
+	if !Conf.OIDC.Enable {
+		c.String(http.StatusNotFound, ErrOIDCDisabled.Error())
+		return
+	}
+
+	if err := CheckOIDCRedirectURL(Conf.OIDC.RedirectURL); err != nil {
+		logging.LogErrorf("OpenID Connect login failed: %s", err)
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	provider, err := getOIDCProvider()
+	if err != nil {
+		logging.LogErrorf("get OpenID Connect provider failed: %s", err)
+		c.String(http.StatusBadGateway, err.Error())
+		return
+	}
+
+	state, err := randomToken(32)
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	nonce, err := randomToken(32)
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	session := util.GetSession(c)
+	workspaceSession := util.GetWorkspaceSession(session)
+	workspaceSession.OIDCState = state
+	workspaceSession.OIDCNonce = nonce
+	workspaceSession.OIDCTo = getOIDCRedirectTo(c.Query("to"))
+	if err = session.Save(c); err != nil {
+		logging.LogErrorf("save session failed: " + err.Error())
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	scopes := Conf.OIDC.Scopes
+	if 1 > len(scopes) {
+		scopes = conf.NewOIDC().Scopes
+	}
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", Conf.OIDC.ClientID)
+	query.Set("redirect_uri", Conf.OIDC.RedirectURL)
+	query.Set("scope", strings.Join(scopes, " "))
+	query.Set("state", workspaceSession.OIDCState)
+	query.Set("nonce", workspaceSession.OIDCNonce)
+
+	location := provider.AuthorizationEndpoint
+	if strings.Contains(location, "?") {
+		location += "&" + query.Encode()
+	} else {
+		location += "?" + query.Encode()
+	}
+	c.Redirect(http.StatusFound, location)
+}
+
+// OIDCCallback 使用授权码换取 ID token，校验后按照角色映射规则登录。
+func OIDCCallback(c *gin.Context) {
+	if !Conf.OIDC.Enable {
+		c.String(http.StatusNotFound, ErrOIDCDisabled.Error())
+		return
+	}
+
+	session := util.GetSession(c)
+	workspaceSession := util.GetWorkspaceSession(session)
+	state, nonce, to := workspaceSession.OIDCState, workspaceSession.OIDCNonce, workspaceSession.OIDCTo
+	workspaceSession.OIDCState, workspaceSession.OIDCNonce, workspaceSession.OIDCTo = "", "", ""
+
+	if errMsg := c.Query("error"); "" != errMsg {
+		logging.LogWarnf("OpenID Connect login failed [error=%s, description=%s, ip=%s]", errMsg, c.Query("error_description"), util.GetRemoteAddr(c.Request))
+		session.Save(c)
+		c.String(http.StatusUnauthorized, errMsg)
+		return
+	}
+
+	if "" == state || state != c.Query("state") {
+		logging.LogWarnf("OpenID Connect login failed [ip=%s]: %s", util.GetRemoteAddr(c.Request), ErrOIDCInvalidState)
+		session.Save(c)
+		c.String(http.StatusBadRequest, ErrOIDCInvalidState.Error())
+		return
+	}
+
+	claims, err := exchangeOIDCCode(c.Query("code"), Conf.OIDC.RedirectURL, nonce)
+	if err != nil {
+		logging.LogWarnf("OpenID Connect login failed [ip=%s]: %s", util.GetRemoteAddr(c.Request), err)
+		session.Save(c)
+		c.String(http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	// 仅使用签发者验证过的邮箱，否则用户可以在签发者处填写任意邮箱来匹配角色映射规则
+	var email string
+	if verified, _ := claims["email_verified"].(bool); verified {
+		email, _ = claims["email"].(string)
+	}
+	subject, _ := claims["sub"].(string)
+	groups := getOIDCGroups(claims)
+	if _, ok := getOIDCRole(email, groups); !ok {
+		logging.LogWarnf("OpenID Connect login failed [email=%s, sub=%s, ip=%s]: %s", email, subject, util.GetRemoteAddr(c.Request), ErrOIDCRoleNotFound)
+		session.Save(c)
+		c.String(http.StatusForbidden, ErrOIDCRoleNotFound.Error())
+		return
+	}
+
+	workspaceSession.OIDCEmail = email
+	workspaceSession.OIDCSubject = subject
+	workspaceSession.OIDCGroups = groups
+	if err = session.Save(c); err != nil {
+		logging.LogErrorf("save session failed: " + err.Error())
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	logging.LogInfof("auth success [oidc=%s, ip=%s]", getOIDCUsername(workspaceSession), util.GetRemoteAddr(c.Request))
+	c.Redirect(http.StatusFound, to)
+}
+
+// setOIDCContext 通过 OpenID Connect 登录的 Cookie 设置请求上下文中的用户名和角色。
+//
+// 每次请求都会重新匹配角色映射规则，修改规则后无需重新登录即可生效。
+func setOIDCContext(c *gin.Context, workspaceSession *util.WorkspaceSession) bool {
+	if !Conf.OIDC.Enable || ("" == workspaceSession.OIDCSubject && "" == workspaceSession.OIDCEmail) {
+		return false
+	}
+
+	role, ok := getOIDCRole(workspaceSession.OIDCEmail, workspaceSession.OIDCGroups)
+	if !ok {
+		return false
+	}
+
+	c.Set(UsernameContextKey, getOIDCUsername(workspaceSession))
+	c.Set(RoleContextKey, getRoleByName(role))
+	return true
+}
+
+func getOIDCUsername(workspaceSession *util.WorkspaceSession) string {
+	if "" != workspaceSession.OIDCEmail {
+		return workspaceSession.OIDCEmail
+	}
+	return workspaceSession.OIDCSubject
+}
+
+func getOIDCRole(email string, groups []string) (role string, ok bool) {
+	email = strings.ToLower(email)
+	for _, rule := range Conf.OIDC.Roles {
+		if !conf.IsMemberRole(rule.Role) {
+			continue
+		}
+
+		if "" != email {
+			for _, e := range rule.Emails {
+				e = strings.ToLower(strings.TrimSpace(e))
+				if e == email || (strings.HasPrefix(e, "@") && strings.HasSuffix(email, e)) {
+					return rule.Role, true
+				}
+			}
+		}
+
+		for _, g := range rule.Groups {
+			if gulu.Str.Contains(g, groups) {
+				return rule.Role, true
+			}
+		}
+	}
+	return
+}
+
+func getOIDCGroups(claims jwt.MapClaims) (ret []string) {
+	claimName := Conf.OIDC.GroupsClaim
+	if "" == claimName {
+		claimName = "groups"
+	}
+
+	switch groups := claims[claimName].(type) {
+	case string:
+		ret = append(ret, groups)
+	case []any:
+		for _, g := range groups {
+			if s, ok := g.(string); ok {
+				ret = append(ret, s)
+			}
+		}
+	}
+	return
+}
+
+// getOIDCRedirectTo 只允许登录后跳转到本站地址。
+func getOIDCRedirectTo(to string) string {
+	if !strings.HasPrefix(to, "/") || strings.HasPrefix(to, "//") || strings.HasPrefix(to, "/\\") {
+		return "/"
+	}
+	return to
+}
+
+// CheckOIDCRedirectURL 检查回调地址，回调地址需要配置为完整的 http(s) 地址，不使用请求头拼接，避免被伪造的 Host 和 X-Forwarded-* 请求头篡改。
+func CheckOIDCRedirectURL(redirectURL string) error {
+	u, err := url.Parse(redirectURL)
+	if nil != err || ("http" != u.Scheme && "https" != u.Scheme) || "" == u.Host {
+		return ErrOIDCInvalidRedirectURL
+	}
+	return nil
+}
+
+func exchangeOIDCCode(code, redirectURL, nonce string) (ret jwt.MapClaims, err error) {
+	if "" == code {
+		return nil, errors.New("missing authorization code")
+	}
+
+	provider, err := getOIDCProvider()
+	if err != nil {
+		return
+	}
+
+	tokenResult := map[string]any{}
+	resp, err := httpclient.NewBrowserRequest().
+		SetBasicAuth(url.QueryEscape(Conf.OIDC.ClientID), url.QueryEscape(Conf.OIDC.ClientSecret)).
+		SetFormData(map[string]string{
+			"grant_type":   "authorization_code",
+			"code":         code,
+			"redirect_uri": redirectURL,
+		}).
+		SetSuccessResult(&tokenResult).
+		Post(provider.TokenEndpoint)
+	if err != nil {
+		return
+	}
+	if !resp.IsSuccessState() {
+		return nil, fmt.Errorf("exchange authorization code failed [%d]: %s", resp.StatusCode, resp.String())
+	}
+
+	idToken, _ := tokenResult["id_token"].(string)
+	if "" == idToken {
+		return nil, errors.New("missing ID token")
+	}
+	return verifyOIDCIDToken(provider, idToken, nonce)
+}
+
+func verifyOIDCIDToken(provider *oidcProvider, idToken, nonce string) (ret jwt.MapClaims, err error) {
+	ret = jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(idToken, ret, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		return getOIDCKey(provider, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}),
+		jwt.WithIssuer(provider.Issuer),
+		jwt.WithAudience(Conf.OIDC.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute))
+	if err != nil {
+		return nil, err
+	}
+
+	if tokenNonce, _ := ret["nonce"].(string); "" == nonce || tokenNonce != nonce {
+		return nil, errors.New("invalid ID token nonce")
+	}
+	return
+}
+
+func getOIDCProvider() (ret *oidcProvider, err error) {
+	oidcProviderLock.Lock()
+	defer oidcProviderLock.Unlock()
+
+	issuer := strings.TrimSuffix(Conf.OIDC.Issuer, "/")
+	if "" == issuer || "" == Conf.OIDC.ClientID {
+		return nil, errors.New("OpenID Connect issuer or client ID is not configured")
+	}
+
+	if nil != oidcProviderCache && strings.TrimSuffix(oidcProviderCache.Issuer, "/") == issuer && time.Since(oidcProviderCache.fetched) < time.Hour {
+		return oidcProviderCache, nil
+	}
+
+	discoveryURL := issuer
+	if !strings.Contains(discoveryURL, "/.well-known/") {
+		discoveryURL += "/.well-known/openid-configuration"
+	}
+
+	ret = &oidcProvider{}
+	resp, err := httpclient.NewBrowserRequest().SetSuccessResult(ret).Get(discoveryURL)
+	if err != nil {
+		return
+	}
+	if !resp.IsSuccessState() {
+		return nil, fmt.Errorf("get OpenID Connect discovery [%s] failed [%d]", discoveryURL, resp.StatusCode)
+	}
+	if "" == ret.AuthorizationEndpoint || "" == ret.TokenEndpoint || "" == ret.JwksURI {
+		return nil, fmt.Errorf("invalid OpenID Connect discovery [%s]", discoveryURL)
+	}
+
+	if err = ret.fetchKeys(); err != nil {
+		return
+	}
+	ret.fetched = time.Now()
+	oidcProviderCache = ret
+	return
+}
+
+// getOIDCKey 获取 ID token 签名公钥，签发者轮换密钥后找不到 kid 时会重新获取一次。
+func getOIDCKey(provider *oidcProvider, kid string) (ret any, err error) {
+	oidcProviderLock.Lock()
+	defer oidcProviderLock.Unlock()
+
+	if ret = provider.getKey(kid); nil != ret {
+		return
+	}
+	if err = provider.fetchKeys(); err != nil {
+		return
+	}
+	if ret = provider.getKey(kid); nil == ret {
+		err = fmt.Errorf("not found ID token key [%s]", kid)
+	}
+	return
+}
+
+func (provider *oidcProvider) getKey(kid string) any {
+	if "" == kid && 1 == len(provider.keys) {
+		for _, key := range provider.keys {
+			return key
+		}
+	}
+	return provider.keys[kid]
+}
+
+func (provider *oidcProvider) fetchKeys() (err error) {
+	jwks := &struct {
+		Keys []*oidcJWK `json:"keys"`
+	}{}
+	resp, err := httpclient.NewBrowserRequest().SetSuccessResult(jwks).Get(provider.JwksURI)
+	if err != nil {
+		return
+	}
+	if !resp.IsSuccessState() {
+		return fmt.Errorf("get OpenID Connect JWKS [%s] failed [%d]", provider.JwksURI, resp.StatusCode)
+	}
+
+	provider.keys = map[string]any{}
+	for _, jwk := range jwks.Keys {
+		if "" != jwk.Use && "sig" != jwk.Use {
+			continue
+		}
+
+		key, parseErr := jwk.publicKey()
+		if nil != parseErr {
+			logging.LogWarnf("parse OpenID Connect JWK [%s] failed: %s", jwk.Kid, parseErr)
+			continue
+		}
+		provider.keys[jwk.Kid] = key
+	}
+	return
+}
+
+func (jwk *oidcJWK) publicKey() (ret any, err error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, e := new(big.Int), new(big.Int)
+		if err = setOIDCBigInt(n, jwk.N); err != nil {
+			return
+		}
+		if err = setOIDCBigInt(e, jwk.E); err != nil {
+			return
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve [%s]", jwk.Crv)
+		}
+		x, y := new(big.Int), new(big.Int)
+		if err = setOIDCBigInt(x, jwk.X); err != nil {
+			return
+		}
+		if err = setOIDCBigInt(y, jwk.Y); err != nil {
+			return
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type [%s]", jwk.Kty)
+}
+
+func setOIDCBigInt(n *big.Int, s string) error {
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return err
+	}
+	n.SetBytes(data)
+	return nil
+}
@@ -0,0 +1,240 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package model
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/memstore"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/siyuan-note/logging"
+	"github.com/siyuan-note/siyuan/kernel/conf"
+	"github.com/siyuan-note/siyuan/kernel/util"
+)
+
+const (
+	testOIDCKid      = "test-key"
+	testOIDCClientID = "siyuan"
+	testOIDCNonce    = "nonce"
+)
+
+// testOIDCIssuer 是模拟的签发者，令牌端点返回 idToken 字段中的 ID token。
+type testOIDCIssuer struct {
+	server  *httptest.Server
+	key     *rsa.PrivateKey
+	idToken string
+}
+
+func newTestOIDCIssuer(t *testing.T) *testOIDCIssuer {
+	// 登录过程会记录日志，日志写到临时目录，不写到包目录下
+	oldLogPath := logging.LogPath
+	logging.SetLogPath(filepath.Join(t.TempDir(), "siyuan.log"))
+	t.Cleanup(func() { logging.SetLogPath(oldLogPath) })
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ret := &testOIDCIssuer{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 ret.server.URL,
+			"authorization_endpoint": ret.server.URL + "/authorize",
+			"token_endpoint":         ret.server.URL + "/token",
+			"jwks_uri":               ret.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": testOIDCKid,
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if "test-code" != r.FormValue("code") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"id_token": ret.idToken})
+	})
+	ret.server = httptest.NewServer(mux)
+	t.Cleanup(ret.server.Close)
+
+	oldConf := Conf
+	Conf = &AppConf{OIDC: &conf.OIDC{
+		Enable:      true,
+		Issuer:      ret.server.URL,
+		ClientID:    testOIDCClientID,
+		RedirectURL: "https://siyuan.example.com/auth/oidc/callback",
+		Roles:       []*conf.OIDCRoleRule{{Emails: []string{"@example.com"}, Role: conf.MemberRoleEditor}},
+	}}
+	oidcProviderCache = nil
+	t.Cleanup(func() {
+		Conf = oldConf
+		oidcProviderCache = nil
+	})
+	return ret
+}
+
+func (issuer *testOIDCIssuer) sign(t *testing.T, key *rsa.PrivateKey, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = testOIDCKid
+	ret, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ret
+}
+
+func (issuer *testOIDCIssuer) claims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":            issuer.server.URL,
+		"aud":            testOIDCClientID,
+		"sub":            "user-1",
+		"email":          "alice@example.com",
+		"email_verified": true,
+		"nonce":          testOIDCNonce,
+		"iat":            time.Now().Unix(),
+		"exp":            time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func TestExchangeOIDCCode(t *testing.T) {
+	issuer := newTestOIDCIssuer(t)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name   string
+		key    *rsa.PrivateKey
+		modify func(claims jwt.MapClaims)
+		nonce  string
+		ok     bool
+	}{
+		{"valid", issuer.key, func(jwt.MapClaims) {}, testOIDCNonce, true},
+		{"bad signature", otherKey, func(jwt.MapClaims) {}, testOIDCNonce, false},
+		{"wrong issuer", issuer.key, func(claims jwt.MapClaims) { claims["iss"] = "https://evil.example.com" }, testOIDCNonce, false},
+		{"wrong audience", issuer.key, func(claims jwt.MapClaims) { claims["aud"] = "other" }, testOIDCNonce, false},
+		{"expired", issuer.key, func(claims jwt.MapClaims) { claims["exp"] = time.Now().Add(-time.Hour).Unix() }, testOIDCNonce, false},
+		{"missing expiration", issuer.key, func(claims jwt.MapClaims) { delete(claims, "exp") }, testOIDCNonce, false},
+		{"wrong nonce", issuer.key, func(claims jwt.MapClaims) { claims["nonce"] = "other" }, testOIDCNonce, false},
+		{"empty session nonce", issuer.key, func(jwt.MapClaims) {}, "", false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			claims := issuer.claims()
+			c.modify(claims)
+			issuer.idToken = issuer.sign(t, c.key, claims)
+
+			ret, err := exchangeOIDCCode("test-code", Conf.OIDC.RedirectURL, c.nonce)
+			if c.ok {
+				if err != nil {
+					t.Fatalf("exchange code failed: %s", err)
+				}
+				if "alice@example.com" != ret["email"] {
+					t.Fatalf("unexpected claims: %v", ret)
+				}
+			} else if err == nil {
+				t.Fatalf("expected error")
+			}
+		})
+	}
+
+	if _, err = exchangeOIDCCode("", Conf.OIDC.RedirectURL, testOIDCNonce); err == nil {
+		t.Fatalf("expected error for empty code")
+	}
+}
+
+func TestOIDCCallback(t *testing.T) {
+	issuer := newTestOIDCIssuer(t)
+	gin.SetMode(gin.TestMode)
+
+	callback := func(sessionState, queryState string, claims jwt.MapClaims) int {
+		issuer.idToken = issuer.sign(t, issuer.key, claims)
+
+		ginServer := gin.New()
+		ginServer.Use(sessions.Sessions("siyuan", memstore.NewStore([]byte("test"))))
+		ginServer.GET(OIDCCallbackPath, func(c *gin.Context) {
+			session := util.GetSession(c)
+			workspaceSession := util.GetWorkspaceSession(session)
+			workspaceSession.OIDCState = sessionState
+			workspaceSession.OIDCNonce = testOIDCNonce
+			workspaceSession.OIDCTo = "/"
+			session.Save(c)
+			OIDCCallback(c)
+		})
+
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, OIDCCallbackPath+"?code=test-code&state="+queryState, nil)
+		ginServer.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := callback("state", "state", issuer.claims()); http.StatusFound != code {
+		t.Fatalf("expected redirect, got [%d]", code)
+	}
+	if code := callback("state", "other", issuer.claims()); http.StatusBadRequest != code {
+		t.Fatalf("expected bad request for wrong state, got [%d]", code)
+	}
+	if code := callback("", "", issuer.claims()); http.StatusBadRequest != code {
+		t.Fatalf("expected bad request for empty state, got [%d]", code)
+	}
+
+	unverified := issuer.claims()
+	unverified["email_verified"] = false
+	if code := callback("state", "state", unverified); http.StatusForbidden != code {
+		t.Fatalf("expected forbidden for unverified email, got [%d]", code)
+	}
+	delete(unverified, "email_verified")
+	if code := callback("state", "state", unverified); http.StatusForbidden != code {
+		t.Fatalf("expected forbidden for missing email_verified, got [%d]", code)
+	}
+}
+
+func TestCheckOIDCRedirectURL(t *testing.T) {
+	for _, u := range []string{"https://siyuan.example.com/auth/oidc/callback", "http://127.0.0.1:6806/auth/oidc/callback"} {
+		if err := CheckOIDCRedirectURL(u); err != nil {
+			t.Fatalf("expected [%s] to be valid", u)
+		}
+	}
+	for _, u := range []string{"", "/auth/oidc/callback", "javascript:alert(1)", "https://"} {
+		if err := CheckOIDCRedirectURL(u); err == nil {
+			t.Fatalf("expected [%s] to be invalid", u)
+		}
+	}
+}
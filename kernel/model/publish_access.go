@@ -0,0 +1,272 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package model
+
+import (
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/siyuan-note/siyuan/kernel/conf"
+	"github.com/siyuan-note/siyuan/kernel/sql"
+	"github.com/siyuan-note/siyuan/kernel/treenode"
+)
+
+const PublishAccessContextKey = "publishAccess"
+
+// PublishAccess 描述了发布服务账户或者 API token 的访问范围。
+//
+// 为 nil 时表示不限制访问范围，所以调用方可以直接在 nil 上调用各个判断方法。
+type PublishAccess struct {
+	notebooks []string                         // 允许访问的笔记本 ID，为空表示不限制
+	paths     []string                         // 允许访问的文档路径前缀，为空表示不限制
+	attrs     []*conf.BasicAuthAccountAttrRule // 文档属性规则
+	docs      map[string]bool                  // 文档 ID 到是否允许访问的缓存，仅在一次请求内有效
+}
+
+// GetPublishAccess 获取当前请求的访问范围，不是发布服务账户或者 API token 的请求、以及未设置访问范围时返回 nil。
+func GetPublishAccess(c *gin.Context) *PublishAccess {
+	if nil == c {
+		return nil
+	}
+
+	if access, exists := c.Get(PublishAccessContextKey); exists {
+		return access.(*PublishAccess)
+	}
+
+	ret := newPublishAccess(c)
+	c.Set(PublishAccessContextKey, ret)
+	return ret
+}
+
+func newPublishAccess(c *gin.Context) *PublishAccess {
+	if token := GetGinContextAPIToken(c); nil != token {
+		if 1 > len(token.Notebooks) {
+			return nil
+		}
+		return &PublishAccess{notebooks: token.Notebooks, docs: map[string]bool{}}
+	}
+
+	if !IsReadOnlyRole(GetGinContextRole(c)) {
+		return nil
+	}
+
+	claims, exists := c.Get(ClaimsContextKey)
+	if !exists {
+		return nil
+	}
+	username, _ := claims.(jwt.MapClaims)["jti"].(string)
+	if "" == username {
+		return nil
+	}
+
+	for _, account := range Conf.Publish.Auth.Accounts {
+		if account.Username == username {
+			if !account.IsRestricted() {
+				return nil
+			}
+			return &PublishAccess{notebooks: account.Notebooks, paths: account.Paths, attrs: account.Attrs, docs: map[string]bool{}}
+		}
+	}
+	return nil
+}
+
+// IsBoxAllowed 判断是否可以访问笔记本。
+func (access *PublishAccess) IsBoxAllowed(boxID string) bool {
+	if nil == access {
+		return true
+	}
+
+	if 1 > len(access.notebooks) {
+		return true
+	}
+	for _, notebook := range access.notebooks {
+		if notebook == boxID {
+			return true
+		}
+	}
+	return false
+}
+
+// IsBlockAllowed 判断是否可以访问块，块不存在时不允许访问。
+func (access *PublishAccess) IsBlockAllowed(id string) bool {
+	if nil == access {
+		return true
+	}
+
+	bt := treenode.GetBlockTree(id)
+	if nil == bt {
+		return false
+	}
+	return access.isDocAllowed(bt.RootID, bt.BoxID, bt.Path, bt.HPath)
+}
+
+// IsDocAllowed 判断是否可以访问文档，p 为文档数据路径，为 / 时表示笔记本根路径。
+func (access *PublishAccess) IsDocAllowed(boxID, p string) bool {
+	if nil == access {
+		return true
+	}
+
+	if "/" == p || "" == p {
+		return access.IsBoxAllowed(boxID)
+	}
+
+	rootID := strings.TrimSuffix(path.Base(p), ".sy")
+	bt := treenode.GetBlockTree(rootID)
+	if nil == bt {
+		return false
+	}
+	return access.isDocAllowed(bt.RootID, boxID, bt.Path, bt.HPath)
+}
+
+// IsAssetAllowed 判断是否可以访问资源文件，资源文件需要被至少一个允许访问的文档引用。
+func (access *PublishAccess) IsAssetAllowed(assetPath string) bool {
+	if nil == access {
+		return true
+	}
+
+	assetPath = strings.TrimPrefix(assetPath, "/")
+	for _, asset := range sql.QueryAssetsByPath(assetPath) {
+		if access.IsBlockAllowed(asset.RootID) {
+			return true
+		}
+	}
+	return false
+}
+
+func (access *PublishAccess) isDocAllowed(rootID, boxID, p, hPath string) bool {
+	if allowed, ok := access.docs[rootID]; ok {
+		return allowed
+	}
+
+	allowed := access.IsBoxAllowed(boxID) && access.isPathAllowed(p, hPath) && access.isAttrsAllowed(rootID)
+	access.docs[rootID] = allowed
+	return allowed
+}
+
+func (access *PublishAccess) isPathAllowed(p, hPath string) bool {
+	if 1 > len(access.paths) {
+		return true
+	}
+
+	p = strings.TrimSuffix(p, ".sy")
+	for _, prefix := range access.paths {
+		prefix = strings.TrimSuffix(strings.TrimSuffix(prefix, ".sy"), "/")
+		if "" == prefix {
+			return true
+		}
+		if !strings.HasPrefix(prefix, "/") {
+			prefix = "/" + prefix
+		}
+
+		if p == prefix || strings.HasPrefix(p, prefix+"/") || hPath == prefix || strings.HasPrefix(hPath, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func (access *PublishAccess) isAttrsAllowed(rootID string) bool {
+	if 1 > len(access.attrs) {
+		return true
+	}
+
+	attrs := sql.GetBlockAttrs(rootID)
+	hasAllowRule, allowed := false, false
+	for _, rule := range access.attrs {
+		val, ok := attrs[rule.Name]
+		matched := ok && ("" == rule.Value || val == rule.Value)
+		if rule.Deny {
+			if matched {
+				return false
+			}
+			continue
+		}
+
+		hasAllowRule = true
+		allowed = allowed || matched
+	}
+	return !hasAllowRule || allowed
+}
+
+// CheckUnrestricted 拒绝设置了访问范围的请求，用于无法按访问范围过滤的接口。
+func CheckUnrestricted(c *gin.Context) {
+	if nil != GetPublishAccess(c) {
+		c.AbortWithStatus(http.StatusForbidden)
+		return
+	}
+	c.Next()
+}
+
+// FilterPublishBlocks 过滤掉当前请求无权访问的块。
+//
+// 块的笔记本和路径可能来自调用方编写的 SQL，所以通过块树按块 ID 判断。
+func FilterPublishBlocks(c *gin.Context, blocks []*Block) (ret []*Block) {
+	access := GetPublishAccess(c)
+	if nil == access {
+		return blocks
+	}
+
+	ret = []*Block{}
+	for _, b := range blocks {
+		if access.IsBlockAllowed(b.ID) {
+			ret = append(ret, b)
+		}
+	}
+	return
+}
+
+// FilterPublishPaths 过滤掉当前请求无权访问的路径，路径 ID 为文档 ID。
+func FilterPublishPaths(c *gin.Context, paths []*Path) (ret []*Path) {
+	access := GetPublishAccess(c)
+	if nil == access {
+		return paths
+	}
+
+	ret = []*Path{}
+	for _, p := range paths {
+		if access.IsBlockAllowed(p.ID) {
+			ret = append(ret, p)
+		}
+	}
+	return
+}
+
+// FilterPublishGraph 过滤掉当前请求无权访问的关系图节点以及相关的连线。
+func FilterPublishGraph(c *gin.Context, nodes []*GraphNode, links []*GraphLink) (retNodes []*GraphNode, retLinks []*GraphLink) {
+	access := GetPublishAccess(c)
+	if nil == access {
+		return nodes, links
+	}
+
+	retNodes, retLinks = []*GraphNode{}, []*GraphLink{}
+	ids := map[string]bool{}
+	for _, node := range nodes {
+		if access.IsBlockAllowed(node.ID) {
+			retNodes = append(retNodes, node)
+			ids[node.ID] = true
+		}
+	}
+	for _, link := range links {
+		if ids[link.From] && ids[link.To] {
+			retLinks = append(retLinks, link)
+		}
+	}
+	return
+}
@@ -296,6 +296,7 @@ func refreshDynamicRefTexts(updatedDefNodes map[string]*ast.Node, updatedTrees m
 
 // ReloadAttrView 用于重新加载属性视图。
 func ReloadAttrView(avID string) {
+	sql.IndexAttributeViewQueue(avID)
 	task.AppendAsyncTaskWithDelay(task.ReloadAttributeView, 200*time.Millisecond, pushReloadAttrView, avID)
 }
 
@@ -1603,6 +1603,8 @@ func processSyncMergeResult(exit, byHand bool, mergeResult *dejavu.MergeResult,
 		if strings.HasSuffix(file.Path, ".sy") {
 			upsertTrees++
 		}
+
+		indexAttributeViewFile(file.Path)
 	}
 
 	removeWidgetDirSet, removePluginSet := hashset.New(), hashset.New()
@@ -1643,6 +1645,8 @@ func processSyncMergeResult(exit, byHand bool, mergeResult *dejavu.MergeResult,
 				removeWidgetDirSet.Add(parts[2])
 			}
 		}
+
+		indexAttributeViewFile(file.Path)
 	}
 
 	if needReloadFlashcard {
@@ -16,7 +16,10 @@
 
 package model
 
-import "github.com/gin-gonic/gin"
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/siyuan-note/siyuan/kernel/conf"
+)
 
 type Role uint
 
@@ -47,6 +50,19 @@ func IsReadOnlyRole(role Role) bool {
 	})
 }
 
+// getRoleByName 获取用户帐号和 API token 配置中角色名称对应的角色。
+func getRoleByName(name string) Role {
+	switch name {
+	case conf.MemberRoleAdmin:
+		return RoleAdministrator
+	case conf.MemberRoleEditor:
+		return RoleEditor
+	case conf.MemberRoleReader:
+		return RoleReader
+	}
+	return RoleVisitor
+}
+
 func GetGinContextRole(c *gin.Context) Role {
 	if role, exists := c.Get(RoleContextKey); exists {
 		return role.(Role)
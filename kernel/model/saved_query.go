@@ -0,0 +1,309 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package model
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/88250/gulu"
+	"github.com/88250/lute/ast"
+	"github.com/siyuan-note/filelock"
+	"github.com/siyuan-note/logging"
+	"github.com/siyuan-note/siyuan/kernel/sql"
+	"github.com/siyuan-note/siyuan/kernel/treenode"
+	"github.com/siyuan-note/siyuan/kernel/util"
+)
+
+// SavedQuery 描述了保存的查询，查询在使用时实时执行，命中块所在的文档作为虚拟文件夹的子文档。
+//
+// 查询语句中可以使用以下参数：
+//
+//	{{today}}：今天，如 20240101
+//	{{yesterday}}：昨天
+//	{{weekStart}}：本周一
+//	{{monthStart}}：本月一日
+//	{{now}}：当前时间，如 20240101120000
+//	{{currentDocID}}：当前文档 ID
+//	{{currentBoxID}}：当前文档所在笔记本 ID
+//
+// 调用方也可以传入自定义参数。SQL 查询中的参数会作为绑定参数传递给数据库，所以参数两侧不能加引号，
+// 比如 SELECT * FROM blocks WHERE updated >= {{weekStart}}。
+type SavedQuery struct {
+	ID     string          `json:"id"`
+	Name   string          `json:"name"`
+	Icon   string          `json:"icon"`
+	Method int             `json:"method"` // 0：关键字，1：查询语法，2：SQL，3：正则表达式
+	Query  string          `json:"query"`  // 搜索关键字或者 SQL 语句
+	Boxes  []string        `json:"boxes"`  // 笔记本过滤，仅在非 SQL 查询时生效
+	Paths  []string        `json:"paths"`  // 路径过滤，仅在非 SQL 查询时生效
+	Types  map[string]bool `json:"types"`  // 类型过滤，仅在非 SQL 查询时生效
+	Sort   int             `json:"sort"`   // 排序方式，同 FullTextSearchBlock 的 orderBy，仅在非 SQL 查询时生效
+	Limit  int             `json:"limit"`  // 命中块数量上限
+}
+
+var (
+	savedQueriesLock       = sync.Mutex{}
+	savedQueryParamRegexp  = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+	ErrSavedQueryNotFound  = errors.New("saved query not found")
+	savedQueryDefaultLimit = 256
+)
+
+func RemoveSavedQuery(id string) (err error) {
+	savedQueriesLock.Lock()
+	defer savedQueriesLock.Unlock()
+
+	savedQueries, err := getSavedQueries()
+	if err != nil {
+		return
+	}
+
+	for i, q := range savedQueries {
+		if q.ID == id {
+			savedQueries = append(savedQueries[:i], savedQueries[i+1:]...)
+			break
+		}
+	}
+
+	err = setSavedQueries(savedQueries)
+	return
+}
+
+func SetSavedQuery(savedQuery *SavedQuery) (err error) {
+	if "" == savedQuery.Name {
+		return errors.New(Conf.Language(142))
+	}
+	if "" == strings.TrimSpace(savedQuery.Query) {
+		return errors.New("query is empty")
+	}
+	if "" == savedQuery.ID {
+		savedQuery.ID = ast.NewNodeID()
+	}
+
+	savedQueriesLock.Lock()
+	defer savedQueriesLock.Unlock()
+
+	savedQueries, err := getSavedQueries()
+	if err != nil {
+		return
+	}
+
+	update := false
+	for i, q := range savedQueries {
+		if q.ID == savedQuery.ID {
+			savedQueries[i] = savedQuery
+			update = true
+			break
+		}
+	}
+	if !update {
+		savedQueries = append(savedQueries, savedQuery)
+	}
+
+	err = setSavedQueries(savedQueries)
+	return
+}
+
+func GetSavedQueries() (ret []*SavedQuery) {
+	savedQueriesLock.Lock()
+	defer savedQueriesLock.Unlock()
+	ret, _ = getSavedQueries()
+	return
+}
+
+func GetSavedQuery(id string) (ret *SavedQuery) {
+	for _, q := range GetSavedQueries() {
+		if q.ID == id {
+			return q
+		}
+	}
+	return
+}
+
+// RunSavedQuery 执行保存的查询，返回命中块所在的文档，按命中顺序排列。
+func RunSavedQuery(id string, params map[string]string) (ret []*Block, err error) {
+	ret = []*Block{}
+	savedQuery := GetSavedQuery(id)
+	if nil == savedQuery {
+		err = ErrSavedQueryNotFound
+		return
+	}
+
+	limit := savedQuery.Limit
+	if 1 > limit {
+		limit = savedQueryDefaultLimit
+	}
+
+	values := savedQueryParams(params)
+	var rootIDs []string
+	if 2 == savedQuery.Method {
+		stmt, args, bindErr := bindSavedQueryParams(savedQuery.Query, values)
+		if nil != bindErr {
+			err = bindErr
+			return
+		}
+
+		for _, b := range sql.SelectBlocksRawStmt(stmt, 1, limit, args...) {
+			rootIDs = append(rootIDs, b.RootID)
+		}
+	} else {
+		query, replaceErr := replaceSavedQueryParams(savedQuery.Query, values)
+		if nil != replaceErr {
+			err = replaceErr
+			return
+		}
+
+		blocks, _, _, _, _ := FullTextSearchBlock(query, savedQuery.Boxes, savedQuery.Paths, savedQuery.Types, savedQuery.Method, savedQuery.Sort, 0, 1, limit)
+		for _, b := range blocks {
+			rootIDs = append(rootIDs, b.RootID)
+		}
+	}
+	rootIDs = gulu.Str.RemoveDuplicatedElem(rootIDs)
+
+	var sqlRoots []*sql.Block
+	for _, root := range sql.GetBlocks(rootIDs) {
+		if nil != root {
+			sqlRoots = append(sqlRoots, root)
+		}
+	}
+	ret = fromSQLBlocks(&sqlRoots, "", 36)
+	return
+}
+
+// savedQueryParams 生成内置参数，调用方传入的同名参数优先。
+func savedQueryParams(params map[string]string) (ret map[string]string) {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	weekday := int(today.Weekday())
+	if 0 == weekday {
+		weekday = 7
+	}
+
+	ret = map[string]string{
+		"today":      today.Format("20060102"),
+		"yesterday":  today.AddDate(0, 0, -1).Format("20060102"),
+		"weekStart":  today.AddDate(0, 0, 1-weekday).Format("20060102"),
+		"monthStart": today.AddDate(0, 0, 1-today.Day()).Format("20060102"),
+		"now":        now.Format("20060102150405"),
+	}
+
+	if currentDocID := params["currentDocID"]; ast.IsNodeIDPattern(currentDocID) {
+		if bt := treenode.GetBlockTree(currentDocID); nil != bt {
+			ret["currentDocID"] = bt.RootID
+			ret["currentBoxID"] = bt.BoxID
+		}
+	}
+
+	for k, v := range params {
+		if "currentDocID" == k || "currentBoxID" == k {
+			continue
+		}
+		ret[k] = v
+	}
+	return
+}
+
+// bindSavedQueryParams 将 SQL 语句中的参数替换为占位符 ?，并按出现顺序返回绑定的参数值。
+//
+// 字符串字面量中的参数（比如 '{{today}}'）替换为占位符后会变成普通的字符串 '?'，所以直接报错。
+func bindSavedQueryParams(stmt string, values map[string]string) (ret string, args []interface{}, err error) {
+	buf := strings.Builder{}
+	last := 0
+	for _, loc := range savedQueryParamRegexp.FindAllStringSubmatchIndex(stmt, -1) {
+		name := stmt[loc[2]:loc[3]]
+		if 1 == strings.Count(stmt[:loc[0]], "'")%2 {
+			err = fmt.Errorf("query parameter [%s] must not be quoted", name)
+			return
+		}
+
+		value, ok := values[name]
+		if !ok {
+			err = fmt.Errorf("unknown query parameter [%s]", name)
+			return
+		}
+		buf.WriteString(stmt[last:loc[0]])
+		buf.WriteString("?")
+		args = append(args, value)
+		last = loc[1]
+	}
+	buf.WriteString(stmt[last:])
+	ret = buf.String()
+	return
+}
+
+func replaceSavedQueryParams(query string, values map[string]string) (ret string, err error) {
+	ret = savedQueryParamRegexp.ReplaceAllStringFunc(query, func(m string) string {
+		name := savedQueryParamRegexp.FindStringSubmatch(m)[1]
+		value, ok := values[name]
+		if !ok {
+			if nil == err {
+				err = fmt.Errorf("unknown query parameter [%s]", name)
+			}
+			return m
+		}
+		return value
+	})
+	return
+}
+
+func setSavedQueries(savedQueries []*SavedQuery) (err error) {
+	dirPath := filepath.Join(util.DataDir, "storage")
+	if err = os.MkdirAll(dirPath, 0755); err != nil {
+		logging.LogErrorf("create storage [saved queries] dir failed: %s", err)
+		return
+	}
+
+	data, err := gulu.JSON.MarshalIndentJSON(savedQueries, "", "  ")
+	if err != nil {
+		logging.LogErrorf("marshal storage [saved queries] failed: %s", err)
+		return
+	}
+
+	lsPath := filepath.Join(dirPath, "saved-queries.json")
+	err = filelock.WriteFile(lsPath, data)
+	if err != nil {
+		logging.LogErrorf("write storage [saved queries] failed: %s", err)
+		return
+	}
+	return
+}
+
+func getSavedQueries() (ret []*SavedQuery, err error) {
+	ret = []*SavedQuery{}
+	dataPath := filepath.Join(util.DataDir, "storage/saved-queries.json")
+	if !filelock.IsExist(dataPath) {
+		return
+	}
+
+	data, err := filelock.ReadFile(dataPath)
+	if err != nil {
+		logging.LogErrorf("read storage [saved queries] failed: %s", err)
+		return
+	}
+
+	if err = gulu.JSON.UnmarshalJSON(data, &ret); err != nil {
+		logging.LogErrorf("unmarshal storage [saved queries] failed: %s", err)
+		return
+	}
+	return
+}
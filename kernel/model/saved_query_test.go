@@ -0,0 +1,47 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBindSavedQueryParams(t *testing.T) {
+	values := map[string]string{"today": "20240101", "currentBoxID": "20240101120000-abcdefg"}
+
+	stmt, args, err := bindSavedQueryParams("SELECT * FROM blocks WHERE content LIKE '%a''b%' AND updated >= {{today}} AND box = {{ currentBoxID }}", values)
+	if nil != err {
+		t.Fatalf("bind failed: %s", err)
+	}
+	if "SELECT * FROM blocks WHERE content LIKE '%a''b%' AND updated >= ? AND box = ?" != stmt {
+		t.Fatalf("unexpected stmt [%s]", stmt)
+	}
+	if !reflect.DeepEqual([]interface{}{"20240101", "20240101120000-abcdefg"}, args) {
+		t.Fatalf("unexpected args %v", args)
+	}
+
+	for _, stmt := range []string{
+		"SELECT * FROM blocks WHERE updated >= '{{today}}'",
+		"SELECT * FROM blocks WHERE content LIKE '%{{today}}%'",
+		"SELECT * FROM blocks WHERE updated >= {{unknown}}",
+	} {
+		if _, _, err = bindSavedQueryParams(stmt, values); nil == err {
+			t.Fatalf("expected error for [%s]", stmt)
+		}
+	}
+}
@@ -311,7 +311,7 @@ func buildEmbedBlock(embedBlockID string, excludeIDs []string, headingMode int,
 	return
 }
 
-func SearchRefBlock(id, rootID, keyword string, beforeLen int, isSquareBrackets, isDatabase bool) (ret []*Block, newDoc bool) {
+func SearchRefBlock(id, rootID, keyword string, beforeLen int, isSquareBrackets, isDatabase, fuzzy bool) (ret []*Block, newDoc bool) {
 	cachedTrees := map[string]*parse.Tree{}
 
 	onlyDoc := false
@@ -365,7 +365,7 @@ func SearchRefBlock(id, rootID, keyword string, beforeLen int, isSquareBrackets,
 		return
 	}
 
-	ret = fullTextSearchRefBlock(keyword, beforeLen, onlyDoc)
+	ret = fullTextSearchRefBlock(keyword, beforeLen, onlyDoc, fuzzy)
 	tmp := ret[:0]
 	var btsID []string
 	for _, b := range ret {
@@ -455,8 +455,8 @@ func prependNotebookNameInHPath(blocks []*Block) {
 }
 
 func FindReplace(keyword, replacement string, replaceTypes map[string]bool, ids []string, paths, boxes []string, types map[string]bool, method, orderBy, groupBy int) (err error) {
-	// method：0：文本，1：查询语法，2：SQL，3：正则表达式
-	if 1 == method || 2 == method {
+	// method：0：文本，1：查询语法，2：SQL，3：正则表达式，4：语义，5：模糊
+	if 1 == method || 2 == method || 4 == method || 5 == method {
 		err = errors.New(Conf.Language(132))
 		return
 	}
@@ -982,9 +982,11 @@ func replaceNodeTokens(n *ast.Node, method int, keyword string, replacement stri
 
 // FullTextSearchBlock 搜索内容块。
 //
-// method：0：关键字，1：查询语法，2：SQL，3：正则表达式
-// orderBy: 0：按块类型（默认），1：按创建时间升序，2：按创建时间降序，3：按更新时间升序，4：按更新时间降序，5：按内容顺序（仅在按文档分组时），6：按相关度升序，7：按相关度降序
+// method：0：关键字，1：查询语法，2：SQL，3：正则表达式，4：语义，5：模糊
+// orderBy: 0：按块类型（默认），1：按创建时间升序，2：按创建时间降序，3：按更新时间升序，4：按更新时间降序，5：按内容顺序（仅在按文档分组时），6：按相关度升序，7：按相关度降序，8：按综合得分降序（仅在关键字、查询语法和模糊搜索时）
 // groupBy：0：不分组，1：按文档分组
+//
+// 查询语法中包含 attr:、tag:、type: 等搜索操作符时按 searchQueryOperators 中描述的语法搜索。
 func FullTextSearchBlock(query string, boxes, paths []string, types map[string]bool, method, orderBy, groupBy, page, pageSize int) (ret []*Block, matchedBlockCount, matchedRootCount, pageCount int, docMode bool) {
 	ret = []*Block{}
 	if "" == query {
@@ -1018,6 +1020,10 @@ func FullTextSearchBlock(query string, boxes, paths []string, types map[string]b
 		pathFilter := buildPathsFilter(paths)
 		if ast.IsNodeIDPattern(query) {
 			blocks, matchedBlockCount, matchedRootCount = searchBySQL("SELECT * FROM `blocks` WHERE `id` = '"+query+"'", beforeLen, page, pageSize)
+		} else if isSearchQuerySyntax(query) {
+			blocks, matchedBlockCount, matchedRootCount = searchByQuerySyntax(query, boxFilter, pathFilter, typeFilter, ignoreFilter, orderBy, beforeLen, page, pageSize)
+		} else if 8 == orderBy {
+			blocks, matchedBlockCount, matchedRootCount = fullTextSearchByFTSWithScore(query, boxFilter, pathFilter, typeFilter, ignoreFilter, beforeLen, page, pageSize)
 		} else {
 			blocks, matchedBlockCount, matchedRootCount = fullTextSearchByFTS(query, boxFilter, pathFilter, typeFilter, ignoreFilter, orderByClause, beforeLen, page, pageSize)
 		}
@@ -1028,6 +1034,21 @@ func FullTextSearchBlock(query string, boxes, paths []string, types map[string]b
 		boxFilter := buildBoxesFilter(boxes)
 		pathFilter := buildPathsFilter(paths)
 		blocks, matchedBlockCount, matchedRootCount = fullTextSearchByRegexp(query, boxFilter, pathFilter, typeFilter, ignoreFilter, orderByClause, beforeLen, page, pageSize)
+	case 4: // 语义
+		typeFilter := buildTypeFilter(types)
+		boxFilter := buildBoxesFilter(boxes)
+		pathFilter := buildPathsFilter(paths)
+		blocks, matchedBlockCount, matchedRootCount = semanticSearch(query, boxFilter, pathFilter, typeFilter, ignoreFilter, beforeLen, page, pageSize)
+	case 5: // 模糊
+		typeFilter := buildTypeFilter(types)
+		boxFilter := buildBoxesFilter(boxes)
+		pathFilter := buildPathsFilter(paths)
+		fuzzy := fuzzyQuery(query)
+		if 8 == orderBy {
+			blocks, matchedBlockCount, matchedRootCount = fullTextSearchByFTSWithScore(fuzzy, boxFilter, pathFilter, typeFilter, ignoreFilter, beforeLen, page, pageSize)
+		} else {
+			blocks, matchedBlockCount, matchedRootCount = fullTextSearchByFTS(fuzzy, boxFilter, pathFilter, typeFilter, ignoreFilter, orderByClause, beforeLen, page, pageSize)
+		}
 	default: // 关键字
 		typeFilter := buildTypeFilter(types)
 		boxFilter := buildBoxesFilter(boxes)
@@ -1037,7 +1058,11 @@ func FullTextSearchBlock(query string, boxes, paths []string, types map[string]b
 		} else {
 			if 2 > len(strings.Split(strings.TrimSpace(query), " ")) {
 				query = stringQuery(query)
-				blocks, matchedBlockCount, matchedRootCount = fullTextSearchByFTS(query, boxFilter, pathFilter, typeFilter, ignoreFilter, orderByClause, beforeLen, page, pageSize)
+				if 8 == orderBy {
+					blocks, matchedBlockCount, matchedRootCount = fullTextSearchByFTSWithScore(query, boxFilter, pathFilter, typeFilter, ignoreFilter, beforeLen, page, pageSize)
+				} else {
+					blocks, matchedBlockCount, matchedRootCount = fullTextSearchByFTS(query, boxFilter, pathFilter, typeFilter, ignoreFilter, orderByClause, beforeLen, page, pageSize)
+				}
 			} else {
 				docMode = true // 文档全文搜索模式 https://github.com/siyuan-note/siyuan/issues/10584
 				blocks, matchedBlockCount, matchedRootCount = fullTextSearchByLikeWithRoot(query, boxFilter, pathFilter, typeFilter, ignoreFilter, orderByClause, beforeLen, page, pageSize)
@@ -1194,6 +1219,9 @@ func buildOrderBy(query string, method, orderBy int) string {
 			return "ORDER BY sort ASC, updated DESC"
 		}
 		return "ORDER BY rank" // 默认是按相关度降序
+	case 8:
+		// 综合得分仅在全文索引搜索时计算，其他搜索方式按更新时间降序
+		return "ORDER BY updated DESC"
 	default:
 		clause := "ORDER BY CASE " +
 			"WHEN name = '${keyword}' THEN 10 " +
@@ -1294,7 +1322,7 @@ func removeLimitClause(stmt string) string {
 	return stmt
 }
 
-func fullTextSearchRefBlock(keyword string, beforeLen int, onlyDoc bool) (ret []*Block) {
+func fullTextSearchRefBlock(keyword string, beforeLen int, onlyDoc, fuzzy bool) (ret []*Block) {
 	keyword = filterQueryInvisibleChars(keyword)
 
 	if id := extractID(keyword); "" != id {
@@ -1303,6 +1331,10 @@ func fullTextSearchRefBlock(keyword string, beforeLen int, onlyDoc bool) (ret []
 	}
 
 	quotedKeyword := stringQuery(keyword)
+	if fuzzy {
+		// 模糊搜索时匹配拼写相近和词形变化的词
+		quotedKeyword = fuzzyQuery(keyword)
+	}
 	table := "blocks_fts" // 大小写敏感
 	if !Conf.Search.CaseSensitive {
 		table = "blocks_fts_case_insensitive"
@@ -1408,21 +1440,59 @@ func fullTextSearchCountByRegexp(exp, boxFilter, pathFilter, typeFilter, ignoreF
 	return
 }
 
+// semanticSearch 按照和查询文本的余弦相似度降序搜索块，最多命中 Conf.Search.Limit 个块。
+func semanticSearch(query, boxFilter, pathFilter, typeFilter, ignoreFilter string, beforeLen, page, pageSize int) (ret []*Block, matchedBlockCount, matchedRootCount int) {
+	ret = []*Block{}
+	filter := " AND type IN " + typeFilter + boxFilter + pathFilter + ignoreFilter
+	ids, err := sql.SemanticSearchBlockIDs(query, filter, Conf.Search.Limit)
+	if err != nil {
+		logging.LogErrorf("semantic search failed: %s", err)
+		util.PushErrMsg(err.Error(), 5000)
+		return
+	}
+
+	matchedBlockCount = len(ids)
+	start, end := (page-1)*pageSize, page*pageSize
+	if start > len(ids) {
+		start = len(ids)
+	}
+	if end > len(ids) {
+		end = len(ids)
+	}
+
+	var blocks []*sql.Block
+	for _, b := range sql.GetBlocks(ids) {
+		if nil != b {
+			blocks = append(blocks, b)
+		}
+	}
+	rootIDs := map[string]bool{}
+	pageBlocks := map[string]bool{}
+	for _, id := range ids[start:end] {
+		pageBlocks[id] = true
+	}
+	var pageSQLBlocks []*sql.Block
+	for _, b := range blocks {
+		rootIDs[b.RootID] = true
+		if pageBlocks[b.ID] {
+			pageSQLBlocks = append(pageSQLBlocks, b)
+		}
+	}
+	matchedRootCount = len(rootIDs)
+
+	ret = fromSQLBlocks(&pageSQLBlocks, "", beforeLen)
+	if 1 > len(ret) {
+		ret = []*Block{}
+	}
+	return
+}
+
 func fullTextSearchByFTS(query, boxFilter, pathFilter, typeFilter, ignoreFilter, orderBy string, beforeLen, page, pageSize int) (ret []*Block, matchedBlockCount, matchedRootCount int) {
 	table := "blocks_fts" // 大小写敏感
 	if !Conf.Search.CaseSensitive {
 		table = "blocks_fts_case_insensitive"
 	}
-	projections := "id, parent_id, root_id, hash, box, path, " +
-		// Search result content snippet returns more text https://github.com/siyuan-note/siyuan/issues/10707
-		"snippet(" + table + ", 6, '" + search.SearchMarkLeft + "', '" + search.SearchMarkRight + "', '...', 512) AS hpath, " +
-		"snippet(" + table + ", 7, '" + search.SearchMarkLeft + "', '" + search.SearchMarkRight + "', '...', 512) AS name, " +
-		"snippet(" + table + ", 8, '" + search.SearchMarkLeft + "', '" + search.SearchMarkRight + "', '...', 512) AS alias, " +
-		"snippet(" + table + ", 9, '" + search.SearchMarkLeft + "', '" + search.SearchMarkRight + "', '...', 512) AS memo, " +
-		"snippet(" + table + ", 10, '" + search.SearchMarkLeft + "', '" + search.SearchMarkRight + "', '...', 64) AS tag, " +
-		"snippet(" + table + ", 11, '" + search.SearchMarkLeft + "', '" + search.SearchMarkRight + "', '...', 512) AS content, " +
-		"fcontent, markdown, length, type, subtype, ial, sort, created, updated"
-	stmt := "SELECT " + projections + " FROM " + table + " WHERE (`" + table + "` MATCH '" + columnFilter() + ":(" + query + ")'"
+	stmt := "SELECT " + ftsProjections(table) + " FROM " + table + " WHERE (`" + table + "` MATCH '" + columnFilter() + ":(" + query + ")'"
 	stmt += ") AND type IN " + typeFilter
 	stmt += boxFilter + pathFilter + ignoreFilter + " " + orderBy
 	stmt += " LIMIT " + strconv.Itoa(pageSize) + " OFFSET " + strconv.Itoa((page-1)*pageSize)
@@ -1436,6 +1506,18 @@ func fullTextSearchByFTS(query, boxFilter, pathFilter, typeFilter, ignoreFilter,
 	return
 }
 
+func ftsProjections(table string) string {
+	return "id, parent_id, root_id, hash, box, path, " +
+		// Search result content snippet returns more text https://github.com/siyuan-note/siyuan/issues/10707
+		"snippet(" + table + ", 6, '" + search.SearchMarkLeft + "', '" + search.SearchMarkRight + "', '...', 512) AS hpath, " +
+		"snippet(" + table + ", 7, '" + search.SearchMarkLeft + "', '" + search.SearchMarkRight + "', '...', 512) AS name, " +
+		"snippet(" + table + ", 8, '" + search.SearchMarkLeft + "', '" + search.SearchMarkRight + "', '...', 512) AS alias, " +
+		"snippet(" + table + ", 9, '" + search.SearchMarkLeft + "', '" + search.SearchMarkRight + "', '...', 512) AS memo, " +
+		"snippet(" + table + ", 10, '" + search.SearchMarkLeft + "', '" + search.SearchMarkRight + "', '...', 64) AS tag, " +
+		"snippet(" + table + ", 11, '" + search.SearchMarkLeft + "', '" + search.SearchMarkRight + "', '...', 512) AS content, " +
+		"fcontent, markdown, length, type, subtype, ial, sort, created, updated"
+}
+
 func fullTextSearchCountByFTS(query, boxFilter, pathFilter, typeFilter, ignoreFilter string) (matchedBlockCount, matchedRootCount int) {
 	table := "blocks_fts" // 大小写敏感
 	if !Conf.Search.CaseSensitive {
@@ -1454,6 +1536,496 @@ func fullTextSearchCountByFTS(query, boxFilter, pathFilter, typeFilter, ignoreFi
 	return
 }
 
+// searchQueryOperators 查询语法中的搜索操作符，包含操作符时按操作符查询语法搜索，否则将查询语法直接作为全文索引查询语法。
+//
+//	attr:name=value  属性值等于 value，attr:name~value 属性值包含 value，attr:name 存在该属性。name 不是内置属性时自动加上 custom- 前缀
+//	tag:name         包含标签，同时匹配子标签
+//	type:p           块类型，支持 d、h、h1~h6、p、l、i、c、m、t、b、s、html、embed、av、audio、video、iframe、widget 以及 doc、heading、paragraph 等全称
+//	path:/a/b        文档路径（人类可读路径）为 /a/b 或者在 /a/b 下
+//	box:name         笔记本名称或者 ID
+//	updated:>date    更新时间，支持 >、>=、<、<=、= 比较，date 支持 2024-01-01、20240101、2024-01、2024、today、yesterday、7d（7 天前）和 2w（2 周前）
+//	created:<date    创建时间，同 updated
+//	has:ref          包含引用，还支持 backlink、tag、name、alias、memo、bookmark、link、asset
+//	is:task-done     已完成的任务列表项，还支持 task、task-todo
+//
+// 词之间默认是 AND 关系，支持 OR、NOT、- 前缀和括号分组，其他的词或者双引号包裹的短语作为关键字在全文索引中搜索。
+var searchQueryOperators = map[string]bool{"attr": true, "tag": true, "type": true, "path": true, "box": true, "updated": true, "created": true, "has": true, "is": true}
+
+var searchQueryTypes = map[string]string{
+	"d": "d", "doc": "d", "document": "d",
+	"h": "h", "heading": "h",
+	"p": "p", "paragraph": "p",
+	"l": "l", "list": "l",
+	"i": "i", "li": "i", "listitem": "i",
+	"c": "c", "code": "c", "codeblock": "c",
+	"m": "m", "math": "m", "mathblock": "m",
+	"t": "t", "table": "t",
+	"b": "b", "quote": "b", "blockquote": "b",
+	"s": "s", "superblock": "s",
+	"html": "html", "htmlblock": "html",
+	"embed": "query_embed", "query_embed": "query_embed",
+	"av": "av", "database": "av",
+	"audio": "audio", "video": "video", "iframe": "iframe", "widget": "widget",
+}
+
+var searchQueryHas = map[string]string{
+	"ref":      "id IN (SELECT block_id FROM refs)",
+	"backlink": "id IN (SELECT def_block_id FROM refs)",
+	"tag":      "tag <> ''",
+	"name":     "name <> ''",
+	"alias":    "alias <> ''",
+	"memo":     "memo <> ''",
+	"bookmark": "id IN (SELECT block_id FROM attributes WHERE name = 'bookmark')",
+	"link":     "id IN (SELECT block_id FROM spans WHERE type = 'a' OR type LIKE 'a %' OR type LIKE '% a' OR type LIKE '% a %')", // 类型是空格分隔的多个类型，比如 strong a
+	"asset":    "id IN (SELECT block_id FROM assets)",
+}
+
+var searchQueryIs = map[string]string{
+	"task": "(type = 'i' AND subtype = 't')",
+	// 任务列表项的 Markdown 以 * [X]、- [X] 或者 1. [X] 开头，LIKE 不区分大小写
+	"task-done": "(type = 'i' AND subtype = 't' AND (markdown LIKE '_ [X]%' OR markdown LIKE '__ [X]%' OR markdown LIKE '___ [X]%'))",
+	"task-todo": "(type = 'i' AND subtype = 't' AND (markdown LIKE '_ [ ]%' OR markdown LIKE '__ [ ]%' OR markdown LIKE '___ [ ]%'))",
+}
+
+var searchQueryRelativeDateRegexp = regexp.MustCompile(`^(\d+)([dw])$`)
+
+type searchQueryToken struct {
+	typ    int    // 0：词，1：左括号，2：右括号，3：- 前缀
+	text   string // 去掉双引号后的文本
+	quoted bool   // 整个词由双引号包裹
+}
+
+func isSearchQuerySyntax(query string) bool {
+	for _, token := range tokenizeSearchQuery(query) {
+		if _, _, ok := searchQueryOperator(token); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func tokenizeSearchQuery(query string) (ret []*searchQueryToken) {
+	runes := []rune(query)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case ' ' == r || '\t' == r || '\n' == r || '　' == r:
+			i++
+		case '(' == r:
+			ret = append(ret, &searchQueryToken{typ: 1})
+			i++
+		case ')' == r:
+			ret = append(ret, &searchQueryToken{typ: 2})
+			i++
+		case '-' == r && i+1 < len(runes) && ' ' != runes[i+1] && ')' != runes[i+1]:
+			ret = append(ret, &searchQueryToken{typ: 3})
+			i++
+		default:
+			buf := strings.Builder{}
+			quoted := '"' == r
+			for i < len(runes) {
+				r = runes[i]
+				if ' ' == r || '\t' == r || '\n' == r || '　' == r || '(' == r || ')' == r {
+					break
+				}
+				if '"' == r { // 引号中的内容作为一个整体，可以包含空格和括号
+					i++
+					for i < len(runes) && '"' != runes[i] {
+						buf.WriteRune(runes[i])
+						i++
+					}
+					i++
+					continue
+				}
+				buf.WriteRune(r)
+				i++
+			}
+			ret = append(ret, &searchQueryToken{text: buf.String(), quoted: quoted})
+		}
+	}
+	return
+}
+
+func searchQueryOperator(token *searchQueryToken) (op, value string, ok bool) {
+	if 0 != token.typ || token.quoted {
+		return
+	}
+	idx := strings.Index(token.text, ":")
+	if 1 > idx {
+		return
+	}
+	op = strings.ToLower(token.text[:idx])
+	if !searchQueryOperators[op] {
+		return
+	}
+	return op, token.text[idx+1:], true
+}
+
+// searchQueryParser 将查询语法编译为 blocks 表上的查询条件，条件中的值都使用 ? 占位符绑定。
+type searchQueryParser struct {
+	tokens  []*searchQueryToken
+	pos     int
+	table   string   // 关键字使用的全文索引表
+	negated bool     // 当前是否处于 NOT 中
+	terms   []string // 用于高亮的关键字
+}
+
+// parseSearchQuery 解析查询语法，返回查询条件、绑定参数和用于高亮的关键字。
+func parseSearchQuery(query, table string) (cond string, args []interface{}, terms []string, err error) {
+	parser := &searchQueryParser{tokens: tokenizeSearchQuery(query), table: table}
+	cond, args, err = parser.parseOr()
+	if nil != err {
+		return
+	}
+	if parser.pos < len(parser.tokens) {
+		err = errors.New("unexpected [)] in search query")
+		return
+	}
+	terms = parser.terms
+	return
+}
+
+func (parser *searchQueryParser) peekKeyword(keyword string) bool {
+	if parser.pos >= len(parser.tokens) {
+		return false
+	}
+	token := parser.tokens[parser.pos]
+	return 0 == token.typ && !token.quoted && keyword == token.text
+}
+
+func (parser *searchQueryParser) parseOr() (cond string, args []interface{}, err error) {
+	cond, args, err = parser.parseAnd()
+	if nil != err {
+		return
+	}
+	for parser.peekKeyword("OR") {
+		parser.pos++
+		right, rightArgs, rightErr := parser.parseAnd()
+		if nil != rightErr {
+			err = rightErr
+			return
+		}
+		cond = "(" + cond + " OR " + right + ")"
+		args = append(args, rightArgs...)
+	}
+	return
+}
+
+func (parser *searchQueryParser) parseAnd() (cond string, args []interface{}, err error) {
+	var conds []string
+	for parser.pos < len(parser.tokens) {
+		if 2 == parser.tokens[parser.pos].typ || parser.peekKeyword("OR") {
+			break
+		}
+		if parser.peekKeyword("AND") {
+			parser.pos++
+			continue
+		}
+
+		c, cArgs, cErr := parser.parseUnary()
+		if nil != cErr {
+			err = cErr
+			return
+		}
+		conds = append(conds, c)
+		args = append(args, cArgs...)
+	}
+
+	if 1 > len(conds) {
+		err = errors.New("empty expression in search query")
+		return
+	}
+	cond = strings.Join(conds, " AND ")
+	if 1 < len(conds) {
+		cond = "(" + cond + ")"
+	}
+	return
+}
+
+func (parser *searchQueryParser) parseUnary() (cond string, args []interface{}, err error) {
+	if 3 == parser.tokens[parser.pos].typ || parser.peekKeyword("NOT") {
+		parser.pos++
+		if parser.pos >= len(parser.tokens) {
+			err = errors.New("missing expression after NOT in search query")
+			return
+		}
+
+		parser.negated = !parser.negated
+		cond, args, err = parser.parseUnary()
+		parser.negated = !parser.negated
+		cond = "NOT " + cond
+		return
+	}
+	return parser.parsePrimary()
+}
+
+func (parser *searchQueryParser) parsePrimary() (cond string, args []interface{}, err error) {
+	token := parser.tokens[parser.pos]
+	parser.pos++
+	switch token.typ {
+	case 1:
+		cond, args, err = parser.parseOr()
+		if nil != err {
+			return
+		}
+		if parser.pos >= len(parser.tokens) || 2 != parser.tokens[parser.pos].typ {
+			err = errors.New("missing [)] in search query")
+			return
+		}
+		parser.pos++
+		if !strings.HasPrefix(cond, "(") {
+			cond = "(" + cond + ")"
+		}
+		return
+	case 2:
+		err = errors.New("unexpected [)] in search query")
+		return
+	}
+
+	if op, value, ok := searchQueryOperator(token); ok {
+		return parser.compileOperator(op, value)
+	}
+	return parser.compileTerm(token)
+}
+
+// compileTerm 将关键字编译为全文索引子查询，未加引号且以 * 结尾的关键字按前缀匹配。
+func (parser *searchQueryParser) compileTerm(token *searchQueryToken) (cond string, args []interface{}, err error) {
+	text := token.text
+	prefix := !token.quoted && 1 < len(text) && strings.HasSuffix(text, "*")
+	if prefix {
+		text = strings.TrimSuffix(text, "*")
+	}
+	if "" == text {
+		err = errors.New("empty keyword in search query")
+		return
+	}
+
+	phrase := "\"" + strings.ReplaceAll(text, "\"", "\"\"") + "\""
+	if prefix {
+		phrase += "*"
+	}
+	if !parser.negated {
+		parser.terms = append(parser.terms, text)
+	}
+	cond = "id IN (SELECT id FROM " + parser.table + " WHERE " + parser.table + " MATCH ?)"
+	args = append(args, columnFilter()+":("+phrase+")")
+	return
+}
+
+func (parser *searchQueryParser) compileOperator(op, value string) (cond string, args []interface{}, err error) {
+	if "" == value && "attr" != op {
+		err = fmt.Errorf("missing value of [%s:] in search query", op)
+		return
+	}
+
+	switch op {
+	case "attr":
+		name, val, cmp := value, "", ""
+		if idx := strings.IndexAny(value, "=~"); 0 <= idx {
+			name, val, cmp = value[:idx], value[idx+1:], value[idx:idx+1]
+		}
+		name = strings.TrimSpace(name)
+		if "" == name {
+			err = errors.New("missing attribute name of [attr:] in search query")
+			return
+		}
+		if !strings.HasPrefix(name, "custom-") && "name" != name && "alias" != name && "memo" != name && "bookmark" != name {
+			// 属性表中只有内置属性和自定义属性，自定义属性可以省略 custom- 前缀
+			name = "custom-" + name
+		}
+
+		switch cmp {
+		case "=":
+			cond = "id IN (SELECT block_id FROM attributes WHERE name = ? AND value = ?)"
+			args = append(args, name, val)
+		case "~":
+			cond = "id IN (SELECT block_id FROM attributes WHERE name = ? AND instr(value, ?) > 0)"
+			args = append(args, name, val)
+		default:
+			cond = "id IN (SELECT block_id FROM attributes WHERE name = ?)"
+			args = append(args, name)
+		}
+	case "tag":
+		tag := strings.Trim(value, "#")
+		cond = "(instr(tag, ?) > 0 OR instr(tag, ?) > 0)"
+		args = append(args, "#"+tag+"#", "#"+tag+"/")
+		if !parser.negated {
+			parser.terms = append(parser.terms, tag)
+		}
+	case "type":
+		typ := strings.ToLower(value)
+		if 2 == len(typ) && 'h' == typ[0] && '1' <= typ[1] && '6' >= typ[1] {
+			cond = "(type = 'h' AND subtype = ?)"
+			args = append(args, typ)
+			return
+		}
+		if abbr, ok := searchQueryTypes[typ]; ok {
+			cond = "type = ?"
+			args = append(args, abbr)
+			return
+		}
+		err = fmt.Errorf("unknown block type [%s] in search query", value)
+	case "path":
+		hpath := "/" + strings.Trim(value, "/")
+		if "/" == hpath {
+			cond = "1 = 1"
+			return
+		}
+		cond = "(hpath = ? OR instr(hpath, ?) = 1)"
+		args = append(args, hpath, hpath+"/")
+	case "box":
+		boxID := value
+		if !ast.IsNodeIDPattern(value) {
+			boxID = ""
+			for _, box := range Conf.GetOpenedBoxes() {
+				if strings.EqualFold(box.Name, value) {
+					boxID = box.ID
+					break
+				}
+			}
+			if "" == boxID {
+				err = fmt.Errorf("notebook [%s] not found in search query", value)
+				return
+			}
+		}
+		cond = "box = ?"
+		args = append(args, boxID)
+	case "updated", "created":
+		cond, args, err = compileSearchQueryDate(op, value)
+	case "has":
+		var ok bool
+		if cond, ok = searchQueryHas[strings.ToLower(value)]; !ok {
+			err = fmt.Errorf("unknown value [%s] of [has:] in search query", value)
+		}
+	case "is":
+		var ok bool
+		if cond, ok = searchQueryIs[strings.ToLower(value)]; !ok {
+			err = fmt.Errorf("unknown value [%s] of [is:] in search query", value)
+		}
+	}
+	return
+}
+
+// compileSearchQueryDate 将日期比较编译为时间范围条件，比如 updated:>2024-01-01 表示 2024-01-02 及以后更新的块。
+func compileSearchQueryDate(column, value string) (cond string, args []interface{}, err error) {
+	cmp := "="
+	for _, c := range []string{">=", "<=", ">", "<", "="} {
+		if strings.HasPrefix(value, c) {
+			cmp = c
+			value = value[len(c):]
+			break
+		}
+	}
+
+	start, end, err := parseSearchQueryDate(value)
+	if nil != err {
+		return
+	}
+
+	layout := "20060102150405"
+	switch cmp {
+	case ">":
+		cond = column + " >= ?"
+		args = append(args, end.Format(layout))
+	case ">=":
+		cond = column + " >= ?"
+		args = append(args, start.Format(layout))
+	case "<":
+		cond = column + " < ?"
+		args = append(args, start.Format(layout))
+	case "<=":
+		cond = column + " < ?"
+		args = append(args, end.Format(layout))
+	default:
+		cond = "(" + column + " >= ? AND " + column + " < ?)"
+		args = append(args, start.Format(layout), end.Format(layout))
+	}
+	return
+}
+
+// parseSearchQueryDate 解析日期，返回日期表示的时间范围 [start, end)。
+func parseSearchQueryDate(value string) (start, end time.Time, err error) {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	switch strings.ToLower(value) {
+	case "today":
+		return today, today.AddDate(0, 0, 1), nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), today, nil
+	}
+
+	if m := searchQueryRelativeDateRegexp.FindStringSubmatch(strings.ToLower(value)); nil != m {
+		days, _ := strconv.Atoi(m[1])
+		if "w" == m[2] {
+			days *= 7
+		}
+		start = today.AddDate(0, 0, -days)
+		return start, start.AddDate(0, 0, 1), nil
+	}
+
+	layouts := []struct {
+		layout        string
+		years, months int
+		days          int
+	}{
+		{"2006-01-02", 0, 0, 1},
+		{"20060102", 0, 0, 1},
+		{"2006-01", 0, 1, 0},
+		{"2006", 1, 0, 0},
+	}
+	for _, l := range layouts {
+		if len(l.layout) != len(value) {
+			continue
+		}
+		if start, err = time.ParseInLocation(l.layout, value, time.Local); nil == err {
+			return start, start.AddDate(l.years, l.months, l.days), nil
+		}
+	}
+	err = fmt.Errorf("invalid date [%s] in search query", value)
+	return
+}
+
+// searchByQuerySyntax 按包含搜索操作符的查询语法搜索。
+func searchByQuerySyntax(query, boxFilter, pathFilter, typeFilter, ignoreFilter string, orderBy, beforeLen, page, pageSize int) (ret []*Block, matchedBlockCount, matchedRootCount int) {
+	ret = []*Block{}
+	table := "blocks_fts" // 大小写敏感
+	if !Conf.Search.CaseSensitive {
+		table = "blocks_fts_case_insensitive"
+	}
+
+	cond, args, terms, err := parseSearchQuery(query, table)
+	if nil != err {
+		util.PushErrMsg(err.Error(), 5000)
+		return
+	}
+
+	var orderByClause string
+	switch orderBy {
+	case 6, 7, 8: // 没有使用全文索引表查询，不支持按相关度排序
+		orderByClause = "ORDER BY sort ASC, updated DESC"
+	default:
+		orderByClause = buildOrderBy(strings.Join(terms, " "), 1, orderBy)
+	}
+
+	where := " WHERE " + cond + " AND type IN " + typeFilter + boxFilter + pathFilter + ignoreFilter
+	stmt := "SELECT * FROM blocks" + where + " " + orderByClause
+	stmt += " LIMIT " + strconv.Itoa(pageSize) + " OFFSET " + strconv.Itoa((page-1)*pageSize)
+	blocks := sql.SelectBlocksRawStmt(stmt, page, pageSize, args...)
+	ret = fromSQLBlocks(&blocks, strings.Join(terms, search.TermSep), beforeLen)
+	if 1 > len(ret) {
+		ret = []*Block{}
+	}
+
+	stmt = "SELECT COUNT(id) AS `matches`, COUNT(DISTINCT(root_id)) AS `docs` FROM blocks" + where
+	result, _ := sql.Query(stmt, 1, args...)
+	if 1 > len(result) {
+		return
+	}
+	matchedBlockCount = int(result[0]["matches"].(int64))
+	matchedRootCount = int(result[0]["docs"].(int64))
+	return
+}
+
 func fullTextSearchByLikeWithRoot(query, boxFilter, pathFilter, typeFilter, ignoreFilter, orderBy string, beforeLen, page, pageSize int) (ret []*Block, matchedBlockCount, matchedRootCount int) {
 	query = strings.ReplaceAll(query, "'", "''") // 不需要转义双引号，因为条件都是通过单引号包裹的，只需要转义单引号即可
 	keywords := strings.Split(query, " ")
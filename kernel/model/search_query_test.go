@@ -0,0 +1,164 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package model
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/88250/vitess-sqlparser/sqlparser"
+	"github.com/siyuan-note/siyuan/kernel/conf"
+)
+
+func TestParseSearchQuery(t *testing.T) {
+	oldConf := Conf
+	Conf = &AppConf{Search: conf.NewSearch()}
+	defer func() { Conf = oldConf }()
+
+	cases := []struct {
+		query string
+		cond  string
+		args  []interface{}
+		terms []string
+	}{
+		{
+			"type:h2 tag:work",
+			"((type = 'h' AND subtype = ?) AND (instr(tag, ?) > 0 OR instr(tag, ?) > 0))",
+			[]interface{}{"h2", "#work#", "#work/"},
+			[]string{"work"},
+		},
+		{
+			"attr:status=done OR attr:priority",
+			"(id IN (SELECT block_id FROM attributes WHERE name = ? AND value = ?) OR id IN (SELECT block_id FROM attributes WHERE name = ?))",
+			[]interface{}{"custom-status", "done", "custom-priority"},
+			nil,
+		},
+		{
+			"path:/a/b -is:task-done",
+			"((hpath = ? OR instr(hpath, ?) = 1) AND NOT " + searchQueryIs["task-done"] + ")",
+			[]interface{}{"/a/b", "/a/b/"},
+			nil,
+		},
+		{
+			`type:p "foo bar" (has:link OR has:ref)`,
+			"(type = ? AND id IN (SELECT id FROM blocks_fts WHERE blocks_fts MATCH ?) AND (" + searchQueryHas["link"] + " OR " + searchQueryHas["ref"] + "))",
+			[]interface{}{"p", columnFilter() + `:("foo bar")`},
+			[]string{"foo bar"},
+		},
+		{
+			"has:tag NOT foo*",
+			"(tag <> '' AND NOT id IN (SELECT id FROM blocks_fts WHERE blocks_fts MATCH ?))",
+			[]interface{}{columnFilter() + `:("foo"*)`},
+			nil,
+		},
+	}
+	for _, c := range cases {
+		cond, args, terms, err := parseSearchQuery(c.query, "blocks_fts")
+		if nil != err {
+			t.Fatalf("parse [%s] failed: %s", c.query, err)
+		}
+		if c.cond != cond {
+			t.Fatalf("parse [%s] got cond [%s], expected [%s]", c.query, cond, c.cond)
+		}
+		if !reflect.DeepEqual(c.args, args) {
+			t.Fatalf("parse [%s] got args %v, expected %v", c.query, args, c.args)
+		}
+		if !reflect.DeepEqual(c.terms, terms) {
+			t.Fatalf("parse [%s] got terms %v, expected %v", c.query, terms, c.terms)
+		}
+		if strings.Count(cond, "?") != len(args) {
+			t.Fatalf("parse [%s] got %d placeholders for %d args", c.query, strings.Count(cond, "?"), len(args))
+		}
+	}
+
+	for _, query := range []string{"(type:p", "type:p)", "type:unknown", "has:unknown", "is:unknown", "updated:>foo", "NOT", "tag:"} {
+		if _, _, _, err := parseSearchQuery(query, "blocks_fts"); nil == err {
+			t.Fatalf("expected error for [%s]", query)
+		}
+	}
+}
+
+// TestSearchQueryHasIs 检查 has: 和 is: 的条件经过 SQL 解析器改写后语义不变，解析器会将 || 改写为 or。
+func TestSearchQueryHasIs(t *testing.T) {
+	var conds []string
+	for _, cond := range searchQueryHas {
+		conds = append(conds, cond)
+	}
+	for _, cond := range searchQueryIs {
+		conds = append(conds, cond)
+	}
+	for _, cond := range conds {
+		if strings.Contains(cond, "||") {
+			t.Fatalf("condition [%s] contains [||]", cond)
+		}
+		if _, err := sqlparser.Parse("SELECT * FROM blocks WHERE " + cond); nil != err {
+			t.Fatalf("parse condition [%s] failed: %s", cond, err)
+		}
+	}
+}
+
+func TestCompileSearchQueryDate(t *testing.T) {
+	cases := []struct {
+		value string
+		cond  string
+		args  []interface{}
+	}{
+		{"2024-01-01", "(updated >= ? AND updated < ?)", []interface{}{"20240101000000", "20240102000000"}},
+		{"=20240101", "(updated >= ? AND updated < ?)", []interface{}{"20240101000000", "20240102000000"}},
+		{">2024-01-01", "updated >= ?", []interface{}{"20240102000000"}},
+		{">=2024-01-01", "updated >= ?", []interface{}{"20240101000000"}},
+		{"<2024-01", "updated < ?", []interface{}{"20240101000000"}},
+		{"<=2024-01", "updated < ?", []interface{}{"20240201000000"}},
+		{"2024", "(updated >= ? AND updated < ?)", []interface{}{"20240101000000", "20250101000000"}},
+		{"2024-12", "(updated >= ? AND updated < ?)", []interface{}{"20241201000000", "20250101000000"}},
+	}
+	for _, c := range cases {
+		cond, args, err := compileSearchQueryDate("updated", c.value)
+		if nil != err {
+			t.Fatalf("compile [%s] failed: %s", c.value, err)
+		}
+		if c.cond != cond || !reflect.DeepEqual(c.args, args) {
+			t.Fatalf("compile [%s] got [%s] %v, expected [%s] %v", c.value, cond, args, c.cond, c.args)
+		}
+	}
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	relatives := map[string]time.Time{
+		"today":     today,
+		"yesterday": today.AddDate(0, 0, -1),
+		"7d":        today.AddDate(0, 0, -7),
+		"2w":        today.AddDate(0, 0, -14),
+	}
+	for value, start := range relatives {
+		s, e, err := parseSearchQueryDate(value)
+		if nil != err {
+			t.Fatalf("parse [%s] failed: %s", value, err)
+		}
+		if !s.Equal(start) || !e.Equal(start.AddDate(0, 0, 1)) {
+			t.Fatalf("parse [%s] got [%s, %s)", value, s, e)
+		}
+	}
+
+	for _, value := range []string{"", "2024-13-01", "2024/01/01", "7x", "tomorrow"} {
+		if _, _, err := compileSearchQueryDate("updated", value); nil == err {
+			t.Fatalf("expected error for [%s]", value)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package model
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/siyuan-note/siyuan/kernel/sql"
+)
+
+// SearchScore 描述了搜索结果的综合得分，各项得分都归一化到 [0, 1]，Total 为加权和。
+type SearchScore struct {
+	Total    float64 `json:"total"`
+	BM25     float64 `json:"bm25"`     // 全文索引相关度
+	Recency  float64 `json:"recency"`  // 更新时间越近得分越高
+	Refs     float64 `json:"refs"`     // 被引用次数越多得分越高
+	RefCount int     `json:"refCount"` // 被引用次数
+}
+
+const (
+	searchScoreWeightBM25    = 0.6
+	searchScoreWeightRecency = 0.25
+	searchScoreWeightRefs    = 0.15
+	searchScoreRecencyDays   = 30.0 // 更新时间超过该天数后得分衰减到一半
+	searchScoreCandidates    = 1024 // 按相关度取前若干个块计算综合得分
+	fuzzyTermCandidates      = 5
+)
+
+// fuzzySuffixes 模糊搜索时去掉的常见英文词尾，用于匹配词形变化。
+var fuzzySuffixes = []string{"ing", "ed", "es", "s", "ly"}
+
+// fuzzyQuery 将关键字转换为全文索引查询语法。
+//
+// 每个词扩展为：原词、原词前缀、去掉常见词尾后的前缀以及块内容中编辑距离相近的词，词之间为 AND 关系。
+// 包含中日韩文字的词不进行扩展。
+func fuzzyQuery(keyword string) string {
+	keyword = strings.ReplaceAll(keyword, "\"", " ")
+	buf := strings.Builder{}
+	for _, part := range strings.Fields(keyword) {
+		alternatives := []string{"\"" + part + "\""}
+		if isFuzzyTerm(part) {
+			alternatives = append(alternatives, "\""+part+"\"*")
+			partLen := utf8.RuneCountInString(part)
+			for _, suffix := range fuzzySuffixes {
+				if strings.HasSuffix(strings.ToLower(part), suffix) && 3 <= partLen-len(suffix) {
+					alternatives = append(alternatives, "\""+part[:len(part)-len(suffix)]+"\"*")
+					break
+				}
+			}
+			for _, term := range sql.FuzzyTerms(part, Conf.Search.CaseSensitive, fuzzyTermCandidates) {
+				alternatives = append(alternatives, "\""+strings.ReplaceAll(term, "\"", "\"\"")+"\"")
+			}
+		}
+
+		if 0 < buf.Len() {
+			buf.WriteString(" AND ")
+		}
+		buf.WriteString("(" + strings.Join(alternatives, " OR ") + ")")
+	}
+
+	ret := buf.String()
+	if "" == ret {
+		return "\"\""
+	}
+	return strings.ReplaceAll(ret, "'", "''")
+}
+
+func isFuzzyTerm(term string) bool {
+	for _, r := range term {
+		if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) {
+			return false
+		}
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// fullTextSearchByFTSWithScore 按综合得分降序搜索，综合得分由 BM25 相关度、更新时间和被引用次数加权得到。
+func fullTextSearchByFTSWithScore(query, boxFilter, pathFilter, typeFilter, ignoreFilter string, beforeLen, page, pageSize int) (ret []*Block, matchedBlockCount, matchedRootCount int) {
+	ret = []*Block{}
+	table := "blocks_fts" // 大小写敏感
+	if !Conf.Search.CaseSensitive {
+		table = "blocks_fts_case_insensitive"
+	}
+	where := " WHERE (`" + table + "` MATCH '" + columnFilter() + ":(" + query + ")') AND type IN " + typeFilter + boxFilter + pathFilter + ignoreFilter
+
+	stmt := "SELECT id, updated, bm25(" + table + ") AS bm25 FROM " + table + where + " ORDER BY rank LIMIT " + strconv.Itoa(searchScoreCandidates)
+	result, _ := sql.QueryNoLimit(stmt)
+	if 1 > len(result) {
+		return
+	}
+
+	var ids []string
+	scores := map[string]*SearchScore{}
+	var maxRelevance float64
+	now := time.Now()
+	for _, row := range result {
+		id, _ := row["id"].(string)
+		bm25, _ := row["bm25"].(float64)
+		updated, _ := row["updated"].(string)
+
+		score := &SearchScore{BM25: -bm25} // bm25() 越小越相关
+		if updatedTime, parseErr := time.ParseInLocation("20060102150405", updated, time.Local); nil == parseErr {
+			ageDays := math.Max(0, now.Sub(updatedTime).Hours()/24)
+			score.Recency = 1 / (1 + ageDays/searchScoreRecencyDays)
+		}
+		maxRelevance = math.Max(maxRelevance, score.BM25)
+		ids = append(ids, id)
+		scores[id] = score
+	}
+
+	refCounts := getSearchRefCounts(ids)
+	var maxRefCount int
+	for _, count := range refCounts {
+		maxRefCount = max(maxRefCount, count)
+	}
+
+	for id, score := range scores {
+		if 0 < maxRelevance {
+			score.BM25 /= maxRelevance
+		}
+		score.RefCount = refCounts[id]
+		if 0 < maxRefCount {
+			score.Refs = math.Log1p(float64(score.RefCount)) / math.Log1p(float64(maxRefCount))
+		}
+		score.Total = searchScoreWeightBM25*score.BM25 + searchScoreWeightRecency*score.Recency + searchScoreWeightRefs*score.Refs
+	}
+	sort.SliceStable(ids, func(i, j int) bool { return scores[ids[i]].Total > scores[ids[j]].Total })
+
+	start, end := (page-1)*pageSize, page*pageSize
+	if start > len(ids) {
+		start = len(ids)
+	}
+	if end > len(ids) {
+		end = len(ids)
+	}
+	pageIDs := ids[start:end]
+	if 0 < len(pageIDs) {
+		stmt = "SELECT " + ftsProjections(table) + " FROM " + table + where + " AND id IN ('" + strings.Join(pageIDs, "','") + "')"
+		blocks := map[string]*Block{}
+		sqlBlocks := sql.SelectBlocksRawStmt(stmt, 1, len(pageIDs))
+		for _, b := range fromSQLBlocks(&sqlBlocks, "", beforeLen) {
+			blocks[b.ID] = b
+		}
+		for _, id := range pageIDs {
+			if b := blocks[id]; nil != b {
+				b.Score = scores[id]
+				ret = append(ret, b)
+			}
+		}
+	}
+
+	matchedBlockCount, matchedRootCount = fullTextSearchCountByFTS(query, boxFilter, pathFilter, typeFilter, ignoreFilter)
+	if matchedBlockCount > len(ids) {
+		// 只有参与计算综合得分的块可以翻页查看
+		matchedBlockCount = len(ids)
+	}
+	return
+}
+
+func getSearchRefCounts(ids []string) (ret map[string]int) {
+	ret = map[string]int{}
+	if 1 > len(ids) {
+		return
+	}
+
+	stmt := "SELECT def_block_id, COUNT(*) AS refs FROM refs WHERE def_block_id IN ('" + strings.Join(ids, "','") + "') GROUP BY def_block_id"
+	result, _ := sql.QueryNoLimit(stmt)
+	for _, row := range result {
+		id, _ := row["def_block_id"].(string)
+		count, _ := row["refs"].(int64)
+		ret[id] = int(count)
+	}
+	return
+}
+
+// ClearSearchScores 清除搜索结果中的得分，仅在需要调试排序时返回得分。
+func ClearSearchScores(blocks []*Block) {
+	for _, b := range blocks {
+		b.Score = nil
+		ClearSearchScores(b.Children)
+	}
+}
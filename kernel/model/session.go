@@ -18,6 +18,7 @@ package model
 
 import (
 	"image/color"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
@@ -30,6 +31,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 	"github.com/siyuan-note/logging"
+	"github.com/siyuan-note/siyuan/kernel/conf"
 	"github.com/siyuan-note/siyuan/kernel/util"
 	"github.com/steambap/captcha"
 )
@@ -43,14 +45,15 @@ func LogoutAuth(c *gin.Context) {
 	ret := gulu.Ret.NewResult()
 	defer c.JSON(http.StatusOK, ret)
 
-	if "" == Conf.AccessAuthCode {
+	session := util.GetSession(c)
+	workspaceSession := util.GetWorkspaceSession(session)
+	if "" == Conf.AccessAuthCode && "" == workspaceSession.Username && "" == workspaceSession.OIDCSubject && "" == workspaceSession.OIDCEmail {
 		ret.Code = -1
 		ret.Msg = Conf.Language(86)
 		ret.Data = map[string]interface{}{"closeTimeout": 5000}
 		return
 	}
 
-	session := util.GetSession(c)
 	util.RemoveWorkspaceSession(session)
 	if err := session.Save(c); err != nil {
 		logging.LogErrorf("saves session failed: " + err.Error())
@@ -95,8 +98,20 @@ func LoginAuth(c *gin.Context) {
 		}
 	}
 
-	authCode := arg["authCode"].(string)
-	if Conf.AccessAuthCode != authCode {
+	// 使用用户帐号登录时传入 username 和 password，否则使用访问授权码 authCode 登录
+	var member *conf.Member
+	var authCode string
+	authed := false
+	if username, _ := arg["username"].(string); "" != username {
+		password, _ := arg["password"].(string)
+		member = AuthMember(username, password)
+		authed = nil != member
+	} else {
+		authCode, _ = arg["authCode"].(string)
+		authed = Conf.AccessAuthCode == authCode
+	}
+
+	if !authed {
 		ret.Code = -1
 		ret.Msg = Conf.Language(83)
 		logging.LogWarnf("invalid auth code [ip=%s]", util.GetRemoteAddr(c.Request))
@@ -115,10 +130,31 @@ func LoginAuth(c *gin.Context) {
 		return
 	}
 
-	workspaceSession.AccessAuthCode = authCode
+	// 使用访问授权码登录时进行两步验证
+	if nil == member {
+		totpCode, _ := arg["totp"].(string)
+		rememberDevice, _ := arg["rememberDevice"].(bool)
+		if !checkLoginTOTP(c, totpCode, rememberDevice) {
+			ret.Code = 2 // 需要渲染两步验证码输入框
+			ret.Msg = Conf.Language(252)
+			if "" != totpCode {
+				ret.Msg = Conf.Language(253)
+				logging.LogWarnf("invalid TOTP code [ip=%s]", util.GetRemoteAddr(c.Request))
+				util.WrongAuthCount++
+			}
+			return
+		}
+	}
+
+	if nil != member {
+		workspaceSession.Username = member.Username
+		logging.LogInfof("auth success [user=%s, ip=%s]", member.Username, util.GetRemoteAddr(c.Request))
+	} else {
+		workspaceSession.AccessAuthCode = authCode
+		logging.LogInfof("auth success [ip=%s]", util.GetRemoteAddr(c.Request))
+	}
 	util.WrongAuthCount = 0
 	workspaceSession.Captcha = gulu.Rand.String(7)
-	logging.LogInfof("auth success [ip=%s]", util.GetRemoteAddr(c.Request))
 	if err := session.Save(c); err != nil {
 		logging.LogErrorf("save session failed: " + err.Error())
 		c.Status(http.StatusInternalServerError)
@@ -157,6 +193,7 @@ func GetCaptcha(c *gin.Context) {
 }
 
 func CheckReadonly(c *gin.Context) {
+	markAudit(c)
 	if util.ReadOnly {
 		result := util.NewResult()
 		result.Code = -1
@@ -179,6 +216,34 @@ func CheckAuth(c *gin.Context) {
 		return
 	}
 
+	// 通过用户帐号登录的 Cookie
+	session := util.GetSession(c)
+	workspaceSession := util.GetWorkspaceSession(session)
+	if member := getSessionMember(workspaceSession.Username); nil != member {
+		setMemberContext(c, member)
+		c.Next()
+		return
+	}
+
+	// 通过 OpenID Connect 登录的 Cookie
+	if setOIDCContext(c, workspaceSession) {
+		c.Next()
+		return
+	}
+
+	// 通过用户帐号 BasicAuth (header: Authorization)
+	if username, password, ok := c.Request.BasicAuth(); ok && util.WorkspaceName != username && !isBasicAuthThrottled(c.Request) {
+		if member := AuthMember(username, password); nil != member {
+			resetBasicAuthFailures(c.Request)
+			setMemberContext(c, member)
+			c.Next()
+			return
+		}
+
+		logging.LogWarnf("invalid basic auth [user=%s, ip=%s]", username, util.GetRemoteAddr(c.Request))
+		addBasicAuthFailure(c.Request)
+	}
+
 	//logging.LogInfof("check auth for [%s]", c.Request.RequestURI)
 	localhost := util.IsLocalHost(c.Request.RemoteAddr)
 
@@ -201,6 +266,10 @@ func CheckAuth(c *gin.Context) {
 			("" != host && !util.IsLocalHost(host)) ||
 			("" != origin && !util.IsLocalOrigin(origin) && !strings.HasPrefix(origin, "chrome-extension://")) ||
 			("" != forwardedHost && !util.IsLocalHost(forwardedHost)) {
+			if redirectOIDCLogin(c) {
+				return
+			}
+
 			c.JSON(http.StatusUnauthorized, map[string]interface{}{"code": -1, "msg": "Auth failed: for security reasons, please set [Access authorization code] when using non-127.0.0.1 access\n\n为安全起见，使用非 127.0.0.1 访问时请设置 [访问授权码]"})
 			c.Abort()
 			return
@@ -246,8 +315,6 @@ func CheckAuth(c *gin.Context) {
 	}
 
 	// 通过 Cookie
-	session := util.GetSession(c)
-	workspaceSession := util.GetWorkspaceSession(session)
 	if workspaceSession.AccessAuthCode == Conf.AccessAuthCode {
 		c.Set(RoleContextKey, RoleAdministrator)
 		c.Next()
@@ -255,13 +322,17 @@ func CheckAuth(c *gin.Context) {
 	}
 
 	// 通过 BasicAuth (header: Authorization)
-	if username, password, ok := c.Request.BasicAuth(); ok {
-		// 使用访问授权码作为密码
-		if util.WorkspaceName == username && Conf.AccessAuthCode == password {
+	if username, password, ok := c.Request.BasicAuth(); ok && util.WorkspaceName == username && !isBasicAuthThrottled(c.Request) {
+		// 使用访问授权码作为密码，启用两步验证后 BasicAuth 无法进行两步验证，所以不再允许使用访问授权码
+		if Conf.AccessAuthCode == password && !isTOTPEnabled() {
+			resetBasicAuthFailures(c.Request)
 			c.Set(RoleContextKey, RoleAdministrator)
 			c.Next()
 			return
 		}
+
+		logging.LogWarnf("invalid basic auth [ip=%s]", util.GetRemoteAddr(c.Request))
+		addBasicAuthFailure(c.Request)
 	}
 
 	// 通过 API token (header: Authorization)
@@ -284,8 +355,10 @@ func CheckAuth(c *gin.Context) {
 				return
 			}
 
-			c.JSON(http.StatusUnauthorized, map[string]interface{}{"code": -1, "msg": "Auth failed [header: Authorization]"})
-			c.Abort()
+			// 签发的 API token
+			if authAPIToken(c, token) {
+				c.Next()
+			}
 			return
 		}
 	}
@@ -298,8 +371,9 @@ func CheckAuth(c *gin.Context) {
 			return
 		}
 
-		c.JSON(http.StatusUnauthorized, map[string]interface{}{"code": -1, "msg": "Auth failed [query: token]"})
-		c.Abort()
+		if authAPIToken(c, token) {
+			c.Next()
+		}
 		return
 	}
 
@@ -327,6 +401,10 @@ func CheckAuth(c *gin.Context) {
 				return
 			}
 
+			if redirectOIDCLogin(c) {
+				return
+			}
+
 			location := url.URL{}
 			queryParams := url.Values{}
 			queryParams.Set("to", c.Request.URL.String())
@@ -347,6 +425,24 @@ func CheckAuth(c *gin.Context) {
 	c.Next()
 }
 
+// redirectOIDCLogin 启用 OpenID Connect 登录时将浏览器页面请求重定向到登录地址。
+func redirectOIDCLogin(c *gin.Context) bool {
+	if !Conf.OIDC.Enable || "GET" != c.Request.Method || c.IsWebsocket() || !strings.HasPrefix(c.GetHeader("User-Agent"), "Mozilla/") ||
+		strings.HasPrefix(c.Request.URL.Path, "/api/") {
+		return false
+	}
+
+	location := url.URL{}
+	queryParams := url.Values{}
+	queryParams.Set("to", c.Request.URL.String())
+	location.RawQuery = queryParams.Encode()
+	location.Path = OIDCLoginPath
+
+	c.Redirect(http.StatusFound, location.String())
+	c.Abort()
+	return true
+}
+
 func CheckAdminRole(c *gin.Context) {
 	if IsAdminRoleContext(c) {
 		c.Next()
@@ -355,6 +451,68 @@ func CheckAdminRole(c *gin.Context) {
 	}
 }
 
+// BasicAuth 无法输入验证码，所以按来源地址单独限制认证失败次数，不和登录页共用验证码计数，避免一个客户端的失败影响其他客户端。
+//
+// 来源地址使用连接地址而不是 X-Forwarded-For 等请求头，避免通过伪造请求头绕过限制。
+const (
+	basicAuthMaxFailures   = 5
+	basicAuthThrottleDelay = 5 * time.Minute
+)
+
+type basicAuthFailure struct {
+	count int
+	last  time.Time
+}
+
+var (
+	basicAuthFailures     = map[string]*basicAuthFailure{}
+	basicAuthFailuresLock = sync.Mutex{}
+)
+
+func basicAuthRemoteHost(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
+func isBasicAuthThrottled(req *http.Request) bool {
+	basicAuthFailuresLock.Lock()
+	defer basicAuthFailuresLock.Unlock()
+
+	failure := basicAuthFailures[basicAuthRemoteHost(req)]
+	return nil != failure && basicAuthMaxFailures <= failure.count && time.Since(failure.last) < basicAuthThrottleDelay
+}
+
+func addBasicAuthFailure(req *http.Request) {
+	basicAuthFailuresLock.Lock()
+	defer basicAuthFailuresLock.Unlock()
+
+	now := time.Now()
+	for host, failure := range basicAuthFailures {
+		if basicAuthThrottleDelay <= now.Sub(failure.last) {
+			delete(basicAuthFailures, host)
+		}
+	}
+
+	host := basicAuthRemoteHost(req)
+	failure := basicAuthFailures[host]
+	if nil == failure {
+		failure = &basicAuthFailure{}
+		basicAuthFailures[host] = failure
+	}
+	failure.count++
+	failure.last = now
+}
+
+func resetBasicAuthFailures(req *http.Request) {
+	basicAuthFailuresLock.Lock()
+	defer basicAuthFailuresLock.Unlock()
+
+	delete(basicAuthFailures, basicAuthRemoteHost(req))
+}
+
 func CheckEditRole(c *gin.Context) {
 	if IsValidRole(GetGinContextRole(c), []Role{
 		RoleAdministrator,
@@ -367,15 +525,23 @@ func CheckEditRole(c *gin.Context) {
 }
 
 func CheckReadRole(c *gin.Context) {
-	if IsValidRole(GetGinContextRole(c), []Role{
+	if !IsValidRole(GetGinContextRole(c), []Role{
 		RoleAdministrator,
 		RoleEditor,
 		RoleReader,
 	}) {
-		c.Next()
-	} else {
 		c.AbortWithStatus(http.StatusForbidden)
+		return
 	}
+
+	// 发布服务账户和 API token 只能访问其访问范围内的资源文件，其他接口在返回结果时进行过滤，无法过滤的接口通过 CheckUnrestricted 拒绝
+	if access := GetPublishAccess(c); nil != access {
+		if strings.HasPrefix(c.Request.URL.Path, "/assets/") && !access.IsAssetAllowed(c.Request.URL.Path) {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+	}
+	c.Next()
 }
 
 var timingAPIs = map[string]int{
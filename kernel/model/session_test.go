@@ -0,0 +1,56 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package model
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBasicAuthThrottle(t *testing.T) {
+	attacker := httptest.NewRequest("GET", "/", nil)
+	attacker.RemoteAddr = "192.0.2.1:1234"
+	attacker.Header.Set("X-Forwarded-For", "198.51.100.1")
+	user := httptest.NewRequest("GET", "/", nil)
+	user.RemoteAddr = "192.0.2.2:1234"
+	defer func() {
+		resetBasicAuthFailures(attacker)
+		resetBasicAuthFailures(user)
+	}()
+
+	for i := 0; i < basicAuthMaxFailures; i++ {
+		if isBasicAuthThrottled(attacker) {
+			t.Fatalf("throttled after [%d] failures", i)
+		}
+		addBasicAuthFailure(attacker)
+	}
+	if !isBasicAuthThrottled(attacker) {
+		t.Fatalf("expected throttled")
+	}
+
+	// 换一个端口或者伪造 X-Forwarded-For 不能绕过限制
+	attacker.RemoteAddr = "192.0.2.1:5678"
+	attacker.Header.Set("X-Forwarded-For", "198.51.100.2")
+	if !isBasicAuthThrottled(attacker) {
+		t.Fatalf("expected throttled")
+	}
+
+	// 其他来源地址不受影响
+	if isBasicAuthThrottled(user) {
+		t.Fatalf("expected other address not throttled")
+	}
+}
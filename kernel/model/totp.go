@@ -0,0 +1,331 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package model
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/siyuan-note/logging"
+	"github.com/siyuan-note/siyuan/kernel/conf"
+	"github.com/siyuan-note/siyuan/kernel/util"
+)
+
+const (
+	totpIssuer            = "SiYuan"
+	totpPeriod            = 30
+	totpDigits            = 6
+	totpRecoveryCodeCount = 10
+	totpDeviceCookieName  = "siyuan-totp-device"
+	totpDeviceMaxAge      = 30 * 24 * time.Hour
+)
+
+var (
+	ErrTOTPAccessAuthCodeNotSet = errors.New("access authorization code is not set")
+	ErrTOTPNotEnabled           = errors.New("two-factor authentication is not enabled")
+	ErrTOTPNotGenerated         = errors.New("two-factor authentication secret is not generated")
+	ErrTOTPInvalidCode          = errors.New("invalid two-factor authentication code")
+)
+
+var totpLock = sync.Mutex{}
+
+// GenerateTOTPSecret 生成待绑定的密钥，返回密钥和用于生成二维码的 otpauth URI，需要调用 EnableTOTP 确认后才会生效。
+func GenerateTOTPSecret() (secret, uri string, err error) {
+	if "" == Conf.AccessAuthCode {
+		err = ErrTOTPAccessAuthCodeNotSet
+		return
+	}
+
+	key := make([]byte, 20)
+	if _, err = rand.Read(key); err != nil {
+		return
+	}
+	secret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(key)
+
+	totpLock.Lock()
+	Conf.TOTP.PendingSecret = secret
+	Conf.Save()
+	totpLock.Unlock()
+
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", totpIssuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(totpDigits))
+	query.Set("period", fmt.Sprint(totpPeriod))
+	uri = "otpauth://totp/" + url.PathEscape(totpIssuer+":"+util.WorkspaceName) + "?" + query.Encode()
+	return
+}
+
+// EnableTOTP 使用验证器生成的验证码确认绑定，返回恢复码明文，恢复码仅在此时返回一次。
+func EnableTOTP(code string) (recoveryCodes []string, err error) {
+	totpLock.Lock()
+	defer totpLock.Unlock()
+
+	if "" == Conf.TOTP.PendingSecret {
+		return nil, ErrTOTPNotGenerated
+	}
+
+	step, ok := verifyTOTPCode(Conf.TOTP.PendingSecret, code, 0)
+	if !ok {
+		return nil, ErrTOTPInvalidCode
+	}
+
+	recoveryCodes, hashes, err := newTOTPRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	Conf.TOTP.Enable = true
+	Conf.TOTP.Secret = Conf.TOTP.PendingSecret
+	Conf.TOTP.PendingSecret = ""
+	Conf.TOTP.LastStep = step
+	Conf.TOTP.Devices = []*conf.TOTPDevice{}
+	Conf.TOTP.RecoveryCodes = hashes
+	Conf.Save()
+	logging.LogInfof("enabled two-factor authentication")
+	return
+}
+
+// DisableTOTP 使用验证码或者恢复码关闭两步验证。
+func DisableTOTP(code string) (err error) {
+	totpLock.Lock()
+	defer totpLock.Unlock()
+
+	if !Conf.TOTP.Enable {
+		return ErrTOTPNotEnabled
+	}
+	if !verifyTOTPOrRecoveryCode(code) {
+		return ErrTOTPInvalidCode
+	}
+
+	Conf.TOTP = conf.NewTOTP()
+	Conf.Save()
+	logging.LogInfof("disabled two-factor authentication")
+	return
+}
+
+// RegenerateTOTPRecoveryCodes 使用验证码重新生成恢复码，原有恢复码失效。
+func RegenerateTOTPRecoveryCodes(code string) (recoveryCodes []string, err error) {
+	totpLock.Lock()
+	defer totpLock.Unlock()
+
+	if !Conf.TOTP.Enable {
+		return nil, ErrTOTPNotEnabled
+	}
+	if !verifyTOTPOrRecoveryCode(code) {
+		return nil, ErrTOTPInvalidCode
+	}
+
+	recoveryCodes, hashes, err := newTOTPRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	Conf.TOTP.RecoveryCodes = hashes
+	Conf.Save()
+	return
+}
+
+func GetTOTPInfo() map[string]any {
+	totpLock.Lock()
+	defer totpLock.Unlock()
+
+	return map[string]any{
+		"enable":        Conf.TOTP.Enable,
+		"recoveryCodes": len(Conf.TOTP.RecoveryCodes),
+		"devices":       len(Conf.TOTP.Devices),
+	}
+}
+
+// ForgetTOTPDevices 移除所有记住的设备，这些设备下次登录时需要重新进行两步验证。
+func ForgetTOTPDevices() {
+	totpLock.Lock()
+	defer totpLock.Unlock()
+
+	Conf.TOTP.Devices = []*conf.TOTPDevice{}
+	Conf.Save()
+}
+
+// isTOTPEnabled 判断是否启用了两步验证。
+func isTOTPEnabled() bool {
+	totpLock.Lock()
+	defer totpLock.Unlock()
+
+	return Conf.TOTP.Enable
+}
+
+// checkLoginTOTP 在使用访问授权码登录时进行两步验证，未启用两步验证或者设备已被记住时直接通过。
+func checkLoginTOTP(c *gin.Context, code string, rememberDevice bool) bool {
+	totpLock.Lock()
+	defer totpLock.Unlock()
+
+	if !Conf.TOTP.Enable || isTOTPDeviceRemembered(c) {
+		return true
+	}
+
+	if !verifyTOTPOrRecoveryCode(code) {
+		return false
+	}
+
+	if rememberDevice {
+		rememberTOTPDevice(c)
+		Conf.Save()
+	}
+	return true
+}
+
+// verifyTOTPOrRecoveryCode 校验验证码或者恢复码，恢复码使用后即失效。调用方需要持有 totpLock。
+func verifyTOTPOrRecoveryCode(code string) bool {
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if "" == code {
+		return false
+	}
+
+	if step, ok := verifyTOTPCode(Conf.TOTP.Secret, code, Conf.TOTP.LastStep); ok {
+		Conf.TOTP.LastStep = step
+		Conf.Save()
+		return true
+	}
+
+	hash := hashTOTPValue(strings.ToLower(code))
+	for i, recoveryCode := range Conf.TOTP.RecoveryCodes {
+		if hmac.Equal([]byte(recoveryCode), []byte(hash)) {
+			Conf.TOTP.RecoveryCodes = append(Conf.TOTP.RecoveryCodes[:i], Conf.TOTP.RecoveryCodes[i+1:]...)
+			Conf.Save()
+			logging.LogWarnf("used a two-factor authentication recovery code, [%d] left", len(Conf.TOTP.RecoveryCodes))
+			return true
+		}
+	}
+	return false
+}
+
+// verifyTOTPCode 校验验证码，允许前后各一个时间步的时钟偏差，已经使用过的时间步（不大于 lastStep）不再接受。
+func verifyTOTPCode(secret, code string, lastStep int64) (step int64, ok bool) {
+	if totpDigits != len(code) {
+		return
+	}
+
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		logging.LogErrorf("decode two-factor authentication secret failed: %s", err)
+		return
+	}
+
+	current := time.Now().Unix() / totpPeriod
+	for _, s := range []int64{current - 1, current, current + 1} {
+		if s <= lastStep {
+			continue
+		}
+		if hmac.Equal([]byte(generateTOTPCode(key, s)), []byte(code)) {
+			return s, true
+		}
+	}
+	return
+}
+
+// generateTOTPCode 按照 RFC 6238 生成验证码。
+func generateTOTPCode(key []byte, step int64) string {
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000)
+}
+
+// newTOTPRecoveryCodes 生成恢复码，返回恢复码明文和用于保存的哈希。
+func newTOTPRecoveryCodes() (codes, hashes []string, err error) {
+	hashes = []string{}
+	for i := 0; i < totpRecoveryCodeCount; i++ {
+		var code string
+		if code, err = randomToken(5); err != nil {
+			return nil, nil, err
+		}
+		codes = append(codes, code)
+		hashes = append(hashes, hashTOTPValue(code))
+	}
+	return
+}
+
+func isTOTPDeviceRemembered(c *gin.Context) bool {
+	token, err := c.Cookie(totpDeviceCookieName)
+	if err != nil || "" == token {
+		return false
+	}
+
+	now := time.Now().UnixMilli()
+	hash := hashTOTPValue(token)
+	for _, device := range Conf.TOTP.Devices {
+		if now < device.Expired && hmac.Equal([]byte(device.Hash), []byte(hash)) {
+			return true
+		}
+	}
+	return false
+}
+
+func rememberTOTPDevice(c *gin.Context) {
+	token, err := randomToken(32)
+	if err != nil {
+		return
+	}
+
+	now := time.Now()
+
+	var devices []*conf.TOTPDevice
+	for _, device := range Conf.TOTP.Devices {
+		if now.UnixMilli() < device.Expired {
+			devices = append(devices, device)
+		}
+	}
+	Conf.TOTP.Devices = append(devices, &conf.TOTPDevice{
+		Hash:    hashTOTPValue(token),
+		Created: now.UnixMilli(),
+		Expired: now.Add(totpDeviceMaxAge).UnixMilli(),
+	})
+
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     totpDeviceCookieName,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   int(totpDeviceMaxAge.Seconds()),
+		Secure:   util.SSL,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func hashTOTPValue(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
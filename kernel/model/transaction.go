@@ -200,10 +200,14 @@ func performTx(tx *Transaction) (ret *TxErr) {
 			ret = tx.doRemoveFlashcards(op)
 		case "setAttrViewName":
 			ret = tx.doSetAttrViewName(op)
+		case "setAttrViewAddressBook":
+			ret = tx.doSetAttrViewAddressBook(op)
 		case "setAttrViewFilters":
 			ret = tx.doSetAttrViewFilters(op)
 		case "setAttrViewSorts":
 			ret = tx.doSetAttrViewSorts(op)
+		case "setAttrViewFormats":
+			ret = tx.doSetAttrViewFormats(op)
 		case "setAttrViewPageSize":
 			ret = tx.doSetAttrViewPageSize(op)
 		case "setAttrViewColWidth":
@@ -252,6 +256,10 @@ func performTx(tx *Transaction) (ret *TxErr) {
 			ret = tx.doReplaceAttrViewBlock(op)
 		case "updateAttrViewColTemplate":
 			ret = tx.doUpdateAttrViewColTemplate(op)
+		case "updateAttrViewColFormula":
+			ret = tx.doUpdateAttrViewColFormula(op)
+		case "updateAttrViewColAutoID":
+			ret = tx.doUpdateAttrViewColAutoID(op)
 		case "addAttrViewView":
 			ret = tx.doAddAttrViewView(op)
 		case "removeAttrViewView":
@@ -274,10 +282,40 @@ func performTx(tx *Transaction) (ret *TxErr) {
 			ret = tx.doHideAttrViewName(op)
 		case "setAttrViewColDate":
 			ret = tx.doSetAttrViewColDate(op)
+		case "setAttrViewColDateCalendar":
+			ret = tx.doSetAttrViewColDateCalendar(op)
 		case "unbindAttrViewBlock":
 			ret = tx.doUnbindAttrViewBlock(op)
 		case "duplicateAttrViewKey":
 			ret = tx.doDuplicateAttrViewKey(op)
+		case "setAttrViewKanbanGroup":
+			ret = tx.doSetAttrViewKanbanGroup(op)
+		case "sortAttrViewKanbanColumn":
+			ret = tx.doSortAttrViewKanbanColumn(op)
+		case "foldAttrViewKanbanColumn":
+			ret = tx.doFoldAttrViewKanbanColumn(op)
+		case "moveAttrViewKanbanCard":
+			ret = tx.doMoveAttrViewKanbanCard(op)
+		case "setAttrViewCalendarDateKey":
+			ret = tx.doSetAttrViewCalendarDateKey(op)
+		case "setAttrViewCalendarMode":
+			ret = tx.doSetAttrViewCalendarMode(op)
+		case "setAttrViewCalendarFirstDayOfWeek":
+			ret = tx.doSetAttrViewCalendarFirstDayOfWeek(op)
+		case "moveAttrViewCalendarEvent":
+			ret = tx.doMoveAttrViewCalendarEvent(op)
+		case "setAttrViewCoverFrom":
+			ret = tx.doSetAttrViewCoverFrom(op)
+		case "setAttrViewCoverFromAssetKeyID":
+			ret = tx.doSetAttrViewCoverFromAssetKeyID(op)
+		case "setAttrViewCardSize":
+			ret = tx.doSetAttrViewCardSize(op)
+		case "setAttrViewFitImage":
+			ret = tx.doSetAttrViewFitImage(op)
+		case "setAttrViewGroup":
+			ret = tx.doSetAttrViewGroup(op)
+		case "foldAttrViewGroup":
+			ret = tx.doFoldAttrViewGroup(op)
 		}
 
 		if nil != ret {
@@ -1470,6 +1508,7 @@ type Transaction struct {
 	Timestamp      int64        `json:"timestamp"`
 	DoOperations   []*Operation `json:"doOperations"`
 	UndoOperations []*Operation `json:"undoOperations"`
+	Username       string       `json:"username,omitempty"` // 提交事务的用户名，通过访问授权码或者 API token 提交时为空
 
 	trees map[string]*parse.Tree
 	nodes map[string]*ast.Node
@@ -1507,8 +1546,20 @@ func (tx *Transaction) commit() (err error) {
 		var sources []interface{}
 		sources = append(sources, tx)
 		util.PushSaveDoc(tree.ID, "tx", sources)
+		recordDocEditor(tree.ID, tx.Username)
 	}
 	refreshDynamicRefTexts(tx.nodes, tx.trees)
+
+	// 数据库变更后更新数据库索引，以便通过 SQL 查询数据库
+	var avIDs []string
+	for _, op := range tx.DoOperations {
+		if "" != op.AvID {
+			avIDs = append(avIDs, op.AvID)
+		}
+	}
+	for _, avID := range gulu.Str.RemoveDuplicatedElem(avIDs) {
+		sql.IndexAttributeViewQueue(avID)
+	}
 	IncSync()
 	tx.state.Store(2)
 	tx.m.Unlock()
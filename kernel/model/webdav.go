@@ -0,0 +1,413 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package model
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/88250/gulu"
+	"github.com/88250/lute/ast"
+	"github.com/siyuan-note/filelock"
+	"github.com/siyuan-note/logging"
+	"github.com/siyuan-note/siyuan/kernel/util"
+	"golang.org/x/net/webdav"
+)
+
+// WorkspaceWebDAV 通过 WebDAV 暴露工作空间。
+//
+// 和直接使用 webdav.Dir 不同，这里读写文件时会和内核使用相同的 filelock 文件锁，写入 data/ 下的文档后会重建索引，
+// 并且会隐藏配置文件等敏感路径、拒绝写入只读笔记本以及检查容量配额。
+type WorkspaceWebDAV struct{}
+
+var ErrorWebDAVQuotaExceeded = errors.New("workspace quota exceeded")
+
+// webDAVHiddenPaths 不通过 WebDAV 暴露的路径，相对于工作空间。
+var webDAVHiddenPaths = []string{
+	".lock",
+	"conf/conf.json",
+	"conf/" + webDAVLocksFileName,
+	"temp",
+}
+
+func (fsys *WorkspaceWebDAV) Mkdir(ctx context.Context, name string, perm os.FileMode) (err error) {
+	absPath, relPath, err := resolveWebDAVPath(name, true)
+	if nil != err {
+		return
+	}
+
+	if err = os.Mkdir(absPath, perm); nil != err {
+		return
+	}
+	afterWebDAVWrite(relPath, true)
+	return
+}
+
+func (fsys *WorkspaceWebDAV) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (ret webdav.File, err error) {
+	writable := 0 != flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND)
+	absPath, relPath, err := resolveWebDAVPath(name, writable)
+	if nil != err {
+		return
+	}
+
+	if writable {
+		info, statErr := os.Stat(absPath)
+		if nil != statErr {
+			if !os.IsNotExist(statErr) || 0 == flag&os.O_CREATE {
+				return nil, statErr
+			}
+			if !gulu.File.IsDir(filepath.Dir(absPath)) {
+				return nil, os.ErrNotExist
+			}
+		} else if info.IsDir() {
+			return nil, os.ErrInvalid
+		}
+
+		file := &webDAVWriteFile{absPath: absPath, relPath: relPath}
+		if nil != info {
+			file.oldSize = info.Size()
+		}
+		if err = os.MkdirAll(util.TempDir, 0755); nil != err {
+			return
+		}
+		if file.tmp, err = os.CreateTemp(util.TempDir, "webdav-*"); nil != err {
+			return
+		}
+		if nil != info && 0 == flag&os.O_TRUNC {
+			// 非截断写入时需要保留原有内容
+			var data []byte
+			if data, err = filelock.ReadFile(absPath); nil != err {
+				file.discard()
+				return
+			}
+			file.tmp.Write(data)
+			if 0 == flag&os.O_APPEND {
+				file.tmp.Seek(0, io.SeekStart)
+			}
+		}
+		return file, nil
+	}
+
+	info, err := os.Stat(absPath)
+	if nil != err {
+		return
+	}
+
+	if !info.IsDir() && isWebDAVKernelFile(relPath) {
+		// 内核管理的文件在锁内一次读出，避免客户端读取时长时间占用文件锁
+		var data []byte
+		if data, err = filelock.ReadFile(absPath); nil != err {
+			return
+		}
+		return &webDAVMemFile{Reader: bytes.NewReader(data), info: info}, nil
+	}
+
+	file, err := os.Open(absPath)
+	if nil != err {
+		return
+	}
+	return &webDAVFile{File: file, relPath: relPath}, nil
+}
+
+func (fsys *WorkspaceWebDAV) RemoveAll(ctx context.Context, name string) (err error) {
+	absPath, relPath, err := resolveWebDAVPath(name, true)
+	if nil != err {
+		return
+	}
+	if "" == relPath {
+		return os.ErrPermission
+	}
+
+	info, err := os.Stat(absPath)
+	if nil != err {
+		return
+	}
+
+	// 文件夹需要遍历统计其下所有文件的大小
+	size := info.Size()
+	if info.IsDir() {
+		size, _ = util.SizeOfDirectory(absPath)
+	}
+
+	if dataPath, ok := getWebDAVDataPath(relPath, info.IsDir()); ok {
+		// 需要在删除前移除索引，移除索引时需要遍历文件夹
+		RemoveIndexes([]string{dataPath})
+	}
+
+	if err = filelock.Remove(absPath); nil != err {
+		return
+	}
+	addWebDAVWorkspaceSize(-size)
+	afterWebDAVWrite(relPath, info.IsDir())
+	return
+}
+
+func (fsys *WorkspaceWebDAV) Rename(ctx context.Context, oldName, newName string) (err error) {
+	oldAbsPath, oldRelPath, err := resolveWebDAVPath(oldName, true)
+	if nil != err {
+		return
+	}
+	newAbsPath, newRelPath, err := resolveWebDAVPath(newName, true)
+	if nil != err {
+		return
+	}
+	if "" == oldRelPath || "" == newRelPath {
+		return os.ErrPermission
+	}
+
+	info, err := os.Stat(oldAbsPath)
+	if nil != err {
+		return
+	}
+
+	if dataPath, ok := getWebDAVDataPath(oldRelPath, info.IsDir()); ok {
+		RemoveIndexes([]string{dataPath})
+	}
+
+	if err = filelock.Rename(oldAbsPath, newAbsPath); nil != err {
+		return
+	}
+	afterWebDAVWrite(newRelPath, info.IsDir())
+	return
+}
+
+func (fsys *WorkspaceWebDAV) Stat(ctx context.Context, name string) (ret os.FileInfo, err error) {
+	absPath, _, err := resolveWebDAVPath(name, false)
+	if nil != err {
+		return
+	}
+	return os.Stat(absPath)
+}
+
+// resolveWebDAVPath 将 WebDAV 路径解析为工作空间下的绝对路径和相对路径（使用 / 分隔，不以 / 开头）。
+func resolveWebDAVPath(name string, write bool) (absPath, relPath string, err error) {
+	relPath = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if isWebDAVHiddenPath(relPath) {
+		err = os.ErrNotExist
+		return
+	}
+	if write && isWebDAVReadOnlyPath(relPath) {
+		err = os.ErrPermission
+		return
+	}
+
+	absPath = filepath.Join(util.WorkspaceDir, filepath.FromSlash(relPath))
+	if !util.IsSubPath(util.WorkspaceDir, absPath) && absPath != util.WorkspaceDir {
+		err = os.ErrPermission
+	}
+	return
+}
+
+// isWebDAVHiddenPath 判断路径是否不通过 WebDAV 暴露，逐级比较且不区分大小写，避免在不区分大小写的文件系统上绕过。
+func isWebDAVHiddenPath(relPath string) bool {
+	parts := strings.Split(relPath, "/")
+	for _, hiddenPath := range webDAVHiddenPaths {
+		hiddenParts := strings.Split(hiddenPath, "/")
+		if len(parts) < len(hiddenParts) {
+			continue
+		}
+
+		hidden := true
+		for i, hiddenPart := range hiddenParts {
+			if !strings.EqualFold(parts[i], hiddenPart) {
+				hidden = false
+				break
+			}
+		}
+		if hidden {
+			return true
+		}
+	}
+	return false
+}
+
+// isWebDAVReadOnlyPath 判断路径是否位于只读笔记本下，笔记本文件夹本身也不允许修改。
+func isWebDAVReadOnlyPath(relPath string) bool {
+	parts := strings.Split(relPath, "/")
+	if 2 > len(parts) || "data" != parts[0] {
+		return false
+	}
+	return gulu.Str.Contains(parts[1], Conf.DAV.ReadOnlyNotebooks)
+}
+
+// isWebDAVKernelFile 判断是否是内核读写的文件，比如 .sy 和各种 .json 配置及数据文件。
+func isWebDAVKernelFile(relPath string) bool {
+	ext := path.Ext(relPath)
+	return ".sy" == ext || ".json" == ext
+}
+
+// getWebDAVDataPath 获取笔记本文件夹下的路径，用于更新索引。文件夹路径以 / 结尾。
+func getWebDAVDataPath(relPath string, isDir bool) (ret string, ok bool) {
+	if !strings.HasPrefix(relPath, "data/") {
+		return
+	}
+
+	ret = strings.TrimPrefix(relPath, "data")
+	boxID := strings.Split(ret, "/")[1]
+	if !ast.IsNodeIDPattern(boxID) {
+		return
+	}
+
+	if isDir {
+		return ret + "/", true
+	}
+	return ret, ".sy" == path.Ext(ret)
+}
+
+func afterWebDAVWrite(relPath string, isDir bool) {
+	if dataPath, ok := getWebDAVDataPath(relPath, isDir); ok {
+		UpsertIndexes([]string{dataPath})
+		util.PushReloadFiletree()
+	}
+
+	if strings.HasPrefix(relPath, "data/") {
+		IncSync()
+	}
+}
+
+type webDAVFile struct {
+	*os.File
+	relPath string
+}
+
+func (file *webDAVFile) Readdir(count int) (ret []fs.FileInfo, err error) {
+	infos, err := file.File.Readdir(count)
+	for _, info := range infos {
+		if isWebDAVHiddenPath(path.Join(file.relPath, info.Name())) {
+			continue
+		}
+		ret = append(ret, info)
+	}
+	return
+}
+
+type webDAVMemFile struct {
+	*bytes.Reader
+	info fs.FileInfo
+}
+
+func (file *webDAVMemFile) Readdir(count int) ([]fs.FileInfo, error) {
+	return nil, os.ErrInvalid
+}
+
+func (file *webDAVMemFile) Stat() (fs.FileInfo, error) {
+	return file.info, nil
+}
+
+func (file *webDAVMemFile) Write(p []byte) (int, error) {
+	return 0, os.ErrPermission
+}
+
+func (file *webDAVMemFile) Close() error {
+	return nil
+}
+
+// webDAVWriteFile 先写入临时文件，关闭时再加锁写入目标文件。
+type webDAVWriteFile struct {
+	absPath string
+	relPath string
+	oldSize int64
+	tmp     *os.File
+}
+
+func (file *webDAVWriteFile) Read(p []byte) (int, error) {
+	return file.tmp.Read(p)
+}
+
+func (file *webDAVWriteFile) Seek(offset int64, whence int) (int64, error) {
+	return file.tmp.Seek(offset, whence)
+}
+
+func (file *webDAVWriteFile) Write(p []byte) (int, error) {
+	return file.tmp.Write(p)
+}
+
+func (file *webDAVWriteFile) Readdir(count int) ([]fs.FileInfo, error) {
+	return nil, os.ErrInvalid
+}
+
+func (file *webDAVWriteFile) Stat() (fs.FileInfo, error) {
+	return file.tmp.Stat()
+}
+
+func (file *webDAVWriteFile) Close() (err error) {
+	defer file.discard()
+
+	info, err := file.tmp.Stat()
+	if nil != err {
+		return
+	}
+	delta := info.Size() - file.oldSize
+	if !CheckWebDAVQuota(delta) {
+		return ErrorWebDAVQuotaExceeded
+	}
+
+	if _, err = file.tmp.Seek(0, io.SeekStart); nil != err {
+		return
+	}
+	if err = filelock.WriteFileByReader(file.absPath, file.tmp); nil != err {
+		logging.LogErrorf("write file [%s] failed: %s", file.absPath, err)
+		return
+	}
+	addWebDAVWorkspaceSize(delta)
+	afterWebDAVWrite(file.relPath, false)
+	return
+}
+
+func (file *webDAVWriteFile) discard() {
+	file.tmp.Close()
+	os.Remove(file.tmp.Name())
+}
+
+var (
+	webDAVWorkspaceSize        int64
+	webDAVWorkspaceSizeUpdated time.Time
+	webDAVWorkspaceSizeLock    = sync.Mutex{}
+)
+
+// CheckWebDAVQuota 检查写入 delta 字节后工作空间大小是否仍在配额内。
+func CheckWebDAVQuota(delta int64) bool {
+	quota := Conf.DAV.Quota
+	if 1 > quota || 1 > delta {
+		return true
+	}
+
+	webDAVWorkspaceSizeLock.Lock()
+	defer webDAVWorkspaceSizeLock.Unlock()
+
+	if time.Since(webDAVWorkspaceSizeUpdated) > 5*time.Minute {
+		// 工作空间大小计算开销较大，缓存一段时间
+		webDAVWorkspaceSize, _ = util.SizeOfDirectory(util.WorkspaceDir)
+		webDAVWorkspaceSizeUpdated = time.Now()
+	}
+	return webDAVWorkspaceSize+delta <= quota
+}
+
+func addWebDAVWorkspaceSize(delta int64) {
+	webDAVWorkspaceSizeLock.Lock()
+	defer webDAVWorkspaceSizeLock.Unlock()
+	webDAVWorkspaceSize += delta
+}
@@ -0,0 +1,271 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package model
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/88250/gulu"
+	"github.com/siyuan-note/filelock"
+	"github.com/siyuan-note/logging"
+	"github.com/siyuan-note/siyuan/kernel/util"
+	"golang.org/x/net/webdav"
+)
+
+const webDAVLocksFileName = "webdav-locks.json"
+
+// WebDAVLockSystem 是持久化到 conf/webdav-locks.json 的 WebDAV 锁，内核重启后客户端持有的锁仍然有效。
+type WebDAVLockSystem struct {
+	locks map[string]*webDAVLock // token -> lock
+	m     sync.Mutex
+}
+
+type webDAVLock struct {
+	Token     string `json:"token"`
+	Root      string `json:"root"`
+	OwnerXML  string `json:"ownerXML"`
+	ZeroDepth bool   `json:"zeroDepth"`
+	Duration  int64  `json:"duration"` // 毫秒，负数表示不过期
+	Expired   int64  `json:"expired"`  // 过期时间，毫秒时间戳，0 表示不过期
+
+	held bool // 是否被正在处理的请求持有，持有期间不能刷新、解锁或者被其他请求确认
+}
+
+func NewWebDAVLockSystem() (ret *WebDAVLockSystem) {
+	ret = &WebDAVLockSystem{locks: map[string]*webDAVLock{}}
+
+	lockFilePath := filepath.Join(util.ConfDir, webDAVLocksFileName)
+	if !filelock.IsExist(lockFilePath) {
+		return
+	}
+
+	data, err := filelock.ReadFile(lockFilePath)
+	if nil != err {
+		logging.LogErrorf("read WebDAV locks failed: %s", err)
+		return
+	}
+
+	var locks []*webDAVLock
+	if err = gulu.JSON.UnmarshalJSON(data, &locks); nil != err {
+		logging.LogErrorf("unmarshal WebDAV locks failed: %s", err)
+		return
+	}
+	for _, lock := range locks {
+		ret.locks[lock.Token] = lock
+	}
+	ret.removeExpired(time.Now())
+	return
+}
+
+func (ls *WebDAVLockSystem) Confirm(now time.Time, name0, name1 string, conditions ...webdav.Condition) (release func(), err error) {
+	ls.m.Lock()
+	defer ls.m.Unlock()
+
+	ls.removeExpired(now)
+	var held []*webDAVLock
+	for _, name := range []string{name0, name1} {
+		if "" == name {
+			continue
+		}
+
+		for _, lock := range ls.locks {
+			if !lock.covers(path.Clean(name)) {
+				continue
+			}
+
+			confirmed := false
+			for _, condition := range conditions {
+				if !condition.Not && condition.Token == lock.Token {
+					confirmed = true
+					break
+				}
+			}
+			if !confirmed {
+				return nil, webdav.ErrConfirmationFailed
+			}
+			if lock.held {
+				return nil, webdav.ErrLocked
+			}
+			if !containsWebDAVLock(held, lock) {
+				held = append(held, lock)
+			}
+		}
+	}
+
+	// 和 webdav.NewMemLS 一样，在请求处理完成前持有确认过的锁
+	for _, lock := range held {
+		lock.held = true
+	}
+	return func() {
+		ls.m.Lock()
+		defer ls.m.Unlock()
+
+		for _, lock := range held {
+			lock.held = false
+		}
+	}, nil
+}
+
+func (ls *WebDAVLockSystem) Create(now time.Time, details webdav.LockDetails) (token string, err error) {
+	ls.m.Lock()
+	defer ls.m.Unlock()
+
+	ls.removeExpired(now)
+	root := path.Clean(details.Root)
+	for _, lock := range ls.locks {
+		if lock.covers(root) || (!details.ZeroDepth && isWebDAVDescendantPath(lock.Root, root)) {
+			return "", webdav.ErrLocked
+		}
+	}
+
+	secret, err := randomToken(16)
+	if nil != err {
+		return
+	}
+
+	lock := &webDAVLock{
+		Token:     "opaquelocktoken:" + secret,
+		Root:      root,
+		OwnerXML:  details.OwnerXML,
+		ZeroDepth: details.ZeroDepth,
+	}
+	lock.refresh(now, details.Duration)
+	ls.locks[lock.Token] = lock
+	ls.save()
+	return lock.Token, nil
+}
+
+func (ls *WebDAVLockSystem) Refresh(now time.Time, token string, duration time.Duration) (ret webdav.LockDetails, err error) {
+	ls.m.Lock()
+	defer ls.m.Unlock()
+
+	ls.removeExpired(now)
+	lock := ls.locks[token]
+	if nil == lock {
+		return ret, webdav.ErrNoSuchLock
+	}
+	if lock.held {
+		return ret, webdav.ErrLocked
+	}
+
+	lock.refresh(now, duration)
+	ls.save()
+	return lock.details(), nil
+}
+
+func (ls *WebDAVLockSystem) Unlock(now time.Time, token string) error {
+	ls.m.Lock()
+	defer ls.m.Unlock()
+
+	ls.removeExpired(now)
+	lock := ls.locks[token]
+	if nil == lock {
+		return webdav.ErrNoSuchLock
+	}
+	if lock.held {
+		return webdav.ErrLocked
+	}
+
+	delete(ls.locks, token)
+	ls.save()
+	return nil
+}
+
+func (ls *WebDAVLockSystem) removeExpired(now time.Time) {
+	removed := false
+	for token, lock := range ls.locks {
+		if 0 < lock.Expired && lock.Expired <= now.UnixMilli() {
+			delete(ls.locks, token)
+			removed = true
+		}
+	}
+	if removed {
+		ls.save()
+	}
+}
+
+func (ls *WebDAVLockSystem) save() {
+	locks := []*webDAVLock{}
+	for _, lock := range ls.locks {
+		locks = append(locks, lock)
+	}
+
+	data, err := gulu.JSON.MarshalIndentJSON(locks, "", "  ")
+	if nil != err {
+		logging.LogErrorf("marshal WebDAV locks failed: %s", err)
+		return
+	}
+
+	lockFilePath := filepath.Join(util.ConfDir, webDAVLocksFileName)
+	if err = os.MkdirAll(util.ConfDir, 0755); nil != err {
+		logging.LogErrorf("create conf dir failed: %s", err)
+		return
+	}
+	if err = filelock.WriteFile(lockFilePath, data); nil != err {
+		logging.LogErrorf("write WebDAV locks failed: %s", err)
+	}
+}
+
+func (lock *webDAVLock) refresh(now time.Time, duration time.Duration) {
+	lock.Duration = duration.Milliseconds()
+	lock.Expired = 0
+	if 0 <= duration {
+		lock.Expired = now.Add(duration).UnixMilli()
+	}
+}
+
+func (lock *webDAVLock) details() webdav.LockDetails {
+	duration := time.Duration(lock.Duration) * time.Millisecond
+	if 0 > lock.Duration {
+		duration = -1
+	}
+	return webdav.LockDetails{
+		Root:      lock.Root,
+		Duration:  duration,
+		OwnerXML:  lock.OwnerXML,
+		ZeroDepth: lock.ZeroDepth,
+	}
+}
+
+// covers 判断锁是否作用于 name，深度为无限的锁同时作用于所有子路径。
+func (lock *webDAVLock) covers(name string) bool {
+	if lock.Root == name {
+		return true
+	}
+	return !lock.ZeroDepth && isWebDAVDescendantPath(name, lock.Root)
+}
+
+func containsWebDAVLock(locks []*webDAVLock, lock *webDAVLock) bool {
+	for _, l := range locks {
+		if l == lock {
+			return true
+		}
+	}
+	return false
+}
+
+func isWebDAVDescendantPath(name, ancestor string) bool {
+	if "/" == ancestor {
+		return "/" != name
+	}
+	return strings.HasPrefix(name, ancestor+"/")
+}
@@ -0,0 +1,741 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package model
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/88250/lute/ast"
+	"github.com/88250/lute/parse"
+	"github.com/siyuan-note/logging"
+	"github.com/siyuan-note/siyuan/kernel/sql"
+	"github.com/siyuan-note/siyuan/kernel/treenode"
+	"github.com/siyuan-note/siyuan/kernel/util"
+	"golang.org/x/net/webdav"
+)
+
+// MarkdownWebDAV 将笔记本以人类可读路径（hpath）映射为 Markdown 文件树：
+//
+//   - 根目录下每个已打开的笔记本对应一个文件夹
+//   - 每篇文档对应一个 `标题.md` 文件，存在子文档时还对应一个同名文件夹用于存放子文档
+//   - GET 返回 ExportStdMarkdown 导出内容，PUT 解析 Markdown 后通过事务写回文档并尽量保留块 ID
+//   - MKCOL/MOVE/DELETE 分别映射为新建/移动（重命名）/删除文档或笔记本
+//
+// 同一层级下存在同名文档时仅第一篇可以通过该视图访问。
+type MarkdownWebDAV struct{}
+
+var markdownWebDAVLock = sync.Mutex{}
+
+const markdownWebDAVExt = ".md"
+
+func (fsys *MarkdownWebDAV) Mkdir(ctx context.Context, name string, perm os.FileMode) (err error) {
+	markdownWebDAVLock.Lock()
+	defer markdownWebDAVLock.Unlock()
+
+	parts := splitMarkdownWebDAVPath(name)
+	if 1 > len(parts) {
+		return os.ErrExist
+	}
+
+	if 1 == len(parts) {
+		if nil != getMarkdownWebDAVBox(parts[0]) {
+			return os.ErrExist
+		}
+
+		boxID, createErr := CreateBox(parts[0])
+		if nil != createErr {
+			return createErr
+		}
+		if _, err = Mount(boxID); nil != err {
+			return
+		}
+		util.PushReloadFiletree()
+		return
+	}
+
+	// 文档对应的文件夹总是存在，这里只需要在不存在同名文档时新建一篇空文档
+	if _, _, _, resolveErr := resolveMarkdownWebDAVPath(name); nil == resolveErr {
+		return nil
+	}
+
+	box, parent, isDir, err := resolveMarkdownWebDAVPath(path.Dir(path.Clean("/" + name)))
+	if nil != err {
+		return
+	}
+	if !isDir {
+		return os.ErrInvalid
+	}
+	_, err = createMarkdownWebDAVDoc(box, parent, parts[len(parts)-1], "")
+	return
+}
+
+func (fsys *MarkdownWebDAV) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (ret webdav.File, err error) {
+	markdownWebDAVLock.Lock()
+	defer markdownWebDAVLock.Unlock()
+
+	if 0 != flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) {
+		name = path.Clean("/" + name)
+		if !strings.HasSuffix(name, markdownWebDAVExt) || 2 > len(splitMarkdownWebDAVPath(name)) {
+			return nil, os.ErrPermission
+		}
+
+		if _, _, _, resolveErr := resolveMarkdownWebDAVPath(name); nil != resolveErr {
+			if 0 == flag&os.O_CREATE {
+				return nil, resolveErr
+			}
+			if _, _, isDir, parentErr := resolveMarkdownWebDAVPath(path.Dir(name)); nil != parentErr || !isDir {
+				return nil, os.ErrNotExist
+			}
+		}
+		return &markdownWebDAVWriteFile{name: name}, nil
+	}
+
+	box, doc, isDir, err := resolveMarkdownWebDAVPath(name)
+	if nil != err {
+		return
+	}
+
+	info := newMarkdownWebDAVFileInfo(name, box, doc, isDir)
+	if isDir {
+		return &markdownWebDAVDir{info: info, box: box, doc: doc}, nil
+	}
+
+	content := []byte(ExportStdMarkdown(doc.ID))
+	info.size = int64(len(content))
+	setMarkdownWebDAVSize(doc, info.size)
+	return &markdownWebDAVReadFile{info: info, Reader: bytes.NewReader(content)}, nil
+}
+
+func (fsys *MarkdownWebDAV) RemoveAll(ctx context.Context, name string) (err error) {
+	markdownWebDAVLock.Lock()
+	defer markdownWebDAVLock.Unlock()
+
+	box, doc, isDir, err := resolveMarkdownWebDAVPath(name)
+	if nil != err {
+		return
+	}
+	if nil == doc {
+		// 不允许通过 WebDAV 删除笔记本
+		return os.ErrPermission
+	}
+
+	if !isDir {
+		err = RemoveDoc(box.ID, doc.Path)
+		return
+	}
+
+	// 删除文档对应的文件夹时仅删除其子文档
+	files, _, err := ListDocTree(box.ID, doc.Path, util.SortModeUnassigned, false, false, Conf.FileTree.MaxListCount)
+	if nil != err {
+		return
+	}
+	for _, file := range files {
+		if err = RemoveDoc(box.ID, file.Path); nil != err {
+			return
+		}
+	}
+	return
+}
+
+func (fsys *MarkdownWebDAV) Rename(ctx context.Context, oldName, newName string) (err error) {
+	markdownWebDAVLock.Lock()
+	defer markdownWebDAVLock.Unlock()
+
+	oldParts, newParts := splitMarkdownWebDAVPath(oldName), splitMarkdownWebDAVPath(newName)
+	if 1 > len(oldParts) || 1 > len(newParts) {
+		return os.ErrPermission
+	}
+
+	box, doc, isDir, err := resolveMarkdownWebDAVPath(oldName)
+	if nil != err {
+		return
+	}
+
+	if nil == doc {
+		if 1 != len(newParts) {
+			return os.ErrPermission
+		}
+		err = RenameBox(box.ID, newParts[0])
+		return
+	}
+
+	if 2 > len(newParts) {
+		return os.ErrPermission
+	}
+
+	title := newParts[len(newParts)-1]
+	if !isDir {
+		if !strings.HasSuffix(title, markdownWebDAVExt) {
+			return os.ErrInvalid
+		}
+		title = strings.TrimSuffix(title, markdownWebDAVExt)
+	}
+
+	toBox, toParent, toIsDir, err := resolveMarkdownWebDAVPath(path.Dir(path.Clean("/" + newName)))
+	if nil != err {
+		return
+	}
+	if !toIsDir {
+		return os.ErrInvalid
+	}
+
+	p := doc.Path
+	toPath, fromPath := "/", "/"
+	if nil != toParent {
+		toPath = toParent.Path
+	}
+	if parentDir := path.Dir(p); "/" != parentDir {
+		fromPath = parentDir + ".sy"
+	}
+	if box.ID != toBox.ID || fromPath != toPath {
+		if nil != toParent && (toParent.ID == doc.ID || strings.HasPrefix(toParent.Path, strings.TrimSuffix(p, ".sy")+"/")) {
+			return os.ErrInvalid
+		}
+
+		if err = MoveDocs([]string{p}, toBox.ID, toPath, nil); nil != err {
+			return
+		}
+		p = path.Join(strings.TrimSuffix(toPath, ".sy"), doc.ID+".sy")
+		util.PushReloadFiletree()
+	}
+
+	if title != markdownWebDAVDocTitle(doc) {
+		err = RenameDoc(toBox.ID, p, title)
+	}
+	return
+}
+
+func (fsys *MarkdownWebDAV) Stat(ctx context.Context, name string) (ret os.FileInfo, err error) {
+	markdownWebDAVLock.Lock()
+	defer markdownWebDAVLock.Unlock()
+
+	box, doc, isDir, err := resolveMarkdownWebDAVPath(name)
+	if nil != err {
+		return
+	}
+	return newMarkdownWebDAVFileInfo(name, box, doc, isDir), nil
+}
+
+// resolveMarkdownWebDAVPath 按层级逐级匹配文档标题来解析路径，返回值 doc 为 nil 时表示根目录或者笔记本目录。
+func resolveMarkdownWebDAVPath(name string) (box *Box, doc *File, isDir bool, err error) {
+	parts := splitMarkdownWebDAVPath(name)
+	if 1 > len(parts) {
+		isDir = true
+		return
+	}
+
+	box = getMarkdownWebDAVBox(parts[0])
+	if nil == box {
+		err = os.ErrNotExist
+		return
+	}
+
+	isDir = true
+	listPath := "/"
+	for i, part := range parts[1:] {
+		last := i == len(parts)-2
+		files, _, listErr := ListDocTree(box.ID, listPath, util.SortModeUnassigned, false, false, Conf.FileTree.MaxListCount)
+		if nil != listErr {
+			err = listErr
+			return
+		}
+
+		title := part
+		isDir = !last || !strings.HasSuffix(part, markdownWebDAVExt)
+		if !isDir {
+			title = strings.TrimSuffix(part, markdownWebDAVExt)
+		}
+
+		doc = nil
+		for _, file := range files {
+			if util.FilterFileName(markdownWebDAVDocTitle(file)) == title {
+				doc = file
+				break
+			}
+		}
+		if nil == doc {
+			err = os.ErrNotExist
+			return
+		}
+		listPath = doc.Path
+	}
+	return
+}
+
+func splitMarkdownWebDAVPath(name string) (ret []string) {
+	name = strings.Trim(path.Clean("/"+name), "/")
+	if "" == name {
+		return
+	}
+	return strings.Split(name, "/")
+}
+
+func getMarkdownWebDAVBox(name string) *Box {
+	for _, box := range Conf.GetOpenedBoxes() {
+		if util.FilterFileName(box.Name) == name {
+			return box
+		}
+	}
+	return nil
+}
+
+func markdownWebDAVDocTitle(doc *File) string {
+	return strings.TrimSuffix(doc.Name, ".sy")
+}
+
+func createMarkdownWebDAVDoc(box *Box, parent *File, title, md string) (tree *parse.Tree, err error) {
+	dir := ""
+	if nil != parent {
+		dir = strings.TrimSuffix(parent.Path, ".sy")
+	}
+	p := dir + "/" + ast.NewNodeID() + ".sy"
+	tree, err = CreateDocByMd(box.ID, p, title, md, nil)
+	if nil != err {
+		return
+	}
+	util.PushReloadFiletree()
+	return
+}
+
+// updateDocByMarkdown 使用 Markdown 更新文档内容。
+//
+// 新旧顶层块按照导出内容一一匹配，匹配上的块保留原有 ID 和属性，内容有变化的块使用 update 操作更新，
+// 其余块通过 insert/delete 操作增删，最后通过 move 操作调整顺序。
+func updateDocByMarkdown(rootID, md string) (err error) {
+	FlushTxQueue()
+	oldTree, err := LoadTreeByBlockID(rootID)
+	if nil != err {
+		return
+	}
+
+	ops := markdownWebDAVOperations(oldTree, md)
+	if 1 > len(ops) {
+		return
+	}
+
+	PerformTransactions(&[]*Transaction{{DoOperations: ops}})
+	FlushTxQueue()
+	refreshProtyle(rootID)
+	return
+}
+
+// markdownWebDAVOperations 计算将文档内容更新为 Markdown 所需的事务操作。
+func markdownWebDAVOperations(oldTree *parse.Tree, md string) (ops []*Operation) {
+	rootID := oldTree.ID
+	md = trimMarkdownWebDAVExtra(md, oldTree.Root.IALAttr("title"))
+	luteEngine := util.NewLute()
+	luteEngine.SetHTMLTag2TextMark(true)
+	_, newTree := luteEngine.Md2BlockDOMTree(md, true)
+	var newNodes []*ast.Node
+	if nil != newTree {
+		for n := newTree.Root.FirstChild; nil != n; n = n.Next {
+			if ast.NodeKramdownBlockIAL == n.Type {
+				continue
+			}
+			newNodes = append(newNodes, n)
+		}
+	}
+	if 1 > len(newNodes) {
+		newNodes = append(newNodes, treenode.NewParagraph(""))
+	}
+
+	var oldNodes []*ast.Node
+	for n := oldTree.Root.FirstChild; nil != n; n = n.Next {
+		if ast.NodeKramdownBlockIAL == n.Type {
+			continue
+		}
+		oldNodes = append(oldNodes, n)
+	}
+
+	// 先按内容精确匹配，再按位置匹配同类型的块
+	matched := map[int]int{} // 新块下标 -> 旧块下标
+	used := map[int]bool{}
+	oldMds, newMds := make([]string, len(oldNodes)), make([]string, len(newNodes))
+	for i, oldNode := range oldNodes {
+		oldMds[i] = treenode.ExportNodeStdMd(oldNode, luteEngine)
+	}
+	for i, newNode := range newNodes {
+		newMds[i] = treenode.ExportNodeStdMd(newNode, luteEngine)
+	}
+	for i, newNode := range newNodes {
+		for j, oldNode := range oldNodes {
+			if used[j] {
+				continue
+			}
+			if isMarkdownWebDAVBlockUnchanged(oldNode, oldMds[j], newNode, newMds[i]) {
+				matched[i] = j
+				used[j] = true
+				break
+			}
+		}
+	}
+	for i, newNode := range newNodes {
+		if _, ok := matched[i]; ok {
+			continue
+		}
+		if i < len(oldNodes) && !used[i] && oldNodes[i].Type == newNode.Type {
+			matched[i] = i
+			used[i] = true
+		}
+	}
+
+	var order []string
+	for j, oldNode := range oldNodes {
+		if !used[j] {
+			ops = append(ops, &Operation{Action: "delete", ID: oldNode.ID})
+			continue
+		}
+		order = append(order, oldNode.ID)
+	}
+
+	previousID := ""
+	for i, newNode := range newNodes {
+		j, ok := matched[i]
+		if !ok {
+			ops = append(ops, &Operation{Action: "insert", ID: newNode.ID, Data: luteEngine.RenderNodeBlockDOM(newNode), PreviousID: previousID, ParentID: rootID})
+			order = insertMarkdownWebDAVOrder(order, newNode.ID, previousID)
+			previousID = newNode.ID
+			continue
+		}
+
+		oldNode := oldNodes[j]
+		if !isMarkdownWebDAVBlockUnchanged(oldNode, oldMds[j], newNode, newMds[i]) {
+			newNode.ID = oldNode.ID
+			newNode.KramdownIAL = oldNode.KramdownIAL
+			ops = append(ops, &Operation{Action: "update", ID: oldNode.ID, Data: luteEngine.RenderNodeBlockDOM(newNode)})
+		}
+
+		idx := indexOfMarkdownWebDAVOrder(order, oldNode.ID)
+		if ("" == previousID && 0 != idx) || ("" != previousID && (0 == idx || order[idx-1] != previousID)) {
+			ops = append(ops, &Operation{Action: "move", ID: oldNode.ID, PreviousID: previousID, ParentID: rootID})
+			order = append(order[:idx], order[idx+1:]...)
+			order = insertMarkdownWebDAVOrder(order, oldNode.ID, previousID)
+		}
+		previousID = oldNode.ID
+	}
+	return
+}
+
+// isMarkdownWebDAVBlockUnchanged 判断块内容是否未变化。
+//
+// 导出时块引用会按照导出设置被转换，因此包含块引用的块退化为比较纯文本内容，避免丢失引用。
+func isMarkdownWebDAVBlockUnchanged(oldNode *ast.Node, oldMd string, newNode *ast.Node, newMd string) bool {
+	if oldNode.Type != newNode.Type {
+		return false
+	}
+	if oldMd == newMd {
+		return true
+	}
+
+	hasRef := false
+	ast.Walk(oldNode, func(n *ast.Node, entering bool) ast.WalkStatus {
+		if !entering {
+			return ast.WalkContinue
+		}
+		if treenode.IsBlockRef(n) || treenode.IsBlockLink(n) || treenode.IsFileAnnotationRef(n) || ast.NodeBlockQueryEmbed == n.Type {
+			hasRef = true
+			return ast.WalkStop
+		}
+		return ast.WalkContinue
+	})
+	if !hasRef {
+		return false
+	}
+	return sql.NodeStaticContent(oldNode, nil, false, false, false) == sql.NodeStaticContent(newNode, nil, false, false, false)
+}
+
+func indexOfMarkdownWebDAVOrder(order []string, id string) int {
+	for i, o := range order {
+		if o == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func insertMarkdownWebDAVOrder(order []string, id, previousID string) []string {
+	idx := 0
+	if "" != previousID {
+		idx = indexOfMarkdownWebDAVOrder(order, previousID) + 1
+	}
+	order = append(order, "")
+	copy(order[idx+1:], order[idx:])
+	order[idx] = id
+	return order
+}
+
+// trimMarkdownWebDAVExtra 去掉导出时添加的 YAML Front Matter 和文档标题。
+func trimMarkdownWebDAVExtra(md, title string) string {
+	md = strings.TrimPrefix(md, "\ufeff")
+	if strings.HasPrefix(md, "---\n") {
+		if end := strings.Index(md[4:], "\n---\n"); -1 < end {
+			md = md[4+end+5:]
+		}
+	}
+
+	if Conf.Export.AddTitle {
+		trimmed := strings.TrimLeft(md, "\n")
+		firstLine, rest, _ := strings.Cut(trimmed, "\n")
+		if strings.TrimSpace(firstLine) == "# "+title {
+			md = rest
+		}
+	}
+	return md
+}
+
+type markdownWebDAVFileInfo struct {
+	name    string
+	isDir   bool
+	modTime time.Time
+	size    int64
+}
+
+// markdownWebDAVSize 缓存文档导出内容的长度，文档修改时间变化后失效。
+type markdownWebDAVSize struct {
+	mtime int64
+	size  int64
+}
+
+var (
+	markdownWebDAVSizes    = map[string]*markdownWebDAVSize{}
+	markdownWebDAVSizeLock = sync.Mutex{}
+)
+
+func getMarkdownWebDAVSize(doc *File) (ret int64, ok bool) {
+	markdownWebDAVSizeLock.Lock()
+	defer markdownWebDAVSizeLock.Unlock()
+
+	if cached := markdownWebDAVSizes[doc.ID]; nil != cached && cached.mtime == doc.Mtime {
+		return cached.size, true
+	}
+	return
+}
+
+func setMarkdownWebDAVSize(doc *File, size int64) {
+	markdownWebDAVSizeLock.Lock()
+	defer markdownWebDAVSizeLock.Unlock()
+
+	markdownWebDAVSizes[doc.ID] = &markdownWebDAVSize{mtime: doc.Mtime, size: size}
+}
+
+func newMarkdownWebDAVFileInfo(name string, box *Box, doc *File, isDir bool) (ret *markdownWebDAVFileInfo) {
+	ret = &markdownWebDAVFileInfo{name: path.Base(path.Clean("/" + name)), isDir: isDir, modTime: time.Now()}
+	if nil != doc {
+		ret.modTime = time.Unix(doc.Mtime, 0)
+		if !isDir {
+			// 列出目录时不导出文档，优先使用读取文档时缓存的长度，否则使用 .sy 文件大小估算
+			if size, ok := getMarkdownWebDAVSize(doc); ok {
+				ret.size = size
+			} else {
+				ret.size = int64(doc.Size)
+			}
+		}
+	} else if nil != box {
+		if t, parseErr := time.ParseInLocation("20060102150405", box.ID[:14], time.Local); nil == parseErr {
+			ret.modTime = t
+		}
+	}
+	return
+}
+
+func (info *markdownWebDAVFileInfo) Name() string {
+	return info.name
+}
+
+// Size 导出内容的长度，未读取过的文档返回估算值。
+func (info *markdownWebDAVFileInfo) Size() int64 {
+	if info.isDir {
+		return 0
+	}
+	return info.size
+}
+
+func (info *markdownWebDAVFileInfo) Mode() fs.FileMode {
+	if info.isDir {
+		return fs.ModeDir | 0755
+	}
+	return 0644
+}
+
+func (info *markdownWebDAVFileInfo) ModTime() time.Time {
+	return info.modTime
+}
+
+func (info *markdownWebDAVFileInfo) IsDir() bool {
+	return info.isDir
+}
+
+func (info *markdownWebDAVFileInfo) Sys() any {
+	return nil
+}
+
+type markdownWebDAVDir struct {
+	info *markdownWebDAVFileInfo
+	box  *Box
+	doc  *File
+	read bool
+}
+
+func (dir *markdownWebDAVDir) Readdir(count int) (ret []fs.FileInfo, err error) {
+	markdownWebDAVLock.Lock()
+	defer markdownWebDAVLock.Unlock()
+
+	if dir.read {
+		if 0 < count {
+			return nil, io.EOF
+		}
+		return
+	}
+	dir.read = true
+
+	if nil == dir.box {
+		for _, box := range Conf.GetOpenedBoxes() {
+			ret = append(ret, newMarkdownWebDAVFileInfo(util.FilterFileName(box.Name), box, nil, true))
+		}
+		return
+	}
+
+	listPath := "/"
+	if nil != dir.doc {
+		listPath = dir.doc.Path
+	}
+	files, _, err := ListDocTree(dir.box.ID, listPath, util.SortModeUnassigned, false, false, Conf.FileTree.MaxListCount)
+	if nil != err {
+		return
+	}
+
+	names := map[string]bool{}
+	for _, file := range files {
+		name := util.FilterFileName(markdownWebDAVDocTitle(file))
+		if names[name] {
+			logging.LogWarnf("duplicated doc title [%s] in [%s]", name, path.Join(dir.box.ID, listPath))
+			continue
+		}
+		names[name] = true
+
+		ret = append(ret, newMarkdownWebDAVFileInfo(name+markdownWebDAVExt, dir.box, file, false))
+		if 0 < file.SubFileCount {
+			ret = append(ret, newMarkdownWebDAVFileInfo(name, dir.box, file, true))
+		}
+	}
+	return
+}
+
+func (dir *markdownWebDAVDir) Stat() (fs.FileInfo, error) {
+	return dir.info, nil
+}
+
+func (dir *markdownWebDAVDir) Read(p []byte) (int, error) {
+	return 0, os.ErrInvalid
+}
+
+func (dir *markdownWebDAVDir) Seek(offset int64, whence int) (int64, error) {
+	return 0, os.ErrInvalid
+}
+
+func (dir *markdownWebDAVDir) Write(p []byte) (int, error) {
+	return 0, os.ErrPermission
+}
+
+func (dir *markdownWebDAVDir) Close() error {
+	return nil
+}
+
+type markdownWebDAVReadFile struct {
+	*bytes.Reader
+	info *markdownWebDAVFileInfo
+}
+
+func (file *markdownWebDAVReadFile) Readdir(count int) ([]fs.FileInfo, error) {
+	return nil, os.ErrInvalid
+}
+
+func (file *markdownWebDAVReadFile) Stat() (fs.FileInfo, error) {
+	return file.info, nil
+}
+
+func (file *markdownWebDAVReadFile) Write(p []byte) (int, error) {
+	return 0, os.ErrPermission
+}
+
+func (file *markdownWebDAVReadFile) Close() error {
+	return nil
+}
+
+// markdownWebDAVWriteFile 缓存写入的内容，在关闭时再应用到文档上。
+type markdownWebDAVWriteFile struct {
+	name string
+	buf  bytes.Buffer
+}
+
+func (file *markdownWebDAVWriteFile) Write(p []byte) (int, error) {
+	return file.buf.Write(p)
+}
+
+func (file *markdownWebDAVWriteFile) Close() (err error) {
+	markdownWebDAVLock.Lock()
+	defer markdownWebDAVLock.Unlock()
+
+	md := file.buf.String()
+	_, doc, _, err := resolveMarkdownWebDAVPath(file.name)
+	if nil == err {
+		if err = updateDocByMarkdown(doc.ID, md); nil != err {
+			logging.LogErrorf("update doc [%s] by markdown failed: %s", doc.ID, err)
+		}
+		return
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return
+	}
+
+	box, parent, _, err := resolveMarkdownWebDAVPath(path.Dir(file.name))
+	if nil != err {
+		return
+	}
+	title := strings.TrimSuffix(path.Base(file.name), markdownWebDAVExt)
+	_, err = createMarkdownWebDAVDoc(box, parent, title, trimMarkdownWebDAVExtra(md, title))
+	return
+}
+
+func (file *markdownWebDAVWriteFile) Read(p []byte) (int, error) {
+	return 0, os.ErrInvalid
+}
+
+func (file *markdownWebDAVWriteFile) Seek(offset int64, whence int) (int64, error) {
+	// 部分客户端写入前会 Seek 到起始位置
+	if 0 == offset && io.SeekStart == whence {
+		return 0, nil
+	}
+	return 0, os.ErrInvalid
+}
+
+func (file *markdownWebDAVWriteFile) Readdir(count int) ([]fs.FileInfo, error) {
+	return nil, os.ErrInvalid
+}
+
+func (file *markdownWebDAVWriteFile) Stat() (fs.FileInfo, error) {
+	return &markdownWebDAVFileInfo{name: path.Base(file.name), modTime: time.Now(), size: int64(file.buf.Len())}, nil
+}
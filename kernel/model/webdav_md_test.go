@@ -0,0 +1,103 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package model
+
+import (
+	"testing"
+
+	"github.com/88250/lute/ast"
+	"github.com/88250/lute/parse"
+	"github.com/siyuan-note/siyuan/kernel/conf"
+	"github.com/siyuan-note/siyuan/kernel/util"
+)
+
+func newTestMarkdownWebDAVTree(t *testing.T, md string) (tree *parse.Tree, ids []string) {
+	luteEngine := util.NewLute()
+	_, tree = luteEngine.Md2BlockDOMTree(md, true)
+	if nil == tree {
+		t.Fatalf("parse markdown failed")
+	}
+	tree.ID = ast.NewNodeID()
+	tree.Root.ID = tree.ID
+	tree.Root.SetIALAttr("title", "test")
+	for n := tree.Root.FirstChild; nil != n; n = n.Next {
+		if ast.NodeKramdownBlockIAL != n.Type {
+			ids = append(ids, n.ID)
+		}
+	}
+	return
+}
+
+func TestMarkdownWebDAVOperations(t *testing.T) {
+	oldConf := Conf
+	Conf = &AppConf{Export: conf.NewExport()}
+	defer func() { Conf = oldConf }()
+
+	md := "foo\n\nbar\n\nbaz\n"
+	tree, ids := newTestMarkdownWebDAVTree(t, md)
+	if 3 != len(ids) {
+		t.Fatalf("expected 3 blocks, got %d", len(ids))
+	}
+
+	// 内容不变时不产生任何操作
+	if ops := markdownWebDAVOperations(tree, md); 0 < len(ops) {
+		t.Fatalf("expected no operations, got %d", len(ops))
+	}
+
+	// 修改和插入块时保留原有块的 ID
+	ops := markdownWebDAVOperations(tree, "foo\n\nbar changed\n\nnew\n\nbaz\n")
+	var updates, inserts int
+	for _, op := range ops {
+		switch op.Action {
+		case "update":
+			updates++
+			if ids[1] != op.ID {
+				t.Fatalf("expected update [%s], got [%s]", ids[1], op.ID)
+			}
+		case "insert":
+			inserts++
+			if ids[1] != op.PreviousID {
+				t.Fatalf("expected insert after [%s], got [%s]", ids[1], op.PreviousID)
+			}
+		default:
+			t.Fatalf("unexpected operation [%s] on [%s]", op.Action, op.ID)
+		}
+	}
+	if 1 != updates || 1 != inserts {
+		t.Fatalf("expected 1 update and 1 insert, got %d and %d", updates, inserts)
+	}
+
+	// 调整顺序时仅移动块
+	ops = markdownWebDAVOperations(tree, "baz\n\nfoo\n\nbar\n")
+	if 1 > len(ops) {
+		t.Fatalf("expected move operations")
+	}
+	for _, op := range ops {
+		if "move" != op.Action {
+			t.Fatalf("unexpected operation [%s] on [%s]", op.Action, op.ID)
+		}
+		if op.ID != ids[0] && op.ID != ids[1] && op.ID != ids[2] {
+			t.Fatalf("unexpected block [%s]", op.ID)
+		}
+	}
+
+	// 删除块时其他块的 ID 不变
+	ops = markdownWebDAVOperations(tree, "foo\n\nbaz\n")
+	if 1 != len(ops) || "delete" != ops[0].Action || ids[1] != ops[0].ID {
+		t.Fatalf("expected delete [%s], got %v", ids[1], ops)
+	}
+}
@@ -150,12 +150,14 @@ func Serve(fastMode bool) {
 		HttpOnly: true,
 	})
 	ginServer.Use(sessions.Sessions("siyuan", sessionStore))
+	ginServer.Use(model.Audit) // 记录写入接口的审计日志
 
 	serveDebug(ginServer)
 	serveAssets(ginServer)
 	serveAppearance(ginServer)
 	serveWebSocket(ginServer)
 	serveWebDAV(ginServer)
+	serveMarkdownWebDAV(ginServer)
 	serveCalDAV(ginServer)
 	serveCardDAV(ginServer)
 	serveExport(ginServer)
@@ -405,6 +407,8 @@ func serveAppearance(ginServer *gin.Engine) {
 
 func serveCheckAuth(ginServer *gin.Engine) {
 	ginServer.GET("/check-auth", serveAuthPage)
+	ginServer.GET(model.OIDCLoginPath, model.OIDCLogin)
+	ginServer.GET(model.OIDCCallbackPath, model.OIDCCallback)
 }
 
 func serveAuthPage(c *gin.Context) {
@@ -451,6 +455,8 @@ func serveAuthPage(c *gin.Context) {
 		"l6":                     model.Conf.Language(178),
 		"l7":                     template.HTML(model.Conf.Language(184)),
 		"l8":                     model.Conf.Language(95),
+		"l9":                     model.Conf.Language(252),
+		"l10":                    model.Conf.Language(254),
 		"appearanceMode":         model.Conf.Appearance.Mode,
 		"appearanceModeOS":       model.Conf.Appearance.ModeOS,
 		"workspace":              util.WorkspaceName,
@@ -470,9 +476,9 @@ func serveAuthPage(c *gin.Context) {
 }
 
 func serveAssets(ginServer *gin.Engine) {
-	ginServer.POST("/upload", model.CheckAuth, model.CheckAdminRole, model.CheckReadonly, model.Upload)
+	ginServer.POST("/upload", model.CheckAuth, model.CheckEditRole, model.CheckReadonly, model.Upload)
 
-	ginServer.GET("/assets/*path", model.CheckAuth, func(context *gin.Context) {
+	ginServer.GET("/assets/*path", model.CheckAuth, model.CheckReadRole, func(context *gin.Context) {
 		requestPath := context.Param("path")
 		relativePath := path.Join("assets", requestPath)
 		p, err := model.GetAssetAbsPath(relativePath)
@@ -675,8 +681,8 @@ func serveWebDAV(ginServer *gin.Engine) {
 	// REF: https://github.com/fungaren/gin-webdav
 	handler := webdav.Handler{
 		Prefix:     "/webdav/",
-		FileSystem: webdav.Dir(util.WorkspaceDir),
-		LockSystem: webdav.NewMemLS(),
+		FileSystem: &model.WorkspaceWebDAV{},
+		LockSystem: model.NewWebDAVLockSystem(),
 		Logger: func(r *http.Request, err error) {
 			if nil != err {
 				logging.LogErrorf("WebDAV [%s %s]: %s", r.Method, r.URL.String(), err.Error())
@@ -687,6 +693,43 @@ func serveWebDAV(ginServer *gin.Engine) {
 
 	ginGroup := ginServer.Group("/webdav", model.CheckAuth, model.CheckAdminRole)
 	// ginGroup.Any NOT support extension methods (PROPFIND etc.)
+	ginGroup.Match(WebDavMethods, "/*path", func(c *gin.Context) {
+		if util.ReadOnly {
+			switch c.Request.Method {
+			case http.MethodPost,
+				http.MethodPut,
+				http.MethodDelete,
+				MethodMkCol,
+				MethodCopy,
+				MethodMove,
+				MethodLock,
+				MethodUnlock,
+				MethodPropPatch:
+				c.AbortWithError(http.StatusForbidden, fmt.Errorf(model.Conf.Language(34)))
+				return
+			}
+		}
+		if http.MethodPut == c.Request.Method && !model.CheckWebDAVQuota(c.Request.ContentLength) {
+			c.AbortWithError(http.StatusInsufficientStorage, model.ErrorWebDAVQuotaExceeded)
+			return
+		}
+		handler.ServeHTTP(c.Writer, c.Request)
+	})
+}
+
+func serveMarkdownWebDAV(ginServer *gin.Engine) {
+	handler := webdav.Handler{
+		Prefix:     "/webdav-md/",
+		FileSystem: &model.MarkdownWebDAV{},
+		LockSystem: webdav.NewMemLS(),
+		Logger: func(r *http.Request, err error) {
+			if nil != err {
+				logging.LogErrorf("Markdown WebDAV [%s %s]: %s", r.Method, r.URL.String(), err.Error())
+			}
+		},
+	}
+
+	ginGroup := ginServer.Group("/webdav-md", model.CheckAuth, model.CheckAdminRole)
 	ginGroup.Match(WebDavMethods, "/*path", func(c *gin.Context) {
 		if util.ReadOnly {
 			switch c.Request.Method {
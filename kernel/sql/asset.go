@@ -116,6 +116,23 @@ func QueryAssetByHash(hash string) (ret *Asset) {
 	return
 }
 
+// QueryAssetsByPath 查询引用了指定资源文件的资源记录，path 形如 assets/foo.png。
+func QueryAssetsByPath(path string) (ret []*Asset) {
+	sqlStmt := "SELECT * FROM assets WHERE path = ?"
+	rows, err := query(sqlStmt, path)
+	if err != nil {
+		logging.LogErrorf("sql query [%s] failed: %s", sqlStmt, err)
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if asset := scanAssetRows(rows); nil != asset {
+			ret = append(ret, asset)
+		}
+	}
+	return
+}
+
 func scanAssetRows(rows *sql.Rows) (ret *Asset) {
 	var asset Asset
 	if err := rows.Scan(&asset.ID, &asset.BlockID, &asset.RootID, &asset.Box, &asset.DocPath, &asset.Path, &asset.Name, &asset.Title, &asset.Hash); err != nil {
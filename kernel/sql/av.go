@@ -32,6 +32,54 @@ import (
 	"github.com/siyuan-note/siyuan/kernel/util"
 )
 
+func RenderAttributeViewKanban(attrView *av.AttributeView, view *av.View, query string) (ret *av.Kanban) {
+	ret = &av.Kanban{Table: RenderAttributeViewTable(attrView, view, query), KanbanColumns: []*av.KanbanColumn{}}
+	groupKey := av.GetKanbanGroupKey(attrView, view.Kanban)
+	if nil == groupKey {
+		return
+	}
+
+	ret.GroupKey = ret.GetColumn(groupKey.ID)
+	if nil == ret.GroupKey {
+		ret.GroupKey = &av.TableColumn{ID: groupKey.ID, Name: groupKey.Name, Type: groupKey.Type, Icon: groupKey.Icon, Options: groupKey.Options}
+	}
+	return
+}
+
+func RenderAttributeViewCalendar(attrView *av.AttributeView, view *av.View, query string) (ret *av.Calendar) {
+	ret = &av.Calendar{Table: RenderAttributeViewTable(attrView, view, query), Mode: av.CalendarModeMonth, Events: []*av.CalendarEvent{}}
+	if nil != view.Calendar {
+		if av.IsCalendarMode(string(view.Calendar.Mode)) {
+			ret.Mode = view.Calendar.Mode
+		}
+		ret.FirstDayOfWeek = view.Calendar.FirstDayOfWeek
+	}
+
+	dateKey := av.GetCalendarDateKey(attrView, view.Calendar)
+	if nil == dateKey {
+		return
+	}
+
+	ret.DateKey = ret.GetColumn(dateKey.ID)
+	if nil == ret.DateKey {
+		ret.DateKey = &av.TableColumn{ID: dateKey.ID, Name: dateKey.Name, Type: dateKey.Type, Icon: dateKey.Icon, Date: dateKey.Date}
+	}
+	return
+}
+
+func RenderAttributeViewGallery(attrView *av.AttributeView, view *av.View, query string) (ret *av.Gallery) {
+	ret = &av.Gallery{Table: RenderAttributeViewTable(attrView, view, query), CardSize: av.CardSizeMedium, Cards: []*av.GalleryCard{}}
+	if nil != view.Gallery {
+		ret.CoverFrom = view.Gallery.CoverFrom
+		ret.CoverFromAssetKeyID = view.Gallery.CoverFromAssetKeyID
+		if av.IsCardSize(string(view.Gallery.CardSize)) {
+			ret.CardSize = view.Gallery.CardSize
+		}
+		ret.FitImage = view.Gallery.FitImage
+	}
+	return
+}
+
 func RenderAttributeViewTable(attrView *av.AttributeView, view *av.View, query string) (ret *av.Table) {
 	ret = &av.Table{
 		ID:               view.ID,
@@ -41,8 +89,10 @@ func RenderAttributeViewTable(attrView *av.AttributeView, view *av.View, query s
 		HideAttrViewName: view.HideAttrViewName,
 		Columns:          []*av.TableColumn{},
 		Rows:             []*av.TableRow{},
-		Filters:          view.Table.Filters,
+		Filters:          view.Table.GetFilter().Children,
 		Sorts:            view.Table.Sorts,
+		Group:            view.Table.Group,
+		Formats:          view.Table.Formats,
 	}
 
 	// 组装列
@@ -51,8 +101,7 @@ func RenderAttributeViewTable(attrView *av.AttributeView, view *av.View, query s
 		if nil != getErr {
 			// 找不到字段则在视图中删除
 
-			switch view.LayoutType {
-			case av.LayoutTypeTable:
+			if view.HasLayoutTable() {
 				for i, column := range view.Table.Columns {
 					if column.ID == col.ID {
 						view.Table.Columns = append(view.Table.Columns[:i], view.Table.Columns[i+1:]...)
@@ -77,6 +126,8 @@ func RenderAttributeViewTable(attrView *av.AttributeView, view *av.View, query s
 			Relation:     key.Relation,
 			Rollup:       key.Rollup,
 			Date:         key.Date,
+			Formula:      key.Formula,
+			AutoID:       key.AutoID,
 			Wrap:         col.Wrap,
 			Hidden:       col.Hidden,
 			Width:        col.Width,
@@ -166,6 +217,8 @@ func RenderAttributeViewTable(attrView *av.AttributeView, view *av.View, query s
 				tableCell.Value = &av.Value{ID: tableCell.ID, KeyID: col.ID, BlockID: rowID, Type: av.KeyTypeCreated}
 			case av.KeyTypeUpdated: // 填充更新时间列值，后面再渲染
 				tableCell.Value = &av.Value{ID: tableCell.ID, KeyID: col.ID, BlockID: rowID, Type: av.KeyTypeUpdated}
+			case av.KeyTypeFormula: // 填充公式列值，后面再计算
+				tableCell.Value = &av.Value{ID: tableCell.ID, KeyID: col.ID, BlockID: rowID, Type: av.KeyTypeFormula}
 			case av.KeyTypeRelation: // 清空关联列值，后面再渲染 https://ld246.com/article/1703831044435
 				if nil != tableCell.Value && nil != tableCell.Value.Relation {
 					tableCell.Value.Relation.Contents = nil
@@ -362,6 +415,12 @@ func RenderAttributeViewTable(attrView *av.AttributeView, view *av.View, query s
 		util.PushErrMsg(fmt.Sprintf(util.Langs[util.Lang][44], util.EscapeHTML(renderTemplateErr.Error())), 30000)
 	}
 
+	// 模板列渲染完成后计算公式列，这样公式就可以使用其他所有字段的值了
+	RenderFormulaCols(attrView, ret, rows)
+
+	// 所有字段值都渲染完成后应用条件格式
+	ret.FormatRows(attrView)
+
 	// 根据搜索条件过滤
 	query = strings.TrimSpace(query)
 	if "" != query {
@@ -415,6 +474,34 @@ func RenderAttributeViewTable(attrView *av.AttributeView, view *av.View, query s
 	return
 }
 
+// RenderFormulaCols 计算表格中的公式列，rows 为各行已经渲染好的关联、汇总、创建时间和更新时间等字段值。
+func RenderFormulaCols(attrView *av.AttributeView, table *av.Table, rows map[string][]*av.KeyValues) {
+	var formulaCols []int
+	for i, col := range table.Columns {
+		if av.KeyTypeFormula == col.Type {
+			formulaCols = append(formulaCols, i)
+		}
+	}
+	if 1 > len(formulaCols) {
+		return
+	}
+
+	for _, row := range table.Rows {
+		ctx := av.NewFormulaContext(attrView, rows[row.ID])
+		for _, cell := range row.Cells {
+			ctx.SetValue(cell.Value)
+		}
+
+		for _, i := range formulaCols {
+			key, _ := attrView.GetKey(table.Columns[i].ID)
+			if nil == key {
+				continue
+			}
+			row.Cells[i].Value.Formula = ctx.Render(key)
+		}
+	}
+}
+
 func RenderTemplateCol(ial map[string]string, rowValues []*av.KeyValues, tplContent string) (ret string, err error) {
 	if "" == ial["id"] {
 		block := getRowBlockValue(rowValues)
@@ -615,6 +702,14 @@ func FillAttributeViewTableCellNilValue(tableCell *av.TableCell, rowID, colID st
 		if nil == tableCell.Value.Rollup {
 			tableCell.Value.Rollup = &av.ValueRollup{}
 		}
+	case av.KeyTypeFormula:
+		if nil == tableCell.Value.Formula {
+			tableCell.Value.Formula = &av.ValueFormula{Type: av.KeyTypeText, Text: &av.ValueText{}}
+		}
+	case av.KeyTypeAutoID:
+		if nil == tableCell.Value.AutoID {
+			tableCell.Value.AutoID = &av.ValueAutoID{}
+		}
 	}
 }
 
@@ -644,7 +739,7 @@ func getAttributeViewContent(avID string) (content string) {
 
 	var view *av.View
 	for _, v := range attrView.Views {
-		if av.LayoutTypeTable == v.LayoutType {
+		if v.HasLayoutTable() {
 			view = v
 			break
 		}
@@ -0,0 +1,274 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package sql
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/siyuan-note/logging"
+	"github.com/siyuan-note/siyuan/kernel/av"
+)
+
+// AttrViewKey 描述了 av_keys 表中的一行，对应数据库的一个字段。
+type AttrViewKey struct {
+	ID   string
+	AvID string
+	Name string
+	Type string
+	Sort int
+}
+
+// AttrViewValue 描述了 av_values 表中的一行，对应数据库中某行的一个字段值。
+//
+// BlockID 为该值所在行绑定的块 ID，可以和 blocks.id 进行连接查询。
+// Number 仅在数字、日期、自动编号和复选框字段上有值，分别为数字、毫秒时间戳、编号和 0/1，便于范围查询和排序。
+type AttrViewValue struct {
+	ID      string
+	AvID    string
+	KeyID   string
+	BlockID string
+	Type    string
+	Content string
+	Number  interface{}
+	Created int64
+	Updated int64
+}
+
+const (
+	AttrViewsPlaceholder      = "(?, ?)"
+	AttrViewKeysPlaceholder   = "(?, ?, ?, ?, ?)"
+	AttrViewValuesPlaceholder = "(?, ?, ?, ?, ?, ?, ?, ?, ?)"
+)
+
+// initAttributeViewTables 创建数据库索引表。
+//
+// 已有的库在表结构版本不变时不会重建，所以这里使用 IF NOT EXISTS 兼容升级上来的库。
+func initAttributeViewTables() {
+	_, err := db.Exec("CREATE TABLE IF NOT EXISTS attribute_views (id, name)")
+	if err != nil {
+		logging.LogFatalf(logging.ExitCodeReadOnlyDatabase, "create table [attribute_views] failed: %s", err)
+	}
+
+	_, err = db.Exec("CREATE TABLE IF NOT EXISTS av_keys (id, av_id, name, type, sort)")
+	if err != nil {
+		logging.LogFatalf(logging.ExitCodeReadOnlyDatabase, "create table [av_keys] failed: %s", err)
+	}
+	_, err = db.Exec("CREATE INDEX IF NOT EXISTS idx_av_keys_av_id ON av_keys(av_id)")
+	if err != nil {
+		logging.LogFatalf(logging.ExitCodeReadOnlyDatabase, "create index [idx_av_keys_av_id] failed: %s", err)
+	}
+
+	_, err = db.Exec("CREATE TABLE IF NOT EXISTS av_values (id, av_id, key_id, block_id, type, content, number, created, updated)")
+	if err != nil {
+		logging.LogFatalf(logging.ExitCodeReadOnlyDatabase, "create table [av_values] failed: %s", err)
+	}
+	_, err = db.Exec("CREATE INDEX IF NOT EXISTS idx_av_values_av_id ON av_values(av_id)")
+	if err != nil {
+		logging.LogFatalf(logging.ExitCodeReadOnlyDatabase, "create index [idx_av_values_av_id] failed: %s", err)
+	}
+	_, err = db.Exec("CREATE INDEX IF NOT EXISTS idx_av_values_block_id ON av_values(block_id)")
+	if err != nil {
+		logging.LogFatalf(logging.ExitCodeReadOnlyDatabase, "create index [idx_av_values_block_id] failed: %s", err)
+	}
+}
+
+func dropAttributeViewTables() {
+	for _, table := range []string{"attribute_views", "av_keys", "av_values"} {
+		if _, err := db.Exec("DROP TABLE IF EXISTS " + table); err != nil {
+			logging.LogFatalf(logging.ExitCodeReadOnlyDatabase, "drop table [%s] failed: %s", table, err)
+		}
+	}
+}
+
+// CountAttributeViews 获取已经索引的数据库数量。
+func CountAttributeViews() (ret int) {
+	row := queryRow("SELECT COUNT(*) FROM attribute_views")
+	if nil == row {
+		return
+	}
+	if err := row.Scan(&ret); err != nil {
+		logging.LogErrorf("count attribute views failed: %s", err)
+	}
+	return
+}
+
+// indexAttributeView 重新索引数据库，数据库文件已经不存在时仅删除索引。
+func indexAttributeView(tx *sql.Tx, avID string) (err error) {
+	if err = deleteAttributeViewByID(tx, avID); err != nil {
+		return
+	}
+
+	attrView, err := av.ParseAttributeView(avID)
+	if err != nil {
+		if errors.Is(err, av.ErrViewNotFound) {
+			err = nil
+		}
+		return
+	}
+	if nil == attrView { // 读取文件失败
+		return
+	}
+
+	keys, values := attrViewRecords(attrView)
+	if err = execStmtTx(tx, "INSERT INTO attribute_views (id, name) VALUES "+AttrViewsPlaceholder, attrView.ID, attrView.Name); err != nil {
+		return
+	}
+	if err = insertAttrViewKeys(tx, keys); err != nil {
+		return
+	}
+	err = insertAttrViewValues(tx, values)
+	return
+}
+
+func deleteAttributeViewByID(tx *sql.Tx, avID string) (err error) {
+	if err = execStmtTx(tx, "DELETE FROM attribute_views WHERE id = ?", avID); err != nil {
+		return
+	}
+	if err = execStmtTx(tx, "DELETE FROM av_keys WHERE av_id = ?", avID); err != nil {
+		return
+	}
+	err = execStmtTx(tx, "DELETE FROM av_values WHERE av_id = ?", avID)
+	return
+}
+
+func attrViewRecords(attrView *av.AttributeView) (keys []*AttrViewKey, values []*AttrViewValue) {
+	for i, kv := range attrView.KeyValues {
+		keys = append(keys, &AttrViewKey{
+			ID:   kv.Key.ID,
+			AvID: attrView.ID,
+			Name: kv.Key.Name,
+			Type: string(kv.Key.Type),
+			Sort: i,
+		})
+
+		for _, value := range kv.Values {
+			if nil == value || "" == value.BlockID {
+				continue
+			}
+
+			values = append(values, &AttrViewValue{
+				ID:      value.ID,
+				AvID:    attrView.ID,
+				KeyID:   kv.Key.ID,
+				BlockID: value.BlockID,
+				Type:    string(value.Type),
+				Content: value.String(false),
+				Number:  attrViewValueNumber(value),
+				Created: value.CreatedAt,
+				Updated: value.UpdatedAt,
+			})
+		}
+	}
+	return
+}
+
+func attrViewValueNumber(value *av.Value) interface{} {
+	switch value.Type {
+	case av.KeyTypeNumber:
+		if nil != value.Number && value.Number.IsNotEmpty {
+			return value.Number.Content
+		}
+	case av.KeyTypeDate:
+		if nil != value.Date && value.Date.IsNotEmpty {
+			return value.Date.Content
+		}
+	case av.KeyTypeCreated:
+		if nil != value.Created && value.Created.IsNotEmpty {
+			return value.Created.Content
+		}
+	case av.KeyTypeUpdated:
+		if nil != value.Updated && value.Updated.IsNotEmpty {
+			return value.Updated.Content
+		}
+	case av.KeyTypeAutoID:
+		if nil != value.AutoID && 0 < value.AutoID.Content {
+			return value.AutoID.Content
+		}
+	case av.KeyTypeCheckbox:
+		if nil != value.Checkbox && value.Checkbox.Checked {
+			return 1
+		}
+		return 0
+	}
+	return nil
+}
+
+func insertAttrViewKeys(tx *sql.Tx, keys []*AttrViewKey) (err error) {
+	if 1 > len(keys) {
+		return
+	}
+
+	valueStrings := make([]string, 0, len(keys))
+	valueArgs := make([]interface{}, 0, len(keys)*strings.Count(AttrViewKeysPlaceholder, "?"))
+	for _, key := range keys {
+		valueStrings = append(valueStrings, AttrViewKeysPlaceholder)
+		valueArgs = append(valueArgs, key.ID)
+		valueArgs = append(valueArgs, key.AvID)
+		valueArgs = append(valueArgs, key.Name)
+		valueArgs = append(valueArgs, key.Type)
+		valueArgs = append(valueArgs, key.Sort)
+	}
+	stmt := fmt.Sprintf("INSERT INTO av_keys (id, av_id, name, type, sort) VALUES %s", strings.Join(valueStrings, ","))
+	err = prepareExecInsertTx(tx, stmt, valueArgs)
+	return
+}
+
+func insertAttrViewValues(tx *sql.Tx, values []*AttrViewValue) (err error) {
+	if 1 > len(values) {
+		return
+	}
+
+	var bulk []*AttrViewValue
+	for _, value := range values {
+		bulk = append(bulk, value)
+		if 512 > len(bulk) {
+			continue
+		}
+
+		if err = insertAttrViewValues0(tx, bulk); err != nil {
+			return
+		}
+		bulk = []*AttrViewValue{}
+	}
+	if 0 < len(bulk) {
+		err = insertAttrViewValues0(tx, bulk)
+	}
+	return
+}
+
+func insertAttrViewValues0(tx *sql.Tx, bulk []*AttrViewValue) (err error) {
+	valueStrings := make([]string, 0, len(bulk))
+	valueArgs := make([]interface{}, 0, len(bulk)*strings.Count(AttrViewValuesPlaceholder, "?"))
+	for _, value := range bulk {
+		valueStrings = append(valueStrings, AttrViewValuesPlaceholder)
+		valueArgs = append(valueArgs, value.ID)
+		valueArgs = append(valueArgs, value.AvID)
+		valueArgs = append(valueArgs, value.KeyID)
+		valueArgs = append(valueArgs, value.BlockID)
+		valueArgs = append(valueArgs, value.Type)
+		valueArgs = append(valueArgs, value.Content)
+		valueArgs = append(valueArgs, value.Number)
+		valueArgs = append(valueArgs, value.Created)
+		valueArgs = append(valueArgs, value.Updated)
+	}
+	stmt := fmt.Sprintf("INSERT INTO av_values (id, av_id, key_id, block_id, type, content, number, created, updated) VALUES %s", strings.Join(valueStrings, ","))
+	err = prepareExecInsertTx(tx, stmt, valueArgs)
+	return
+}
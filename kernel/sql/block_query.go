@@ -234,6 +234,11 @@ func queryAliases(searchIgnoreLines []string) (ret []string) {
 	return
 }
 
+// QueryDocIDsByTitle 根据标题查询文档 ID。
+func QueryDocIDsByTitle(title string) (ret []string) {
+	return queryDocIDsByTitle(title, nil)
+}
+
 func queryDocIDsByTitle(title string, excludeIDs []string) (ret []string) {
 	ret = []string{}
 	notIn := "('" + strings.Join(excludeIDs, "','") + "')"
@@ -349,6 +354,40 @@ func QueryBookmarkBlocks() (ret []*Block) {
 	return
 }
 
+// QueryReminderBlocks 查询设置了提醒的块。
+func QueryReminderBlocks() (ret []*Block) {
+	sqlStmt := "SELECT * FROM blocks WHERE ial LIKE ?"
+	rows, err := query(sqlStmt, "%custom-reminder-wechat=%")
+	if err != nil {
+		logging.LogErrorf("sql query [%s] failed: %s", sqlStmt, err)
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if block := scanBlockRows(rows); nil != block {
+			ret = append(ret, block)
+		}
+	}
+	return
+}
+
+// QueryDatedTaskBlocks 查询内容中带有日期（yyyy-MM-dd）的任务列表项。
+func QueryDatedTaskBlocks() (ret []*Block) {
+	sqlStmt := "SELECT * FROM blocks WHERE type = 'i' AND subtype = 't' AND content GLOB ?"
+	rows, err := query(sqlStmt, "*[0-9][0-9][0-9][0-9]-[0-9][0-9]-[0-9][0-9]*")
+	if err != nil {
+		logging.LogErrorf("sql query [%s] failed: %s", sqlStmt, err)
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if block := scanBlockRows(rows); nil != block {
+			ret = append(ret, block)
+		}
+	}
+	return
+}
+
 func QueryBookmarkLabels() (ret []string) {
 	ret = []string{}
 	sqlStmt := "SELECT * FROM blocks WHERE ial LIKE ?"
@@ -378,10 +417,16 @@ func QueryNoLimit(stmt string) (ret []map[string]interface{}, err error) {
 	return queryRawStmt(stmt, math.MaxInt)
 }
 
-func Query(stmt string, limit int) (ret []map[string]interface{}, err error) {
+// Query 执行查询语句，args 为语句中 ? 占位符绑定的参数。
+func Query(stmt string, limit int, args ...interface{}) (ret []map[string]interface{}, err error) {
 	// Kernel API `/api/query/sql` support `||` operator https://github.com/siyuan-note/siyuan/issues/9662
 	// 这里为了支持 || 操作符，使用了另一个 sql 解析器，但是这个解析器无法处理 UNION https://github.com/siyuan-note/siyuan/issues/8226
 	// 考虑到 UNION 的使用场景不多，这里还是以支持 || 操作符为主
+	if 0 < len(args) {
+		// 解析器会改写 ? 占位符，有绑定参数时不改写语句
+		return queryRawStmt(stmt, limit, args...)
+	}
+
 	p := sqlparser2.NewParser(strings.NewReader(stmt))
 	parsedStmt2, err := p.ParseStatement()
 	if err != nil {
@@ -389,7 +434,7 @@ func Query(stmt string, limit int) (ret []map[string]interface{}, err error) {
 			// 这个解析器无法处理 || 连接字符串操作符
 			parsedStmt, err2 := sqlparser.Parse(stmt)
 			if nil != err2 {
-				return queryRawStmt(stmt, limit)
+				return queryRawStmt(stmt, limit, args...)
 			}
 
 			switch parsedStmt.(type) {
@@ -405,10 +450,10 @@ func Query(stmt string, limit int) (ret []map[string]interface{}, err error) {
 				union.Limit = limitClause
 				stmt = sqlparser.String(union)
 			default:
-				return queryRawStmt(stmt, limit)
+				return queryRawStmt(stmt, limit, args...)
 			}
 		} else {
-			return queryRawStmt(stmt, limit)
+			return queryRawStmt(stmt, limit, args...)
 		}
 	} else {
 		switch parsedStmt2.(type) {
@@ -419,12 +464,12 @@ func Query(stmt string, limit int) (ret []map[string]interface{}, err error) {
 			}
 			stmt = slct.String()
 		default:
-			return queryRawStmt(stmt, limit)
+			return queryRawStmt(stmt, limit, args...)
 		}
 	}
 
 	ret = []map[string]interface{}{}
-	rows, err := query(stmt)
+	rows, err := query(stmt, args...)
 	if err != nil {
 		logging.LogWarnf("sql query [%s] failed: %s", stmt, err)
 		return
@@ -512,8 +557,8 @@ func getLimitClause(parsedStmt sqlparser.Statement, limit int) (ret *sqlparser.L
 	return
 }
 
-func queryRawStmt(stmt string, limit int) (ret []map[string]interface{}, err error) {
-	rows, err := query(stmt)
+func queryRawStmt(stmt string, limit int, args ...interface{}) (ret []map[string]interface{}, err error) {
+	rows, err := query(stmt, args...)
 	if err != nil {
 		if strings.Contains(err.Error(), "syntax error") {
 			return
@@ -559,10 +604,17 @@ func SelectBlocksRawStmtNoParse(stmt string, limit int) (ret []*Block) {
 	return selectBlocksRawStmt(stmt, limit)
 }
 
-func SelectBlocksRawStmt(stmt string, page, limit int) (ret []*Block) {
+// SelectBlocksRawStmt 执行块查询语句，args 为语句中 ? 占位符绑定的参数。
+//
+// 解析器会将 ? 占位符改写为 :v1 这样的命名参数，所以有绑定参数时不改写语句，调用方需要自己加上 LIMIT 子句。
+func SelectBlocksRawStmt(stmt string, page, limit int, args ...interface{}) (ret []*Block) {
+	if 0 < len(args) {
+		return selectBlocksRawStmt(stmt, limit, args...)
+	}
+
 	parsedStmt, err := sqlparser.Parse(stmt)
 	if err != nil {
-		return selectBlocksRawStmt(stmt, limit)
+		return selectBlocksRawStmt(stmt, limit, args...)
 	}
 
 	switch parsedStmt.(type) {
@@ -606,7 +658,7 @@ func SelectBlocksRawStmt(stmt string, page, limit int) (ret []*Block) {
 	stmt = strings.ReplaceAll(stmt, "\\\"", "\"")
 	stmt = strings.ReplaceAll(stmt, "\\\\*", "\\*")
 	stmt = strings.ReplaceAll(stmt, "from dual", "")
-	rows, err := query(stmt)
+	rows, err := query(stmt, args...)
 	if err != nil {
 		if strings.Contains(err.Error(), "syntax error") {
 			return
@@ -674,8 +726,8 @@ func SelectBlocksRegex(stmt string, exp *regexp.Regexp, name, alias, memo, ial b
 	return
 }
 
-func selectBlocksRawStmt(stmt string, limit int) (ret []*Block) {
-	rows, err := query(stmt)
+func selectBlocksRawStmt(stmt string, limit int, args ...interface{}) (ret []*Block) {
+	rows, err := query(stmt, args...)
 	if err != nil {
 		if strings.Contains(err.Error(), "syntax error") {
 			return
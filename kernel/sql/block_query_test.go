@@ -0,0 +1,83 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+//go:build fts5
+
+package sql
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/siyuan-note/siyuan/kernel/util"
+)
+
+func TestSelectRawStmtArgs(t *testing.T) {
+	util.DBPath = filepath.Join(t.TempDir(), "siyuan.db")
+	initDBConnection()
+	initDBTables()
+	defer closeDatabase()
+
+	blocks := []*Block{
+		{ID: "20240101120000-aaaaaaa", RootID: "20240101120000-aaaaaaa", Box: "box1", Type: "d", Content: "foo"},
+		{ID: "20240101120000-bbbbbbb", RootID: "20240101120000-aaaaaaa", Box: "box1", Type: "p", Content: "foo bar"},
+		{ID: "20240101120000-ccccccc", RootID: "20240101120000-aaaaaaa", Box: "box1", Type: "p", Content: "bar"},
+		{ID: "20240101120000-ddddddd", RootID: "20240101120000-ddddddd", Box: "box2", Type: "p", Content: "foo baz"},
+	}
+	for _, b := range blocks {
+		if _, err := db.Exec("INSERT INTO blocks (id, parent_id, root_id, hash, box, path, hpath, name, alias, memo, tag, content, fcontent, markdown, length, type, subtype, ial, sort, created, updated) VALUES (?, '', ?, '', ?, '', '', '', '', '', '', ?, '', '', 0, ?, '', '', 0, '', '')",
+			b.ID, b.RootID, b.Box, b.Content, b.Type); err != nil {
+			t.Fatalf("insert block failed: %s", err)
+		}
+	}
+
+	// 解析器会将 ? 改写为 :v1，将 || 改写为 or，有绑定参数时不能改写语句
+	stmt := "SELECT * FROM blocks WHERE type = ? AND box = ? AND (' ' || content || ' ') LIKE ? ORDER BY id LIMIT ?"
+	ret := SelectBlocksRawStmt(stmt, 1, 32, "p", "box1", "% foo %", 8)
+	if 1 != len(ret) || "20240101120000-bbbbbbb" != ret[0].ID {
+		t.Fatalf("unexpected blocks %v", ret)
+	}
+
+	ret = SelectBlocksRawStmt("SELECT * FROM blocks WHERE root_id = ? AND id <> ? AND content LIKE ? ORDER BY id LIMIT 8", 1, 32, "20240101120000-aaaaaaa", "20240101120000-aaaaaaa", "%bar%")
+	if 2 != len(ret) || "20240101120000-bbbbbbb" != ret[0].ID || "20240101120000-ccccccc" != ret[1].ID {
+		t.Fatalf("unexpected blocks %v", ret)
+	}
+
+	spans := []*Span{
+		{ID: "20240101120000-eeeeeee", BlockID: "20240101120000-bbbbbbb", Content: "foo", Type: "textmark strong"},
+		{ID: "20240101120000-fffffff", BlockID: "20240101120000-bbbbbbb", Content: "bar", Type: "textmark a"},
+		{ID: "20240101120000-ggggggg", BlockID: "20240101120000-ddddddd", Content: "baz", Type: "textmark a"},
+	}
+	for _, s := range spans {
+		if _, err := db.Exec("INSERT INTO spans (id, block_id, root_id, box, path, content, markdown, type, ial) VALUES (?, ?, '', '', '', ?, '', ?, '')",
+			s.ID, s.BlockID, s.Content, s.Type); err != nil {
+			t.Fatalf("insert span failed: %s", err)
+		}
+	}
+
+	retSpans := SelectSpansRawStmt("SELECT * FROM spans WHERE block_id = ? AND (' ' || type || ' ') LIKE ?", 8, "20240101120000-bbbbbbb", "% a %")
+	if 1 != len(retSpans) || "20240101120000-fffffff" != retSpans[0].ID {
+		t.Fatalf("unexpected spans %v", retSpans)
+	}
+	if retSpans = SelectSpansRawStmt("SELECT * FROM spans WHERE (' ' || type || ' ') LIKE ? ORDER BY id", 1, "% textmark %"); 1 != len(retSpans) {
+		t.Fatalf("unexpected spans %v", retSpans)
+	}
+
+	result, err := Query("SELECT COUNT(id) AS `matches` FROM blocks WHERE type = ? AND (' ' || content || ' ') LIKE ?", 1, "p", "% foo %")
+	if nil != err || 1 != len(result) || int64(2) != result[0]["matches"] {
+		t.Fatalf("unexpected result %v: %v", result, err)
+	}
+}
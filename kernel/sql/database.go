@@ -87,6 +87,8 @@ func InitDatabase(forceRebuild bool) (err error) {
 	if !forceRebuild {
 		// 检查数据库结构版本，如果版本不一致的话说明改过表结构，需要重建
 		if util.DatabaseVer == getDatabaseVer() {
+			initAttributeViewTables()
+			initEmbeddingTables()
 			return
 		}
 		logging.LogInfof("the database structure is changed, rebuilding database...")
@@ -219,6 +221,10 @@ func initDBTables() {
 	if err != nil {
 		logging.LogFatalf(logging.ExitCodeReadOnlyDatabase, "create table [refs] failed: %s", err)
 	}
+
+	dropAttributeViewTables()
+	initAttributeViewTables()
+	initEmbeddingTables()
 }
 
 func initDBConnection() {
@@ -1517,4 +1523,18 @@ func SQLTemplateFuncs(templateFuncMap *template.FuncMap) {
 		ret, _ = Query(stmt, 1024)
 		return
 	}
+
+	// 以下函数将参数绑定到语句中的 ? 占位符上，参数无需转义，也不需要在占位符两侧加引号
+	(*templateFuncMap)["queryBlocksWithArgs"] = func(stmt string, args ...interface{}) (retBlocks []*Block) {
+		retBlocks = SelectBlocksRawStmt(stmt, 1, 512, args...)
+		return
+	}
+	(*templateFuncMap)["querySpansWithArgs"] = func(stmt string, args ...interface{}) (retSpans []*Span) {
+		retSpans = SelectSpansRawStmt(stmt, 512, args...)
+		return
+	}
+	(*templateFuncMap)["querySQLWithArgs"] = func(stmt string, args ...interface{}) (ret []map[string]interface{}) {
+		ret, _ = Query(stmt, 1024, args...)
+		return
+	}
 }
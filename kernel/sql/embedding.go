@@ -0,0 +1,390 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package sql
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/siyuan-note/logging"
+	"github.com/siyuan-note/siyuan/kernel/util"
+)
+
+// EmbeddingProvider 用于生成文本的向量表示。
+type EmbeddingProvider interface {
+	// Model 返回模型名称，模型变化后已有的向量会失效并重新生成。
+	Model() string
+
+	// Embed 按顺序返回每段文本的向量。
+	Embed(texts []string) ([][]float32, error)
+}
+
+// EmbeddingBlockTypes 参与语义搜索的块类型，列表、列表项、超级块和引述块的内容和子块重复，所以不生成向量。
+const EmbeddingBlockTypes = "('d', 'h', 'p', 'c', 'm', 't', 'html')"
+
+const (
+	embeddingBatchSize    = 32
+	embeddingMaxTextLen   = 2048
+	embeddingRetryBackoff = time.Minute
+	embeddingMaxFailures  = 3         // 块连续失败的次数达到上限后暂时跳过，避免服务始终拒绝的块反复重试
+	embeddingFailureTTL   = time.Hour // 跳过的块在这段时间后重新尝试
+)
+
+var (
+	embeddingProvider     EmbeddingProvider
+	embeddingProviderLock = sync.RWMutex{}
+	embeddingSignal       = make(chan bool, 1)
+	embeddingWorkerOnce   = sync.Once{}
+
+	ErrEmbeddingProviderNotSet = errors.New("embedding provider is not set")
+)
+
+// embeddingFailure 记录块生成向量失败的情况，块内容变化后重新计数。
+type embeddingFailure struct {
+	hash  string
+	count int
+	last  time.Time
+}
+
+var (
+	embeddingFailures     = map[string]*embeddingFailure{}
+	embeddingFailuresLock = sync.Mutex{}
+)
+
+// SetEmbeddingProvider 设置向量生成服务，传入 nil 时停止生成向量。
+func SetEmbeddingProvider(provider EmbeddingProvider) {
+	embeddingProviderLock.Lock()
+	embeddingProvider = provider
+	embeddingProviderLock.Unlock()
+
+	embeddingFailuresLock.Lock()
+	embeddingFailures = map[string]*embeddingFailure{}
+	embeddingFailuresLock.Unlock()
+
+	if nil == provider {
+		return
+	}
+
+	embeddingWorkerOnce.Do(func() {
+		go embeddingWorker()
+	})
+	signalEmbedding()
+}
+
+func getEmbeddingProvider() EmbeddingProvider {
+	embeddingProviderLock.RLock()
+	defer embeddingProviderLock.RUnlock()
+	return embeddingProvider
+}
+
+// initEmbeddingTables 创建向量表，已有的库在表结构版本不变时不会重建，所以这里使用 IF NOT EXISTS。
+func initEmbeddingTables() {
+	_, err := db.Exec("CREATE TABLE IF NOT EXISTS blocks_embeddings (id, root_id, hash, model, vector)")
+	if err != nil {
+		logging.LogFatalf(logging.ExitCodeReadOnlyDatabase, "create table [blocks_embeddings] failed: %s", err)
+	}
+	_, err = db.Exec("CREATE INDEX IF NOT EXISTS idx_blocks_embeddings_id ON blocks_embeddings(id)")
+	if err != nil {
+		logging.LogFatalf(logging.ExitCodeReadOnlyDatabase, "create index [idx_blocks_embeddings_id] failed: %s", err)
+	}
+	_, err = db.Exec("CREATE INDEX IF NOT EXISTS idx_blocks_embeddings_root_id ON blocks_embeddings(root_id)")
+	if err != nil {
+		logging.LogFatalf(logging.ExitCodeReadOnlyDatabase, "create index [idx_blocks_embeddings_root_id] failed: %s", err)
+	}
+}
+
+// syncEmbeddings 在队列操作提交前清理失效的向量，新增和变更的块在队列提交后由后台生成向量。
+func syncEmbeddings(tx *sql.Tx, op *dbQueueOperation) (err error) {
+	switch op.action {
+	case "index", "upsert":
+		tree := op.upsertTree
+		if "index" == op.action {
+			tree = op.indexTree
+		}
+		// 块被删除或者内容哈希变化后向量失效
+		stmt := "DELETE FROM blocks_embeddings WHERE root_id = ? AND NOT EXISTS (SELECT 1 FROM blocks WHERE blocks.id = blocks_embeddings.id AND blocks.hash = blocks_embeddings.hash)"
+		err = execStmtTx(tx, stmt, tree.ID)
+	case "update_block_content":
+		err = execStmtTx(tx, "DELETE FROM blocks_embeddings WHERE id = ?", op.block.ID)
+	case "delete_id":
+		err = execStmtTx(tx, "DELETE FROM blocks_embeddings WHERE root_id = ?", op.removeTreeID)
+	case "delete_ids":
+		for _, rootID := range op.removeTreeIDs {
+			if err = execStmtTx(tx, "DELETE FROM blocks_embeddings WHERE root_id = ?", rootID); err != nil {
+				return
+			}
+		}
+	case "delete", "delete_box":
+		err = execStmtTx(tx, "DELETE FROM blocks_embeddings WHERE NOT EXISTS (SELECT 1 FROM blocks WHERE blocks.id = blocks_embeddings.id)")
+	}
+	return
+}
+
+func signalEmbedding() {
+	select {
+	case embeddingSignal <- true:
+	default:
+	}
+}
+
+func embeddingWorker() {
+	for range embeddingSignal {
+		for {
+			if util.IsExiting.Load() {
+				return
+			}
+
+			provider := getEmbeddingProvider()
+			if nil == provider {
+				break
+			}
+
+			done, err := embedPendingBlocks(provider)
+			if err != nil {
+				logging.LogErrorf("generate block embeddings failed: %s", err)
+				time.Sleep(embeddingRetryBackoff)
+				break
+			}
+			if done {
+				break
+			}
+		}
+	}
+}
+
+// embedPendingBlocks 为一批还没有向量的块生成向量，没有待处理的块时返回 done。
+func embedPendingBlocks(provider EmbeddingProvider) (done bool, err error) {
+	model := provider.Model()
+	args := []interface{}{model}
+	stmt := "SELECT id, root_id, hash, content FROM blocks WHERE type IN " + EmbeddingBlockTypes + " AND content <> '' AND NOT EXISTS (SELECT 1 FROM blocks_embeddings WHERE blocks_embeddings.id = blocks.id AND blocks_embeddings.model = ?)"
+	if skipped := skippedEmbeddingBlocks(); 0 < len(skipped) {
+		stmt += " AND id || hash NOT IN (" + strings.Repeat("?, ", len(skipped)-1) + "?)"
+		args = append(args, skipped...)
+	}
+	stmt += " LIMIT ?"
+	args = append(args, embeddingBatchSize)
+	rows, err := query(stmt, args...)
+	if err != nil {
+		return
+	}
+
+	var ids, rootIDs, hashes, texts []string
+	for rows.Next() {
+		var id, rootID, hash, content string
+		if err = rows.Scan(&id, &rootID, &hash, &content); err != nil {
+			rows.Close()
+			return
+		}
+		ids = append(ids, id)
+		rootIDs = append(rootIDs, rootID)
+		hashes = append(hashes, hash)
+		if runes := []rune(content); embeddingMaxTextLen < len(runes) {
+			content = string(runes[:embeddingMaxTextLen])
+		}
+		texts = append(texts, content)
+	}
+	rows.Close()
+	if 1 > len(ids) {
+		done = true
+		return
+	}
+
+	vectors, err := provider.Embed(texts)
+	if nil == err && len(vectors) != len(ids) {
+		err = errors.New("embedding count mismatch")
+	}
+	if err != nil {
+		addEmbeddingFailures(ids, hashes)
+		return
+	}
+
+	txLock.Lock()
+	defer txLock.Unlock()
+
+	tx, err := beginTx()
+	if err != nil {
+		return
+	}
+	for i, id := range ids {
+		if err = execStmtTx(tx, "DELETE FROM blocks_embeddings WHERE id = ?", id); err != nil {
+			tx.Rollback()
+			return
+		}
+		// 生成向量期间块可能已经被修改或者删除，哈希不一致时不写入，由下一批重新生成
+		stmt = "INSERT INTO blocks_embeddings (id, root_id, hash, model, vector) SELECT ?, ?, ?, ?, ? WHERE EXISTS (SELECT 1 FROM blocks WHERE id = ? AND hash = ?)"
+		if err = execStmtTx(tx, stmt, id, rootIDs[i], hashes[i], model, encodeEmbedding(vectors[i]), id, hashes[i]); err != nil {
+			tx.Rollback()
+			return
+		}
+	}
+	if err = commitTx(tx); err != nil {
+		return
+	}
+	removeEmbeddingFailures(ids)
+	return
+}
+
+func addEmbeddingFailures(ids, hashes []string) {
+	embeddingFailuresLock.Lock()
+	defer embeddingFailuresLock.Unlock()
+
+	now := time.Now()
+	for i, id := range ids {
+		failure := embeddingFailures[id]
+		if nil == failure || failure.hash != hashes[i] {
+			failure = &embeddingFailure{hash: hashes[i]}
+			embeddingFailures[id] = failure
+		}
+		failure.count++
+		failure.last = now
+		if embeddingMaxFailures == failure.count {
+			logging.LogWarnf("skip block [%s] embedding for [%s] after [%d] failures", id, embeddingFailureTTL, failure.count)
+		}
+	}
+}
+
+func removeEmbeddingFailures(ids []string) {
+	embeddingFailuresLock.Lock()
+	defer embeddingFailuresLock.Unlock()
+
+	for _, id := range ids {
+		delete(embeddingFailures, id)
+	}
+}
+
+// skippedEmbeddingBlocks 返回需要跳过的块，元素为块 ID 和内容哈希的拼接。
+func skippedEmbeddingBlocks() (ret []interface{}) {
+	embeddingFailuresLock.Lock()
+	defer embeddingFailuresLock.Unlock()
+
+	for id, failure := range embeddingFailures {
+		if embeddingFailureTTL < time.Since(failure.last) {
+			// 到期后重新尝试，再次失败时会立即跳过
+			failure.count = embeddingMaxFailures - 1
+			continue
+		}
+		if embeddingMaxFailures <= failure.count {
+			ret = append(ret, id+failure.hash)
+		}
+	}
+	return
+}
+
+// SemanticSearchBlockIDs 按余弦相似度降序返回和查询文本最相近的块 ID，filter 为 blocks 表上的过滤条件，以 AND 开头。
+func SemanticSearchBlockIDs(text, filter string, limit int) (ret []string, err error) {
+	provider := getEmbeddingProvider()
+	if nil == provider {
+		err = ErrEmbeddingProviderNotSet
+		return
+	}
+
+	vectors, err := provider.Embed([]string{text})
+	if err != nil {
+		return
+	}
+	if 1 > len(vectors) {
+		return
+	}
+	queryVector := vectors[0]
+
+	stmt := "SELECT id, vector FROM blocks_embeddings WHERE model = ? AND id IN (SELECT id FROM blocks WHERE 1 = 1 " + filter + ")"
+	rows, err := query(stmt, provider.Model())
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	type scored struct {
+		id    string
+		score float64
+	}
+	var results []*scored
+	for rows.Next() {
+		var id string
+		var data []byte
+		if err = rows.Scan(&id, &data); err != nil {
+			return
+		}
+		results = append(results, &scored{id: id, score: cosineSimilarity(queryVector, decodeEmbedding(data))})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool { return results[i].score > results[j].score })
+	for i, r := range results {
+		if i >= limit {
+			break
+		}
+		ret = append(ret, r.id)
+	}
+	return
+}
+
+// CountEmbeddings 返回已经生成向量的块数量和待生成向量的块数量。
+func CountEmbeddings() (embedded, pending int) {
+	provider := getEmbeddingProvider()
+	if nil == provider {
+		return
+	}
+
+	row := queryRow("SELECT COUNT(*) FROM blocks_embeddings WHERE model = ?", provider.Model())
+	if nil != row {
+		row.Scan(&embedded)
+	}
+	row = queryRow("SELECT COUNT(*) FROM blocks WHERE type IN "+EmbeddingBlockTypes+" AND content <> '' AND NOT EXISTS (SELECT 1 FROM blocks_embeddings WHERE blocks_embeddings.id = blocks.id AND blocks_embeddings.model = ?)", provider.Model())
+	if nil != row {
+		row.Scan(&pending)
+	}
+	return
+}
+
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) || 1 > len(a) {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if 0 == normA || 0 == normB {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+func encodeEmbedding(vector []float32) []byte {
+	ret := make([]byte, 4*len(vector))
+	for i, v := range vector {
+		binary.LittleEndian.PutUint32(ret[i*4:], math.Float32bits(v))
+	}
+	return ret
+}
+
+func decodeEmbedding(data []byte) (ret []float32) {
+	ret = make([]float32, len(data)/4)
+	for i := range ret {
+		ret[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:]))
+	}
+	return
+}
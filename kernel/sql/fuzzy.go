@@ -0,0 +1,283 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package sql
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode"
+
+	"github.com/siyuan-note/logging"
+)
+
+const (
+	fuzzyMaxWordLen        = 32               // 超过该长度的词（比如链接和哈希）不加入词表
+	fuzzyVocabularyRefresh = 10 * time.Minute // 数据库变更后词表最多每隔这段时间重建一次
+)
+
+// fuzzyCJKGramLens 是中日文连续文本切分的片段长度，中日文词之间没有分隔符，所以按固定长度切分后加入词表。
+var fuzzyCJKGramLens = []int{3, 4}
+
+// fuzzyVocabulary 是块内容中的词表，按词的字符数分组，用于查找拼写相近的词。
+type fuzzyVocabulary struct {
+	words      map[int]map[string]int // 字符数 -> 词 -> 出现次数，区分大小写
+	lowerWords map[int]map[string]int // 同 words，词统一转为小写
+	version    uint64
+	built      time.Time
+}
+
+var (
+	fuzzyVocab          *fuzzyVocabulary
+	fuzzyVocabLock      = sync.Mutex{} // 仅保护 fuzzyVocab 的读写，构建词表时不持有
+	fuzzyVocabBuildLock = sync.Mutex{} // 保证同一时间只有一个构建词表的任务
+	fuzzyVocabBuilding  = atomic.Bool{}
+	fuzzyVocabVersion   = atomic.Uint64{} // 数据库变更后递增，词表版本落后时按时间间隔重建
+)
+
+// FuzzyTerms 查找块内容中和 term 拼写相近的词，按编辑距离升序、出现次数降序返回，不包含 term 本身。
+//
+// siyuan 分词器按字符切分，全文索引词表中只有单个字符，所以这里在内存中维护块内容的词表，
+// 只和字符数相差不超过最大编辑距离的词计算编辑距离。
+func FuzzyTerms(term string, caseSensitive bool, limit int) (ret []string) {
+	term = strings.TrimSpace(term)
+	runes := []rune(term)
+	if 3 > len(runes) {
+		return
+	}
+
+	maxDist := 1
+	if 4 < len(runes) {
+		maxDist = 2
+	}
+
+	vocab := getFuzzyVocabulary()
+	if nil == vocab {
+		return
+	}
+	words := vocab.words
+	if !caseSensitive {
+		words = vocab.lowerWords
+	}
+
+	type candidate struct {
+		term  string
+		dist  int
+		count int
+	}
+	var candidates []*candidate
+	lowerTerm := strings.ToLower(term)
+	for l := len(runes) - maxDist; l <= len(runes)+maxDist; l++ {
+		for word, count := range words[l] {
+			lowerWord := strings.ToLower(word)
+			if lowerWord == lowerTerm {
+				continue
+			}
+			if dist := editDistance(lowerTerm, lowerWord, maxDist); dist <= maxDist {
+				candidates = append(candidates, &candidate{term: word, dist: dist, count: count})
+			}
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].dist != candidates[j].dist {
+			return candidates[i].dist < candidates[j].dist
+		}
+		if candidates[i].count != candidates[j].count {
+			return candidates[i].count > candidates[j].count
+		}
+		return candidates[i].term < candidates[j].term
+	})
+	for i, c := range candidates {
+		if i >= limit {
+			break
+		}
+		ret = append(ret, c.term)
+	}
+	return
+}
+
+// getFuzzyVocabulary 获取词表，首次使用时同步构建。
+//
+// 数据库变更后超过刷新间隔时在后台重建，重建期间继续使用旧词表，搜索不需要等待。
+func getFuzzyVocabulary() *fuzzyVocabulary {
+	fuzzyVocabLock.Lock()
+	vocab := fuzzyVocab
+	fuzzyVocabLock.Unlock()
+
+	if nil == vocab {
+		return rebuildFuzzyVocabulary()
+	}
+
+	if vocab.version != fuzzyVocabVersion.Load() && time.Since(vocab.built) >= fuzzyVocabularyRefresh {
+		if fuzzyVocabBuilding.CompareAndSwap(false, true) {
+			go func() {
+				defer fuzzyVocabBuilding.Store(false)
+				rebuildFuzzyVocabulary()
+			}()
+		}
+	}
+	return vocab
+}
+
+func rebuildFuzzyVocabulary() *fuzzyVocabulary {
+	fuzzyVocabBuildLock.Lock()
+	defer fuzzyVocabBuildLock.Unlock()
+
+	fuzzyVocabLock.Lock()
+	vocab := fuzzyVocab
+	fuzzyVocabLock.Unlock()
+	version := fuzzyVocabVersion.Load()
+	if nil != vocab && vocab.version == version {
+		// 等待构建锁期间其他任务已经构建好了
+		return vocab
+	}
+
+	newVocab, err := buildFuzzyVocabulary()
+	if err != nil {
+		logging.LogErrorf("build fuzzy vocabulary failed: %s", err)
+		return vocab
+	}
+	newVocab.version = version
+
+	fuzzyVocabLock.Lock()
+	fuzzyVocab = newVocab
+	fuzzyVocabLock.Unlock()
+	return newVocab
+}
+
+func buildFuzzyVocabulary() (ret *fuzzyVocabulary, err error) {
+	rows, err := query("SELECT content FROM blocks WHERE content <> ''")
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	ret = &fuzzyVocabulary{words: map[int]map[string]int{}, lowerWords: map[int]map[string]int{}, built: time.Now()}
+	for rows.Next() {
+		var content string
+		if err = rows.Scan(&content); err != nil {
+			return nil, err
+		}
+
+		for _, word := range splitFuzzyWords(content) {
+			l := len([]rune(word))
+			if 2 > l || fuzzyMaxWordLen < l {
+				continue
+			}
+			addFuzzyWord(ret.words, l, word)
+			addFuzzyWord(ret.lowerWords, l, strings.ToLower(word))
+		}
+	}
+	err = rows.Err()
+	return
+}
+
+// splitFuzzyWords 将内容切分为词，中日文连续文本和其他文字分开，并按 fuzzyCJKGramLens 切分为片段。
+func splitFuzzyWords(content string) (ret []string) {
+	var word, cjk []rune
+	flush := func() {
+		if 0 < len(word) {
+			ret = append(ret, string(word))
+			word = word[:0]
+		}
+		if 0 < len(cjk) {
+			for _, n := range fuzzyCJKGramLens {
+				for i := 0; i+n <= len(cjk); i++ {
+					ret = append(ret, string(cjk[i:i+n]))
+				}
+			}
+			cjk = cjk[:0]
+		}
+	}
+
+	for _, r := range content {
+		switch {
+		case isFuzzyCJK(r):
+			if 0 < len(word) {
+				ret = append(ret, string(word))
+				word = word[:0]
+			}
+			cjk = append(cjk, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if 0 < len(cjk) {
+				flush()
+			}
+			word = append(word, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return
+}
+
+func isFuzzyCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r)
+}
+
+func addFuzzyWord(words map[int]map[string]int, l int, word string) {
+	group := words[l]
+	if nil == group {
+		group = map[string]int{}
+		words[l] = group
+	}
+	group[word]++
+}
+
+// invalidateFuzzyVocabulary 在数据库变更后标记词表过期。
+func invalidateFuzzyVocabulary() {
+	fuzzyVocabVersion.Add(1)
+}
+
+// editDistance 计算 Damerau-Levenshtein（相邻交换）编辑距离，超过 maxDist 时提前返回 maxDist+1。
+func editDistance(a, b string, maxDist int) int {
+	ra, rb := []rune(a), []rune(b)
+	if diff := len(ra) - len(rb); diff > maxDist || -diff > maxDist {
+		return maxDist + 1
+	}
+
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if 1 < i && 1 < j && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				curr[j] = min(curr[j], prev2[j-2]+1)
+			}
+			rowMin = min(rowMin, curr[j])
+		}
+		if rowMin > maxDist {
+			return maxDist + 1
+		}
+		prev2, prev, curr = prev, curr, prev2
+	}
+	return prev[len(rb)]
+}
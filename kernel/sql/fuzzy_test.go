@@ -0,0 +1,73 @@
+// SiYuan - Refactor your thinking
+// Copyright (c) 2020-present, b3log.org
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+//go:build fts5
+
+package sql
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/siyuan-note/siyuan/kernel/util"
+)
+
+func TestSplitFuzzyWords(t *testing.T) {
+	got := splitFuzzyWords("使用golang编写数据库, hello-world")
+	expected := []string{"golang", "编写数", "写数据", "数据库", "编写数据", "写数据库", "hello", "world"}
+	if !reflect.DeepEqual(expected, got) {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestFuzzyTerms(t *testing.T) {
+	util.DBPath = filepath.Join(t.TempDir(), "siyuan.db")
+	initDBConnection()
+	initDBTables()
+	defer closeDatabase()
+	fuzzyVocab = nil
+	defer func() { fuzzyVocab = nil }()
+
+	for i, content := range []string{"使用数据库存储笔记", "Database and databases", "数据库索引"} {
+		if _, err := db.Exec("INSERT INTO blocks (id, parent_id, root_id, hash, box, path, hpath, name, alias, memo, tag, content, fcontent, markdown, length, type, subtype, ial, sort, created, updated) VALUES (?, '', '', '', '', '', '', '', '', '', '', ?, '', '', 0, 'p', '', '', 0, '', '')",
+			"20240101120000-aaaaaa"+string(rune('a'+i)), content); err != nil {
+			t.Fatalf("insert block failed: %s", err)
+		}
+	}
+
+	if terms := FuzzyTerms("数据苦", false, 3); 1 > len(terms) || "数据库" != terms[0] {
+		t.Fatalf("unexpected terms %v", terms)
+	}
+	if terms := FuzzyTerms("databse", false, 3); 1 > len(terms) || "database" != terms[0] {
+		t.Fatalf("unexpected terms %v", terms)
+	}
+
+	// 词表过期后在后台重建，重建期间仍然使用旧词表
+	old := getFuzzyVocabulary()
+	old.built = old.built.Add(-fuzzyVocabularyRefresh)
+	invalidateFuzzyVocabulary()
+	if getFuzzyVocabulary() != old {
+		t.Fatalf("expected stale vocabulary while rebuilding")
+	}
+	for deadline := time.Now().Add(5 * time.Second); fuzzyVocabBuilding.Load() && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if getFuzzyVocabulary() == old {
+		t.Fatalf("expected rebuilt vocabulary")
+	}
+}
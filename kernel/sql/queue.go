@@ -40,7 +40,7 @@ var (
 
 type dbQueueOperation struct {
 	inQueueTime                   time.Time
-	action                        string      // upsert/delete/delete_id/rename/rename_sub_tree/delete_box/delete_box_refs/index/delete_ids/update_block_content/delete_assets
+	action                        string      // upsert/delete/delete_id/rename/rename_sub_tree/delete_box/delete_box_refs/index/delete_ids/update_block_content/delete_assets/index_av
 	indexTree                     *parse.Tree // index
 	upsertTree                    *parse.Tree // upsert/update_refs/delete_refs
 	removeTreeBox, removeTreePath string      // delete
@@ -51,6 +51,7 @@ type dbQueueOperation struct {
 	block                         *Block      // update_block_content
 	id                            string      // index_node
 	removeAssetHashes             []string    // delete_assets
+	avID                          string      // index_av
 }
 
 func FlushTxJob() {
@@ -106,6 +107,12 @@ func FlushQueue() {
 			continue
 		}
 
+		if err = syncEmbeddings(tx, op); err != nil {
+			tx.Rollback()
+			logging.LogErrorf("sync embeddings of queue operation [%s] failed: %s", op.action, err)
+			continue
+		}
+
 		if err = commitTx(tx); err != nil {
 			logging.LogErrorf("commit tx failed: %s", err)
 			continue
@@ -129,6 +136,11 @@ func FlushQueue() {
 	util.BroadcastByType("main", "databaseIndexCommit", 0, "", nil)
 
 	eventbus.Publish(eventbus.EvtSQLIndexFlushed)
+	invalidateFuzzyVocabulary()
+
+	if nil != getEmbeddingProvider() {
+		signalEmbedding()
+	}
 }
 
 func execOp(op *dbQueueOperation, tx *sql.Tx, context map[string]interface{}) (err error) {
@@ -165,6 +177,8 @@ func execOp(op *dbQueueOperation, tx *sql.Tx, context map[string]interface{}) (e
 		err = deleteAssetsByHashes(tx, op.removeAssetHashes)
 	case "index_node":
 		err = indexNode(tx, op.id)
+	case "index_av":
+		err = indexAttributeView(tx, op.avID)
 	default:
 		msg := fmt.Sprintf("unknown operation [%s]", op.action)
 		logging.LogErrorf(msg)
@@ -187,6 +201,21 @@ func IndexNodeQueue(id string) {
 	appendOperation(newOp)
 }
 
+// IndexAttributeViewQueue 重新索引数据库的字段和值，数据库已经被删除时会移除其索引。
+func IndexAttributeViewQueue(avID string) {
+	dbQueueLock.Lock()
+	defer dbQueueLock.Unlock()
+
+	newOp := &dbQueueOperation{avID: avID, inQueueTime: time.Now(), action: "index_av"}
+	for i, op := range operationQueue {
+		if "index_av" == op.action && op.avID == avID {
+			operationQueue[i] = newOp
+			return
+		}
+	}
+	appendOperation(newOp)
+}
+
 func BatchRemoveAssetsQueue(hashes []string) {
 	if 1 > len(hashes) {
 		return
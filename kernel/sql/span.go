@@ -37,7 +37,14 @@ type Span struct {
 	IAL      string
 }
 
-func SelectSpansRawStmt(stmt string, limit int) (ret []*Span) {
+// SelectSpansRawStmt 执行行级元素查询语句，args 为语句中 ? 占位符绑定的参数。
+//
+// 解析器会将 ? 占位符改写为 :v1 这样的命名参数，所以有绑定参数时不改写语句。
+func SelectSpansRawStmt(stmt string, limit int, args ...interface{}) (ret []*Span) {
+	if 0 < len(args) {
+		return selectSpansRawStmt(stmt, limit, args...)
+	}
+
 	parsedStmt, err := sqlparser.Parse(stmt)
 	if err != nil {
 		//logging.LogErrorf("select [%s] failed: %s", stmt, err)
@@ -64,7 +71,7 @@ func SelectSpansRawStmt(stmt string, limit int) (ret []*Span) {
 	stmt = strings.ReplaceAll(stmt, "\\\"", "\"")
 	stmt = strings.ReplaceAll(stmt, "\\\\*", "\\*")
 	stmt = strings.ReplaceAll(stmt, "from dual", "")
-	rows, err := query(stmt)
+	rows, err := query(stmt, args...)
 	if err != nil {
 		if strings.Contains(err.Error(), "syntax error") {
 			return
@@ -80,6 +87,27 @@ func SelectSpansRawStmt(stmt string, limit int) (ret []*Span) {
 	return
 }
 
+func selectSpansRawStmt(stmt string, limit int, args ...interface{}) (ret []*Span) {
+	rows, err := query(stmt, args...)
+	if err != nil {
+		if strings.Contains(err.Error(), "syntax error") {
+			return
+		}
+		logging.LogWarnf("sql query [%s] failed: %s", stmt, err)
+		return
+	}
+	defer rows.Close()
+
+	noLimit := !containsLimitClause(stmt)
+	for rows.Next() {
+		if noLimit && limit <= len(ret) {
+			break
+		}
+		ret = append(ret, scanSpanRows(rows))
+	}
+	return
+}
+
 func QueryTagSpansByLabel(label string) (ret []*Span) {
 	stmt := "SELECT * FROM spans WHERE type LIKE '%tag%' AND content LIKE '%" + label + "%' GROUP BY block_id"
 	rows, err := query(stmt)
@@ -35,7 +35,15 @@ type SessionData struct {
 
 type WorkspaceSession struct {
 	AccessAuthCode string
+	Username       string // 通过用户帐号登录时的用户名
 	Captcha        string
+
+	OIDCEmail   string   // 通过 OpenID Connect 登录时的邮箱
+	OIDCSubject string   // 通过 OpenID Connect 登录时的用户标识
+	OIDCGroups  []string // 通过 OpenID Connect 登录时的用户组
+	OIDCState   string   // OpenID Connect 登录过程中的 state
+	OIDCNonce   string   // OpenID Connect 登录过程中的 nonce
+	OIDCTo      string   // OpenID Connect 登录完成后跳转的地址
 }
 
 // Save saves the current session of the specified context.